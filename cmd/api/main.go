@@ -11,6 +11,7 @@ import (
 	"pharmacy-management-backend/delivery/route"
 	"pharmacy-management-backend/infrastructure"
 	"pharmacy-management-backend/infrastructure/middleware"
+	"pharmacy-management-backend/migrations"
 	"pharmacy-management-backend/repository"
 	"pharmacy-management-backend/usecase"
 	"pharmacy-management-backend/utils"
@@ -20,6 +21,10 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// lowStockQueueSize is how many sales can wait for a low stock check before
+// further checks are dropped
+const lowStockQueueSize = 256
+
 func main() {
 	// Initialize logger
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
@@ -38,6 +43,27 @@ func main() {
 	}
 	defer db.Close()
 
+	// Apply or verify schema migrations
+	migrator, err := infrastructure.NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to load migrations")
+	}
+	if cfg.AutoMigrate {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to apply migrations")
+		}
+		logger.Info().Int("applied", applied).Msg("Database schema is up to date")
+	} else {
+		pending, err := migrator.Pending(context.Background())
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to check migrations")
+		}
+		if len(pending) > 0 {
+			logger.Fatal().Int("pending", len(pending)).Msg("Database schema is out of date; run cmd/migrate up or set AUTO_MIGRATE=true")
+		}
+	}
+
 	// Initialize Twilio service
 	twilioService := infrastructure.NewTwilioService(cfg, logger)
 
@@ -54,14 +80,28 @@ func main() {
 	medicineRepo := repository.NewMedicineRepository(db, logger)
 	saleRepo := repository.NewSaleRepository(db, logger)
 	orderRepo := repository.NewOrderRepository(db, logger)
+	inventoryRepo := repository.NewInventoryRepository(db, logger)
+	supplierRepo := repository.NewSupplierRepository(db, logger)
+	purchaseOrderRepo := repository.NewPurchaseOrderRepository(db, logger)
+	promotionRepo := repository.NewPromotionRepository(db, logger)
+	shiftRepo := repository.NewShiftRepository(db, logger)
+	customerRepo := repository.NewCustomerRepository(db, logger)
 
 	// Initialize use cases
 	authUsecase := usecase.NewAuthUsecase(authRepo, twilioService, cfg)
 	userUsecase := usecase.NewUserUsecase(authRepo)
 	pharmacyUsecase := usecase.NewPharmacyUsecase(pharmacyRepo)
 	medicineUsecase := usecase.NewMedicineUsecase(medicineRepo, pharmacyRepo)
-	saleUsecase := usecase.NewSaleUsecase(saleRepo, medicineRepo)
+	inventoryUsecase := usecase.NewInventoryUsecase(inventoryRepo, medicineRepo, authRepo, twilioService)
+	lowStock := make(chan usecase.LowStockCheck, lowStockQueueSize)
+	saleUsecase := usecase.NewSaleUsecase(saleRepo, medicineRepo, pharmacyRepo, promotionRepo, shiftRepo, customerRepo, lowStock,
+		cfg.IdempotencyKeyTTL, cfg.CartReservationTTL, cfg.PublicURL+"/api/receipts/verify")
 	orderUsecase := usecase.NewOrderUsecase(orderRepo)
+	supplierUsecase := usecase.NewSupplierUsecase(supplierRepo)
+	purchaseOrderUsecase := usecase.NewPurchaseOrderUsecase(purchaseOrderRepo, supplierRepo, medicineRepo)
+	promotionUsecase := usecase.NewPromotionUsecase(promotionRepo, medicineRepo, saleRepo)
+	shiftUsecase := usecase.NewShiftUsecase(shiftRepo)
+	customerUsecase := usecase.NewCustomerUsecase(customerRepo, saleRepo)
 
 	// Initialize Gin router
 	router := gin.Default()
@@ -70,7 +110,39 @@ func main() {
 	router.Use(middleware.LoggerMiddleware(logger))
 
 	// Set up routes
-	route.SetupRoutes(router, authUsecase, userUsecase, pharmacyUsecase, medicineUsecase, saleUsecase, orderUsecase, cfg, v)
+	route.SetupRoutes(router, authUsecase, userUsecase, pharmacyUsecase, medicineUsecase, saleUsecase, orderUsecase, inventoryUsecase, supplierUsecase, purchaseOrderUsecase, promotionUsecase, shiftUsecase, customerUsecase, cfg, v)
+
+	// Release cart reservations as they run out
+	sweepCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	go func() {
+		ticker := time.NewTicker(cfg.ReservationSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sweepCtx.Done():
+				return
+			case <-ticker.C:
+				released, err := saleUsecase.ReleaseExpiredReservations(sweepCtx)
+				if err != nil {
+					logger.Error().Err(err).Msg("Failed to release expired cart reservations")
+				} else if released > 0 {
+					logger.Info().Int("released", released).Msg("Released expired cart reservations")
+				}
+			}
+		}
+	}()
+
+	// Check sold variants against their reorder points, one sale at a time
+	lowStockDone := make(chan struct{})
+	go func() {
+		defer close(lowStockDone)
+		for check := range lowStock {
+			if err := inventoryUsecase.NotifyLowStock(context.Background(), check.PharmacyID, check.VariantIDs); err != nil {
+				logger.Error().Err(err).Str("pharmacy_id", check.PharmacyID.String()).Msg("Failed to send low stock alerts")
+			}
+		}
+	}()
 
 	// Start server with graceful shutdown
 	srv := &http.Server{
@@ -96,5 +168,14 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Fatal().Err(err).Msg("Server shutdown failed")
 	}
+
+	// No sale can queue a check any more; finish the ones already queued
+	stopSweeper()
+	close(lowStock)
+	select {
+	case <-lowStockDone:
+	case <-ctx.Done():
+		logger.Warn().Int("pending", len(lowStock)).Msg("Gave up on queued low stock checks")
+	}
 	logger.Info().Msg("Server shutdown complete")
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"pharmacy-management-backend/config"
+	"pharmacy-management-backend/infrastructure"
+	"pharmacy-management-backend/migrations"
+
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up             apply all pending migrations
+  down           roll back the most recent migration
+  status         list migrations and whether they are applied
+  to <version>   migrate up or down to the given version (0 rolls back everything)`
+
+func main() {
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	logger := zerolog.New(zerolog.NewConsoleWriter()).With().Timestamp().Logger()
+
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	// Initialize database
+	db, err := infrastructure.NewDatabase(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to initialize database")
+	}
+	defer db.Close()
+
+	migrator, err := infrastructure.NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Failed to load migrations")
+	}
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Migration failed")
+		}
+		logger.Info().Int("applied", applied).Msg("Migrations complete")
+	case "down":
+		if err := migrator.Down(ctx); err != nil {
+			logger.Fatal().Err(err).Msg("Rollback failed")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Failed to read migration status")
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+	case "to":
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		target, err := strconv.Atoi(os.Args[2])
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Invalid target version")
+		}
+		if err := migrator.To(ctx, target); err != nil {
+			logger.Fatal().Err(err).Msg("Migration failed")
+		}
+		logger.Info().Int("version", target).Msg("Migrations complete")
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
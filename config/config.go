@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +17,17 @@ type Config struct {
 	TwilioToken string
 	TwilioFrom  string
 	MockTwilio  bool
+	AutoMigrate bool
+	// IdempotencyKeyTTL is how long a sale's Idempotency-Key can be replayed
+	IdempotencyKeyTTL time.Duration
+	// CartReservationTTL is how long an item added to a cart holds its stock
+	CartReservationTTL time.Duration
+	// ReservationSweepInterval is how often expired cart reservations are
+	// released
+	ReservationSweepInterval time.Duration
+	// PublicURL is where customers reach the API, for links printed on
+	// receipts
+	PublicURL string
 }
 
 // Load loads configuration from environment variables
@@ -28,6 +41,12 @@ func Load() (*Config, error) {
 		TwilioToken: getEnv("TWILIO_TOKEN", ""),
 		TwilioFrom:  getEnv("TWILIO_FROM", ""),
 		MockTwilio:  getEnvBool("TWILIO_MOCK", false),
+		AutoMigrate: getEnvBool("AUTO_MIGRATE", false),
+
+		IdempotencyKeyTTL:        getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		CartReservationTTL:       getEnvDuration("CART_RESERVATION_TTL", 15*time.Minute),
+		ReservationSweepInterval: getEnvDuration("RESERVATION_SWEEP_INTERVAL", time.Minute),
+		PublicURL:                strings.TrimRight(getEnv("PUBLIC_URL", "http://localhost:8080"), "/"),
 	}
 	return cfg, nil
 }
@@ -47,3 +66,14 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvDuration retrieves an environment variable as a duration such as
+// "24h", falling back to the default if it is unset or invalid
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/usecase"
+	"pharmacy-management-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// CustomerHandler handles customer-related HTTP requests
+type CustomerHandler struct {
+	usecase   usecase.CustomerUsecase
+	validator *validator.Validate
+}
+
+// NewCustomerHandler creates a new CustomerHandler
+func NewCustomerHandler(usecase usecase.CustomerUsecase, validator *validator.Validate) *CustomerHandler {
+	return &CustomerHandler{usecase, validator}
+}
+
+// Create handles POST /api/customers
+func (h *CustomerHandler) Create(c *gin.Context) {
+	var input domain.CustomerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	customer, err := h.usecase.Create(c.Request.Context(), role.(string), pharmacyID, input)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, customer)
+}
+
+// Search handles GET /api/customers?q=, matching names and phone numbers
+func (h *CustomerHandler) Search(c *gin.Context) {
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	customers, err := h.usecase.Search(c.Request.Context(), role.(string), pharmacyID, c.Query("q"))
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, customers)
+}
+
+// GetByID handles GET /api/customers/:id
+func (h *CustomerHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid customer ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	customer, err := h.usecase.GetByID(c.Request.Context(), role.(string), pharmacyID, id)
+	if err != nil {
+		switch err {
+		case domain.ErrCustomerNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// Update handles PUT /api/customers/:id
+func (h *CustomerHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid customer ID"))
+		return
+	}
+
+	var input domain.CustomerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	customer, err := h.usecase.Update(c.Request.Context(), role.(string), pharmacyID, id, input)
+	if err != nil {
+		switch err {
+		case domain.ErrCustomerNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, customer)
+}
+
+// Delete handles DELETE /api/customers/:id
+func (h *CustomerHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid customer ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	if err := h.usecase.Delete(c.Request.Context(), role.(string), pharmacyID, id); err != nil {
+		switch err {
+		case domain.ErrCustomerNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted successfully"})
+}
+
+// GetPurchases handles GET /api/customers/:id/purchases, paged by limit (at
+// most 100) and offset
+func (h *CustomerHandler) GetPurchases(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid customer ID"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid limit"))
+		return
+	}
+	limit = min(limit, maxPageSize)
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid offset"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	page, err := h.usecase.GetPurchases(c.Request.Context(), role.(string), pharmacyID, id, limit, offset)
+	if err != nil {
+		switch err {
+		case domain.ErrCustomerNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
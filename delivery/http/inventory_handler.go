@@ -0,0 +1,181 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/usecase"
+	"pharmacy-management-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// InventoryHandler handles stock-level HTTP requests
+type InventoryHandler struct {
+	usecase   usecase.InventoryUsecase
+	validator *validator.Validate
+}
+
+// NewInventoryHandler creates a new InventoryHandler
+func NewInventoryHandler(usecase usecase.InventoryUsecase, validator *validator.Validate) *InventoryHandler {
+	return &InventoryHandler{usecase, validator}
+}
+
+// GetLowStock handles GET /api/inventory/low-stock
+func (h *InventoryHandler) GetLowStock(c *gin.Context) {
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	items, err := h.usecase.GetLowStock(c.Request.Context(), role.(string), pharmacyID)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// GetExpiring handles GET /api/inventory/expiring?within=90d
+func (h *InventoryHandler) GetExpiring(c *gin.Context) {
+	within, err := parseWithin(c.DefaultQuery("within", "90d"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	medicines, err := h.usecase.GetExpiring(c.Request.Context(), role.(string), pharmacyID, within)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, medicines)
+}
+
+// CreateWriteOff handles POST /api/inventory/write-offs
+func (h *InventoryHandler) CreateWriteOff(c *gin.Context) {
+	var input domain.CreateWriteOffInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	writeOff, err := h.usecase.WriteOff(c.Request.Context(), role.(string), userID, pharmacyID, input)
+	if err != nil {
+		switch err {
+		case domain.ErrLotNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrLotNotExpired, domain.ErrInsufficientStock:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, writeOff)
+}
+
+// GetWriteOffs handles GET /api/inventory/write-offs?from=2024-01-01&to=2024-02-01,
+// defaulting to the current month
+func (h *InventoryHandler) GetWriteOffs(c *gin.Context) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 1, 0)
+
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, fromStr, time.Local)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid from date"))
+			return
+		}
+		from = parsed
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, toStr, time.Local)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid to date"))
+			return
+		}
+		to = parsed
+	}
+	if !to.After(from) {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("to must be after from"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	report, err := h.usecase.GetWriteOffs(c.Request.Context(), role.(string), pharmacyID, from, to)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseWithin parses a window such as "90d", "12w" or a Go duration like "36h"
+func parseWithin(s string) (time.Duration, error) {
+	invalid := errors.New("invalid within; use e.g. 90d, 12w or 36h")
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil || days < 0 {
+			return 0, invalid
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	if n, ok := strings.CutSuffix(s, "w"); ok {
+		weeks, err := strconv.Atoi(n)
+		if err != nil || weeks < 0 {
+			return 0, invalid
+		}
+		return time.Duration(weeks) * 7 * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, invalid
+	}
+	return d, nil
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"pharmacy-management-backend/domain"
 	"pharmacy-management-backend/usecase"
@@ -187,10 +188,12 @@ func (h *MedicineHandler) CreateVariant(c *gin.Context) {
 	}
 
 	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
 	pharmacyIDStr, _ := c.Get("pharmacy_id")
 	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
 
-	if err := h.usecase.CreateVariant(c.Request.Context(), role.(string), pharmacyID, medicineID, input); err != nil {
+	if err := h.usecase.CreateVariant(c.Request.Context(), role.(string), userID, pharmacyID, medicineID, input); err != nil {
 		switch err {
 		case domain.ErrMedicineNotFound:
 			utils.ErrorResponse(c, http.StatusNotFound, err)
@@ -336,6 +339,8 @@ func (h *MedicineHandler) DeleteVariant(c *gin.Context) {
 			utils.ErrorResponse(c, http.StatusNotFound, err)
 		case domain.ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrVariantHasHistory:
+			utils.ErrorResponse(c, http.StatusConflict, err)
 		default:
 			utils.ErrorResponse(c, http.StatusInternalServerError, err)
 		}
@@ -344,3 +349,221 @@ func (h *MedicineHandler) DeleteVariant(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Medicine variant deleted successfully"})
 }
+
+// CreateLot handles POST /api/medicines/:id/variants/:variant_id/lots
+func (h *MedicineHandler) CreateLot(c *gin.Context) {
+	idStr := c.Param("id")
+	medicineID, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid medicine ID"))
+		return
+	}
+
+	variantIDStr := c.Param("variant_id")
+	variantID, err := uuid.Parse(variantIDStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid variant ID"))
+		return
+	}
+
+	var input domain.CreateMedicineLotInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	if err := h.usecase.CreateLot(c.Request.Context(), role.(string), userID, pharmacyID, medicineID, variantID, input); err != nil {
+		switch err {
+		case domain.ErrVariantNotFound, domain.ErrMedicineNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrLotNumberTaken:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Medicine lot created successfully"})
+}
+
+// GetLots handles GET /api/medicines/:id/variants/:variant_id/lots
+func (h *MedicineHandler) GetLots(c *gin.Context) {
+	idStr := c.Param("id")
+	medicineID, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid medicine ID"))
+		return
+	}
+
+	variantIDStr := c.Param("variant_id")
+	variantID, err := uuid.Parse(variantIDStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid variant ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	lots, err := h.usecase.GetLots(c.Request.Context(), role.(string), pharmacyID, medicineID, variantID)
+	if err != nil {
+		switch err {
+		case domain.ErrVariantNotFound, domain.ErrMedicineNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, lots)
+}
+
+// AdjustStock handles POST /api/medicines/:id/variants/:variant_id/adjustments
+func (h *MedicineHandler) AdjustStock(c *gin.Context) {
+	idStr := c.Param("id")
+	medicineID, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid medicine ID"))
+		return
+	}
+
+	variantIDStr := c.Param("variant_id")
+	variantID, err := uuid.Parse(variantIDStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid variant ID"))
+		return
+	}
+
+	var input domain.StockAdjustmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	movement, err := h.usecase.AdjustStock(c.Request.Context(), role.(string), userID, pharmacyID, medicineID, variantID, input)
+	if err != nil {
+		switch err {
+		case domain.ErrVariantNotFound, domain.ErrMedicineNotFound, domain.ErrLotNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrInvalidAdjustment:
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		case domain.ErrInsufficientStock:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, movement)
+}
+
+// GetStockMovements handles GET /api/medicines/:id/variants/:variant_id/movements
+func (h *MedicineHandler) GetStockMovements(c *gin.Context) {
+	idStr := c.Param("id")
+	medicineID, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid medicine ID"))
+		return
+	}
+
+	variantIDStr := c.Param("variant_id")
+	variantID, err := uuid.Parse(variantIDStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid variant ID"))
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid limit"))
+		return
+	}
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid offset"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	movements, err := h.usecase.GetStockMovements(c.Request.Context(), role.(string), pharmacyID, medicineID, variantID, limit, offset)
+	if err != nil {
+		switch err {
+		case domain.ErrVariantNotFound, domain.ErrMedicineNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, movements)
+}
+
+// GetCatalogChanges handles GET /api/sync/catalog, returning the catalog
+// changes after ?cursor= (omit it for the whole catalog), up to ?limit=
+func (h *MedicineHandler) GetCatalogChanges(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "500"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid limit; use 1 to 1000"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	delta, err := h.usecase.GetCatalogChanges(c.Request.Context(), role.(string), pharmacyID, c.Query("cursor"), limit)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidCursor:
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, delta)
+}
@@ -136,6 +136,92 @@ func (h *PharmacyHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Pharmacy updated successfully"})
 }
 
+// UpdateTaxSettings handles PUT /api/pharmacies/:id/tax-settings
+func (h *PharmacyHandler) UpdateTaxSettings(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid pharmacy ID"))
+		return
+	}
+
+	var input domain.TaxSettings
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Validate input
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	// Admins have no pharmacy in their token
+	pharmacyIDVal, _ := c.Get("pharmacy_id")
+	pharmacyIDStr, _ := pharmacyIDVal.(string)
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr)
+
+	pharmacy, err := h.usecase.UpdateTaxSettings(c.Request.Context(), role.(string), pharmacyID, id, input)
+	if err != nil {
+		switch err {
+		case domain.ErrNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, pharmacy)
+}
+
+// UpdateReceiptSettings handles PUT /api/pharmacies/:id/receipt-settings
+func (h *PharmacyHandler) UpdateReceiptSettings(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid pharmacy ID"))
+		return
+	}
+
+	var input domain.ReceiptSettings
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Validate input
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	// Admins have no pharmacy in their token
+	pharmacyIDVal, _ := c.Get("pharmacy_id")
+	pharmacyIDStr, _ := pharmacyIDVal.(string)
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr)
+
+	pharmacy, err := h.usecase.UpdateReceiptSettings(c.Request.Context(), role.(string), pharmacyID, id, input)
+	if err != nil {
+		switch err {
+		case domain.ErrNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, pharmacy)
+}
+
 // Delete handles DELETE /api/pharmacies/:id
 func (h *PharmacyHandler) Delete(c *gin.Context) {
 	idStr := c.Param("id")
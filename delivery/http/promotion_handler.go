@@ -0,0 +1,238 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/usecase"
+	"pharmacy-management-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// PromotionHandler handles promotion and manual discount HTTP requests
+type PromotionHandler struct {
+	usecase   usecase.PromotionUsecase
+	validator *validator.Validate
+}
+
+// NewPromotionHandler creates a new PromotionHandler
+func NewPromotionHandler(usecase usecase.PromotionUsecase, validator *validator.Validate) *PromotionHandler {
+	return &PromotionHandler{usecase, validator}
+}
+
+// Create handles POST /api/promotions
+func (h *PromotionHandler) Create(c *gin.Context) {
+	var input domain.PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	promotion, err := h.usecase.Create(c.Request.Context(), role.(string), pharmacyID, input)
+	if err != nil {
+		promotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, promotion)
+}
+
+// GetAll handles GET /api/promotions
+func (h *PromotionHandler) GetAll(c *gin.Context) {
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	promotions, err := h.usecase.GetAll(c.Request.Context(), role.(string), pharmacyID)
+	if err != nil {
+		promotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotions)
+}
+
+// GetByID handles GET /api/promotions/:id
+func (h *PromotionHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid promotion ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	promotion, err := h.usecase.GetByID(c.Request.Context(), role.(string), pharmacyID, id)
+	if err != nil {
+		promotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// Update handles PUT /api/promotions/:id
+func (h *PromotionHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid promotion ID"))
+		return
+	}
+
+	var input domain.PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	promotion, err := h.usecase.Update(c.Request.Context(), role.(string), pharmacyID, id, input)
+	if err != nil {
+		promotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, promotion)
+}
+
+// Delete handles DELETE /api/promotions/:id
+func (h *PromotionHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid promotion ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	if err := h.usecase.Delete(c.Request.Context(), role.(string), pharmacyID, id); err != nil {
+		promotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted successfully"})
+}
+
+// RequestManualDiscount handles POST /api/cart/discounts
+func (h *PromotionHandler) RequestManualDiscount(c *gin.Context) {
+	var input domain.ManualDiscountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	discount, err := h.usecase.RequestManualDiscount(c.Request.Context(), role.(string), userID, pharmacyID, input)
+	if err != nil {
+		promotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, discount)
+}
+
+// GetManualDiscounts handles GET /api/manual-discounts?status=pending
+func (h *PromotionHandler) GetManualDiscounts(c *gin.Context) {
+	status := domain.ManualDiscountStatus(c.Query("status"))
+	switch status {
+	case "", domain.ManualDiscountPending, domain.ManualDiscountApproved, domain.ManualDiscountRejected:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid status"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	discounts, err := h.usecase.GetManualDiscounts(c.Request.Context(), role.(string), pharmacyID, status)
+	if err != nil {
+		promotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, discounts)
+}
+
+// ApproveManualDiscount handles PUT /api/manual-discounts/:id/approve
+func (h *PromotionHandler) ApproveManualDiscount(c *gin.Context) {
+	h.reviewManualDiscount(c, true)
+}
+
+// RejectManualDiscount handles PUT /api/manual-discounts/:id/reject
+func (h *PromotionHandler) RejectManualDiscount(c *gin.Context) {
+	h.reviewManualDiscount(c, false)
+}
+
+func (h *PromotionHandler) reviewManualDiscount(c *gin.Context, approve bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid manual discount ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	discount, err := h.usecase.ReviewManualDiscount(c.Request.Context(), role.(string), userID, pharmacyID, id, approve)
+	if err != nil {
+		promotionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, discount)
+}
+
+// promotionError writes the response for a promotion usecase error
+func promotionError(c *gin.Context, err error) {
+	switch err {
+	case domain.ErrPromotionNotFound, domain.ErrManualDiscountNotFound, domain.ErrMedicineNotFound, domain.ErrCartItemNotFound:
+		utils.ErrorResponse(c, http.StatusNotFound, err)
+	case domain.ErrUnauthorized:
+		utils.ErrorResponse(c, http.StatusForbidden, err)
+	case domain.ErrInvalidPromotion, domain.ErrInvalidManualDiscount:
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+	case domain.ErrManualDiscountReviewed:
+		utils.ErrorResponse(c, http.StatusConflict, err)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+	}
+}
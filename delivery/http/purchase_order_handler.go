@@ -0,0 +1,258 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/usecase"
+	"pharmacy-management-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// PurchaseOrderHandler handles purchasing HTTP requests
+type PurchaseOrderHandler struct {
+	usecase   usecase.PurchaseOrderUsecase
+	validator *validator.Validate
+}
+
+// NewPurchaseOrderHandler creates a new PurchaseOrderHandler
+func NewPurchaseOrderHandler(usecase usecase.PurchaseOrderUsecase, validator *validator.Validate) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{usecase, validator}
+}
+
+// Create handles POST /api/purchase-orders
+func (h *PurchaseOrderHandler) Create(c *gin.Context) {
+	var input domain.PurchaseOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	order, err := h.usecase.Create(c.Request.Context(), role.(string), userID, pharmacyID, input)
+	if err != nil {
+		switch err {
+		case domain.ErrSupplierNotFound, domain.ErrVariantNotFound, domain.ErrMedicineNotFound:
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// GetAll handles GET /api/purchase-orders?status=sent&limit=50&offset=0
+func (h *PurchaseOrderHandler) GetAll(c *gin.Context) {
+	status := domain.PurchaseOrderStatus(c.Query("status"))
+	switch status {
+	case "", domain.PurchaseOrderDraft, domain.PurchaseOrderSent, domain.PurchaseOrderPartiallyReceived,
+		domain.PurchaseOrderReceived, domain.PurchaseOrderCancelled:
+	default:
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid status"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid limit"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid offset"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	orders, err := h.usecase.GetAll(c.Request.Context(), role.(string), pharmacyID, status, limit, offset)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+// GetByID handles GET /api/purchase-orders/:id
+func (h *PurchaseOrderHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid purchase order ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	order, err := h.usecase.GetByID(c.Request.Context(), role.(string), pharmacyID, id)
+	if err != nil {
+		switch err {
+		case domain.ErrPurchaseOrderNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// Update handles PUT /api/purchase-orders/:id
+func (h *PurchaseOrderHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid purchase order ID"))
+		return
+	}
+
+	var input domain.PurchaseOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	order, err := h.usecase.Update(c.Request.Context(), role.(string), pharmacyID, id, input)
+	if err != nil {
+		switch err {
+		case domain.ErrPurchaseOrderNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrSupplierNotFound, domain.ErrVariantNotFound, domain.ErrMedicineNotFound:
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrInvalidPurchaseOrderStatus:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// UpdateStatus handles PUT /api/purchase-orders/:id/status
+func (h *PurchaseOrderHandler) UpdateStatus(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid purchase order ID"))
+		return
+	}
+
+	var input domain.UpdatePurchaseOrderStatusInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	order, err := h.usecase.UpdateStatus(c.Request.Context(), role.(string), pharmacyID, id, input)
+	if err != nil {
+		switch err {
+		case domain.ErrPurchaseOrderNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrInvalidPurchaseOrderStatus:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// Receive handles POST /api/purchase-orders/:id/receipts
+func (h *PurchaseOrderHandler) Receive(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid purchase order ID"))
+		return
+	}
+
+	var input domain.ReceiveGoodsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	receipt, err := h.usecase.Receive(c.Request.Context(), role.(string), userID, pharmacyID, id, input)
+	if err != nil {
+		switch err {
+		case domain.ErrPurchaseOrderNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrPurchaseOrderItemNotFound:
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrInvalidPurchaseOrderStatus, domain.ErrOverReceipt, domain.ErrLotNumberTaken:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, receipt)
+}
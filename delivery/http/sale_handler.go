@@ -4,8 +4,10 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/infrastructure/receipt"
 	"pharmacy-management-backend/usecase"
 	"pharmacy-management-backend/utils"
 
@@ -142,59 +144,336 @@ func (h *SaleHandler) RemoveFromCart(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Item removed from cart"})
 }
 
-// ConfirmSale handles POST /api/sales
+// UpdateCartItem handles PUT /api/cart/:item_id, setting the line's quantity
+func (h *SaleHandler) UpdateCartItem(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("item_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid cart item ID"))
+		return
+	}
+
+	var input domain.UpdateCartItemInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	if err := h.usecase.UpdateCartItem(c.Request.Context(), role.(string), userID, pharmacyID, itemID, input); err != nil {
+		switch err {
+		case domain.ErrCartItemNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrInsufficientStock:
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cart item updated"})
+}
+
+// ClearCart handles DELETE /api/cart
+func (h *SaleHandler) ClearCart(c *gin.Context) {
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	if err := h.usecase.ClearCart(c.Request.Context(), role.(string), userID); err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared"})
+}
+
+// ParkCart handles POST /api/cart/park, setting the cart aside under a label
+func (h *SaleHandler) ParkCart(c *gin.Context) {
+	var input domain.ParkCartInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	parked, err := h.usecase.ParkCart(c.Request.Context(), role.(string), userID, pharmacyID, input)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrCartEmpty:
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, parked)
+}
+
+// GetParkedCarts handles GET /api/cart/parked
+func (h *SaleHandler) GetParkedCarts(c *gin.Context) {
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	parked, err := h.usecase.GetParkedCarts(c.Request.Context(), role.(string), userID, pharmacyID)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, parked)
+}
+
+// ResumeCart handles POST /api/cart/parked/:id/resume, returning the resumed
+// cart
+func (h *SaleHandler) ResumeCart(c *gin.Context) {
+	parkedCartID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid parked cart ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	cart, err := h.usecase.ResumeCart(c.Request.Context(), role.(string), userID, pharmacyID, parkedCartID)
+	if err != nil {
+		switch err {
+		case domain.ErrParkedCartNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrCartNotEmpty:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// ConfirmSale handles POST /api/sales. Retries that send the same
+// Idempotency-Key header and body get the original sale back.
 func (h *SaleHandler) ConfirmSale(c *gin.Context) {
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	if len(idempotencyKey) > 255 {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("idempotency key must be at most 255 characters"))
+		return
+	}
+
+	var input domain.ConfirmSaleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
 	role, _ := c.Get("role")
 	userIDStr, _ := c.Get("user_id")
 	userID, _ := uuid.Parse(userIDStr.(string))
 	pharmacyIDStr, _ := c.Get("pharmacy_id")
 	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
 
-	sale, err := h.usecase.ConfirmSale(c.Request.Context(), role.(string), userID, pharmacyID)
+	sale, err := h.usecase.ConfirmSale(c.Request.Context(), role.(string), userID, pharmacyID, idempotencyKey, input)
 	if err != nil {
 		switch err {
 		case domain.ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, err)
-		case domain.ErrInsufficientStock:
+		case domain.ErrIdempotencyKeyInUse, domain.ErrManualDiscountUsed, domain.ErrNoOpenShift, domain.ErrShiftClosed:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		case domain.ErrCartEmpty, domain.ErrInsufficientStock, domain.ErrInsufficientPayment, domain.ErrOverpayment, domain.ErrTenderReferenceRequired:
 			utils.ErrorResponse(c, http.StatusBadRequest, err)
-		case domain.ErrSaleNotFound:
+		case domain.ErrSaleNotFound, domain.ErrCustomerNotFound:
 			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrIdempotencyKeyReused:
+			utils.ErrorResponse(c, http.StatusUnprocessableEntity, err)
 		default:
 			utils.ErrorResponse(c, http.StatusInternalServerError, err)
 		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Sale confirmed", "sale_id": sale.ID})
+	c.JSON(http.StatusCreated, sale)
 }
 
-// GetSales handles GET /api/sales
-func (h *SaleHandler) GetSales(c *gin.Context) {
-	limitStr := c.DefaultQuery("limit", "20")
-	offsetStr := c.DefaultQuery("offset", "0")
+// SyncSales handles POST /api/sync/sales, recording a batch of sales made
+// offline and returning each one's result in the order sent
+func (h *SaleHandler) SyncSales(c *gin.Context) {
+	var input domain.SyncSalesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
 
-	limit, err := strconv.Atoi(limitStr)
+	results, err := h.usecase.SyncSales(c.Request.Context(), role.(string), userID, pharmacyID, input)
 	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrShiftClosed:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// maxPageSize caps the limit of paged listings
+const maxPageSize = 100
+
+// GetSales handles GET /api/sales, listing sale headers. Sales can be
+// filtered by ?from=2024-01-01&to=2024-02-01 (to exclusive), user_id,
+// customer_id, medicine_id, min_total and max_total, sorted by
+// ?sort=date|-date|total|-total and paged by limit (at most 100) and offset.
+func (h *SaleHandler) GetSales(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 0 {
 		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid limit"))
 		return
 	}
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil {
+	limit = min(limit, maxPageSize)
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
 		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid offset"))
 		return
 	}
 
+	filter := domain.SaleFilter{Sort: domain.SaleSort(c.DefaultQuery("sort", string(domain.SaleSortDateDesc))), Limit: limit, Offset: offset}
+	if !filter.Sort.IsValid() {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid sort; use date, -date, total or -total"))
+		return
+	}
+	for name, date := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid "+name+" date"))
+				return
+			}
+			*date = parsed
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.To.After(filter.From) {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("to must be after from"))
+		return
+	}
+	for name, id := range map[string]*uuid.UUID{"user_id": &filter.UserID, "customer_id": &filter.CustomerID, "medicine_id": &filter.MedicineID} {
+		if value := c.Query(name); value != "" {
+			parsed, err := uuid.Parse(value)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid "+name))
+				return
+			}
+			*id = parsed
+		}
+	}
+	for name, amount := range map[string]**domain.Money{"min_total": &filter.MinTotal, "max_total": &filter.MaxTotal} {
+		if value := c.Query(name); value != "" {
+			parsed, err := domain.ParseMoney(value)
+			if err != nil {
+				utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid "+name))
+				return
+			}
+			*amount = &parsed
+		}
+	}
+
 	role, _ := c.Get("role")
 	pharmacyIDStr, _ := c.Get("pharmacy_id")
-	pharmacyID, err := uuid.Parse(pharmacyIDStr.(string))
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	page, err := h.usecase.GetSales(c.Request.Context(), role.(string), pharmacyID, filter)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid pharmacy ID"))
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
 		return
 	}
 
-	sales, err := h.usecase.GetSales(c.Request.Context(), role.(string), pharmacyID, limit, offset)
+	c.JSON(http.StatusOK, page)
+}
+
+// GetSale handles GET /api/sales/:id
+func (h *SaleHandler) GetSale(c *gin.Context) {
+	saleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid sale ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	sale, err := h.usecase.GetSale(c.Request.Context(), role.(string), pharmacyID, saleID)
 	if err != nil {
 		switch err {
+		case domain.ErrSaleNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
 		case domain.ErrUnauthorized:
 			utils.ErrorResponse(c, http.StatusForbidden, err)
 		default:
@@ -203,10 +482,11 @@ func (h *SaleHandler) GetSales(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, sales)
+	c.JSON(http.StatusOK, sale)
 }
 
-// GetReceipt handles GET /api/sales/:id/receipt
+// GetReceipt handles GET /api/sales/:id/receipt. With
+// ?format=html|pdf|escpos|text the receipt is rendered for printing instead.
 func (h *SaleHandler) GetReceipt(c *gin.Context) {
 	saleIDStr := c.Param("id")
 	saleID, err := uuid.Parse(saleIDStr)
@@ -219,6 +499,11 @@ func (h *SaleHandler) GetReceipt(c *gin.Context) {
 	pharmacyIDStr, _ := c.Get("pharmacy_id")
 	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
 
+	if format := c.Query("format"); format != "" && format != "json" {
+		h.renderReceipt(c, role.(string), pharmacyID, saleID, format)
+		return
+	}
+
 	receipt, err := h.usecase.GetReceipt(c.Request.Context(), role.(string), pharmacyID, saleID)
 	if err != nil {
 		switch err {
@@ -232,16 +517,212 @@ func (h *SaleHandler) GetReceipt(c *gin.Context) {
 		return
 	}
 
-	// Map the Receipt data to the flattened ReceiptResponse struct
-	response := domain.ReceiptResponse{
-		ID:         receipt.ID,
-		SaleID:     receipt.SaleID,
-		Items:      receipt.Content.Items,
-		PharmacyID: receipt.Content.PharmacyID,
-		SaleDate:   receipt.Content.SaleDate,
-		TotalPrice: receipt.Content.TotalPrice,
-		CreatedAt:  receipt.CreatedAt,
+	c.JSON(http.StatusOK, receiptResponse(receipt))
+}
+
+// GetReceiptByNumber handles GET /api/receipts/:number
+func (h *SaleHandler) GetReceiptByNumber(c *gin.Context) {
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	receipt, err := h.usecase.GetReceiptByNumber(c.Request.Context(), role.(string), pharmacyID, c.Param("number"))
+	if err != nil {
+		switch err {
+		case domain.ErrReceiptNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if format := c.Query("format"); format != "" && format != "json" {
+		h.renderReceipt(c, role.(string), pharmacyID, receipt.SaleID, format)
+		return
+	}
+	c.JSON(http.StatusOK, receiptResponse(receipt))
+}
+
+// VerifyReceipt handles GET /api/receipts/verify, the link in a receipt's QR
+// code
+func (h *SaleHandler) VerifyReceipt(c *gin.Context) {
+	pharmacyID, err := uuid.Parse(c.Query("pharmacy_id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid pharmacy ID"))
+		return
+	}
+	number, hash := c.Query("number"), c.Query("hash")
+	if number == "" || hash == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("number and hash are required"))
+		return
+	}
+
+	result, err := h.usecase.VerifyReceipt(c.Request.Context(), pharmacyID, number, hash)
+	if err != nil {
+		switch err {
+		case domain.ErrReceiptNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// VerifyReceiptChain handles GET /api/pharmacies/:id/receipt-chain
+func (h *SaleHandler) VerifyReceiptChain(c *gin.Context) {
+	pharmacyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid pharmacy ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	// Admins have no pharmacy in their token
+	callerPharmacyIDVal, _ := c.Get("pharmacy_id")
+	callerPharmacyIDStr, _ := callerPharmacyIDVal.(string)
+	callerPharmacyID, _ := uuid.Parse(callerPharmacyIDStr)
+
+	report, err := h.usecase.VerifyReceiptChain(c.Request.Context(), role.(string), callerPharmacyID, pharmacyID)
+	if err != nil {
+		switch err {
+		case domain.ErrNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// receiptResponse maps a Receipt to the flattened ReceiptResponse struct,
+// which is what the customer is given
+func receiptResponse(receipt *domain.Receipt) domain.ReceiptResponse {
+	content := receipt.Content.CustomerCopy()
+	return domain.ReceiptResponse{
+		ID:            receipt.ID,
+		SaleID:        receipt.SaleID,
+		Number:        receipt.Number,
+		Hash:          receipt.Hash,
+		VerifyURL:     receipt.VerifyURL,
+		Items:         content.Items,
+		PharmacyID:    content.PharmacyID,
+		SaleDate:      content.SaleDate,
+		TotalPrice:    content.TotalPrice,
+		TotalNet:      content.TotalNet,
+		TotalTax:      content.TotalTax,
+		TaxLines:      content.TaxLines,
+		TotalDiscount: content.TotalDiscount,
+		Discounts:     content.Discounts,
+		Payments:      content.Payments,
+		Change:        content.Change,
+		CreatedAt:     receipt.CreatedAt,
+	}
+}
+
+// renderReceipt writes a sale's receipt rendered in the named format
+func (h *SaleHandler) renderReceipt(c *gin.Context, role string, pharmacyID, saleID uuid.UUID, name string) {
+	format, err := receipt.ParseFormat(name)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	body, err := h.usecase.RenderReceipt(c.Request.Context(), role, pharmacyID, saleID, format)
+	if err != nil {
+		switch err {
+		case domain.ErrSaleNotFound, domain.ErrNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if format == receipt.FormatPDF {
+		c.Header("Content-Disposition", `inline; filename="receipt-`+saleID.String()+`.pdf"`)
+	}
+	c.Data(http.StatusOK, format.ContentType(), body)
+}
+
+// CreateReturn handles POST /api/sales/:id/returns
+func (h *SaleHandler) CreateReturn(c *gin.Context) {
+	saleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid sale ID"))
+		return
+	}
+
+	var input domain.CreateSaleReturnInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	saleReturn, err := h.usecase.CreateReturn(c.Request.Context(), role.(string), userID, pharmacyID, saleID, input)
+	if err != nil {
+		switch err {
+		case domain.ErrSaleNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrSaleItemNotFound:
+			utils.ErrorResponse(c, http.StatusBadRequest, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrReturnExceedsSold, domain.ErrLotNotFound, domain.ErrNoOpenShift, domain.ErrShiftClosed:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, saleReturn)
+}
+
+// GetReturns handles GET /api/sales/:id/returns
+func (h *SaleHandler) GetReturns(c *gin.Context) {
+	saleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid sale ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	returns, err := h.usecase.GetReturns(c.Request.Context(), role.(string), pharmacyID, saleID)
+	if err != nil {
+		switch err {
+		case domain.ErrSaleNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, returns)
 }
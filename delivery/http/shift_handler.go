@@ -0,0 +1,166 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/usecase"
+	"pharmacy-management-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// ShiftHandler handles cashier shift HTTP requests
+type ShiftHandler struct {
+	usecase   usecase.ShiftUsecase
+	validator *validator.Validate
+}
+
+// NewShiftHandler creates a new ShiftHandler
+func NewShiftHandler(usecase usecase.ShiftUsecase, validator *validator.Validate) *ShiftHandler {
+	return &ShiftHandler{usecase, validator}
+}
+
+// Open handles POST /api/shifts
+func (h *ShiftHandler) Open(c *gin.Context) {
+	var input domain.OpenShiftInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	shift, err := h.usecase.Open(c.Request.Context(), role.(string), userID, pharmacyID, input)
+	if err != nil {
+		shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, shift)
+}
+
+// GetCurrent handles GET /api/shifts/current
+func (h *ShiftHandler) GetCurrent(c *gin.Context) {
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+
+	shift, err := h.usecase.GetCurrent(c.Request.Context(), role.(string), userID)
+	if err != nil {
+		shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shift)
+}
+
+// Close handles POST /api/shifts/:id/close
+func (h *ShiftHandler) Close(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid shift ID"))
+		return
+	}
+
+	var input domain.CloseShiftInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	userIDStr, _ := c.Get("user_id")
+	userID, _ := uuid.Parse(userIDStr.(string))
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	report, err := h.usecase.Close(c.Request.Context(), role.(string), userID, pharmacyID, id, input)
+	if err != nil {
+		shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetAll handles GET /api/shifts
+func (h *ShiftHandler) GetAll(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid limit"))
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid offset"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	shifts, err := h.usecase.GetAll(c.Request.Context(), role.(string), pharmacyID, limit, offset)
+	if err != nil {
+		shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, shifts)
+}
+
+// GetReport handles GET /api/shifts/:id/report
+func (h *ShiftHandler) GetReport(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid shift ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	report, err := h.usecase.GetReport(c.Request.Context(), role.(string), pharmacyID, id)
+	if err != nil {
+		shiftError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// shiftError writes the response for a shift usecase error
+func shiftError(c *gin.Context, err error) {
+	switch err {
+	case domain.ErrShiftNotFound, domain.ErrNoOpenShift:
+		utils.ErrorResponse(c, http.StatusNotFound, err)
+	case domain.ErrUnauthorized:
+		utils.ErrorResponse(c, http.StatusForbidden, err)
+	case domain.ErrCashCountRequired, domain.ErrDuplicateCount:
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+	case domain.ErrShiftAlreadyOpen, domain.ErrShiftClosed:
+		utils.ErrorResponse(c, http.StatusConflict, err)
+	default:
+		utils.ErrorResponse(c, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/usecase"
+	"pharmacy-management-backend/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// SupplierHandler handles supplier-related HTTP requests
+type SupplierHandler struct {
+	usecase   usecase.SupplierUsecase
+	validator *validator.Validate
+}
+
+// NewSupplierHandler creates a new SupplierHandler
+func NewSupplierHandler(usecase usecase.SupplierUsecase, validator *validator.Validate) *SupplierHandler {
+	return &SupplierHandler{usecase, validator}
+}
+
+// Create handles POST /api/suppliers
+func (h *SupplierHandler) Create(c *gin.Context) {
+	var input domain.SupplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	supplier, err := h.usecase.Create(c.Request.Context(), role.(string), pharmacyID, input)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, supplier)
+}
+
+// GetAll handles GET /api/suppliers
+func (h *SupplierHandler) GetAll(c *gin.Context) {
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	suppliers, err := h.usecase.GetAll(c.Request.Context(), role.(string), pharmacyID)
+	if err != nil {
+		switch err {
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, suppliers)
+}
+
+// GetByID handles GET /api/suppliers/:id
+func (h *SupplierHandler) GetByID(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid supplier ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	supplier, err := h.usecase.GetByID(c.Request.Context(), role.(string), pharmacyID, id)
+	if err != nil {
+		switch err {
+		case domain.ErrSupplierNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+// Update handles PUT /api/suppliers/:id
+func (h *SupplierHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid supplier ID"))
+		return
+	}
+
+	var input domain.SupplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := h.validator.Struct(input); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err)
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	supplier, err := h.usecase.Update(c.Request.Context(), role.(string), pharmacyID, id, input)
+	if err != nil {
+		switch err {
+		case domain.ErrSupplierNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+// Delete handles DELETE /api/suppliers/:id
+func (h *SupplierHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, errors.New("invalid supplier ID"))
+		return
+	}
+
+	role, _ := c.Get("role")
+	pharmacyIDStr, _ := c.Get("pharmacy_id")
+	pharmacyID, _ := uuid.Parse(pharmacyIDStr.(string))
+
+	if err := h.usecase.Delete(c.Request.Context(), role.(string), pharmacyID, id); err != nil {
+		switch err {
+		case domain.ErrSupplierNotFound:
+			utils.ErrorResponse(c, http.StatusNotFound, err)
+		case domain.ErrUnauthorized:
+			utils.ErrorResponse(c, http.StatusForbidden, err)
+		case domain.ErrSupplierHasPurchaseOrders:
+			utils.ErrorResponse(c, http.StatusConflict, err)
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Supplier deleted successfully"})
+}
@@ -19,6 +19,12 @@ func SetupRoutes(
 	medicineUsecase usecase.MedicineUsecase,
 	saleUsecase usecase.SaleUsecase,
 	orderUsecase usecase.OrderUsecase,
+	inventoryUsecase usecase.InventoryUsecase,
+	supplierUsecase usecase.SupplierUsecase,
+	purchaseOrderUsecase usecase.PurchaseOrderUsecase,
+	promotionUsecase usecase.PromotionUsecase,
+	shiftUsecase usecase.ShiftUsecase,
+	customerUsecase usecase.CustomerUsecase,
 	cfg *config.Config,
 	validator *validator.Validate,
 ) {
@@ -29,12 +35,19 @@ func SetupRoutes(
 	medicineHandler := http.NewMedicineHandler(medicineUsecase, validator)
 	saleHandler := http.NewSaleHandler(saleUsecase, validator)
 	orderHandler := http.NewOrderHandler(orderUsecase, validator)
+	inventoryHandler := http.NewInventoryHandler(inventoryUsecase, validator)
+	supplierHandler := http.NewSupplierHandler(supplierUsecase, validator)
+	purchaseOrderHandler := http.NewPurchaseOrderHandler(purchaseOrderUsecase, validator)
+	promotionHandler := http.NewPromotionHandler(promotionUsecase, validator)
+	shiftHandler := http.NewShiftHandler(shiftUsecase, validator)
+	customerHandler := http.NewCustomerHandler(customerUsecase, validator)
 
 	// Middleware
 	authMiddleware := middleware.AuthMiddleware(cfg)
 	adminMiddleware := middleware.RoleMiddleware("admin")
 	adminOwnerMiddleware := middleware.RoleMiddleware("admin", "owner")
 	saleMiddleware := middleware.RoleMiddleware("owner", "pharmacist")
+	ownerMiddleware := middleware.RoleMiddleware("owner")
 
 	// Auth routes (public)
 	auth := r.Group("/auth")
@@ -64,6 +77,9 @@ func SetupRoutes(
 		pharmacies.GET("/", pharmacyHandler.GetAll)
 		pharmacies.GET("/:id", pharmacyHandler.GetByID)
 		pharmacies.PUT("/:id", adminOwnerMiddleware, pharmacyHandler.Update)
+		pharmacies.PUT("/:id/tax-settings", adminOwnerMiddleware, pharmacyHandler.UpdateTaxSettings)
+		pharmacies.PUT("/:id/receipt-settings", adminOwnerMiddleware, pharmacyHandler.UpdateReceiptSettings)
+		pharmacies.GET("/:id/receipt-chain", adminOwnerMiddleware, saleHandler.VerifyReceiptChain)
 		pharmacies.DELETE("/:id", adminMiddleware, pharmacyHandler.Delete)
 	}
 
@@ -82,6 +98,10 @@ func SetupRoutes(
 		medicines.GET("/:id/variants/:variant_id", medicineHandler.GetVariantByID)
 		medicines.PUT("/:id/variants/:variant_id", adminOwnerMiddleware, medicineHandler.UpdateVariant)
 		medicines.DELETE("/:id/variants/:variant_id", adminMiddleware, medicineHandler.DeleteVariant)
+		medicines.POST("/:id/variants/:variant_id/lots", adminOwnerMiddleware, medicineHandler.CreateLot)
+		medicines.GET("/:id/variants/:variant_id/lots", medicineHandler.GetLots)
+		medicines.POST("/:id/variants/:variant_id/adjustments", adminOwnerMiddleware, medicineHandler.AdjustStock)
+		medicines.GET("/:id/variants/:variant_id/movements", medicineHandler.GetStockMovements)
 	}
 
 	// Sale routes (protected)
@@ -90,7 +110,20 @@ func SetupRoutes(
 	{
 		sales.POST("/", saleHandler.ConfirmSale)
 		sales.GET("/", saleHandler.GetSales)
+		sales.GET("/:id", saleHandler.GetSale)
 		sales.GET("/:id/receipt", saleHandler.GetReceipt)
+		sales.POST("/:id/returns", saleHandler.CreateReturn)
+		sales.GET("/:id/returns", saleHandler.GetReturns)
+	}
+
+	// Receipt verification (public), linked from receipts' QR codes
+	r.GET("/api/receipts/verify", saleHandler.VerifyReceipt)
+
+	// Receipt routes (protected)
+	receipts := r.Group("/api/receipts")
+	receipts.Use(authMiddleware, saleMiddleware)
+	{
+		receipts.GET("/:number", saleHandler.GetReceiptByNumber)
 	}
 
 	// Cart routes (protected)
@@ -99,7 +132,97 @@ func SetupRoutes(
 	{
 		cart.POST("/", saleHandler.AddToCart)
 		cart.GET("/", saleHandler.GetCart)
+		cart.DELETE("/", saleHandler.ClearCart)
+		cart.PUT("/:item_id", saleHandler.UpdateCartItem)
 		cart.DELETE("/:item_id", saleHandler.RemoveFromCart)
+		cart.POST("/park", saleHandler.ParkCart)
+		cart.GET("/parked", saleHandler.GetParkedCarts)
+		cart.POST("/parked/:id/resume", saleHandler.ResumeCart)
+		cart.POST("/discounts", promotionHandler.RequestManualDiscount)
+	}
+
+	// Offline POS sync routes (protected)
+	sync := r.Group("/api/sync")
+	sync.Use(authMiddleware, saleMiddleware)
+	{
+		sync.POST("/sales", saleHandler.SyncSales)
+		sync.GET("/catalog", medicineHandler.GetCatalogChanges)
+	}
+
+	// Inventory routes (protected)
+	inventory := r.Group("/api/inventory")
+	inventory.Use(authMiddleware, saleMiddleware)
+	{
+		inventory.GET("/low-stock", inventoryHandler.GetLowStock)
+		inventory.GET("/expiring", inventoryHandler.GetExpiring)
+		inventory.POST("/write-offs", ownerMiddleware, inventoryHandler.CreateWriteOff)
+		inventory.GET("/write-offs", ownerMiddleware, inventoryHandler.GetWriteOffs)
+	}
+
+	// Supplier routes (protected)
+	suppliers := r.Group("/api/suppliers")
+	suppliers.Use(authMiddleware, saleMiddleware)
+	{
+		suppliers.POST("/", ownerMiddleware, supplierHandler.Create)
+		suppliers.GET("/", supplierHandler.GetAll)
+		suppliers.GET("/:id", supplierHandler.GetByID)
+		suppliers.PUT("/:id", ownerMiddleware, supplierHandler.Update)
+		suppliers.DELETE("/:id", ownerMiddleware, supplierHandler.Delete)
+	}
+
+	// Purchase order routes (protected)
+	purchaseOrders := r.Group("/api/purchase-orders")
+	purchaseOrders.Use(authMiddleware, saleMiddleware)
+	{
+		purchaseOrders.POST("/", ownerMiddleware, purchaseOrderHandler.Create)
+		purchaseOrders.GET("/", purchaseOrderHandler.GetAll)
+		purchaseOrders.GET("/:id", purchaseOrderHandler.GetByID)
+		purchaseOrders.PUT("/:id", ownerMiddleware, purchaseOrderHandler.Update)
+		purchaseOrders.PUT("/:id/status", ownerMiddleware, purchaseOrderHandler.UpdateStatus)
+		purchaseOrders.POST("/:id/receipts", purchaseOrderHandler.Receive)
+	}
+
+	// Promotion routes (protected)
+	promotions := r.Group("/api/promotions")
+	promotions.Use(authMiddleware, saleMiddleware)
+	{
+		promotions.POST("/", ownerMiddleware, promotionHandler.Create)
+		promotions.GET("/", promotionHandler.GetAll)
+		promotions.GET("/:id", promotionHandler.GetByID)
+		promotions.PUT("/:id", ownerMiddleware, promotionHandler.Update)
+		promotions.DELETE("/:id", ownerMiddleware, promotionHandler.Delete)
+	}
+
+	// Manual discount routes (protected)
+	manualDiscounts := r.Group("/api/manual-discounts")
+	manualDiscounts.Use(authMiddleware, ownerMiddleware)
+	{
+		manualDiscounts.GET("/", promotionHandler.GetManualDiscounts)
+		manualDiscounts.PUT("/:id/approve", promotionHandler.ApproveManualDiscount)
+		manualDiscounts.PUT("/:id/reject", promotionHandler.RejectManualDiscount)
+	}
+
+	// Shift routes (protected)
+	shifts := r.Group("/api/shifts")
+	shifts.Use(authMiddleware, saleMiddleware)
+	{
+		shifts.POST("/", shiftHandler.Open)
+		shifts.GET("/", ownerMiddleware, shiftHandler.GetAll)
+		shifts.GET("/current", shiftHandler.GetCurrent)
+		shifts.POST("/:id/close", shiftHandler.Close)
+		shifts.GET("/:id/report", ownerMiddleware, shiftHandler.GetReport)
+	}
+
+	// Customer routes (protected)
+	customers := r.Group("/api/customers")
+	customers.Use(authMiddleware, saleMiddleware)
+	{
+		customers.POST("/", customerHandler.Create)
+		customers.GET("/", customerHandler.Search)
+		customers.GET("/:id", customerHandler.GetByID)
+		customers.PUT("/:id", customerHandler.Update)
+		customers.DELETE("/:id", ownerMiddleware, customerHandler.Delete)
+		customers.GET("/:id/purchases", customerHandler.GetPurchases)
 	}
 
 	// Order routes (protected)
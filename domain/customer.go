@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Customer represents a person registered with a pharmacy so their sales can
+// be looked up later
+type Customer struct {
+	ID          uuid.UUID `json:"id"`
+	PharmacyID  uuid.UUID `json:"pharmacy_id"`
+	FullName    string    `json:"full_name"`
+	PhoneNumber string    `json:"phone_number"`
+	Notes       string    `json:"notes"`
+	Allergies   string    `json:"allergies"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CustomerInput for creating or updating a customer
+type CustomerInput struct {
+	FullName    string `json:"full_name" validate:"required,min=2,max=100"`
+	PhoneNumber string `json:"phone_number" validate:"omitempty,phone"`
+	Notes       string `json:"notes" validate:"max=1000"`
+	Allergies   string `json:"allergies" validate:"max=1000"`
+}
+
+// CustomerPurchase is a sale made to a customer with its lines
+type CustomerPurchase struct {
+	SaleHeader
+	Items []SaleResponse `json:"items"`
+}
+
+// CustomerPurchasePage is one page of a customer's purchases, latest first.
+// Total counts all of the customer's purchases.
+type CustomerPurchasePage struct {
+	Purchases []CustomerPurchase `json:"purchases"`
+	Total     int                `json:"total"`
+	Limit     int                `json:"limit"`
+	Offset    int                `json:"offset"`
+}
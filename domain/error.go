@@ -24,7 +24,53 @@ var (
 	ErrInvalidPharmacy     = errors.New("invalid pharmacy")
 	ErrBarcodeTaken        = errors.New("barcode already taken")
 	ErrMedicineHasVariants = errors.New("medicine has variants and cannot be deleted")
+	ErrVariantHasHistory   = errors.New("medicine variant has stock history and cannot be deleted")
 	ErrCartItemNotFound    = errors.New("cart item not found")
+	ErrCartEmpty           = errors.New("cart is empty")
+	ErrCartNotEmpty        = errors.New("cart is not empty; park or clear it first")
+	ErrParkedCartNotFound  = errors.New("parked cart not found")
 	ErrSaleNotFound        = errors.New("sale not found")
+	ErrSaleExists          = errors.New("a sale with this ID already exists")
+	ErrReceiptNotFound     = errors.New("receipt not found")
 	ErrOrderNotFound       = errors.New("order not found")
+	ErrLotNotFound         = errors.New("medicine lot not found")
+	ErrLotNumberTaken      = errors.New("lot number already exists for this variant")
+	ErrInvalidAdjustment   = errors.New("damage can only decrease stock")
+	ErrLotNotExpired       = errors.New("medicine lot has not expired")
+
+	ErrSupplierNotFound           = errors.New("supplier not found")
+	ErrSupplierHasPurchaseOrders  = errors.New("supplier has purchase orders and cannot be deleted")
+	ErrPurchaseOrderNotFound      = errors.New("purchase order not found")
+	ErrPurchaseOrderItemNotFound  = errors.New("purchase order item not found")
+	ErrInvalidPurchaseOrderStatus = errors.New("purchase order status does not allow this action")
+	ErrOverReceipt                = errors.New("received quantity exceeds quantity ordered")
+
+	ErrCustomerNotFound = errors.New("customer not found")
+
+	ErrSaleItemNotFound  = errors.New("sale item not found")
+	ErrReturnExceedsSold = errors.New("return quantity exceeds quantity sold less prior returns")
+
+	ErrIdempotencyKeyInUse  = errors.New("a request with this idempotency key is still in progress")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+	ErrSaleInFuture         = errors.New("sale time is in the future")
+	ErrPriceMismatch        = errors.New("price charged does not match the catalog price")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+
+	ErrPromotionNotFound      = errors.New("promotion not found")
+	ErrInvalidPromotion       = errors.New("promotion fields do not match its type and scope")
+	ErrManualDiscountNotFound = errors.New("manual discount not found")
+	ErrInvalidManualDiscount  = errors.New("manual discount must set exactly one of rate or amount")
+	ErrManualDiscountReviewed = errors.New("manual discount has already been reviewed")
+	ErrManualDiscountUsed     = errors.New("manual discount has already been used")
+
+	ErrInsufficientPayment     = errors.New("tenders do not cover the sale total")
+	ErrOverpayment             = errors.New("only cash tenders can exceed the sale total")
+	ErrTenderReferenceRequired = errors.New("insurance and credit account tenders need a reference")
+
+	ErrShiftNotFound     = errors.New("shift not found")
+	ErrNoOpenShift       = errors.New("no open shift; open one before confirming sales")
+	ErrShiftAlreadyOpen  = errors.New("user already has an open shift")
+	ErrShiftClosed       = errors.New("shift is closed")
+	ErrCashCountRequired = errors.New("closing a shift needs a cash count")
+	ErrDuplicateCount    = errors.New("each tender can only be counted once")
 )
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey is a client-supplied key that makes retrying a sale
+// confirmation safe. Keys are scoped to the user who sent them. RequestHash
+// identifies the request body the key was first sent with. SaleID and
+// Response (the confirmed Sale as JSON) are set in the same transaction that
+// records the sale; until then the key only marks a request in progress.
+type IdempotencyKey struct {
+	Key         string     `json:"key"`
+	UserID      uuid.UUID  `json:"user_id"`
+	RequestHash string     `json:"-"`
+	SaleID      *uuid.UUID `json:"sale_id,omitempty"`
+	Response    []byte     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpiringLot is a lot with stock left that expires within a report window
+// or has already expired. Value is the quantity at the variant's cost price.
+type ExpiringLot struct {
+	LotID      uuid.UUID `json:"lot_id"`
+	VariantID  uuid.UUID `json:"variant_id"`
+	Brand      string    `json:"brand"`
+	Unit       string    `json:"unit"`
+	LotNumber  string    `json:"lot_number"`
+	ExpiryDate time.Time `json:"expiry_date"`
+	Quantity   int       `json:"quantity"`
+	Value      Money     `json:"value"`
+	Expired    bool      `json:"expired"`
+}
+
+// ExpiringMedicine groups a medicine's expiring lots with their totals
+type ExpiringMedicine struct {
+	MedicineID   uuid.UUID     `json:"medicine_id"`
+	MedicineName string        `json:"medicine_name"`
+	Quantity     int           `json:"quantity"`
+	Value        Money         `json:"value"`
+	Lots         []ExpiringLot `json:"lots"`
+}
+
+// WriteOff records expired stock removed from a lot and the value lost, at
+// the variant's cost price
+type WriteOff struct {
+	ID         uuid.UUID `json:"id"`
+	PharmacyID uuid.UUID `json:"pharmacy_id"`
+	VariantID  uuid.UUID `json:"variant_id"`
+	LotID      uuid.UUID `json:"lot_id"`
+	LotNumber  string    `json:"lot_number"`
+	Quantity   int       `json:"quantity"`
+	UnitValue  Money     `json:"unit_value"`
+	LossValue  Money     `json:"loss_value"`
+	Reason     string    `json:"reason"`
+	UserID     uuid.UUID `json:"user_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WriteOffReport lists write-offs in a period with the total loss
+type WriteOffReport struct {
+	From      time.Time  `json:"from"`
+	To        time.Time  `json:"to"`
+	WriteOffs []WriteOff `json:"write_offs"`
+	TotalLoss Money      `json:"total_loss"`
+}
+
+// CreateWriteOffInput for writing off an expired lot. A zero Quantity writes
+// off everything left in the lot.
+type CreateWriteOffInput struct {
+	LotID    uuid.UUID `json:"lot_id" validate:"required"`
+	Quantity int       `json:"quantity" validate:"gte=0"`
+	Reason   string    `json:"reason" validate:"required,min=3,max=255"`
+}
@@ -6,64 +6,153 @@ import (
 	"github.com/google/uuid"
 )
 
-// Medicine represents a medicine entity
+// Medicine represents a medicine entity. TaxCategory decides the VAT rate
+// its variants are sold at; Category is a free-form group such as
+// "analgesics" that promotions can target.
 type Medicine struct {
-	ID          uuid.UUID `json:"id" validate:"required"`
-	PharmacyID  uuid.UUID `json:"pharmacy_id" validate:"required"`
-	Name        string    `json:"name" validate:"required,min=2,max=100"`
-	Description string    `json:"description" validate:"max=500"`
-	Picture     string    `json:"picture" validate:"omitempty,url"`
-	CreatedAt   time.Time `json:"created_at" validate:"required"`
-	UpdatedAt   time.Time `json:"updated_at" validate:"required"`
+	ID          uuid.UUID         `json:"id" validate:"required"`
+	PharmacyID  uuid.UUID         `json:"pharmacy_id" validate:"required"`
+	Name        string            `json:"name" validate:"required,min=2,max=100"`
+	Description string            `json:"description" validate:"max=500"`
+	Picture     string            `json:"picture" validate:"omitempty,url"`
+	Category    string            `json:"category" validate:"max=50"`
+	TaxCategory TaxCategory       `json:"tax_category" validate:"required,oneof=standard zero_rated exempt"`
+	CreatedAt   time.Time         `json:"created_at" validate:"required"`
+	UpdatedAt   time.Time         `json:"updated_at" validate:"required"`
 	Variants    []MedicineVariant `json:"variants" validate:"dive"`
-
 }
 
-// MedicineVariant represents a variant of a medicine
+// MedicineVariant represents a variant of a medicine. Stock and ExpiryDate
+// are derived from the variant's unexpired lots: Stock is their total quantity
+// and ExpiryDate the earliest expiry among those still holding stock.
+// Available is the part of Stock not reserved by carts. The variant counts as
+// low on stock below ReorderPoint; 0 disables tracking.
+// CostPrice is the weighted-average unit cost of stock on hand, updated on
+// every goods receipt.
 type MedicineVariant struct {
-	ID           uuid.UUID `json:"id" validate:"required"`
-	MedicineID   uuid.UUID `json:"medicine_id" validate:"required"`
-	Brand        string    `json:"brand" validate:"required,min=2,max=100"`
-	Barcode      string    `json:"barcode" validate:"required,barcode"`
-	Unit         string    `json:"unit" validate:"required,min=1,max=50"`
-	PricePerUnit float64   `json:"price_per_unit" validate:"required,gt=0"`
-	ExpiryDate   time.Time `json:"expiry_date" validate:"required,future_date"`
-	Stock        int       `json:"stock" validate:"required,gte=0"`
-	CreatedAt    time.Time `json:"created_at" validate:"required"`
-	UpdatedAt    time.Time `json:"updated_at" validate:"required"`
+	ID              uuid.UUID `json:"id" validate:"required"`
+	MedicineID      uuid.UUID `json:"medicine_id" validate:"required"`
+	Brand           string    `json:"brand" validate:"required,min=2,max=100"`
+	Barcode         string    `json:"barcode" validate:"required,barcode"`
+	Unit            string    `json:"unit" validate:"required,min=1,max=50"`
+	PricePerUnit    Money     `json:"price_per_unit" validate:"required,gt=0"`
+	CostPrice       Money     `json:"cost_price" validate:"gte=0"`
+	ExpiryDate      time.Time `json:"expiry_date"`
+	Stock           int       `json:"stock"`
+	Available       int       `json:"available"`
+	ReorderPoint    int       `json:"reorder_point" validate:"gte=0"`
+	ReorderQuantity int       `json:"reorder_quantity" validate:"gte=0"`
+	CreatedAt       time.Time `json:"created_at" validate:"required"`
+	UpdatedAt       time.Time `json:"updated_at" validate:"required"`
+}
+
+// VariantPrice is a variant's selling price from EffectiveFrom until its next
+// price
+type VariantPrice struct {
+	VariantID     uuid.UUID `json:"variant_id"`
+	PricePerUnit  Money     `json:"price_per_unit"`
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// PriceAt returns the price in effect at a time from a variant's prices,
+// oldest first. Before the first of them the first price applies, as a
+// variant has no price before it is created. ok is false without prices.
+func PriceAt(prices []VariantPrice, at time.Time) (price Money, ok bool) {
+	if len(prices) == 0 {
+		return 0, false
+	}
+	price = prices[0].PricePerUnit
+	for _, p := range prices[1:] {
+		if p.EffectiveFrom.After(at) {
+			break
+		}
+		price = p.PricePerUnit
+	}
+	return price, true
+}
+
+// LowStockItem is a variant whose stock has fallen below its reorder point
+type LowStockItem struct {
+	MedicineVariant
+	MedicineName string `json:"medicine_name"`
 }
 
-// CreateMedicineInput for creating a medicine
+// MedicineLot represents a batch of a variant received with a single expiry
+// date. Quarantined units were returned by customers and are held back from
+// sale; they are not part of Quantity.
+type MedicineLot struct {
+	ID          uuid.UUID `json:"id"`
+	VariantID   uuid.UUID `json:"variant_id"`
+	LotNumber   string    `json:"lot_number"`
+	ExpiryDate  time.Time `json:"expiry_date"`
+	Quantity    int       `json:"quantity"`
+	Quarantined int       `json:"quarantined"`
+	ReceivedAt  time.Time `json:"received_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// LotAllocation records how many units of a sale line came from a lot
+type LotAllocation struct {
+	LotID      uuid.UUID `json:"lot_id"`
+	LotNumber  string    `json:"lot_number"`
+	ExpiryDate time.Time `json:"expiry_date"`
+	Quantity   int       `json:"quantity"`
+}
+
+// CreateMedicineInput for creating a medicine. TaxCategory defaults to
+// standard.
 type CreateMedicineInput struct {
-	PharmacyID  uuid.UUID `json:"pharmacy_id" validate:"required"`
-	Name        string    `json:"name" validate:"required,min=2,max=100"`
-	Description string    `json:"description" validate:"max=500"`
-	Picture     string    `json:"picture" validate:"omitempty,url"`
+	PharmacyID  uuid.UUID   `json:"pharmacy_id" validate:"required"`
+	Name        string      `json:"name" validate:"required,min=2,max=100"`
+	Description string      `json:"description" validate:"max=500"`
+	Picture     string      `json:"picture" validate:"omitempty,url"`
+	Category    string      `json:"category" validate:"max=50"`
+	TaxCategory TaxCategory `json:"tax_category" validate:"omitempty,oneof=standard zero_rated exempt"`
 }
 
-// UpdateMedicineInput for updating a medicine
+// UpdateMedicineInput for updating a medicine. TaxCategory is left unchanged
+// when omitted.
 type UpdateMedicineInput struct {
-	Name        string `json:"name" validate:"required,min=2,max=100"`
-	Description string `json:"description" validate:"max=500"`
-	Picture     string `json:"picture" validate:"omitempty,url"`
+	Name        string      `json:"name" validate:"required,min=2,max=100"`
+	Description string      `json:"description" validate:"max=500"`
+	Picture     string      `json:"picture" validate:"omitempty,url"`
+	Category    string      `json:"category" validate:"max=50"`
+	TaxCategory TaxCategory `json:"tax_category" validate:"omitempty,oneof=standard zero_rated exempt"`
 }
 
-// CreateMedicineVariantInput for creating a medicine variant
+// CreateMedicineVariantInput for creating a medicine variant. ExpiryDate and
+// Stock describe the initial lot, bought at CostPrice.
 type CreateMedicineVariantInput struct {
-	Brand        string    `json:"brand" validate:"required,min=2,max=100"`
-	Barcode      string    `json:"barcode" validate:"required,barcode"`
-	Unit         string    `json:"unit" validate:"required,min=1,max=50"`
-	PricePerUnit float64   `json:"price_per_unit" validate:"required,gt=0"`
-	ExpiryDate   time.Time `json:"expiry_date" validate:"required,future_date"`
-	Stock        int       `json:"stock" validate:"required,gte=0"`
+	Brand           string    `json:"brand" validate:"required,min=2,max=100"`
+	Barcode         string    `json:"barcode" validate:"required,barcode"`
+	Unit            string    `json:"unit" validate:"required,min=1,max=50"`
+	PricePerUnit    Money     `json:"price_per_unit" validate:"required,gt=0"`
+	CostPrice       Money     `json:"cost_price" validate:"gte=0"`
+	LotNumber       string    `json:"lot_number" validate:"max=50"`
+	ExpiryDate      time.Time `json:"expiry_date" validate:"required,future_date"`
+	Stock           int       `json:"stock" validate:"required,gte=0"`
+	ReorderPoint    int       `json:"reorder_point" validate:"gte=0"`
+	ReorderQuantity int       `json:"reorder_quantity" validate:"gte=0"`
 }
 
-// UpdateMedicineVariantInput for updating a medicine variant
+// UpdateMedicineVariantInput for updating a medicine variant. Stock and
+// expiry are managed through lots. CostPrice corrects the average cost and is
+// left unchanged when omitted.
 type UpdateMedicineVariantInput struct {
-	Brand        string    `json:"brand" validate:"required,min=2,max=100"`
-	Barcode      string    `json:"barcode" validate:"required,barcode"`
-	Unit         string    `json:"unit" validate:"required,min=1,max=50"`
-	PricePerUnit float64   `json:"price_per_unit" validate:"required,gt=0"`
-	ExpiryDate   time.Time `json:"expiry_date" validate:"required,future_date"`
-	Stock        int       `json:"stock" validate:"required,gte=0"`
+	Brand           string `json:"brand" validate:"required,min=2,max=100"`
+	Barcode         string `json:"barcode" validate:"required,barcode"`
+	Unit            string `json:"unit" validate:"required,min=1,max=50"`
+	PricePerUnit    Money  `json:"price_per_unit" validate:"required,gt=0"`
+	CostPrice       *Money `json:"cost_price" validate:"omitempty,gte=0"`
+	ReorderPoint    int    `json:"reorder_point" validate:"gte=0"`
+	ReorderQuantity int    `json:"reorder_quantity" validate:"gte=0"`
+}
+
+// CreateMedicineLotInput for receiving a new lot of a variant
+type CreateMedicineLotInput struct {
+	LotNumber  string    `json:"lot_number" validate:"required,min=1,max=50"`
+	ExpiryDate time.Time `json:"expiry_date" validate:"required,future_date"`
+	Quantity   int       `json:"quantity" validate:"required,gt=0"`
+	ReceivedAt time.Time `json:"received_at"`
 }
@@ -0,0 +1,140 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Money is an amount in minor units: 1250 is 12.50. Adding, subtracting and
+// multiplying by quantities is exact. Rounding only happens when an amount is
+// divided or parsed from a decimal with more than two places, and always goes
+// to the nearest minor unit with halves rounded away from zero (12.345 is
+// 12.35, -0.005 is -0.01), which is also how Postgres rounds NUMERIC.
+//
+// Money is encoded in JSON as a string with exactly two decimals ("12.50") so
+// clients never see binary floating point. Decoding also accepts JSON numbers.
+// In Postgres it is stored as NUMERIC(12,2).
+type Money int64
+
+// MinorUnits is the number of minor units in one major unit
+const MinorUnits = 100
+
+// ErrInvalidMoney is returned when an amount cannot be parsed
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// ParseMoney parses a decimal amount such as "12.5", "-3" or "1e2"
+func ParseMoney(s string) (Money, error) {
+	// big.Rat also takes fractions, base prefixes and digit separators
+	if strings.ContainsFunc(s, func(c rune) bool { return !strings.ContainsRune("+-.0123456789eE", c) }) {
+		return 0, ErrInvalidMoney
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, ErrInvalidMoney
+	}
+	r.Mul(r, big.NewRat(MinorUnits, 1))
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	// Round half away from zero: |rem|/den >= 1/2
+	if rem.Sign() != 0 && new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		q.Add(q, big.NewInt(int64(rem.Sign())))
+	}
+	if !q.IsInt64() {
+		return 0, ErrInvalidMoney
+	}
+	return Money(q.Int64()), nil
+}
+
+// Mul returns the amount for quantity units
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// Div divides the amount into n parts, rounding to the nearest minor unit
+func (m Money) Div(n int) Money {
+	d := Money(n)
+	q, r := m/d, m%d
+	if r < 0 {
+		r = -r
+	}
+	if d < 0 {
+		d = -d
+	}
+	if 2*r >= d {
+		if (m < 0) != (n < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q
+}
+
+// String formats the amount with two decimals, e.g. "12.50"
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/MinorUnits, v%MinorUnits)
+}
+
+// MarshalJSON encodes the amount as a two-decimal string
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.String())
+}
+
+// UnmarshalJSON decodes a string or number amount
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan implements sql.Scanner for NUMERIC columns
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * MinorUnits)
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value implements driver.Valuer, sending the amount as a decimal string
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,188 @@
+package domain_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"pharmacy-management-backend/domain"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want domain.Money
+	}{
+		{"12.5", 1250},
+		{"12.50", 1250},
+		{"0", 0},
+		{"+5", 500},
+		{".5", 50},
+		{"1e2", 10000},
+		{"1E-2", 1},
+		{"-3", -300},
+		{"-0.01", -1},
+		// Halves round away from zero
+		{"12.345", 1235},
+		{"-12.345", -1235},
+		{"0.005", 1},
+		{"-0.005", -1},
+		// More than two decimals round to the nearest minor unit
+		{"12.344", 1234},
+		{"12.3449999", 1234},
+		{"0.004999", 0},
+		{"-0.004999", 0},
+		{"1.23456789", 123},
+		{"19.999", 2000},
+	}
+	for _, tt := range tests {
+		got, err := domain.ParseMoney(tt.in)
+		if err != nil {
+			t.Errorf("ParseMoney(%q): unexpected error %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoneyMalformed(t *testing.T) {
+	for _, in := range []string{"", "abc", "12.3.4", "12,50", " 1", "1 ", "$5", "NaN", "Inf", "1/2", "0x10", "0b11", "1_000", "1e30"} {
+		if got, err := domain.ParseMoney(in); !errors.Is(err, domain.ErrInvalidMoney) {
+			t.Errorf("ParseMoney(%q) = %d, %v; want ErrInvalidMoney", in, got, err)
+		}
+	}
+}
+
+func TestMoneyDiv(t *testing.T) {
+	tests := []struct {
+		m    domain.Money
+		n    int
+		want domain.Money
+	}{
+		{1000, 3, 333},
+		{200, 3, 67},
+		{0, 7, 0},
+		// Halves round away from zero whatever the signs
+		{5, 2, 3},
+		{-5, 2, -3},
+		{5, -2, -3},
+		{-5, -2, 3},
+		{100, 8, 13},
+		{-100, 8, -13},
+		{-1000, 3, -333},
+		{-200, 3, -67},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Div(tt.n); got != tt.want {
+			t.Errorf("%d.Div(%d) = %d, want %d", tt.m, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		m    domain.Money
+		want string
+	}{
+		{0, `"0.00"`},
+		{5, `"0.05"`},
+		{1250, `"12.50"`},
+		{100000, `"1000.00"`},
+		{-1, `"-0.01"`},
+		{-1235, `"-12.35"`},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.m)
+		if err != nil {
+			t.Fatalf("Marshal(%d): %v", tt.m, err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("Marshal(%d) = %s, want %s", tt.m, data, tt.want)
+		}
+		var back domain.Money
+		if err := json.Unmarshal(data, &back); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if back != tt.m {
+			t.Errorf("round trip of %d gave %d", tt.m, back)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want domain.Money
+	}{
+		{`"12.5"`, 1250},
+		{`12.5`, 1250},
+		{`19.99`, 1999},
+		{`0.1`, 10},
+		{`"12.345"`, 1235},
+		{`-0.005`, -1},
+		{`7`, 700},
+	}
+	for _, tt := range tests {
+		var got domain.Money
+		if err := json.Unmarshal([]byte(tt.in), &got); err != nil {
+			t.Errorf("Unmarshal(%s): unexpected error %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	// null leaves the amount alone
+	got := domain.Money(42)
+	if err := json.Unmarshal([]byte(`null`), &got); err != nil || got != 42 {
+		t.Errorf("Unmarshal(null) = %d, %v; want 42, nil", got, err)
+	}
+
+	for _, in := range []string{`"abc"`, `""`, `true`, `"1/2"`, `[1]`} {
+		var m domain.Money
+		if err := json.Unmarshal([]byte(in), &m); err == nil {
+			t.Errorf("Unmarshal(%s) = %d, want an error", in, m)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		src  any
+		want domain.Money
+	}{
+		// lib/pq hands NUMERIC columns over as text
+		{[]byte("12.50"), 1250},
+		{[]byte("0.10"), 10},
+		{[]byte("-3.00"), -300},
+		{[]byte("9999999999.99"), 999999999999},
+		{"12.50", 1250},
+		{int64(7), 700},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		got := domain.Money(-42)
+		if err := got.Scan(tt.src); err != nil {
+			t.Errorf("Scan(%v): unexpected error %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Scan(%v) = %d, want %d", tt.src, got, tt.want)
+		}
+	}
+
+	for _, src := range []any{[]byte("abc"), 12.5, true} {
+		var m domain.Money
+		if err := m.Scan(src); err == nil {
+			t.Errorf("Scan(%v) = %d, want an error", src, m)
+		}
+	}
+
+	v, err := domain.Money(-1250).Value()
+	if err != nil || v != "-12.50" {
+		t.Errorf("Value() = %v, %v; want -12.50", v, err)
+	}
+}
@@ -41,7 +41,7 @@ type OrderItem struct {
 	OrderID           uuid.UUID `json:"order_id"`
 	MedicineVariantID uuid.UUID `json:"medicine_variant_id"`
 	Quantity          int       `json:"quantity"`
-	PricePerUnit      float64   `json:"price_per_unit"`
+	PricePerUnit      Money     `json:"price_per_unit"`
 	CreatedAt         time.Time `json:"created_at"`
 	// Temporary fields for response
 	MedicineName string `json:"medicine_name,omitempty"`
@@ -66,15 +66,15 @@ type PatientResponse struct {
 
 // OrderItemResponse defines an item in the order details response
 type OrderItemResponse struct {
-	MedicineName string  `json:"medicine_name"`
-	Unit         string  `json:"unit"`
-	Quantity     int     `json:"quantity"`
-	PricePerUnit float64 `json:"price_per_unit"`
+	MedicineName string `json:"medicine_name"`
+	Unit         string `json:"unit"`
+	Quantity     int    `json:"quantity"`
+	PricePerUnit Money  `json:"price_per_unit"`
 }
 
 // OrderDetailsResponse defines the response for order details
 type OrderDetailsResponse struct {
 	Patient    PatientResponse     `json:"patient"`
 	Items      []OrderItemResponse `json:"items"`
-	TotalPrice float64             `json:"total_price"`
+	TotalPrice Money               `json:"total_price"`
 }
@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentMethod is how a tender was paid
+type PaymentMethod string
+
+const (
+	PaymentCash          PaymentMethod = "cash"
+	PaymentCard          PaymentMethod = "card"
+	PaymentMobileMoney   PaymentMethod = "mobile_money"
+	PaymentInsurance     PaymentMethod = "insurance"
+	PaymentCreditAccount PaymentMethod = "credit_account"
+)
+
+// Payment is one tender against a sale. Amount is what was handed over and
+// Change what was given back from it, so it paid Amount less Change. Only
+// cash tenders give change. Reference is a card slip, transaction, claim or
+// account number.
+type Payment struct {
+	ID        uuid.UUID     `json:"id"`
+	SaleID    uuid.UUID     `json:"sale_id"`
+	Method    PaymentMethod `json:"method"`
+	Amount    Money         `json:"amount"`
+	Change    Money         `json:"change"`
+	Reference string        `json:"reference,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// TenderInput for one tender towards a sale. Insurance and credit account
+// tenders need a Reference.
+type TenderInput struct {
+	Method    PaymentMethod `json:"method" validate:"required,oneof=cash card mobile_money insurance credit_account"`
+	Amount    Money         `json:"amount" validate:"gt=0"`
+	Reference string        `json:"reference" validate:"max=100"`
+}
+
+// ConfirmSaleInput for confirming the cart as a sale, optionally made to one
+// of the pharmacy's customers
+type ConfirmSaleInput struct {
+	Tenders    []TenderInput `json:"tenders" validate:"required,min=1,dive"`
+	CustomerID *uuid.UUID    `json:"customer_id"`
+}
+
+// ApplyTenders turns tenders into payments towards total. The tenders must
+// cover total, and anything over it must be cash; change is given from the
+// cash tenders, last first.
+func ApplyTenders(total Money, tenders []TenderInput) ([]Payment, error) {
+	var tendered, cash Money
+	for _, t := range tenders {
+		if t.Reference == "" && (t.Method == PaymentInsurance || t.Method == PaymentCreditAccount) {
+			return nil, ErrTenderReferenceRequired
+		}
+		tendered += t.Amount
+		if t.Method == PaymentCash {
+			cash += t.Amount
+		}
+	}
+	if tendered < total {
+		return nil, ErrInsufficientPayment
+	}
+	change := tendered - total
+	if change > cash {
+		return nil, ErrOverpayment
+	}
+
+	payments := make([]Payment, len(tenders))
+	for i := len(tenders) - 1; i >= 0; i-- {
+		t := tenders[i]
+		payments[i] = Payment{Method: t.Method, Amount: t.Amount, Reference: t.Reference}
+		if t.Method == PaymentCash && change > 0 {
+			payments[i].Change = min(change, t.Amount)
+			change -= payments[i].Change
+		}
+	}
+	return payments, nil
+}
+
+// TotalChange is the change given across payments
+func TotalChange(payments []Payment) Money {
+	var change Money
+	for _, p := range payments {
+		change += p.Change
+	}
+	return change
+}
@@ -0,0 +1,76 @@
+package domain_test
+
+import (
+	"errors"
+	"testing"
+
+	"pharmacy-management-backend/domain"
+)
+
+func TestApplyTenders(t *testing.T) {
+	cash := func(amount domain.Money) domain.TenderInput {
+		return domain.TenderInput{Method: domain.PaymentCash, Amount: amount}
+	}
+	card := func(amount domain.Money) domain.TenderInput {
+		return domain.TenderInput{Method: domain.PaymentCard, Amount: amount}
+	}
+	tests := []struct {
+		name    string
+		total   domain.Money
+		tenders []domain.TenderInput
+		change  []domain.Money
+		err     error
+	}{
+		{"exact cash", 1000, []domain.TenderInput{cash(1000)}, []domain.Money{0}, nil},
+		{"cash change", 1250, []domain.TenderInput{cash(2000)}, []domain.Money{750}, nil},
+		{"exact card", 1000, []domain.TenderInput{card(1000)}, []domain.Money{0}, nil},
+		{"card then cash change", 1500, []domain.TenderInput{card(600), cash(1000)}, []domain.Money{0, 100}, nil},
+		{"cash then card change", 1500, []domain.TenderInput{cash(1000), card(600)}, []domain.Money{100, 0}, nil},
+		{"cash not needed", 1500, []domain.TenderInput{card(1500), cash(500)}, []domain.Money{0, 500}, nil},
+		// Change comes from the last cash tender first
+		{"last cash first", 1200, []domain.TenderInput{cash(500), cash(1000)}, []domain.Money{0, 300}, nil},
+		{"change across cash", 1100, []domain.TenderInput{cash(500), card(1000), cash(200)}, []domain.Money{400, 0, 200}, nil},
+		{"referenced insurance", 1000, []domain.TenderInput{{Method: domain.PaymentInsurance, Amount: 800, Reference: "POL-1"}, cash(500)},
+			[]domain.Money{0, 300}, nil},
+		{"cash short", 1000, []domain.TenderInput{cash(999)}, nil, domain.ErrInsufficientPayment},
+		{"mixed short", 1500, []domain.TenderInput{card(700), cash(700)}, nil, domain.ErrInsufficientPayment},
+		{"card over", 1500, []domain.TenderInput{card(2000)}, nil, domain.ErrOverpayment},
+		{"change over cash", 1500, []domain.TenderInput{card(1600), cash(100)}, nil, domain.ErrOverpayment},
+		{"insurance without reference", 1000, []domain.TenderInput{{Method: domain.PaymentInsurance, Amount: 1000}}, nil,
+			domain.ErrTenderReferenceRequired},
+		{"credit without reference", 1000, []domain.TenderInput{{Method: domain.PaymentCreditAccount, Amount: 1000}}, nil,
+			domain.ErrTenderReferenceRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments, err := domain.ApplyTenders(tt.total, tt.tenders)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if len(payments) != len(tt.tenders) {
+				t.Fatalf("expected %d payments, got %+v", len(tt.tenders), payments)
+			}
+			var paid domain.Money
+			for i, p := range payments {
+				if p.Method != tt.tenders[i].Method || p.Amount != tt.tenders[i].Amount || p.Change != tt.change[i] {
+					t.Fatalf("payment %d is %+v, want %s %d with change %d", i, p, tt.tenders[i].Method, tt.tenders[i].Amount, tt.change[i])
+				}
+				paid += p.Amount - p.Change
+			}
+			// What is kept after change is exactly the total
+			if paid != tt.total {
+				t.Fatalf("payments keep %d, want %d", paid, tt.total)
+			}
+			var change domain.Money
+			for _, c := range tt.change {
+				change += c
+			}
+			if got := domain.TotalChange(payments); got != change {
+				t.Fatalf("TotalChange = %d, want %d", got, change)
+			}
+		})
+	}
+}
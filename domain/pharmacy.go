@@ -7,9 +7,11 @@ import (
 )
 
 type Pharmacy struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Address   string    `json:"address"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uuid.UUID       `json:"id"`
+	Name            string          `json:"name"`
+	Address         string          `json:"address"`
+	TaxSettings     TaxSettings     `json:"tax_settings"`
+	ReceiptSettings ReceiptSettings `json:"receipt_settings"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
 }
@@ -0,0 +1,324 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PromotionType is how a promotion works out its discount
+type PromotionType string
+
+const (
+	// PromotionPercentage takes Rate basis points off
+	PromotionPercentage PromotionType = "percentage"
+	// PromotionFixed takes Amount off each unit of a line, or once off a basket
+	PromotionFixed PromotionType = "fixed"
+	// PromotionBuyXGetY gives GetQuantity units free for every BuyQuantity
+	// bought on the same line
+	PromotionBuyXGetY PromotionType = "buy_x_get_y"
+)
+
+// PromotionScope is what a promotion discounts
+type PromotionScope string
+
+const (
+	PromotionScopeLine   PromotionScope = "line"
+	PromotionScopeBasket PromotionScope = "basket"
+)
+
+// Promotion is a discount rule a pharmacy runs between StartsAt and EndsAt.
+// A line promotion applies to every medicine unless limited to MedicineID or
+// to medicines in Category. A basket promotion applies once the basket,
+// after line discounts, reaches MinSubtotal.
+type Promotion struct {
+	ID          uuid.UUID      `json:"id"`
+	PharmacyID  uuid.UUID      `json:"pharmacy_id"`
+	Name        string         `json:"name"`
+	Type        PromotionType  `json:"type"`
+	Scope       PromotionScope `json:"scope"`
+	Rate        int            `json:"rate"`
+	Amount      Money          `json:"amount"`
+	BuyQuantity int            `json:"buy_quantity"`
+	GetQuantity int            `json:"get_quantity"`
+	MedicineID  *uuid.UUID     `json:"medicine_id"`
+	Category    string         `json:"category"`
+	MinSubtotal Money          `json:"min_subtotal"`
+	Active      bool           `json:"active"`
+	StartsAt    time.Time      `json:"starts_at"`
+	EndsAt      time.Time      `json:"ends_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// PromotionInput for creating or updating a promotion. Active defaults to
+// true.
+type PromotionInput struct {
+	Name        string         `json:"name" validate:"required,min=2,max=100"`
+	Type        PromotionType  `json:"type" validate:"required,oneof=percentage fixed buy_x_get_y"`
+	Scope       PromotionScope `json:"scope" validate:"required,oneof=line basket"`
+	Rate        int            `json:"rate" validate:"gte=0,lte=10000"`
+	Amount      Money          `json:"amount" validate:"gte=0"`
+	BuyQuantity int            `json:"buy_quantity" validate:"gte=0"`
+	GetQuantity int            `json:"get_quantity" validate:"gte=0"`
+	MedicineID  *uuid.UUID     `json:"medicine_id"`
+	Category    string         `json:"category" validate:"max=50"`
+	MinSubtotal Money          `json:"min_subtotal" validate:"gte=0"`
+	Active      *bool          `json:"active"`
+	StartsAt    time.Time      `json:"starts_at" validate:"required"`
+	EndsAt      time.Time      `json:"ends_at" validate:"required,gtfield=StartsAt"`
+}
+
+// Valid reports whether the input's fields make sense for its type and scope
+func (in PromotionInput) Valid() bool {
+	switch in.Type {
+	case PromotionPercentage:
+		if in.Rate == 0 {
+			return false
+		}
+	case PromotionFixed:
+		if in.Amount == 0 {
+			return false
+		}
+	case PromotionBuyXGetY:
+		if in.Scope != PromotionScopeLine || in.BuyQuantity == 0 || in.GetQuantity == 0 {
+			return false
+		}
+	}
+	if in.Scope == PromotionScopeBasket {
+		return in.MedicineID == nil && in.Category == ""
+	}
+	return in.MinSubtotal == 0
+}
+
+// RunsAt reports whether the promotion is switched on and in its date range
+func (p Promotion) RunsAt(at time.Time) bool {
+	return p.Active && !at.Before(p.StartsAt) && at.Before(p.EndsAt)
+}
+
+// matches reports whether a line promotion covers the line's medicine
+func (p Promotion) matches(line PricedLine) bool {
+	if p.MedicineID != nil && *p.MedicineID != line.MedicineID {
+		return false
+	}
+	return p.Category == "" || p.Category == line.Category
+}
+
+// lineDiscount is the discount a line promotion gives a line
+func (p Promotion) lineDiscount(line PricedLine) Money {
+	switch p.Type {
+	case PromotionPercentage:
+		return line.Subtotal().Mul(p.Rate).Div(BasisPoints)
+	case PromotionFixed:
+		return p.Amount.Mul(line.Quantity)
+	case PromotionBuyXGetY:
+		free := line.Quantity / (p.BuyQuantity + p.GetQuantity) * p.GetQuantity
+		return line.PricePerUnit.Mul(free)
+	}
+	return 0
+}
+
+// basketDiscount is the discount a basket promotion gives a basket
+func (p Promotion) basketDiscount(amount Money) Money {
+	if amount < p.MinSubtotal {
+		return 0
+	}
+	switch p.Type {
+	case PromotionPercentage:
+		return amount.Mul(p.Rate).Div(BasisPoints)
+	case PromotionFixed:
+		return p.Amount
+	}
+	return 0
+}
+
+// ManualDiscountStatus is where a manual discount is in its approval
+type ManualDiscountStatus string
+
+const (
+	ManualDiscountPending  ManualDiscountStatus = "pending"
+	ManualDiscountApproved ManualDiscountStatus = "approved"
+	ManualDiscountRejected ManualDiscountStatus = "rejected"
+)
+
+// ManualDiscount is an ad-hoc discount on one cart line, or on the whole
+// basket when CartID is nil, taking either Rate basis points or Amount off.
+// Discounts requested by pharmacists wait for an owner's approval; approved
+// discounts apply to the requester's next sale, which sets SaleID.
+type ManualDiscount struct {
+	ID         uuid.UUID            `json:"id"`
+	PharmacyID uuid.UUID            `json:"pharmacy_id"`
+	UserID     uuid.UUID            `json:"user_id"`
+	CartID     *uuid.UUID           `json:"cart_id"`
+	Rate       int                  `json:"rate"`
+	Amount     Money                `json:"amount"`
+	Reason     string               `json:"reason"`
+	Status     ManualDiscountStatus `json:"status"`
+	ReviewedBy *uuid.UUID           `json:"reviewed_by"`
+	ReviewedAt *time.Time           `json:"reviewed_at"`
+	SaleID     *uuid.UUID           `json:"sale_id"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+// ManualDiscountInput for requesting a manual discount. Exactly one of Rate
+// and Amount must be set.
+type ManualDiscountInput struct {
+	CartID *uuid.UUID `json:"cart_id"`
+	Rate   int        `json:"rate" validate:"required_without=Amount,excluded_with=Amount,gte=0,lte=10000"`
+	Amount Money      `json:"amount" validate:"required_without=Rate,excluded_with=Rate,gte=0"`
+	Reason string     `json:"reason" validate:"required,min=3,max=255"`
+}
+
+// Valid reports whether the input sets exactly one of Rate and Amount
+func (in ManualDiscountInput) Valid() bool {
+	return (in.Rate > 0) != (in.Amount > 0)
+}
+
+// discount is the discount a manual discount gives an amount
+func (d ManualDiscount) discount(amount Money) Money {
+	if d.Rate > 0 {
+		return amount.Mul(d.Rate).Div(BasisPoints)
+	}
+	return d.Amount
+}
+
+// AppliedDiscount records a promotion or manual discount given on a sale
+type AppliedDiscount struct {
+	PromotionID      *uuid.UUID     `json:"promotion_id,omitempty"`
+	ManualDiscountID *uuid.UUID     `json:"manual_discount_id,omitempty"`
+	Name             string         `json:"name"`
+	Scope            PromotionScope `json:"scope"`
+	Amount           Money          `json:"amount"`
+}
+
+// PricedLine is a cart line as seen by ApplyDiscounts
+type PricedLine struct {
+	CartID       uuid.UUID
+	MedicineID   uuid.UUID
+	Category     string
+	PricePerUnit Money
+	Quantity     int
+}
+
+// Subtotal is the line's price before discounts
+func (l PricedLine) Subtotal() Money {
+	return l.PricePerUnit.Mul(l.Quantity)
+}
+
+// Pricing is the outcome of ApplyDiscounts. LineDiscounts holds each line's
+// discount, with its share of basket discounts, in the order of the lines.
+type Pricing struct {
+	LineDiscounts []Money
+	Applied       []AppliedDiscount
+	Subtotal      Money
+	Discount      Money
+}
+
+// ApplyDiscounts works out the discounts on a basket at a point in time.
+// Each line gets the best line promotion that covers it, then any manual
+// discount on it. The basket then gets the best basket promotion it
+// qualifies for, then any manual basket discount, shared across the lines in
+// proportion to what is left of them. Discounts never take a line below zero.
+func ApplyDiscounts(lines []PricedLine, promotions []Promotion, manual []ManualDiscount, at time.Time) Pricing {
+	pricing := Pricing{LineDiscounts: make([]Money, len(lines))}
+	remaining := make([]Money, len(lines))
+	for i, line := range lines {
+		remaining[i] = line.Subtotal()
+		pricing.Subtotal += remaining[i]
+
+		var best *Promotion
+		var bestAmount Money
+		for j := range promotions {
+			p := &promotions[j]
+			if p.Scope != PromotionScopeLine || !p.RunsAt(at) || !p.matches(line) {
+				continue
+			}
+			if amount := min(p.lineDiscount(line), remaining[i]); amount > bestAmount {
+				best, bestAmount = p, amount
+			}
+		}
+		if best != nil {
+			remaining[i] -= bestAmount
+			pricing.LineDiscounts[i] += bestAmount
+			pricing.Applied = addApplied(pricing.Applied, AppliedDiscount{PromotionID: &best.ID, Name: best.Name, Scope: PromotionScopeLine, Amount: bestAmount})
+		}
+
+		for _, d := range manual {
+			if d.CartID == nil || *d.CartID != line.CartID {
+				continue
+			}
+			if amount := min(d.discount(remaining[i]), remaining[i]); amount > 0 {
+				remaining[i] -= amount
+				pricing.LineDiscounts[i] += amount
+				pricing.Applied = addApplied(pricing.Applied, AppliedDiscount{ManualDiscountID: &d.ID, Name: d.Reason, Scope: PromotionScopeLine, Amount: amount})
+			}
+		}
+	}
+
+	var basket Money
+	for _, amount := range remaining {
+		basket += amount
+	}
+	left := basket
+
+	var best *Promotion
+	var bestAmount Money
+	for j := range promotions {
+		p := &promotions[j]
+		if p.Scope != PromotionScopeBasket || !p.RunsAt(at) {
+			continue
+		}
+		if amount := min(p.basketDiscount(basket), left); amount > bestAmount {
+			best, bestAmount = p, amount
+		}
+	}
+	if best != nil {
+		left -= bestAmount
+		pricing.Applied = append(pricing.Applied, AppliedDiscount{PromotionID: &best.ID, Name: best.Name, Scope: PromotionScopeBasket, Amount: bestAmount})
+	}
+	for _, d := range manual {
+		if d.CartID != nil {
+			continue
+		}
+		if amount := min(d.discount(left), left); amount > 0 {
+			left -= amount
+			pricing.Applied = append(pricing.Applied, AppliedDiscount{ManualDiscountID: &d.ID, Name: d.Reason, Scope: PromotionScopeBasket, Amount: amount})
+		}
+	}
+
+	// Share the basket discount out, rounding cumulatively so the shares add
+	// up to it exactly
+	if total := basket - left; total > 0 {
+		var before Money
+		for i := range lines {
+			after := before + remaining[i]
+			pricing.LineDiscounts[i] += total.Mul(int(after)).Div(int(basket)) - total.Mul(int(before)).Div(int(basket))
+			before = after
+		}
+	}
+
+	for _, amount := range pricing.LineDiscounts {
+		pricing.Discount += amount
+	}
+	return pricing
+}
+
+// addApplied adds a discount to the matching entry of applied, so a
+// promotion given on several lines is listed once
+func addApplied(applied []AppliedDiscount, d AppliedDiscount) []AppliedDiscount {
+	for i := range applied {
+		a := &applied[i]
+		if a.Scope == d.Scope && sameID(a.PromotionID, d.PromotionID) && sameID(a.ManualDiscountID, d.ManualDiscountID) {
+			a.Amount += d.Amount
+			return applied
+		}
+	}
+	return append(applied, d)
+}
+
+func sameID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
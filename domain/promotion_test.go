@@ -0,0 +1,225 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+func TestManualDiscountInputValid(t *testing.T) {
+	v := validator.New()
+	tests := []struct {
+		name  string
+		input domain.ManualDiscountInput
+		valid bool
+	}{
+		{"rate", domain.ManualDiscountInput{Rate: 1000, Reason: "Loyal customer"}, true},
+		{"amount", domain.ManualDiscountInput{Amount: 500, Reason: "Loyal customer"}, true},
+		{"neither", domain.ManualDiscountInput{Reason: "Loyal customer"}, false},
+		{"both", domain.ManualDiscountInput{Rate: 1000, Amount: 500, Reason: "Loyal customer"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.input.Valid(); got != tt.valid {
+				t.Fatalf("Valid() = %v, want %v", got, tt.valid)
+			}
+			if err := v.Struct(tt.input); (err == nil) != tt.valid {
+				t.Fatalf("validation error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+// promotion returns an active promotion running a day either side of at
+func promotion(at time.Time, typ domain.PromotionType, scope domain.PromotionScope) domain.Promotion {
+	return domain.Promotion{ID: uuid.New(), Name: string(typ), Type: typ, Scope: scope, Active: true,
+		StartsAt: at.AddDate(0, 0, -1), EndsAt: at.AddDate(0, 0, 1)}
+}
+
+func line(price domain.Money, quantity int) domain.PricedLine {
+	return domain.PricedLine{CartID: uuid.New(), MedicineID: uuid.New(), PricePerUnit: price, Quantity: quantity}
+}
+
+// checkTotals checks that a pricing's line discounts and applied discounts
+// both add up to its total discount
+func checkTotals(t *testing.T, pricing domain.Pricing) {
+	t.Helper()
+	var lines, applied domain.Money
+	for _, d := range pricing.LineDiscounts {
+		lines += d
+	}
+	for _, a := range pricing.Applied {
+		applied += a.Amount
+	}
+	if lines != pricing.Discount || applied != pricing.Discount {
+		t.Fatalf("line discounts %d and applied discounts %d must both add up to %d", lines, applied, pricing.Discount)
+	}
+}
+
+func TestApplyDiscountsBasketShares(t *testing.T) {
+	at := time.Now()
+	fixed := func(amount domain.Money) domain.Promotion {
+		p := promotion(at, domain.PromotionFixed, domain.PromotionScopeBasket)
+		p.Amount = amount
+		return p
+	}
+	percentage := func(rate int) domain.Promotion {
+		p := promotion(at, domain.PromotionPercentage, domain.PromotionScopeBasket)
+		p.Rate = rate
+		return p
+	}
+	tests := []struct {
+		name      string
+		lines     []domain.PricedLine
+		promotion domain.Promotion
+		want      []domain.Money
+	}{
+		{"even", []domain.PricedLine{line(1000, 1), line(1000, 1)}, fixed(100), []domain.Money{50, 50}},
+		{"thirds", []domain.PricedLine{line(333, 1), line(333, 1), line(334, 1)}, fixed(100), []domain.Money{33, 34, 33}},
+		{"odd cent", []domain.PricedLine{line(100, 1), line(100, 1), line(100, 1)}, fixed(1), []domain.Money{0, 1, 0}},
+		{"percentage", []domain.PricedLine{line(999, 3), line(1, 7), line(4999, 1)}, percentage(1250), []domain.Money{374, 1, 625}},
+		{"capped at basket", []domain.PricedLine{line(300, 1), line(700, 1)}, fixed(5000), []domain.Money{300, 700}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricing := domain.ApplyDiscounts(tt.lines, []domain.Promotion{tt.promotion}, nil, at)
+			checkTotals(t, pricing)
+			for i, want := range tt.want {
+				if pricing.LineDiscounts[i] != want {
+					t.Fatalf("line discounts %v, want %v", pricing.LineDiscounts, tt.want)
+				}
+			}
+		})
+	}
+
+	// Shares add back to the discount exactly whatever the line amounts
+	for n := 1; n <= 7; n++ {
+		for amount := domain.Money(1); amount <= 300; amount += 7 {
+			lines := make([]domain.PricedLine, n)
+			for i := range lines {
+				lines[i] = line(amount+domain.Money(i*13), i%3+1)
+			}
+			pricing := domain.ApplyDiscounts(lines, []domain.Promotion{percentage(333), fixed(17)}, nil, at)
+			checkTotals(t, pricing)
+		}
+	}
+}
+
+func TestApplyDiscountsBuyXGetY(t *testing.T) {
+	at := time.Now()
+	p := promotion(at, domain.PromotionBuyXGetY, domain.PromotionScopeLine)
+	p.BuyQuantity, p.GetQuantity = 2, 1
+	tests := []struct {
+		quantity int
+		want     domain.Money
+	}{
+		{1, 0},
+		{2, 0},
+		{3, 500},
+		{5, 500},
+		{6, 1000},
+		{7, 1000},
+	}
+	for _, tt := range tests {
+		pricing := domain.ApplyDiscounts([]domain.PricedLine{line(500, tt.quantity)}, []domain.Promotion{p}, nil, at)
+		checkTotals(t, pricing)
+		if pricing.Discount != tt.want {
+			t.Errorf("buy 2 get 1 on %d units: discount %d, want %d", tt.quantity, pricing.Discount, tt.want)
+		}
+	}
+}
+
+func TestApplyDiscountsDateBounds(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name             string
+		startsAt, endsAt time.Time
+		active           bool
+		applies          bool
+	}{
+		{"running", at.Add(-time.Hour), at.Add(time.Hour), true, true},
+		{"starts now", at, at.Add(time.Hour), true, true},
+		{"ends now", at.Add(-time.Hour), at, true, false},
+		{"not started", at.Add(time.Second), at.Add(time.Hour), true, false},
+		{"ended", at.Add(-2 * time.Hour), at.Add(-time.Hour), true, false},
+		{"switched off", at.Add(-time.Hour), at.Add(time.Hour), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := promotion(at, domain.PromotionPercentage, domain.PromotionScopeLine)
+			p.Rate, p.StartsAt, p.EndsAt, p.Active = 1000, tt.startsAt, tt.endsAt, tt.active
+			pricing := domain.ApplyDiscounts([]domain.PricedLine{line(1000, 1)}, []domain.Promotion{p}, nil, at)
+			if applied := pricing.Discount == 100; applied != tt.applies {
+				t.Fatalf("discount %d, want applied %v", pricing.Discount, tt.applies)
+			}
+		})
+	}
+}
+
+func TestApplyDiscountsMatching(t *testing.T) {
+	at := time.Now()
+	painkiller := line(1000, 1)
+	painkiller.Category = "analgesic"
+	vitamin := line(1000, 1)
+	vitamin.Category = "supplement"
+
+	byCategory := promotion(at, domain.PromotionPercentage, domain.PromotionScopeLine)
+	byCategory.Rate, byCategory.Category = 1000, "analgesic"
+	byMedicine := promotion(at, domain.PromotionFixed, domain.PromotionScopeLine)
+	byMedicine.Amount, byMedicine.MedicineID = 250, &vitamin.MedicineID
+	everything := promotion(at, domain.PromotionPercentage, domain.PromotionScopeLine)
+	everything.Rate = 500
+
+	tests := []struct {
+		name       string
+		promotions []domain.Promotion
+		want       []domain.Money
+	}{
+		{"category", []domain.Promotion{byCategory}, []domain.Money{100, 0}},
+		{"medicine", []domain.Promotion{byMedicine}, []domain.Money{0, 250}},
+		{"unrestricted", []domain.Promotion{everything}, []domain.Money{50, 50}},
+		// Each line gets its best promotion only
+		{"best wins", []domain.Promotion{everything, byCategory, byMedicine}, []domain.Money{100, 250}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pricing := domain.ApplyDiscounts([]domain.PricedLine{painkiller, vitamin}, tt.promotions, nil, at)
+			checkTotals(t, pricing)
+			if pricing.LineDiscounts[0] != tt.want[0] || pricing.LineDiscounts[1] != tt.want[1] {
+				t.Fatalf("line discounts %v, want %v", pricing.LineDiscounts, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDiscountsManual(t *testing.T) {
+	at := time.Now()
+	a, b := line(1000, 2), line(500, 1)
+	promo := promotion(at, domain.PromotionPercentage, domain.PromotionScopeLine)
+	promo.Rate, promo.MedicineID = 1000, &a.MedicineID
+	minimum := promotion(at, domain.PromotionFixed, domain.PromotionScopeBasket)
+	minimum.Amount, minimum.MinSubtotal = 300, 5000
+
+	manual := []domain.ManualDiscount{
+		// A line discount comes off what the promotion left of the line
+		{ID: uuid.New(), CartID: &a.CartID, Rate: 5000, Reason: "Damaged box"},
+		// A line discount never takes the line below zero
+		{ID: uuid.New(), CartID: &b.CartID, Amount: 9999, Reason: "Sample"},
+		{ID: uuid.New(), Amount: 150, Reason: "Loyal customer"},
+	}
+	pricing := domain.ApplyDiscounts([]domain.PricedLine{a, b}, []domain.Promotion{promo, minimum}, manual, at)
+	checkTotals(t, pricing)
+	// Line a: 2000 - 200 promotion - 900 manual leaves 900, which takes the
+	// whole 150 basket discount as line b is already free. The basket is
+	// under the promotion's minimum.
+	if pricing.Subtotal != 2500 || pricing.LineDiscounts[0] != 1250 || pricing.LineDiscounts[1] != 500 || pricing.Discount != 1750 {
+		t.Fatalf("unexpected pricing %+v", pricing)
+	}
+	if len(pricing.Applied) != 4 {
+		t.Fatalf("expected the promotion and three manual discounts, got %+v", pricing.Applied)
+	}
+}
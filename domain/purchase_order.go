@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PurchaseOrderStatus is the lifecycle state of a purchase order
+type PurchaseOrderStatus string
+
+const (
+	PurchaseOrderDraft             PurchaseOrderStatus = "draft"
+	PurchaseOrderSent              PurchaseOrderStatus = "sent"
+	PurchaseOrderPartiallyReceived PurchaseOrderStatus = "partially_received"
+	PurchaseOrderReceived          PurchaseOrderStatus = "received"
+	PurchaseOrderCancelled         PurchaseOrderStatus = "cancelled"
+)
+
+// PurchaseOrder represents stock ordered from a supplier
+type PurchaseOrder struct {
+	ID           uuid.UUID           `json:"id"`
+	PharmacyID   uuid.UUID           `json:"pharmacy_id"`
+	SupplierID   uuid.UUID           `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name"`
+	Status       PurchaseOrderStatus `json:"status"`
+	Notes        string              `json:"notes"`
+	TotalCost    Money               `json:"total_cost"`
+	CreatedBy    uuid.UUID           `json:"created_by"`
+	Items        []PurchaseOrderItem `json:"items"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// PurchaseOrderItem is a line of a purchase order for one variant
+type PurchaseOrderItem struct {
+	ID               uuid.UUID `json:"id"`
+	PurchaseOrderID  uuid.UUID `json:"purchase_order_id"`
+	VariantID        uuid.UUID `json:"variant_id"`
+	MedicineName     string    `json:"medicine_name"`
+	Brand            string    `json:"brand"`
+	Unit             string    `json:"unit"`
+	QuantityOrdered  int       `json:"quantity_ordered"`
+	QuantityReceived int       `json:"quantity_received"`
+	UnitCost         Money     `json:"unit_cost"`
+}
+
+// GoodsReceipt records stock delivered against a purchase order
+type GoodsReceipt struct {
+	ID              uuid.UUID          `json:"id"`
+	PurchaseOrderID uuid.UUID          `json:"purchase_order_id"`
+	ReceivedBy      uuid.UUID          `json:"received_by"`
+	ReceivedAt      time.Time          `json:"received_at"`
+	Lines           []GoodsReceiptLine `json:"lines"`
+}
+
+// GoodsReceiptLine is the quantity of a purchase order item delivered into
+// one lot at the cost actually paid
+type GoodsReceiptLine struct {
+	ID                  uuid.UUID `json:"id"`
+	PurchaseOrderItemID uuid.UUID `json:"purchase_order_item_id"`
+	VariantID           uuid.UUID `json:"variant_id"`
+	LotID               uuid.UUID `json:"lot_id"`
+	LotNumber           string    `json:"lot_number"`
+	ExpiryDate          time.Time `json:"expiry_date"`
+	Quantity            int       `json:"quantity"`
+	UnitCost            Money     `json:"unit_cost"`
+}
+
+// PurchaseOrderInput for creating or editing a draft purchase order
+type PurchaseOrderInput struct {
+	SupplierID uuid.UUID                `json:"supplier_id" validate:"required"`
+	Notes      string                   `json:"notes" validate:"max=500"`
+	Items      []PurchaseOrderItemInput `json:"items" validate:"required,min=1,dive"`
+}
+
+// PurchaseOrderItemInput is one line of a PurchaseOrderInput
+type PurchaseOrderItemInput struct {
+	VariantID uuid.UUID `json:"variant_id" validate:"required"`
+	Quantity  int       `json:"quantity" validate:"required,gt=0"`
+	UnitCost  Money     `json:"unit_cost" validate:"gte=0"`
+}
+
+// UpdatePurchaseOrderStatusInput for sending or cancelling a purchase order
+type UpdatePurchaseOrderStatusInput struct {
+	Status PurchaseOrderStatus `json:"status" validate:"required,oneof=sent cancelled"`
+}
+
+// ReceiveGoodsInput for recording a delivery against a purchase order
+type ReceiveGoodsInput struct {
+	Lines []ReceiveGoodsLineInput `json:"lines" validate:"required,min=1,dive"`
+}
+
+// ReceiveGoodsLineInput is one delivered lot. UnitCost defaults to the
+// purchase order line's cost when omitted.
+type ReceiveGoodsLineInput struct {
+	ItemID     uuid.UUID `json:"item_id" validate:"required"`
+	LotNumber  string    `json:"lot_number" validate:"required,min=1,max=50"`
+	ExpiryDate time.Time `json:"expiry_date" validate:"required,future_date"`
+	Quantity   int       `json:"quantity" validate:"required,gt=0"`
+	UnitCost   *Money    `json:"unit_cost" validate:"omitempty,gte=0"`
+}
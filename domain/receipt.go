@@ -1,39 +1,266 @@
 package domain
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type ReceiptItem struct {
-	Brand        string  `json:"brand" validate:"required"`
-	MedicineName string  `json:"medicine_name" validate:"required"`
-	PricePerUnit float64 `json:"price_per_unit" validate:"required"`
-	Quantity     int     `json:"quantity" validate:"required"`
-	Subtotal     float64 `json:"subtotal" validate:"required"`
+	Brand        string `json:"brand" validate:"required"`
+	MedicineName string `json:"medicine_name" validate:"required"`
+	PricePerUnit Money  `json:"price_per_unit" validate:"required"`
+	Quantity     int    `json:"quantity" validate:"required"`
+	Subtotal     Money  `json:"subtotal" validate:"required"`
+	// Lots the items were dispensed from, for recalls
+	Lots []LotAllocation `json:"lots,omitempty"`
+	// Discount comes off Subtotal; the rest is split by VAT, and Gross is
+	// what the customer pays for the line
+	Discount    Money       `json:"discount,omitempty"`
+	TaxCategory TaxCategory `json:"tax_category,omitempty"`
+	TaxRate     int         `json:"tax_rate"`
+	Net         Money       `json:"net"`
+	Tax         Money       `json:"tax"`
+	Gross       Money       `json:"gross"`
+	// Cost and margin are for the pharmacy only; see CustomerCopy
+	UnitCost Money `json:"unit_cost,omitempty"`
+	Margin   Money `json:"margin,omitempty"`
 }
 
 type ReceiptContent struct {
 	Items      []ReceiptItem `json:"items" validate:"required"`
 	PharmacyID uuid.UUID     `json:"pharmacy_id" validate:"required"`
 	SaleDate   time.Time     `json:"sale_date" validate:"required"`
-	TotalPrice float64       `json:"total_price" validate:"required"`
+	TotalPrice Money         `json:"total_price" validate:"required"`
+	TotalNet   Money         `json:"total_net"`
+	TotalTax   Money         `json:"total_tax"`
+	TaxLines   []TaxLine     `json:"tax_lines,omitempty"`
+	// Promotions and manual discounts applied, totalling TotalDiscount
+	TotalDiscount Money             `json:"total_discount,omitempty"`
+	Discounts     []AppliedDiscount `json:"discounts,omitempty"`
+	// Tenders paid and the cash change given
+	Payments  []Payment `json:"payments,omitempty"`
+	Change    Money     `json:"change,omitempty"`
+	TotalCost Money     `json:"total_cost,omitempty"`
+	Margin    Money     `json:"margin,omitempty"`
 }
 
+// CustomerCopy returns the content without cost and margin figures, for
+// anything shown to the customer
+func (c ReceiptContent) CustomerCopy() ReceiptContent {
+	items := make([]ReceiptItem, len(c.Items))
+	for i, item := range c.Items {
+		item.UnitCost = 0
+		item.Margin = 0
+		items[i] = item
+	}
+	c.Items = items
+	c.TotalCost = 0
+	c.Margin = 0
+	return c
+}
+
+// ReceiptSettings is how a pharmacy's receipts are printed and numbered.
+// Header and Footer are printed above and below the sale as given, line
+// breaks included. PaperWidth is the thermal roll width in millimetres.
+// Receipt numbers start with NumberPrefix and, with YearlyReset, the year of
+// the sale, numbering from 1 again each year.
+type ReceiptSettings struct {
+	Header       string `json:"header" validate:"max=500"`
+	Footer       string `json:"footer" validate:"max=500"`
+	PaperWidth   int    `json:"paper_width" validate:"oneof=58 80"`
+	NumberPrefix string `json:"number_prefix" validate:"max=20,printascii,excludesall=/?#%"`
+	YearlyReset  bool   `json:"yearly_reset"`
+}
+
+// NumberSeries is the series a receipt for a sale made at the given time is
+// numbered in: its year (UTC) with a yearly reset, and 0 otherwise
+func (s ReceiptSettings) NumberSeries(saleDate time.Time) int {
+	if s.YearlyReset {
+		return saleDate.UTC().Year()
+	}
+	return 0
+}
+
+// ReceiptNumber formats the nth receipt of a series, e.g. "MAIN-2026-000042"
+func (s ReceiptSettings) ReceiptNumber(series int, n int64) string {
+	number := fmt.Sprintf("%06d", n)
+	if series != 0 {
+		number = fmt.Sprintf("%d-%s", series, number)
+	}
+	if s.NumberPrefix != "" {
+		number = s.NumberPrefix + "-" + number
+	}
+	return number
+}
+
+// DefaultPaperWidth is the roll width receipts are laid out for unless a
+// pharmacy chooses otherwise
+const DefaultPaperWidth = 80
+
+// Receipt is a sale's receipt. Number is allocated by CreateSale, one after
+// another per pharmacy; receipts from before numbering have none.
+//
+// CreateSale also chains each receipt to the pharmacy's one before it: Hash
+// covers the receipt and PreviousHash, the hash of the receipt at
+// ChainPosition-1, so a receipt cannot be edited, removed or inserted without
+// breaking the chain. Receipts from before chaining are at position 0.
 type Receipt struct {
-	ID        uuid.UUID      `json:"id" validate:"required"`
-	SaleID    uuid.UUID      `json:"sale_id" validate:"required"`
-	Content   ReceiptContent `json:"content" validate:"required"`
-	CreatedAt time.Time      `json:"created_at" validate:"required"`
+	ID            uuid.UUID      `json:"id" validate:"required"`
+	SaleID        uuid.UUID      `json:"sale_id" validate:"required"`
+	Number        string         `json:"number,omitempty"`
+	Content       ReceiptContent `json:"content" validate:"required"`
+	ChainPosition int64          `json:"chain_position,omitempty"`
+	PreviousHash  string         `json:"previous_hash,omitempty"`
+	Hash          string         `json:"hash,omitempty"`
+	CreatedAt     time.Time      `json:"created_at" validate:"required"`
+	// Temporary field for response
+	VerifyURL string `json:"verify_url,omitempty"`
+}
+
+// receiptHashInput is the canonical form of a receipt that its hash covers
+type receiptHashInput struct {
+	ID            uuid.UUID      `json:"id"`
+	SaleID        uuid.UUID      `json:"sale_id"`
+	Number        string         `json:"number"`
+	ChainPosition int64          `json:"chain_position"`
+	PreviousHash  string         `json:"previous_hash"`
+	Content       ReceiptContent `json:"content"`
+}
+
+// ComputeHash returns the hex SHA-256 of the receipt's canonical form: its
+// IDs, number, place in the chain, previous hash and content
+func (r Receipt) ComputeHash() (string, error) {
+	b, err := json.Marshal(receiptHashInput{
+		ID:            r.ID,
+		SaleID:        r.SaleID,
+		Number:        r.Number,
+		ChainPosition: r.ChainPosition,
+		PreviousHash:  r.PreviousHash,
+		Content:       r.Content,
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerificationURL is the link printed as a QR code on the receipt, at which
+// anyone holding it can check its hash. It is empty for unchained receipts.
+func (r Receipt) VerificationURL(base string) string {
+	if r.Hash == "" {
+		return ""
+	}
+	q := url.Values{}
+	q.Set("pharmacy_id", r.Content.PharmacyID.String())
+	q.Set("number", r.Number)
+	q.Set("hash", r.Hash)
+	return base + "?" + q.Encode()
+}
+
+// ReceiptChainHead is the last receipt chained for a pharmacy
+type ReceiptChainHead struct {
+	PharmacyID uuid.UUID `json:"pharmacy_id"`
+	Position   int64     `json:"position"`
+	Hash       string    `json:"hash"`
+}
+
+// Reasons a receipt chain fails to verify
+const (
+	ChainBreakAltered = "receipt does not match its hash"
+	ChainBreakLink    = "previous hash does not match the receipt before"
+	ChainBreakMissing = "receipts are missing before this one"
+	ChainBreakTail    = "receipts are missing from the end of the chain"
+)
+
+// ChainBreak is a point at which a pharmacy's receipt chain fails to verify
+type ChainBreak struct {
+	Position  int64     `json:"position"`
+	ReceiptID uuid.UUID `json:"receipt_id,omitempty"`
+	Number    string    `json:"number,omitempty"`
+	Reason    string    `json:"reason"`
+}
+
+// ChainReport is the result of walking a pharmacy's receipt chain. Feed it
+// the receipts in chain order with Check, then Finish it with the head.
+type ChainReport struct {
+	PharmacyID uuid.UUID    `json:"pharmacy_id"`
+	Checked    int          `json:"checked"`
+	Valid      bool         `json:"valid"`
+	Breaks     []ChainBreak `json:"breaks"`
+
+	position int64
+	hash     string
+}
+
+// Check verifies the next receipt in the chain against the one before
+func (c *ChainReport) Check(r Receipt) error {
+	brk := func(reason string) {
+		c.Breaks = append(c.Breaks, ChainBreak{Position: r.ChainPosition, ReceiptID: r.ID, Number: r.Number, Reason: reason})
+	}
+	hash, err := r.ComputeHash()
+	if err != nil {
+		return err
+	}
+	if hash != r.Hash {
+		brk(ChainBreakAltered)
+	}
+	if r.ChainPosition != c.position+1 {
+		brk(ChainBreakMissing)
+	} else if r.PreviousHash != c.hash {
+		brk(ChainBreakLink)
+	}
+	c.Checked++
+	c.position = r.ChainPosition
+	c.hash = r.Hash
+	return nil
+}
+
+// Finish checks that the last receipt checked is the head of the chain
+func (c *ChainReport) Finish(head ReceiptChainHead) {
+	if head.Position != c.position || head.Hash != c.hash {
+		c.Breaks = append(c.Breaks, ChainBreak{Position: head.Position, Reason: ChainBreakTail})
+	}
+	c.Valid = len(c.Breaks) == 0
+	if c.Breaks == nil {
+		c.Breaks = []ChainBreak{}
+	}
+}
+
+// ReceiptVerification is the result of checking a single receipt's hash.
+// The sale's date and total are only given when the hash matches.
+type ReceiptVerification struct {
+	PharmacyID uuid.UUID  `json:"pharmacy_id"`
+	Number     string     `json:"number"`
+	Valid      bool       `json:"valid"`
+	Reason     string     `json:"reason,omitempty"`
+	SaleDate   *time.Time `json:"sale_date,omitempty"`
+	TotalPrice *Money     `json:"total_price,omitempty"`
 }
 
 type ReceiptResponse struct {
 	ID         uuid.UUID     `json:"id"`
 	SaleID     uuid.UUID     `json:"sale_id"`
+	Number     string        `json:"number,omitempty"`
+	Hash       string        `json:"hash,omitempty"`
+	VerifyURL  string        `json:"verify_url,omitempty"`
 	Items      []ReceiptItem `json:"items"`
 	PharmacyID uuid.UUID     `json:"pharmacy_id"`
 	SaleDate   time.Time     `json:"sale_date"`
-	TotalPrice float64       `json:"total_price"`
-	CreatedAt  time.Time     `json:"created_at"`
+	TotalPrice Money         `json:"total_price"`
+	TotalNet   Money         `json:"total_net"`
+	TotalTax   Money         `json:"total_tax"`
+	TaxLines   []TaxLine     `json:"tax_lines"`
+	// Discounts applied, totalling TotalDiscount
+	TotalDiscount Money             `json:"total_discount"`
+	Discounts     []AppliedDiscount `json:"discounts"`
+	Payments      []Payment         `json:"payments"`
+	Change        Money             `json:"change"`
+	CreatedAt     time.Time         `json:"created_at"`
 }
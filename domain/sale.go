@@ -6,19 +6,32 @@ import (
 	"github.com/google/uuid"
 )
 
-// Cart represents an item in the user's cart
+// Cart represents an item in the user's cart. Until ReservedUntil the line
+// holds its quantity back from other carts and sales; once that passes, or
+// the reservation is released, the units can be sold to anyone again. Lines
+// of a parked cart have its ParkedCartID; the rest make up the active cart.
 type Cart struct {
-	ID                uuid.UUID `json:"id" validate:"required"`
-	UserID            uuid.UUID `json:"user_id" validate:"required"`
-	PharmacyID        uuid.UUID `json:"pharmacy_id" validate:"required"`
-	MedicineVariantID uuid.UUID `json:"medicine_variant_id" validate:"required"`
-	Quantity          int       `json:"quantity" validate:"required,gt=0"`
-	CreatedAt         time.Time `json:"created_at" validate:"required"`
+	ID                uuid.UUID  `json:"id" validate:"required"`
+	UserID            uuid.UUID  `json:"user_id" validate:"required"`
+	PharmacyID        uuid.UUID  `json:"pharmacy_id" validate:"required"`
+	MedicineVariantID uuid.UUID  `json:"medicine_variant_id" validate:"required"`
+	Quantity          int        `json:"quantity" validate:"required,gt=0"`
+	ReservedUntil     *time.Time `json:"reserved_until"`
+	ParkedCartID      *uuid.UUID `json:"parked_cart_id,omitempty"`
+	CreatedAt         time.Time  `json:"created_at" validate:"required"`
 	// Temporary fields for response
-	MedicineName string  `json:"medicine,omitempty"`
-	PricePerUnit float64 `json:"price_per_unit,omitempty"`
-	Unit         string  `json:"unit,omitempty"`
-	ImageURL     string  `json:"image_url,omitempty"`
+	MedicineName string `json:"medicine,omitempty"`
+	PricePerUnit Money  `json:"price_per_unit,omitempty"`
+	Unit         string `json:"unit,omitempty"`
+	ImageURL     string `json:"image_url,omitempty"`
+}
+
+// CheckoutLine is a line of a cart being checked out, with its variant and
+// medicine as they stand once the cart and the variant's stock are locked
+type CheckoutLine struct {
+	Cart     Cart
+	Variant  MedicineVariant
+	Medicine Medicine
 }
 
 // CreateCartInput for adding an item to the cart
@@ -27,49 +40,205 @@ type CreateCartInput struct {
 	Quantity          int       `json:"quantity" validate:"required,gt=0"`
 }
 
-// CartResponse represents the response structure for a cart item
+// UpdateCartItemInput for setting the quantity of a cart line
+type UpdateCartItemInput struct {
+	Quantity int `json:"quantity" validate:"required,gt=0"`
+}
+
+// ParkedCart is a cart set aside under a label, such as the customer's name,
+// while the user serves someone else. Its lines keep their reservations
+// until they run out. Lines and Quantity count its lines and their units.
+type ParkedCart struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	PharmacyID uuid.UUID `json:"pharmacy_id"`
+	Label      string    `json:"label"`
+	Lines      int       `json:"lines"`
+	Quantity   int       `json:"quantity"`
+	ParkedAt   time.Time `json:"parked_at"`
+}
+
+// ParkCartInput for parking the active cart
+type ParkCartInput struct {
+	Label string `json:"label" validate:"required,max=100"`
+}
+
+// CartResponse represents the response structure for a cart item. Discount
+// is the line's share of every promotion and manual discount in the cart.
+// ReservedUntil is when the line's reservation runs out, nil once it has.
 type CartResponse struct {
-	ID           uuid.UUID `json:"id"`
-	Medicine     string    `json:"medicine"`
-	PricePerUnit float64   `json:"price_per_unit"`
-	Unit         string    `json:"unit"`
-	ImageURL     string    `json:"image_url"`
-	Quantity     int       `json:"quantity"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID            uuid.UUID  `json:"id"`
+	Medicine      string     `json:"medicine"`
+	PricePerUnit  Money      `json:"price_per_unit"`
+	Unit          string     `json:"unit"`
+	ImageURL      string     `json:"image_url"`
+	Quantity      int        `json:"quantity"`
+	Subtotal      Money      `json:"subtotal"`
+	Discount      Money      `json:"discount"`
+	ReservedUntil *time.Time `json:"reserved_until"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Reserved reports whether the line still holds its stock at t
+func (c Cart) Reserved(t time.Time) bool {
+	return c.ReservedUntil != nil && c.ReservedUntil.After(t)
+}
+
+// CartView is the user's cart priced with the promotions that would apply if
+// it were checked out now. Total is before any VAT added at checkout.
+type CartView struct {
+	Items     []CartResponse    `json:"items"`
+	Subtotal  Money             `json:"subtotal"`
+	Discount  Money             `json:"discount"`
+	Total     Money             `json:"total"`
+	Discounts []AppliedDiscount `json:"discounts"`
 }
 
-// SaleItem represents an item in a sale
+// SaleItem represents an item in a sale. UnitCost is the variant's cost
+// price at the time of sale; ReturnedQuantity counts units since returned.
+// Discount comes off the line total before tax; Net, Tax and Gross split what
+// is left at TaxRate (basis points).
 type SaleItem struct {
-	ID                uuid.UUID `json:"id" validate:"required"`
-	SaleID            uuid.UUID `json:"sale_id" validate:"required"`
-	MedicineVariantID uuid.UUID `json:"medicine_variant_id" validate:"required"`
-	Quantity          int       `json:"quantity" validate:"required,gt=0"`
-	PricePerUnit      float64   `json:"price_per_unit" validate:"required,gt=0"`
-	CreatedAt         time.Time `json:"created_at" validate:"required"`
+	ID                uuid.UUID   `json:"id" validate:"required"`
+	SaleID            uuid.UUID   `json:"sale_id" validate:"required"`
+	MedicineVariantID uuid.UUID   `json:"medicine_variant_id" validate:"required"`
+	Quantity          int         `json:"quantity" validate:"required,gt=0"`
+	PricePerUnit      Money       `json:"price_per_unit" validate:"required,gt=0"`
+	UnitCost          Money       `json:"unit_cost" validate:"gte=0"`
+	ReturnedQuantity  int         `json:"returned_quantity"`
+	Discount          Money       `json:"discount"`
+	TaxCategory       TaxCategory `json:"tax_category"`
+	TaxRate           int         `json:"tax_rate"`
+	Net               Money       `json:"net"`
+	Tax               Money       `json:"tax"`
+	Gross             Money       `json:"gross"`
+	CreatedAt         time.Time   `json:"created_at" validate:"required"`
+	// Lots the quantity was taken from, filled in by CreateSale
+	Lots []LotAllocation `json:"lots,omitempty"`
 	// Temporary fields for response
 	MedicineName string `json:"medicine,omitempty"`
 	Unit         string `json:"unit,omitempty"`
 	ImageURL     string `json:"image_url,omitempty"`
 }
 
-// Sale represents a completed sale
+// Sale represents a completed sale. TotalPrice is the gross amount paid,
+// split into TotalNet and TotalTax, after TotalDiscount was taken off by the
+// promotions and manual discounts in Discounts. TotalRefunded is the amount
+// refunded by returns against it. Payments are the tenders it was paid with,
+// Change the cash given back. ShiftID is the cashier's shift it was taken in.
+// IdempotencyKey, when set, is the reserved
+// key that CreateSale completes with the sale.
 type Sale struct {
-	ID         uuid.UUID `json:"id" validate:"required"`
-	UserID     uuid.UUID `json:"user_id" validate:"required"`
-	PharmacyID uuid.UUID `json:"pharmacy_id" validate:"required"`
-	TotalPrice float64   `json:"total_price" validate:"required,gte=0"`
-	SaleDate   time.Time `json:"sale_date" validate:"required"`
-	CreatedAt  time.Time `json:"created_at" validate:"required"`
-	UpdatedAt  time.Time `json:"updated_at" validate:"required"`
+	ID            uuid.UUID  `json:"id" validate:"required"`
+	UserID        uuid.UUID  `json:"user_id" validate:"required"`
+	PharmacyID    uuid.UUID  `json:"pharmacy_id" validate:"required"`
+	ShiftID       *uuid.UUID `json:"shift_id,omitempty"`
+	CustomerID    *uuid.UUID `json:"customer_id,omitempty"`
+	ReceiptNumber string     `json:"receipt_number,omitempty"`
+	TotalPrice    Money      `json:"total_price" validate:"required,gte=0"`
+	TotalNet      Money      `json:"total_net"`
+	TotalTax      Money      `json:"total_tax"`
+	TotalDiscount Money      `json:"total_discount"`
+	TotalRefunded Money      `json:"total_refunded"`
+	SaleDate      time.Time  `json:"sale_date" validate:"required"`
+	CreatedAt     time.Time  `json:"created_at" validate:"required"`
+	UpdatedAt     time.Time  `json:"updated_at" validate:"required"`
+
+	Discounts []AppliedDiscount `json:"discounts,omitempty"`
+	Payments  []Payment         `json:"payments,omitempty"`
+	Change    Money             `json:"change"`
+	// Full name of the user who made the sale, filled in by GetSaleByID
+	Cashier string `json:"cashier,omitempty"`
+
+	IdempotencyKey string `json:"-"`
+}
+
+// SaleHeader is a sale as listed in the sale history. ItemCount is the
+// number of lines on the sale and UnitCount the units sold across them.
+type SaleHeader struct {
+	ID            uuid.UUID  `json:"id"`
+	ReceiptNumber string     `json:"receipt_number,omitempty"`
+	PharmacyID    uuid.UUID  `json:"pharmacy_id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	Cashier       string     `json:"cashier"`
+	CustomerID    *uuid.UUID `json:"customer_id,omitempty"`
+	TotalPrice    Money      `json:"total_price"`
+	TotalRefunded Money      `json:"total_refunded"`
+	ItemCount     int        `json:"item_count"`
+	UnitCount     int        `json:"unit_count"`
+	SaleDate      time.Time  `json:"sale_date"`
+}
+
+// SaleSort is the order sales are listed in; a leading "-" sorts descending
+type SaleSort string
+
+const (
+	SaleSortDate      SaleSort = "date"
+	SaleSortDateDesc  SaleSort = "-date"
+	SaleSortTotal     SaleSort = "total"
+	SaleSortTotalDesc SaleSort = "-total"
+)
+
+// IsValid checks if the sort order is known
+func (s SaleSort) IsValid() bool {
+	switch s {
+	case SaleSortDate, SaleSortDateDesc, SaleSortTotal, SaleSortTotalDesc:
+		return true
+	}
+	return false
+}
+
+// SaleFilter selects sales from the sale history. Zero fields do not filter:
+// a nil PharmacyID lists every pharmacy's sales. From is inclusive and To
+// exclusive; MedicineID matches sales of any of the medicine's variants.
+// Sales are listed newest first unless Sort says otherwise, ties broken by ID.
+type SaleFilter struct {
+	PharmacyID uuid.UUID
+	From       time.Time
+	To         time.Time
+	UserID     uuid.UUID
+	CustomerID uuid.UUID
+	MedicineID uuid.UUID
+	MinTotal   *Money
+	MaxTotal   *Money
+	Sort       SaleSort
+	Limit      int
+	Offset     int
+}
+
+// SalePage is one page of the sale history. Total counts every sale the
+// filter matches.
+type SalePage struct {
+	Sales  []SaleHeader `json:"sales"`
+	Total  int          `json:"total"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+}
+
+// SaleDetail is a sale with its lines
+type SaleDetail struct {
+	Sale
+	Items []SaleResponse `json:"items"`
 }
 
-// SaleResponse represents the response structure for a sale
+// SaleResponse represents the response structure for a sale's line. Margin is
+// the line's net revenue less its cost, net of returns. Net, Tax and Gross
+// are the line as sold.
 type SaleResponse struct {
-	ID           uuid.UUID `json:"id"`
-	Medicine     string    `json:"medicine"`
-	PricePerUnit float64   `json:"price_per_unit"`
-	Unit         string    `json:"unit"`
-	ImageURL     string    `json:"image_url"`
-	Quantity     int       `json:"quantity"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID               uuid.UUID   `json:"id"`
+	Medicine         string      `json:"medicine"`
+	PricePerUnit     Money       `json:"price_per_unit"`
+	UnitCost         Money       `json:"unit_cost"`
+	Margin           Money       `json:"margin"`
+	Discount         Money       `json:"discount"`
+	TaxCategory      TaxCategory `json:"tax_category"`
+	TaxRate          int         `json:"tax_rate"`
+	Net              Money       `json:"net"`
+	Tax              Money       `json:"tax"`
+	Gross            Money       `json:"gross"`
+	Unit             string      `json:"unit"`
+	ImageURL         string      `json:"image_url"`
+	Quantity         int         `json:"quantity"`
+	ReturnedQuantity int         `json:"returned_quantity"`
+	CreatedAt        time.Time   `json:"created_at"`
 }
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SaleReturn records items a customer brought back from a completed sale.
+// Returned units go back into the lots they were sold from, either as
+// sellable stock or, when Quarantine is set, held aside in the lot's
+// quarantined quantity. The refund is paid out through RefundMethod in the
+// shift ShiftID, if the user had one open.
+type SaleReturn struct {
+	ID           uuid.UUID        `json:"id"`
+	SaleID       uuid.UUID        `json:"sale_id"`
+	UserID       uuid.UUID        `json:"user_id"`
+	ShiftID      *uuid.UUID       `json:"shift_id"`
+	Reason       string           `json:"reason"`
+	Quarantine   bool             `json:"quarantine"`
+	RefundMethod PaymentMethod    `json:"refund_method"`
+	TotalRefund  Money            `json:"total_refund"`
+	Items        []SaleReturnItem `json:"items"`
+	CreditNote   CreditNote       `json:"credit_note"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// SaleReturnItem is the quantity of one sale item returned, refunded at the
+// price it was sold for. Tax is the part of Refund that was VAT.
+type SaleReturnItem struct {
+	ID                uuid.UUID `json:"id"`
+	ReturnID          uuid.UUID `json:"return_id"`
+	SaleItemID        uuid.UUID `json:"sale_item_id"`
+	MedicineVariantID uuid.UUID `json:"medicine_variant_id"`
+	Quantity          int       `json:"quantity"`
+	PricePerUnit      Money     `json:"price_per_unit"`
+	UnitCost          Money     `json:"unit_cost"`
+	Refund            Money     `json:"refund"`
+	Tax               Money     `json:"tax"`
+	// Lots the quantity was returned to, filled in by CreateReturn
+	Lots []LotAllocation `json:"lots,omitempty"`
+}
+
+// CreditNote is the customer's receipt for a return, linked to the original
+// sale's receipt
+type CreditNote struct {
+	ID        uuid.UUID         `json:"id"`
+	ReturnID  uuid.UUID         `json:"return_id"`
+	ReceiptID uuid.UUID         `json:"receipt_id"`
+	Content   CreditNoteContent `json:"content"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// CreditNoteContent is the printable content of a credit note. Items are
+// listed with their refunded subtotals.
+type CreditNoteContent struct {
+	Items       []ReceiptItem `json:"items"`
+	PharmacyID  uuid.UUID     `json:"pharmacy_id"`
+	SaleID      uuid.UUID     `json:"sale_id"`
+	ReceiptID   uuid.UUID     `json:"receipt_id"`
+	Reason      string        `json:"reason"`
+	ReturnDate  time.Time     `json:"return_date"`
+	TotalRefund Money         `json:"total_refund"`
+	TotalTax    Money         `json:"total_tax"`
+	TaxLines    []TaxLine     `json:"tax_lines,omitempty"`
+}
+
+// CreateSaleReturnInput for returning items of a sale. The refund is paid in
+// cash unless RefundMethod says otherwise.
+type CreateSaleReturnInput struct {
+	Items        []SaleReturnItemInput `json:"items" validate:"required,min=1,dive"`
+	Reason       string                `json:"reason" validate:"required,min=3,max=255"`
+	Quarantine   bool                  `json:"quarantine"`
+	RefundMethod PaymentMethod         `json:"refund_method" validate:"omitempty,oneof=cash card mobile_money insurance credit_account"`
+}
+
+// SaleReturnItemInput is one line of a CreateSaleReturnInput
+type SaleReturnItemInput struct {
+	SaleItemID uuid.UUID `json:"sale_item_id" validate:"required"`
+	Quantity   int       `json:"quantity" validate:"required,gt=0"`
+}
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShiftStatus is whether a shift is still taking sales
+type ShiftStatus string
+
+const (
+	ShiftOpen   ShiftStatus = "open"
+	ShiftClosed ShiftStatus = "closed"
+)
+
+// Shift is a cashier's session at the till. It opens with OpeningFloat in
+// the cash drawer, every sale the cashier confirms and refund they pay out is
+// attached to it, and it closes with the amounts counted per tender. A user has at most one open
+// shift.
+type Shift struct {
+	ID           uuid.UUID     `json:"id"`
+	PharmacyID   uuid.UUID     `json:"pharmacy_id"`
+	UserID       uuid.UUID     `json:"user_id"`
+	OpeningFloat Money         `json:"opening_float"`
+	Status       ShiftStatus   `json:"status"`
+	Counts       []TenderCount `json:"counts,omitempty"`
+	OpenedAt     time.Time     `json:"opened_at"`
+	ClosedAt     *time.Time    `json:"closed_at"`
+}
+
+// OpenShiftInput for opening a shift
+type OpenShiftInput struct {
+	OpeningFloat Money `json:"opening_float" validate:"gte=0"`
+}
+
+// TenderCount is the amount counted for one tender when a shift closes
+type TenderCount struct {
+	Method PaymentMethod `json:"method" validate:"required,oneof=cash card mobile_money insurance credit_account"`
+	Amount Money         `json:"amount" validate:"gte=0"`
+}
+
+// CloseShiftInput for closing a shift. Counts must include cash; other
+// tenders are counted only if given.
+type CloseShiftInput struct {
+	Counts []TenderCount `json:"counts" validate:"required,min=1,dive"`
+}
+
+// TenderTotal is what a shift's sales took through one tender. Amount is
+// what was handed over and Change what was given back from it.
+type TenderTotal struct {
+	Method PaymentMethod
+	Count  int
+	Amount Money
+	Change Money
+}
+
+// ShiftTotals is what a shift's sales took and its returns refunded, by
+// tender. A refund's Amount is what was paid out; it gives no change.
+type ShiftTotals struct {
+	SaleCount  int
+	TotalSales Money
+	Tenders    []TenderTotal
+	Refunds    []TenderTotal
+}
+
+// TenderReport is one tender's line of a Z-report. Expected is what should
+// be on hand: what was taken less what was refunded, which for cash includes
+// the opening float. Counted and Variance are nil when the tender was not
+// counted.
+type TenderReport struct {
+	Method   PaymentMethod `json:"method"`
+	Count    int           `json:"count"`
+	Taken    Money         `json:"taken"`
+	Refunded Money         `json:"refunded"`
+	Expected Money         `json:"expected"`
+	Counted  *Money        `json:"counted"`
+	Variance *Money        `json:"variance"`
+}
+
+// ShiftReport is a shift's Z-report: what was taken, refunded, expected and
+// counted per tender. Variance is counted less expected across the counted
+// tenders.
+type ShiftReport struct {
+	Shift        Shift          `json:"shift"`
+	SaleCount    int            `json:"sale_count"`
+	TotalSales   Money          `json:"total_sales"`
+	ReturnCount  int            `json:"return_count"`
+	TotalRefunds Money          `json:"total_refunds"`
+	Tenders      []TenderReport `json:"tenders"`
+	Variance     Money          `json:"variance"`
+}
+
+// NewShiftReport builds a shift's Z-report from its totals. Cash is always
+// listed, followed by the other tenders that were taken, refunded or counted.
+func NewShiftReport(shift Shift, totals ShiftTotals) ShiftReport {
+	report := ShiftReport{Shift: shift, SaleCount: totals.SaleCount, TotalSales: totals.TotalSales}
+	line := func(method PaymentMethod) *TenderReport {
+		for i := range report.Tenders {
+			if report.Tenders[i].Method == method {
+				return &report.Tenders[i]
+			}
+		}
+		report.Tenders = append(report.Tenders, TenderReport{Method: method})
+		return &report.Tenders[len(report.Tenders)-1]
+	}
+
+	line(PaymentCash).Expected = shift.OpeningFloat
+	for _, t := range totals.Tenders {
+		l := line(t.Method)
+		l.Count += t.Count
+		l.Taken += t.Amount - t.Change
+		l.Expected += t.Amount - t.Change
+	}
+	for _, t := range totals.Refunds {
+		l := line(t.Method)
+		l.Refunded += t.Amount
+		l.Expected -= t.Amount
+		report.ReturnCount += t.Count
+		report.TotalRefunds += t.Amount
+	}
+	for _, c := range shift.Counts {
+		l := line(c.Method)
+		counted, variance := c.Amount, c.Amount-l.Expected
+		l.Counted, l.Variance = &counted, &variance
+		report.Variance += variance
+	}
+	return report
+}
@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockMovementType is the reason a lot's quantity changed
+type StockMovementType string
+
+const (
+	StockMovementSale           StockMovementType = "sale"
+	StockMovementReceipt        StockMovementType = "receipt"
+	StockMovementAdjustment     StockMovementType = "adjustment"
+	StockMovementDamage         StockMovementType = "damage"
+	StockMovementExpiryWriteOff StockMovementType = "expiry_write_off"
+	StockMovementTransfer       StockMovementType = "transfer"
+	StockMovementReturn         StockMovementType = "return"
+)
+
+// StockMovement is an append-only ledger entry for a change to one lot.
+// Quantity is signed; QuantityBefore and QuantityAfter are the variant's
+// on-hand quantity across all of its lots, expired ones included.
+type StockMovement struct {
+	ID             uuid.UUID         `json:"id"`
+	VariantID      uuid.UUID         `json:"variant_id"`
+	LotID          uuid.UUID         `json:"lot_id"`
+	LotNumber      string            `json:"lot_number"`
+	Type           StockMovementType `json:"type"`
+	Quantity       int               `json:"quantity"`
+	QuantityBefore int               `json:"quantity_before"`
+	QuantityAfter  int               `json:"quantity_after"`
+	Reason         string            `json:"reason"`
+	UserID         uuid.UUID         `json:"user_id"`
+	SaleID         *uuid.UUID        `json:"sale_id,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+}
+
+// StockAdjustmentInput for a manual correction to a lot's quantity
+type StockAdjustmentInput struct {
+	LotID    uuid.UUID         `json:"lot_id" validate:"required"`
+	Type     StockMovementType `json:"type" validate:"required,oneof=adjustment damage transfer"`
+	Quantity int               `json:"quantity" validate:"required,ne=0"`
+	Reason   string            `json:"reason" validate:"required,min=3,max=255"`
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Supplier represents a company a pharmacy buys stock from
+type Supplier struct {
+	ID          uuid.UUID `json:"id"`
+	PharmacyID  uuid.UUID `json:"pharmacy_id"`
+	Name        string    `json:"name"`
+	ContactName string    `json:"contact_name"`
+	PhoneNumber string    `json:"phone_number"`
+	Email       string    `json:"email"`
+	Address     string    `json:"address"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SupplierInput for creating or updating a supplier
+type SupplierInput struct {
+	Name        string `json:"name" validate:"required,min=2,max=100"`
+	ContactName string `json:"contact_name" validate:"max=100"`
+	PhoneNumber string `json:"phone_number" validate:"omitempty,phone"`
+	Email       string `json:"email" validate:"omitempty,email,max=100"`
+	Address     string `json:"address" validate:"max=500"`
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SyncSaleItemInput is one line of a sale made offline, at the price the
+// customer was charged
+type SyncSaleItemInput struct {
+	MedicineVariantID uuid.UUID `json:"medicine_variant_id" validate:"required"`
+	Quantity          int       `json:"quantity" validate:"required,gt=0"`
+	PricePerUnit      Money     `json:"price_per_unit" validate:"required,gt=0"`
+}
+
+// SyncSaleInput is a sale made offline. The POS generates its ID, which
+// becomes the sale's, so syncing it again is harmless; SoldAt is when it was
+// made.
+type SyncSaleInput struct {
+	ID      uuid.UUID           `json:"id" validate:"required"`
+	SoldAt  time.Time           `json:"sold_at" validate:"required"`
+	Items   []SyncSaleItemInput `json:"items" validate:"required,min=1,dive"`
+	Tenders []TenderInput       `json:"tenders" validate:"required,min=1,dive"`
+}
+
+// SyncSalesInput for syncing a batch of up to 100 offline sales, applied in
+// order
+type SyncSalesInput struct {
+	Sales []SyncSaleInput `json:"sales" validate:"required,min=1,max=100,dive"`
+}
+
+// SyncStatus is the outcome of syncing one sale
+type SyncStatus string
+
+const (
+	// SyncCreated sales were recorded by this sync
+	SyncCreated SyncStatus = "created"
+	// SyncDuplicate sales had already been recorded by an earlier sync
+	SyncDuplicate SyncStatus = "duplicate"
+	// SyncConflict sales need more stock than the pharmacy has, or were
+	// charged other than the catalog price at the time; they can be synced
+	// again once stock or prices are corrected
+	SyncConflict SyncStatus = "conflict"
+	// SyncRejected sales can never be recorded as sent
+	SyncRejected SyncStatus = "rejected"
+)
+
+// StockConflict is a variant a synced sale needs more of than is available
+type StockConflict struct {
+	MedicineVariantID uuid.UUID `json:"medicine_variant_id"`
+	Requested         int       `json:"requested"`
+	Available         int       `json:"available"`
+}
+
+// PriceConflict is a variant a synced sale charged other than its catalog
+// price when the sale was made
+type PriceConflict struct {
+	MedicineVariantID uuid.UUID `json:"medicine_variant_id"`
+	Charged           Money     `json:"charged"`
+	Price             Money     `json:"price"`
+}
+
+// SyncWarningNoShift warns that a synced sale was recorded outside any shift,
+// as its user had no shift open at the pharmacy
+const SyncWarningNoShift = "no open shift: sale recorded outside any shift"
+
+// SyncSaleResult is the outcome of syncing one sale. Error says why a sale
+// was rejected or conflicts; Conflicts lists the variants short of stock and
+// PriceConflicts those charged at the wrong price. Warnings note anything
+// unusual about a sale that was recorded.
+type SyncSaleResult struct {
+	ID             uuid.UUID       `json:"id"`
+	Status         SyncStatus      `json:"status"`
+	ReceiptNumber  string          `json:"receipt_number,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	Conflicts      []StockConflict `json:"conflicts,omitempty"`
+	PriceConflicts []PriceConflict `json:"price_conflicts,omitempty"`
+	Warnings       []string        `json:"warnings,omitempty"`
+}
+
+// CatalogItem is a sellable variant as a POS caches it, or the tombstone of
+// a deleted one. ChangedAt is the last change to the variant or its medicine.
+type CatalogItem struct {
+	VariantID    uuid.UUID   `json:"variant_id"`
+	MedicineID   uuid.UUID   `json:"medicine_id"`
+	Name         string      `json:"name,omitempty"`
+	Brand        string      `json:"brand,omitempty"`
+	Barcode      string      `json:"barcode,omitempty"`
+	Unit         string      `json:"unit,omitempty"`
+	PricePerUnit Money       `json:"price_per_unit,omitempty"`
+	Category     string      `json:"category,omitempty"`
+	TaxCategory  TaxCategory `json:"tax_category,omitempty"`
+	Deleted      bool        `json:"deleted"`
+	ChangedAt    time.Time   `json:"changed_at"`
+}
+
+// CatalogCursor is the position in a pharmacy's catalog changes after the
+// last change a POS has seen. The zero cursor is before every change.
+type CatalogCursor struct {
+	ChangedAt time.Time
+	VariantID uuid.UUID
+}
+
+// String encodes the cursor for a client to send back; the zero cursor is ""
+func (c CatalogCursor) String() string {
+	if c.ChangedAt.IsZero() && c.VariantID == uuid.Nil {
+		return ""
+	}
+	raw := c.ChangedAt.UTC().Format(time.RFC3339Nano) + "_" + c.VariantID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCatalogCursor decodes a cursor from CatalogCursor.String
+func ParseCatalogCursor(s string) (CatalogCursor, error) {
+	if s == "" {
+		return CatalogCursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return CatalogCursor{}, ErrInvalidCursor
+	}
+	at, id, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return CatalogCursor{}, ErrInvalidCursor
+	}
+	changedAt, err := time.Parse(time.RFC3339Nano, at)
+	if err != nil {
+		return CatalogCursor{}, ErrInvalidCursor
+	}
+	variantID, err := uuid.Parse(id)
+	if err != nil {
+		return CatalogCursor{}, ErrInvalidCursor
+	}
+	return CatalogCursor{ChangedAt: changedAt, VariantID: variantID}, nil
+}
+
+// CatalogDelta is a page of catalog changes, oldest first. Cursor is where
+// the next request should continue from; HasMore is set when more changes
+// are already waiting there.
+type CatalogDelta struct {
+	Items   []CatalogItem `json:"items"`
+	Cursor  string        `json:"cursor"`
+	HasMore bool          `json:"has_more"`
+}
@@ -0,0 +1,66 @@
+package domain
+
+// TaxCategory is how a medicine is treated for VAT
+type TaxCategory string
+
+const (
+	// TaxStandard is taxed at the pharmacy's VAT rate
+	TaxStandard TaxCategory = "standard"
+	// TaxZeroRated is taxable at 0%, reported separately from exempt sales
+	TaxZeroRated TaxCategory = "zero_rated"
+	// TaxExempt is outside the scope of VAT
+	TaxExempt TaxCategory = "exempt"
+)
+
+// BasisPoints is a rate in hundredths of a percent: 1500 is 15%
+const BasisPoints = 10000
+
+// TaxSettings is a pharmacy's VAT configuration. VATRate is in basis points.
+// When PricesIncludeTax is set, shelf prices are gross and the tax is taken
+// out of them; otherwise tax is added on top.
+type TaxSettings struct {
+	VATRate          int  `json:"vat_rate" validate:"gte=0,lte=10000"`
+	PricesIncludeTax bool `json:"prices_include_tax"`
+}
+
+// Rate returns the rate in basis points for a tax category
+func (s TaxSettings) Rate(category TaxCategory) int {
+	if category == TaxStandard {
+		return s.VATRate
+	}
+	return 0
+}
+
+// Split breaks a line amount at the given rate into net, tax and gross. The
+// tax is rounded once per line, so net + tax always equals gross.
+func (s TaxSettings) Split(amount Money, rate int) (net, tax, gross Money) {
+	if s.PricesIncludeTax {
+		net = amount.Mul(BasisPoints).Div(BasisPoints + rate)
+		return net, amount - net, amount
+	}
+	tax = amount.Mul(rate).Div(BasisPoints)
+	return amount, tax, amount + tax
+}
+
+// TaxLine totals the lines of a sale that share a tax category and rate
+type TaxLine struct {
+	TaxCategory TaxCategory `json:"tax_category"`
+	TaxRate     int         `json:"tax_rate"`
+	Net         Money       `json:"net"`
+	Tax         Money       `json:"tax"`
+	Gross       Money       `json:"gross"`
+}
+
+// AddTaxLine adds a line's amounts to the matching entry of lines, appending
+// a new entry the first time a category and rate are seen
+func AddTaxLine(lines []TaxLine, category TaxCategory, rate int, net, tax, gross Money) []TaxLine {
+	for i := range lines {
+		if lines[i].TaxCategory == category && lines[i].TaxRate == rate {
+			lines[i].Net += net
+			lines[i].Tax += tax
+			lines[i].Gross += gross
+			return lines
+		}
+	}
+	return append(lines, TaxLine{TaxCategory: category, TaxRate: rate, Net: net, Tax: tax, Gross: gross})
+}
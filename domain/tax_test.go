@@ -0,0 +1,133 @@
+package domain_test
+
+import (
+	"testing"
+
+	"pharmacy-management-backend/domain"
+)
+
+func TestTaxSettingsRate(t *testing.T) {
+	s := domain.TaxSettings{VATRate: 1500}
+	tests := []struct {
+		category domain.TaxCategory
+		want     int
+	}{
+		{domain.TaxStandard, 1500},
+		{domain.TaxZeroRated, 0},
+		{domain.TaxExempt, 0},
+	}
+	for _, tt := range tests {
+		if got := s.Rate(tt.category); got != tt.want {
+			t.Errorf("Rate(%s) = %d, want %d", tt.category, got, tt.want)
+		}
+	}
+}
+
+func TestTaxSettingsSplit(t *testing.T) {
+	exclusive := domain.TaxSettings{VATRate: 1500}
+	inclusive := domain.TaxSettings{VATRate: 1500, PricesIncludeTax: true}
+	tests := []struct {
+		name            string
+		settings        domain.TaxSettings
+		category        domain.TaxCategory
+		amount          domain.Money
+		net, tax, gross domain.Money
+	}{
+		// Exclusive prices have the tax added on top
+		{"exclusive", exclusive, domain.TaxStandard, 1000, 1000, 150, 1150},
+		{"exclusive rounds half up", exclusive, domain.TaxStandard, 333, 333, 50, 383},
+		{"exclusive rounds down", exclusive, domain.TaxStandard, 332, 332, 50, 382},
+		{"exclusive refund", exclusive, domain.TaxStandard, -1000, -1000, -150, -1150},
+		{"exclusive zero-rated", exclusive, domain.TaxZeroRated, 1000, 1000, 0, 1000},
+		{"exclusive exempt", exclusive, domain.TaxExempt, 1000, 1000, 0, 1000},
+		// Inclusive prices have the tax taken out, leaving gross as priced
+		{"inclusive", inclusive, domain.TaxStandard, 1150, 1000, 150, 1150},
+		{"inclusive rounds net", inclusive, domain.TaxStandard, 1000, 870, 130, 1000},
+		{"inclusive one cent", inclusive, domain.TaxStandard, 1, 1, 0, 1},
+		{"inclusive refund", inclusive, domain.TaxStandard, -1150, -1000, -150, -1150},
+		{"inclusive zero-rated", inclusive, domain.TaxZeroRated, 1000, 1000, 0, 1000},
+		{"inclusive exempt", inclusive, domain.TaxExempt, 1000, 1000, 0, 1000},
+		{"zero amount", inclusive, domain.TaxStandard, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			net, tax, gross := tt.settings.Split(tt.amount, tt.settings.Rate(tt.category))
+			if net != tt.net || tax != tt.tax || gross != tt.gross {
+				t.Fatalf("Split(%d) = %d, %d, %d; want %d, %d, %d", tt.amount, net, tax, gross, tt.net, tt.tax, tt.gross)
+			}
+		})
+	}
+}
+
+// Every line splits exactly, so nothing is lost or made up in rounding
+func TestTaxSettingsSplitAddsUp(t *testing.T) {
+	for _, rate := range []int{0, 500, 700, 1500, 1750, 2000} {
+		for _, includeTax := range []bool{false, true} {
+			s := domain.TaxSettings{VATRate: rate, PricesIncludeTax: includeTax}
+			for amount := domain.Money(-500); amount <= 5000; amount++ {
+				net, tax, gross := s.Split(amount, rate)
+				if net+tax != gross {
+					t.Fatalf("rate %d, inclusive %v: Split(%d) = %d + %d != %d", rate, includeTax, amount, net, tax, gross)
+				}
+				if includeTax && gross != amount || !includeTax && net != amount {
+					t.Fatalf("rate %d, inclusive %v: Split(%d) changed the priced amount: %d, %d, %d", rate, includeTax, amount, net, tax, gross)
+				}
+			}
+		}
+	}
+}
+
+func TestAddTaxLine(t *testing.T) {
+	for _, includeTax := range []bool{false, true} {
+		s := domain.TaxSettings{VATRate: 1500, PricesIncludeTax: includeTax}
+		lines := []struct {
+			category domain.TaxCategory
+			amount   domain.Money
+		}{
+			{domain.TaxStandard, 333},
+			{domain.TaxExempt, 1250},
+			{domain.TaxStandard, 333},
+			{domain.TaxZeroRated, 499},
+			{domain.TaxStandard, 1},
+			{domain.TaxExempt, 750},
+			{domain.TaxStandard, 19999},
+		}
+
+		var taxLines []domain.TaxLine
+		var totalNet, totalTax, totalGross domain.Money
+		for _, line := range lines {
+			rate := s.Rate(line.category)
+			net, tax, gross := s.Split(line.amount, rate)
+			totalNet += net
+			totalTax += tax
+			totalGross += gross
+			taxLines = domain.AddTaxLine(taxLines, line.category, rate, net, tax, gross)
+		}
+
+		// Exempt and zero-rated lines are both at 0% but reported apart
+		if len(taxLines) != 3 || taxLines[0].TaxCategory != domain.TaxStandard || taxLines[1].TaxCategory != domain.TaxExempt ||
+			taxLines[2].TaxCategory != domain.TaxZeroRated {
+			t.Fatalf("inclusive %v: unexpected tax lines %+v", includeTax, taxLines)
+		}
+		if taxLines[1].Tax != 0 || taxLines[1].Net != 2000 || taxLines[2].Tax != 0 || taxLines[2].Net != 499 {
+			t.Fatalf("inclusive %v: untaxed lines must not be taxed, got %+v", includeTax, taxLines)
+		}
+
+		// The tax lines add up to the sale totals to the minor unit
+		var net, tax, gross domain.Money
+		for _, l := range taxLines {
+			if l.Net+l.Tax != l.Gross {
+				t.Fatalf("inclusive %v: tax line does not add up: %+v", includeTax, l)
+			}
+			net += l.Net
+			tax += l.Tax
+			gross += l.Gross
+		}
+		if net != totalNet || tax != totalTax || gross != totalGross {
+			t.Fatalf("inclusive %v: tax lines total %d/%d/%d, sale totals %d/%d/%d", includeTax, net, tax, gross, totalNet, totalTax, totalGross)
+		}
+		if includeTax && gross != 23165 {
+			t.Fatalf("inclusive prices must total what was priced, got %d", gross)
+		}
+	}
+}
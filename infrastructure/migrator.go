@@ -0,0 +1,354 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// migrationLockID is the Postgres advisory lock key held while migrating
+const migrationLockID = 72707369
+
+var migrationFileRegexp = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration represents a single versioned schema migration
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrator applies and rolls back schema migrations tracked in schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	logger     zerolog.Logger
+	migrations []Migration
+}
+
+// NewMigrator loads migrations from fsys and creates a new Migrator
+func NewMigrator(db *sql.DB, fsys fs.FS, logger zerolog.Logger) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load migrations")
+		return nil, err
+	}
+	return &Migrator{db, logger, migrations}, nil
+}
+
+// loadMigrations reads and pairs up/down files, sorted by version
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := migrationFileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("conflicting names for migration %d: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the highest known migration version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// ensureTable creates the schema_migrations tracking table if missing
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	query := `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version    INTEGER PRIMARY KEY,
+            name       TEXT NOT NULL,
+            applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+        )
+    `
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		m.logger.Error().Err(err).Msg("Failed to create schema_migrations table")
+		return err
+	}
+	return nil
+}
+
+// applied returns the applied versions with their timestamps. It only reads:
+// without a schema_migrations table nothing has been applied.
+func (m *Migrator) applied(ctx context.Context) (map[int]time.Time, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		m.logger.Error().Err(err).Msg("Failed to check for schema_migrations table")
+		return nil, err
+	}
+	applied := make(map[int]time.Time)
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := m.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		m.logger.Error().Err(err).Msg("Failed to read schema_migrations")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			m.logger.Error().Err(err).Msg("Failed to scan schema migration")
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Version returns the highest applied migration version, or 0 if none
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Status lists every known migration and whether it is applied
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that have not been applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies all pending migrations and returns how many were applied
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return 0, err
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, mig := range pending {
+		if err := m.run(ctx, mig, true); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// Down rolls back the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		m.logger.Info().Msg("No migrations to roll back")
+		return nil
+	}
+	mig, ok := m.find(version)
+	if !ok {
+		return fmt.Errorf("applied migration %d is unknown to this build", version)
+	}
+	return m.run(ctx, mig, false)
+}
+
+// To migrates up or down until target is the highest applied version
+func (m *Migrator) To(ctx context.Context, target int) error {
+	if target < 0 {
+		return fmt.Errorf("invalid target version %d", target)
+	}
+	if _, ok := m.find(target); !ok && target != 0 {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Roll back newer migrations first, newest to oldest
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; ok && mig.Version > target {
+			if err := m.run(ctx, mig, false); err != nil {
+				return err
+			}
+		}
+	}
+	for v := range applied {
+		if _, ok := m.find(v); !ok && v > target {
+			return fmt.Errorf("applied migration %d is unknown to this build", v)
+		}
+	}
+
+	// Then apply missing ones up to the target, oldest to newest
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; !ok && mig.Version <= target {
+			if err := m.run(ctx, mig, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// find looks up a migration by version
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+// run applies or reverts a single migration in its own transaction
+func (m *Migrator) run(ctx context.Context, mig Migration, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+	logger := m.logger.With().Int("version", mig.Version).Str("name", mig.Name).Str("direction", direction).Logger()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	// Serialize concurrent migrators (e.g. several instances booting at once)
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+		logger.Error().Err(err).Msg("Failed to acquire migration lock")
+		return err
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, mig.Version).Scan(&exists); err != nil {
+		logger.Error().Err(err).Msg("Failed to check migration state")
+		return err
+	}
+	if exists == up {
+		// Another migrator got here first
+		return nil
+	}
+
+	body := mig.Down
+	if up {
+		body = mig.Up
+	}
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		logger.Error().Err(err).Msg("Failed to run migration")
+		return fmt.Errorf("migration %d_%s %s: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`, mig.Version, mig.Name, time.Now())
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to record migration")
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error().Err(err).Msg("Failed to commit migration")
+		return err
+	}
+	logger.Info().Msg("Migration applied")
+	return nil
+}
@@ -0,0 +1,66 @@
+package receipt
+
+import "bytes"
+
+// ESC/POS commands, as understood by most thermal receipt printers
+var (
+	escInit        = []byte{0x1b, '@'}
+	escBoldOn      = []byte{0x1b, 'E', 1}
+	escBoldOff     = []byte{0x1b, 'E', 0}
+	escFeed        = []byte{0x1b, 'd', 4}
+	escPartCut     = []byte{0x1d, 'V', 66, 0}
+	escAlignLeft   = []byte{0x1b, 'a', 0}
+	escAlignCenter = []byte{0x1b, 'a', 1}
+)
+
+// renderESCPOS renders the layout as ESC/POS commands in the printer's
+// standard font, ending with the verification QR code, a feed and a partial
+// cut
+func renderESCPOS(v view) []byte {
+	// The printer draws the link as a QR code instead of printing it
+	verifyURL := v.VerifyURL
+	v.VerifyURL = ""
+
+	var b bytes.Buffer
+	b.Write(escInit)
+	b.Write(escAlignLeft)
+	for _, l := range layout(v) {
+		if l.bold {
+			b.Write(escBoldOn)
+		}
+		b.WriteString(ascii(l.text))
+		if l.bold {
+			b.Write(escBoldOff)
+		}
+		b.WriteByte('\n')
+	}
+	if verifyURL != "" {
+		b.Write(escAlignCenter)
+		b.WriteString("Scan to verify this receipt\n")
+		writeQR(&b, verifyURL, v.PaperWidth)
+		b.Write(escAlignLeft)
+	}
+	b.Write(escFeed)
+	b.Write(escPartCut)
+	return b.Bytes()
+}
+
+// writeQR writes the GS ( k commands that have the printer draw data as a
+// model 2 QR code with medium error correction, sized for the paper width
+func writeQR(b *bytes.Buffer, data string, paperWidth int) {
+	module := byte(5)
+	if paperWidth == 58 {
+		module = 4
+	}
+	qr := func(fn byte, params ...byte) {
+		n := len(params) + 2
+		b.Write([]byte{0x1d, '(', 'k', byte(n), byte(n >> 8), '1', fn})
+		b.Write(params)
+	}
+	qr('A', '2', 0)
+	qr('C', module)
+	qr('E', '1')
+	qr('P', append([]byte{'0'}, ascii(data)...)...)
+	qr('Q', '0')
+	b.WriteByte('\n')
+}
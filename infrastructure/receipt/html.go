@@ -0,0 +1,73 @@
+package receipt
+
+import (
+	"bytes"
+	"html/template"
+)
+
+var htmlTemplate = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Receipt {{.Reference}}</title>
+<style>
+@page { size: {{.PaperWidth}}mm auto; margin: 0; }
+body { width: {{.PaperWidth}}mm; margin: 0 auto; padding: 3mm; box-sizing: border-box; font: 11px/1.3 monospace; color: #000; }
+h1 { font-size: 14px; margin: 0; }
+header, footer { text-align: center; }
+p { margin: 0; }
+table { width: 100%; border-collapse: collapse; }
+td { padding: 0; vertical-align: top; }
+td.amount { text-align: right; white-space: nowrap; }
+tr.bold td { font-weight: bold; }
+a { color: #000; word-break: break-all; }
+hr { border: 0; border-top: 1px dashed #000; margin: 2mm 0; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Name}}</h1>
+<p>{{.Address}}</p>
+{{range .Header}}<p>{{.}}</p>
+{{end}}</header>
+<hr>
+<table>
+<tr><td>Receipt</td><td class="amount">{{.Reference}}</td></tr>
+<tr><td>Date</td><td class="amount">{{.Date}}</td></tr>
+</table>
+<hr>
+<table>
+{{range .Items}}<tr><td colspan="2">{{.Name}}</td></tr>
+<tr><td>&nbsp;&nbsp;{{.Quantity}} x {{.Price}}</td><td class="amount">{{.Amount}}</td></tr>
+{{if .Discount}}<tr><td>&nbsp;&nbsp;Discount</td><td class="amount">{{.Discount}}</td></tr>
+{{end}}{{end}}</table>
+<hr>
+<table>
+{{range .Totals}}<tr{{if .Bold}} class="bold"{{end}}><td>{{.Label}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</table>
+{{if .Payments}}<hr>
+<table>
+{{range .Payments}}<tr><td>{{.Label}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Footer}}<hr>
+<footer>
+{{range .Footer}}<p>{{.}}</p>
+{{end}}</footer>
+{{end}}{{if .VerifyURL}}<hr>
+<footer>
+<p>Verify this receipt at</p>
+<p><a href="{{.VerifyURL}}">{{.VerifyURL}}</a></p>
+</footer>
+{{end}}</body>
+</html>
+`))
+
+// renderHTML renders a standalone page sized for the paper roll, for
+// printing from a browser
+func renderHTML(v view) ([]byte, error) {
+	var b bytes.Buffer
+	if err := htmlTemplate.Execute(&b, v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,148 @@
+package receipt
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// line is one line of the fixed-width layout, padded to its full width
+type line struct {
+	text string
+	bold bool
+}
+
+// columns is how many characters of the printer's standard font fit across
+// a roll of the given width in millimetres
+func columns(paperWidth int) int {
+	if paperWidth == 58 {
+		return 32
+	}
+	return 48
+}
+
+// layout lays a receipt out in fixed-width lines for its paper width
+func layout(v view) []line {
+	w := columns(v.PaperWidth)
+	var lines []line
+	center := func(s string, bold bool) {
+		for _, part := range wrap(s, w) {
+			left := (w - utf8.RuneCountInString(part)) / 2
+			lines = append(lines, line{text: pad(strings.Repeat(" ", left)+part, w), bold: bold})
+		}
+	}
+	text := func(s string) {
+		for _, part := range wrap(s, w) {
+			lines = append(lines, line{text: pad(part, w)})
+		}
+	}
+	pair := func(label, amount string, bold bool) {
+		room := w - utf8.RuneCountInString(amount) - 1
+		label = truncate(label, room)
+		lines = append(lines, line{text: pad(label, room+1) + amount, bold: bold})
+	}
+	rule := func() {
+		lines = append(lines, line{text: strings.Repeat("-", w)})
+	}
+
+	center(v.Name, true)
+	center(v.Address, false)
+	for _, h := range v.Header {
+		center(h, false)
+	}
+	rule()
+	pair("Receipt", v.Reference, false)
+	pair("Date", v.Date, false)
+	rule()
+	for _, it := range v.Items {
+		text(it.Name)
+		pair("  "+strconv.Itoa(it.Quantity)+" x "+it.Price, it.Amount, false)
+		if it.Discount != "" {
+			pair("  Discount", it.Discount, false)
+		}
+	}
+	rule()
+	for _, r := range v.Totals {
+		pair(r.Label, r.Amount, r.Bold)
+	}
+	if len(v.Payments) > 0 {
+		rule()
+		for _, r := range v.Payments {
+			pair(r.Label, r.Amount, r.Bold)
+		}
+	}
+	if len(v.Footer) > 0 {
+		rule()
+		for _, f := range v.Footer {
+			center(f, false)
+		}
+	}
+	if v.VerifyURL != "" {
+		rule()
+		center("Verify this receipt at", false)
+		text(v.VerifyURL)
+	}
+	return lines
+}
+
+// wrap breaks s into lines of at most width characters, at spaces where it
+// can
+func wrap(s string, width int) []string {
+	var lines []string
+	var current []rune
+	for _, word := range strings.Fields(s) {
+		r := []rune(word)
+		if len(current) > 0 && len(current)+1+len(r) > width {
+			lines = append(lines, string(current))
+			current = nil
+		}
+		if len(current) > 0 {
+			current = append(current, ' ')
+		}
+		current = append(current, r...)
+		for len(current) > width {
+			lines = append(lines, string(current[:width]))
+			current = current[width:]
+		}
+	}
+	if len(current) > 0 {
+		lines = append(lines, string(current))
+	}
+	return lines
+}
+
+// pad pads s with spaces to width characters
+func pad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// truncate cuts s to at most width characters
+func truncate(s string, width int) string {
+	if r := []rune(s); len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
+
+// renderText renders the layout as plain text
+func renderText(v view) []byte {
+	var b strings.Builder
+	for _, l := range layout(v) {
+		b.WriteString(strings.TrimRight(l.text, " "))
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
+
+// ascii replaces characters a printer's built-in fonts cannot show
+func ascii(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,69 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// pdfMargin is the margin around the text, in points
+const pdfMargin = 8.0
+
+// renderPDF renders the layout as a single-page PDF the width of the paper
+// roll and as long as the receipt, set in the standard Courier fonts so no
+// font has to be embedded
+func renderPDF(v view) []byte {
+	lines := layout(v)
+	width := float64(v.PaperWidth) * 72 / 25.4
+	// Courier characters are 0.6 of the font size wide
+	size := (width - 2*pdfMargin) / (float64(columns(v.PaperWidth)) * 0.6)
+	leading := size * 1.2
+	height := 2*pdfMargin + float64(len(lines))*leading
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n%.2f TL\n%.2f %.2f Td\n", leading, pdfMargin, height-pdfMargin-size)
+	bold := false
+	fmt.Fprintf(&content, "/F1 %.2f Tf\n", size)
+	for _, l := range lines {
+		if l.bold != bold {
+			font := "/F1"
+			if l.bold {
+				font = "/F2"
+			}
+			fmt.Fprintf(&content, "%s %.2f Tf\n", font, size)
+			bold = l.bold
+		}
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscape(ascii(strings.TrimRight(l.text, " "))))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 4 0 R /F2 5 0 R >> >> /Contents 6 0 R >>",
+			width, height),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// pdfEscape escapes the characters that end or escape a PDF string
+func pdfEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`).Replace(s)
+}
@@ -0,0 +1,201 @@
+// Package receipt renders sale receipts for printing: as a standalone HTML
+// page, a PDF, ESC/POS commands for thermal printers, or plain text.
+//
+// Every format is generated locally from the same view of the receipt, and
+// all but HTML share one fixed-width layout sized for the pharmacy's paper
+// roll, so a receipt looks the same on screen, on paper and in a PDF.
+//
+// A chained receipt ends with the link at which its hash can be verified,
+// printed as a QR code on thermal printers and as the link itself elsewhere.
+package receipt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"pharmacy-management-backend/domain"
+)
+
+// Format is a rendered receipt's format
+type Format string
+
+const (
+	FormatHTML   Format = "html"
+	FormatPDF    Format = "pdf"
+	FormatESCPOS Format = "escpos"
+	FormatText   Format = "text"
+)
+
+// ErrUnknownFormat is returned for a format Render does not produce
+var ErrUnknownFormat = errors.New("unknown receipt format; use html, pdf, escpos or text")
+
+// ParseFormat parses a format name as given in a request
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatHTML, FormatPDF, FormatESCPOS, FormatText:
+		return f, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType is the MIME type of a format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatPDF:
+		return "application/pdf"
+	case FormatESCPOS:
+		return "application/octet-stream"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Document is what a receipt is rendered from. The receipt should be the
+// customer's copy of its content.
+type Document struct {
+	Pharmacy domain.Pharmacy
+	Receipt  domain.Receipt
+}
+
+// Render renders a receipt in a format
+func Render(doc Document, format Format) ([]byte, error) {
+	v := newView(doc)
+	switch format {
+	case FormatHTML:
+		return renderHTML(v)
+	case FormatPDF:
+		return renderPDF(v), nil
+	case FormatESCPOS:
+		return renderESCPOS(v), nil
+	case FormatText:
+		return renderText(v), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// row is a label and an amount on one line
+type row struct {
+	Label  string
+	Amount string
+	Bold   bool
+}
+
+// item is one receipt line
+type item struct {
+	Name     string
+	Quantity int
+	Price    string
+	Amount   string
+	Discount string
+}
+
+// view is a receipt reduced to the text every format prints
+type view struct {
+	Name       string
+	Address    string
+	Header     []string
+	Footer     []string
+	PaperWidth int
+	Reference  string
+	Date       string
+	Items      []item
+	Totals     []row
+	Payments   []row
+	VerifyURL  string
+}
+
+func newView(doc Document) view {
+	c := doc.Receipt.Content
+	settings := doc.Pharmacy.ReceiptSettings
+	v := view{
+		Name:       doc.Pharmacy.Name,
+		Address:    doc.Pharmacy.Address,
+		Header:     splitLines(settings.Header),
+		Footer:     splitLines(settings.Footer),
+		PaperWidth: settings.PaperWidth,
+		Reference:  doc.Receipt.Number,
+		Date:       c.SaleDate.Format("2006-01-02 15:04"),
+		VerifyURL:  doc.Receipt.VerifyURL,
+	}
+	// Receipts from before numbering are known by their sale
+	if v.Reference == "" {
+		v.Reference = strings.ToUpper(doc.Receipt.SaleID.String()[:8])
+	}
+	if v.PaperWidth != 58 {
+		v.PaperWidth = domain.DefaultPaperWidth
+	}
+
+	var subtotal domain.Money
+	for _, it := range c.Items {
+		name := it.MedicineName
+		if it.Brand != "" && it.Brand != it.MedicineName {
+			name += " (" + it.Brand + ")"
+		}
+		line := item{Name: name, Quantity: it.Quantity, Price: it.PricePerUnit.String(), Amount: it.Subtotal.String()}
+		if it.Discount > 0 {
+			line.Discount = (-it.Discount).String()
+		}
+		v.Items = append(v.Items, line)
+		subtotal += it.Subtotal
+	}
+
+	v.Totals = append(v.Totals, row{Label: "Subtotal", Amount: subtotal.String()})
+	for _, d := range c.Discounts {
+		v.Totals = append(v.Totals, row{Label: d.Name, Amount: (-d.Amount).String()})
+	}
+	if c.TotalTax > 0 {
+		v.Totals = append(v.Totals, row{Label: "Net", Amount: c.TotalNet.String()})
+		for _, t := range c.TaxLines {
+			if t.Tax > 0 {
+				v.Totals = append(v.Totals, row{Label: "VAT " + rate(t.TaxRate), Amount: t.Tax.String()})
+			}
+		}
+	}
+	v.Totals = append(v.Totals, row{Label: "TOTAL", Amount: c.TotalPrice.String(), Bold: true})
+
+	for _, p := range c.Payments {
+		label := methodLabel(p.Method)
+		if p.Reference != "" {
+			label += " " + p.Reference
+		}
+		v.Payments = append(v.Payments, row{Label: label, Amount: p.Amount.String()})
+	}
+	if c.Change > 0 {
+		v.Payments = append(v.Payments, row{Label: "Change", Amount: c.Change.String()})
+	}
+	return v
+}
+
+// rate formats basis points as a percentage, e.g. 1500 as "15%"
+func rate(bp int) string {
+	if bp%100 == 0 {
+		return fmt.Sprintf("%d%%", bp/100)
+	}
+	return strings.TrimRight(fmt.Sprintf("%d.%02d", bp/100, bp%100), "0") + "%"
+}
+
+func methodLabel(m domain.PaymentMethod) string {
+	switch m {
+	case domain.PaymentCash:
+		return "Cash"
+	case domain.PaymentCard:
+		return "Card"
+	case domain.PaymentMobileMoney:
+		return "Mobile money"
+	case domain.PaymentInsurance:
+		return "Insurance"
+	case domain.PaymentCreditAccount:
+		return "Credit account"
+	}
+	return string(m)
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
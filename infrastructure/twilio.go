@@ -8,6 +8,11 @@ import (
 	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
 )
 
+// SMSSender sends SMS messages
+type SMSSender interface {
+	SendSMS(to, body string) error
+}
+
 // TwilioService defines the interface for sending SMS
 type TwilioService struct {
 	client *twilio.RestClient
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS patients;
+DROP TABLE IF EXISTS hospitals;
+DROP TABLE IF EXISTS receipts;
+DROP TABLE IF EXISTS sale_items;
+DROP TABLE IF EXISTS sales;
+DROP TABLE IF EXISTS carts;
+DROP TABLE IF EXISTS medicine_variants;
+DROP TABLE IF EXISTS medicines;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS pharmacies;
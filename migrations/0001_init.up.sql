@@ -0,0 +1,138 @@
+CREATE TABLE pharmacies (
+    id         UUID PRIMARY KEY,
+    name       VARCHAR(100) NOT NULL,
+    address    TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- pharmacy_id has no foreign key: admins are stored with the nil UUID.
+CREATE TABLE users (
+    id              UUID PRIMARY KEY,
+    phone_number    VARCHAR(20) NOT NULL UNIQUE,
+    password        TEXT NOT NULL,
+    full_name       VARCHAR(100) NOT NULL,
+    role            VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'owner', 'pharmacist')),
+    pharmacy_id     UUID NOT NULL,
+    profile_picture TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_users_pharmacy_id ON users (pharmacy_id);
+
+CREATE TABLE refresh_tokens (
+    id         UUID PRIMARY KEY,
+    user_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token      TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE password_reset_tokens (
+    token      TEXT PRIMARY KEY,
+    user_id    UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE medicines (
+    id          UUID PRIMARY KEY,
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id),
+    name        VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    picture     TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_medicines_pharmacy_id ON medicines (pharmacy_id);
+
+CREATE TABLE medicine_variants (
+    id             UUID PRIMARY KEY,
+    medicine_id    UUID NOT NULL REFERENCES medicines (id),
+    brand          VARCHAR(100) NOT NULL,
+    barcode        VARCHAR(50) NOT NULL UNIQUE,
+    unit           VARCHAR(50) NOT NULL,
+    price_per_unit DOUBLE PRECISION NOT NULL CHECK (price_per_unit > 0),
+    expiry_date    TIMESTAMPTZ NOT NULL,
+    stock          INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_medicine_variants_medicine_id ON medicine_variants (medicine_id);
+
+CREATE TABLE carts (
+    id                  UUID PRIMARY KEY,
+    user_id             UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    pharmacy_id         UUID NOT NULL REFERENCES pharmacies (id),
+    medicine_variant_id UUID NOT NULL REFERENCES medicine_variants (id) ON DELETE CASCADE,
+    quantity            INTEGER NOT NULL CHECK (quantity > 0),
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, medicine_variant_id)
+);
+
+CREATE TABLE sales (
+    id          UUID PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users (id),
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id),
+    total_price DOUBLE PRECISION NOT NULL CHECK (total_price >= 0),
+    sale_date   TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_sales_pharmacy_id ON sales (pharmacy_id, sale_date DESC);
+
+CREATE TABLE sale_items (
+    id                  UUID PRIMARY KEY,
+    sale_id             UUID NOT NULL REFERENCES sales (id) ON DELETE CASCADE,
+    medicine_variant_id UUID NOT NULL REFERENCES medicine_variants (id),
+    quantity            INTEGER NOT NULL CHECK (quantity > 0),
+    price_per_unit      DOUBLE PRECISION NOT NULL CHECK (price_per_unit > 0),
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_sale_items_sale_id ON sale_items (sale_id);
+
+CREATE TABLE receipts (
+    id         UUID PRIMARY KEY,
+    sale_id    UUID NOT NULL UNIQUE REFERENCES sales (id) ON DELETE CASCADE,
+    content    JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE hospitals (
+    id         UUID PRIMARY KEY,
+    name       VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE patients (
+    id                     UUID PRIMARY KEY,
+    full_name              VARCHAR(100) NOT NULL,
+    phone_number           VARCHAR(20) NOT NULL,
+    emergency_phone_number VARCHAR(20) NOT NULL DEFAULT '',
+    created_at             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at             TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE orders (
+    id          UUID PRIMARY KEY,
+    hospital_id UUID NOT NULL REFERENCES hospitals (id),
+    patient_id  UUID NOT NULL REFERENCES patients (id),
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id),
+    order_date  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_orders_pharmacy_id ON orders (pharmacy_id, order_date DESC);
+
+CREATE TABLE order_items (
+    id                  UUID PRIMARY KEY,
+    order_id            UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    medicine_variant_id UUID NOT NULL REFERENCES medicine_variants (id),
+    quantity            INTEGER NOT NULL CHECK (quantity > 0),
+    price_per_unit      DOUBLE PRECISION NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_order_items_order_id ON order_items (order_id);
@@ -0,0 +1,15 @@
+ALTER TABLE medicine_variants
+    ADD COLUMN expiry_date TIMESTAMPTZ,
+    ADD COLUMN stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0);
+
+UPDATE medicine_variants mv
+SET stock = COALESCE((SELECT SUM(l.quantity) FROM medicine_lots l WHERE l.variant_id = mv.id), 0),
+    expiry_date = COALESCE(
+        (SELECT MIN(l.expiry_date) FROM medicine_lots l WHERE l.variant_id = mv.id AND l.quantity > 0),
+        mv.created_at
+    );
+
+ALTER TABLE medicine_variants ALTER COLUMN expiry_date SET NOT NULL;
+
+DROP TABLE IF EXISTS sale_item_lots;
+DROP TABLE IF EXISTS medicine_lots;
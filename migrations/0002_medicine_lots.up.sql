@@ -0,0 +1,30 @@
+CREATE TABLE medicine_lots (
+    id          UUID PRIMARY KEY,
+    variant_id  UUID NOT NULL REFERENCES medicine_variants (id) ON DELETE CASCADE,
+    lot_number  VARCHAR(50) NOT NULL,
+    expiry_date TIMESTAMPTZ NOT NULL,
+    quantity    INTEGER NOT NULL CHECK (quantity >= 0),
+    received_at TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (variant_id, lot_number)
+);
+CREATE INDEX idx_medicine_lots_variant_expiry ON medicine_lots (variant_id, expiry_date);
+
+CREATE TABLE sale_item_lots (
+    sale_item_id UUID NOT NULL REFERENCES sale_items (id) ON DELETE CASCADE,
+    lot_id       UUID NOT NULL REFERENCES medicine_lots (id),
+    quantity     INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (sale_item_id, lot_id)
+);
+CREATE INDEX idx_sale_item_lots_lot_id ON sale_item_lots (lot_id);
+
+-- Existing single-expiry stock becomes one lot per variant
+INSERT INTO medicine_lots (id, variant_id, lot_number, expiry_date, quantity, received_at, created_at, updated_at)
+SELECT gen_random_uuid(), id, 'LEGACY', expiry_date, stock, created_at, NOW(), NOW()
+FROM medicine_variants
+WHERE stock > 0;
+
+ALTER TABLE medicine_variants
+    DROP COLUMN stock,
+    DROP COLUMN expiry_date;
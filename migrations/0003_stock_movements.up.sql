@@ -0,0 +1,43 @@
+CREATE TABLE stock_movements (
+    id              UUID PRIMARY KEY,
+    variant_id      UUID NOT NULL REFERENCES medicine_variants (id) ON DELETE RESTRICT,
+    lot_id          UUID NOT NULL REFERENCES medicine_lots (id) ON DELETE RESTRICT,
+    type            VARCHAR(20) NOT NULL CHECK (type IN ('sale', 'receipt', 'adjustment', 'damage', 'expiry_write_off', 'transfer', 'return')),
+    quantity        INTEGER NOT NULL CHECK (quantity <> 0),
+    quantity_before INTEGER NOT NULL,
+    quantity_after  INTEGER NOT NULL,
+    reason          TEXT NOT NULL DEFAULT '',
+    user_id         UUID NOT NULL,
+    sale_id         UUID REFERENCES sales (id),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- Breaks ties between movements written at the same instant
+    seq             BIGSERIAL NOT NULL
+);
+CREATE INDEX idx_stock_movements_variant_created ON stock_movements (variant_id, created_at);
+
+-- The ledger is append-only
+CREATE FUNCTION stock_movements_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_movements_no_update
+    BEFORE UPDATE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_immutable();
+
+CREATE TRIGGER stock_movements_no_delete
+    BEFORE DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_immutable();
+
+-- Opening balances so the ledger reconciles with existing lots; the nil user
+-- marks entries made by the system
+INSERT INTO stock_movements (id, variant_id, lot_id, type, quantity, quantity_before, quantity_after, reason, user_id, created_at)
+SELECT gen_random_uuid(), variant_id, id, 'adjustment', quantity,
+       running - quantity, running, 'Opening balance', '00000000-0000-0000-0000-000000000000', NOW()
+FROM (
+    SELECT id, variant_id, quantity,
+           SUM(quantity) OVER (PARTITION BY variant_id ORDER BY received_at, id) AS running
+    FROM medicine_lots
+    WHERE quantity > 0
+) lots;
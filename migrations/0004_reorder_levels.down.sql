@@ -0,0 +1,4 @@
+ALTER TABLE medicine_variants
+    DROP COLUMN low_stock_alerted_at,
+    DROP COLUMN reorder_quantity,
+    DROP COLUMN reorder_point;
@@ -0,0 +1,5 @@
+ALTER TABLE medicine_variants
+    ADD COLUMN reorder_point INTEGER NOT NULL DEFAULT 0 CHECK (reorder_point >= 0),
+    ADD COLUMN reorder_quantity INTEGER NOT NULL DEFAULT 0 CHECK (reorder_quantity >= 0),
+    -- Set when the owner has been told the variant is low; cleared on restock
+    ADD COLUMN low_stock_alerted_at TIMESTAMPTZ;
@@ -0,0 +1,13 @@
+CREATE TABLE write_offs (
+    id          UUID PRIMARY KEY,
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id),
+    variant_id  UUID NOT NULL REFERENCES medicine_variants (id) ON DELETE RESTRICT,
+    lot_id      UUID NOT NULL REFERENCES medicine_lots (id) ON DELETE RESTRICT,
+    quantity    INTEGER NOT NULL CHECK (quantity > 0),
+    unit_value  DOUBLE PRECISION NOT NULL,
+    loss_value  DOUBLE PRECISION NOT NULL,
+    reason      TEXT NOT NULL,
+    user_id     UUID NOT NULL REFERENCES users (id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_write_offs_pharmacy_created ON write_offs (pharmacy_id, created_at);
@@ -0,0 +1,55 @@
+CREATE TABLE suppliers (
+    id           UUID PRIMARY KEY,
+    pharmacy_id  UUID NOT NULL REFERENCES pharmacies (id),
+    name         VARCHAR(100) NOT NULL,
+    contact_name VARCHAR(100) NOT NULL DEFAULT '',
+    phone_number VARCHAR(20) NOT NULL DEFAULT '',
+    email        VARCHAR(100) NOT NULL DEFAULT '',
+    address      TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_suppliers_pharmacy_id ON suppliers (pharmacy_id);
+
+CREATE TABLE purchase_orders (
+    id          UUID PRIMARY KEY,
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id),
+    supplier_id UUID NOT NULL REFERENCES suppliers (id),
+    status      VARCHAR(20) NOT NULL CHECK (status IN ('draft', 'sent', 'partially_received', 'received', 'cancelled')),
+    notes       TEXT NOT NULL DEFAULT '',
+    created_by  UUID NOT NULL REFERENCES users (id),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_purchase_orders_pharmacy_created ON purchase_orders (pharmacy_id, created_at);
+CREATE INDEX idx_purchase_orders_supplier_id ON purchase_orders (supplier_id);
+
+CREATE TABLE purchase_order_items (
+    id                UUID PRIMARY KEY,
+    purchase_order_id UUID NOT NULL REFERENCES purchase_orders (id) ON DELETE CASCADE,
+    variant_id        UUID NOT NULL REFERENCES medicine_variants (id),
+    quantity_ordered  INTEGER NOT NULL CHECK (quantity_ordered > 0),
+    quantity_received INTEGER NOT NULL DEFAULT 0 CHECK (quantity_received >= 0),
+    unit_cost         DOUBLE PRECISION NOT NULL CHECK (unit_cost >= 0),
+    position          INTEGER NOT NULL,
+    CHECK (quantity_received <= quantity_ordered)
+);
+CREATE INDEX idx_purchase_order_items_order_id ON purchase_order_items (purchase_order_id);
+
+CREATE TABLE goods_receipts (
+    id                UUID PRIMARY KEY,
+    purchase_order_id UUID NOT NULL REFERENCES purchase_orders (id),
+    received_by       UUID NOT NULL REFERENCES users (id),
+    received_at       TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_goods_receipts_order_id ON goods_receipts (purchase_order_id);
+
+CREATE TABLE goods_receipt_lines (
+    id                     UUID PRIMARY KEY,
+    goods_receipt_id       UUID NOT NULL REFERENCES goods_receipts (id) ON DELETE CASCADE,
+    purchase_order_item_id UUID NOT NULL REFERENCES purchase_order_items (id),
+    lot_id                 UUID NOT NULL REFERENCES medicine_lots (id),
+    quantity               INTEGER NOT NULL CHECK (quantity > 0),
+    unit_cost              DOUBLE PRECISION NOT NULL CHECK (unit_cost >= 0)
+);
+CREATE INDEX idx_goods_receipt_lines_receipt_id ON goods_receipt_lines (goods_receipt_id);
@@ -0,0 +1,3 @@
+ALTER TABLE sale_items DROP COLUMN unit_cost;
+
+ALTER TABLE medicine_variants DROP COLUMN cost_price;
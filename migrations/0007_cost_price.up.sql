@@ -0,0 +1,7 @@
+ALTER TABLE medicine_variants
+    -- Weighted-average unit cost of stock on hand, updated on goods receipt
+    ADD COLUMN cost_price DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (cost_price >= 0);
+
+ALTER TABLE sale_items
+    -- The variant's cost price when the sale was made
+    ADD COLUMN unit_cost DOUBLE PRECISION NOT NULL DEFAULT 0;
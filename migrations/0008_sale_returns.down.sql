@@ -0,0 +1,12 @@
+DROP TABLE credit_notes;
+DROP TABLE sale_return_item_lots;
+DROP TABLE sale_return_items;
+DROP TABLE sale_returns;
+
+ALTER TABLE sale_items
+    DROP CONSTRAINT sale_items_returned_within_quantity,
+    DROP COLUMN returned_quantity;
+
+ALTER TABLE sales DROP COLUMN total_refunded;
+
+ALTER TABLE medicine_lots DROP COLUMN quarantined;
@@ -0,0 +1,49 @@
+ALTER TABLE medicine_lots
+    -- Returned units held back from sale pending inspection
+    ADD COLUMN quarantined INTEGER NOT NULL DEFAULT 0 CHECK (quarantined >= 0);
+
+ALTER TABLE sales
+    ADD COLUMN total_refunded DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+ALTER TABLE sale_items
+    ADD COLUMN returned_quantity INTEGER NOT NULL DEFAULT 0 CHECK (returned_quantity >= 0),
+    ADD CONSTRAINT sale_items_returned_within_quantity CHECK (returned_quantity <= quantity);
+
+CREATE TABLE sale_returns (
+    id           UUID PRIMARY KEY,
+    sale_id      UUID NOT NULL REFERENCES sales (id) ON DELETE CASCADE,
+    user_id      UUID NOT NULL REFERENCES users (id),
+    reason       VARCHAR(255) NOT NULL,
+    quarantine   BOOLEAN NOT NULL DEFAULT FALSE,
+    total_refund DOUBLE PRECISION NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_sale_returns_sale_id ON sale_returns (sale_id);
+
+CREATE TABLE sale_return_items (
+    id             UUID PRIMARY KEY,
+    return_id      UUID NOT NULL REFERENCES sale_returns (id) ON DELETE CASCADE,
+    sale_item_id   UUID NOT NULL REFERENCES sale_items (id) ON DELETE CASCADE,
+    quantity       INTEGER NOT NULL CHECK (quantity > 0),
+    price_per_unit DOUBLE PRECISION NOT NULL,
+    unit_cost      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    refund         DOUBLE PRECISION NOT NULL,
+    position       INTEGER NOT NULL
+);
+CREATE INDEX idx_sale_return_items_return_id ON sale_return_items (return_id);
+CREATE INDEX idx_sale_return_items_sale_item_id ON sale_return_items (sale_item_id);
+
+CREATE TABLE sale_return_item_lots (
+    return_item_id UUID NOT NULL REFERENCES sale_return_items (id) ON DELETE CASCADE,
+    lot_id         UUID NOT NULL REFERENCES medicine_lots (id),
+    quantity       INTEGER NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (return_item_id, lot_id)
+);
+
+CREATE TABLE credit_notes (
+    id         UUID PRIMARY KEY,
+    return_id  UUID NOT NULL UNIQUE REFERENCES sale_returns (id) ON DELETE CASCADE,
+    receipt_id UUID NOT NULL REFERENCES receipts (id) ON DELETE CASCADE,
+    content    JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
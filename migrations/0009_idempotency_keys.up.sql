@@ -0,0 +1,15 @@
+-- Client-supplied keys that let a retried sale confirmation replay the
+-- original result. request_hash identifies the request body the key was
+-- first sent with. sale_id and response are NULL while the request is in
+-- progress.
+CREATE TABLE idempotency_keys (
+    user_id      UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    key          VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    sale_id      UUID REFERENCES sales (id) ON DELETE CASCADE,
+    response     JSONB,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,32 @@
+ALTER TABLE medicine_variants
+    ALTER COLUMN price_per_unit TYPE DOUBLE PRECISION,
+    ALTER COLUMN cost_price TYPE DOUBLE PRECISION;
+
+ALTER TABLE sales
+    ALTER COLUMN total_price TYPE DOUBLE PRECISION,
+    ALTER COLUMN total_refunded TYPE DOUBLE PRECISION;
+
+ALTER TABLE sale_items
+    ALTER COLUMN price_per_unit TYPE DOUBLE PRECISION,
+    ALTER COLUMN unit_cost TYPE DOUBLE PRECISION;
+
+ALTER TABLE order_items
+    ALTER COLUMN price_per_unit TYPE DOUBLE PRECISION;
+
+ALTER TABLE write_offs
+    ALTER COLUMN unit_value TYPE DOUBLE PRECISION,
+    ALTER COLUMN loss_value TYPE DOUBLE PRECISION;
+
+ALTER TABLE purchase_order_items
+    ALTER COLUMN unit_cost TYPE DOUBLE PRECISION;
+
+ALTER TABLE goods_receipt_lines
+    ALTER COLUMN unit_cost TYPE DOUBLE PRECISION;
+
+ALTER TABLE sale_returns
+    ALTER COLUMN total_refund TYPE DOUBLE PRECISION;
+
+ALTER TABLE sale_return_items
+    ALTER COLUMN price_per_unit TYPE DOUBLE PRECISION,
+    ALTER COLUMN unit_cost TYPE DOUBLE PRECISION,
+    ALTER COLUMN refund TYPE DOUBLE PRECISION;
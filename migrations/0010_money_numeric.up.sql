@@ -0,0 +1,35 @@
+-- Money is stored as exact decimals with two places, matching domain.Money.
+-- Existing amounts are rounded half away from zero.
+
+ALTER TABLE medicine_variants
+    ALTER COLUMN price_per_unit TYPE NUMERIC(12, 2) USING ROUND(price_per_unit::numeric, 2),
+    ALTER COLUMN cost_price TYPE NUMERIC(12, 2) USING ROUND(cost_price::numeric, 2);
+
+ALTER TABLE sales
+    ALTER COLUMN total_price TYPE NUMERIC(12, 2) USING ROUND(total_price::numeric, 2),
+    ALTER COLUMN total_refunded TYPE NUMERIC(12, 2) USING ROUND(total_refunded::numeric, 2);
+
+ALTER TABLE sale_items
+    ALTER COLUMN price_per_unit TYPE NUMERIC(12, 2) USING ROUND(price_per_unit::numeric, 2),
+    ALTER COLUMN unit_cost TYPE NUMERIC(12, 2) USING ROUND(unit_cost::numeric, 2);
+
+ALTER TABLE order_items
+    ALTER COLUMN price_per_unit TYPE NUMERIC(12, 2) USING ROUND(price_per_unit::numeric, 2);
+
+ALTER TABLE write_offs
+    ALTER COLUMN unit_value TYPE NUMERIC(12, 2) USING ROUND(unit_value::numeric, 2),
+    ALTER COLUMN loss_value TYPE NUMERIC(12, 2) USING ROUND(loss_value::numeric, 2);
+
+ALTER TABLE purchase_order_items
+    ALTER COLUMN unit_cost TYPE NUMERIC(12, 2) USING ROUND(unit_cost::numeric, 2);
+
+ALTER TABLE goods_receipt_lines
+    ALTER COLUMN unit_cost TYPE NUMERIC(12, 2) USING ROUND(unit_cost::numeric, 2);
+
+ALTER TABLE sale_returns
+    ALTER COLUMN total_refund TYPE NUMERIC(12, 2) USING ROUND(total_refund::numeric, 2);
+
+ALTER TABLE sale_return_items
+    ALTER COLUMN price_per_unit TYPE NUMERIC(12, 2) USING ROUND(price_per_unit::numeric, 2),
+    ALTER COLUMN unit_cost TYPE NUMERIC(12, 2) USING ROUND(unit_cost::numeric, 2),
+    ALTER COLUMN refund TYPE NUMERIC(12, 2) USING ROUND(refund::numeric, 2);
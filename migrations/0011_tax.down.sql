@@ -0,0 +1,18 @@
+ALTER TABLE sale_return_items DROP COLUMN tax;
+
+ALTER TABLE sales
+    DROP COLUMN total_net,
+    DROP COLUMN total_tax;
+
+ALTER TABLE sale_items
+    DROP COLUMN tax_category,
+    DROP COLUMN tax_rate,
+    DROP COLUMN net,
+    DROP COLUMN tax,
+    DROP COLUMN gross;
+
+ALTER TABLE medicines DROP COLUMN tax_category;
+
+ALTER TABLE pharmacies
+    DROP COLUMN vat_rate,
+    DROP COLUMN prices_include_tax;
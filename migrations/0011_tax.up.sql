@@ -0,0 +1,27 @@
+ALTER TABLE pharmacies
+    -- Standard VAT rate in basis points (1500 = 15%)
+    ADD COLUMN vat_rate INTEGER NOT NULL DEFAULT 0 CHECK (vat_rate BETWEEN 0 AND 10000),
+    ADD COLUMN prices_include_tax BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE medicines
+    ADD COLUMN tax_category VARCHAR(20) NOT NULL DEFAULT 'standard'
+        CHECK (tax_category IN ('standard', 'zero_rated', 'exempt'));
+
+-- Sales made before tax was tracked were untaxed: net equals gross
+ALTER TABLE sale_items
+    ADD COLUMN tax_category VARCHAR(20) NOT NULL DEFAULT 'standard'
+        CHECK (tax_category IN ('standard', 'zero_rated', 'exempt')),
+    ADD COLUMN tax_rate INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN net NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN tax NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN gross NUMERIC(12, 2) NOT NULL DEFAULT 0;
+UPDATE sale_items SET net = price_per_unit * quantity, gross = price_per_unit * quantity;
+
+ALTER TABLE sales
+    ADD COLUMN total_net NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN total_tax NUMERIC(12, 2) NOT NULL DEFAULT 0;
+UPDATE sales SET total_net = total_price;
+
+ALTER TABLE sale_return_items
+    -- Tax included in the refund
+    ADD COLUMN tax NUMERIC(12, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,11 @@
+DROP TABLE sale_discounts;
+
+ALTER TABLE sales DROP COLUMN total_discount;
+
+ALTER TABLE sale_items DROP COLUMN discount;
+
+DROP TABLE manual_discounts;
+
+DROP TABLE promotions;
+
+ALTER TABLE medicines DROP COLUMN category;
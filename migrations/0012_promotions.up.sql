@@ -0,0 +1,63 @@
+ALTER TABLE medicines
+    -- Free-form group promotions can target, e.g. 'analgesics'
+    ADD COLUMN category VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE TABLE promotions (
+    id           UUID PRIMARY KEY,
+    pharmacy_id  UUID NOT NULL REFERENCES pharmacies (id) ON DELETE CASCADE,
+    name         VARCHAR(100) NOT NULL,
+    type         VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed', 'buy_x_get_y')),
+    scope        VARCHAR(20) NOT NULL CHECK (scope IN ('line', 'basket')),
+    rate         INTEGER NOT NULL DEFAULT 0 CHECK (rate BETWEEN 0 AND 10000),
+    amount       NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    buy_quantity INTEGER NOT NULL DEFAULT 0 CHECK (buy_quantity >= 0),
+    get_quantity INTEGER NOT NULL DEFAULT 0 CHECK (get_quantity >= 0),
+    medicine_id  UUID REFERENCES medicines (id) ON DELETE CASCADE,
+    category     VARCHAR(50) NOT NULL DEFAULT '',
+    min_subtotal NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (min_subtotal >= 0),
+    active       BOOLEAN NOT NULL DEFAULT TRUE,
+    starts_at    TIMESTAMPTZ NOT NULL,
+    ends_at      TIMESTAMPTZ NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+CREATE INDEX idx_promotions_pharmacy_dates ON promotions (pharmacy_id, starts_at, ends_at);
+
+-- Ad-hoc discounts on a cart line (cart_id set) or basket, approved by an
+-- owner. cart_id is not a foreign key: cart rows are deleted at checkout.
+CREATE TABLE manual_discounts (
+    id          UUID PRIMARY KEY,
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    cart_id     UUID,
+    rate        INTEGER NOT NULL DEFAULT 0 CHECK (rate BETWEEN 0 AND 10000),
+    amount      NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    reason      VARCHAR(255) NOT NULL,
+    status      VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'approved', 'rejected')),
+    reviewed_by UUID REFERENCES users (id),
+    reviewed_at TIMESTAMPTZ,
+    sale_id     UUID REFERENCES sales (id) ON DELETE SET NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_manual_discounts_pharmacy_status ON manual_discounts (pharmacy_id, status);
+CREATE INDEX idx_manual_discounts_user_id ON manual_discounts (user_id);
+
+ALTER TABLE sale_items
+    ADD COLUMN discount NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE sales
+    ADD COLUMN total_discount NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+-- Promotions and manual discounts given on a sale. name is kept so the
+-- record survives the promotion being deleted.
+CREATE TABLE sale_discounts (
+    sale_id            UUID NOT NULL REFERENCES sales (id) ON DELETE CASCADE,
+    promotion_id       UUID REFERENCES promotions (id) ON DELETE SET NULL,
+    manual_discount_id UUID REFERENCES manual_discounts (id) ON DELETE SET NULL,
+    name               VARCHAR(255) NOT NULL,
+    scope              VARCHAR(20) NOT NULL,
+    amount             NUMERIC(12, 2) NOT NULL,
+    position           INTEGER NOT NULL,
+    PRIMARY KEY (sale_id, position)
+);
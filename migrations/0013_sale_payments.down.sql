@@ -0,0 +1 @@
+DROP TABLE sale_payments;
@@ -0,0 +1,13 @@
+-- Tenders a sale was paid with. change_given is the cash handed back from a
+-- cash tender, so each row paid amount - change_given.
+CREATE TABLE sale_payments (
+    id           UUID PRIMARY KEY,
+    sale_id      UUID NOT NULL REFERENCES sales (id) ON DELETE CASCADE,
+    method       VARCHAR(20) NOT NULL CHECK (method IN ('cash', 'card', 'mobile_money', 'insurance', 'credit_account')),
+    amount       NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    change_given NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (change_given >= 0 AND change_given <= amount),
+    reference    VARCHAR(100) NOT NULL DEFAULT '',
+    position     INTEGER NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (sale_id, position)
+);
@@ -0,0 +1,5 @@
+ALTER TABLE sales DROP COLUMN shift_id;
+
+DROP TABLE shift_counts;
+
+DROP TABLE shifts;
@@ -0,0 +1,24 @@
+CREATE TABLE shifts (
+    id            UUID PRIMARY KEY,
+    pharmacy_id   UUID NOT NULL REFERENCES pharmacies (id) ON DELETE CASCADE,
+    user_id       UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    opening_float NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (opening_float >= 0),
+    status        VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    opened_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_at     TIMESTAMPTZ
+);
+CREATE INDEX idx_shifts_pharmacy_opened_at ON shifts (pharmacy_id, opened_at);
+-- A user has at most one open shift
+CREATE UNIQUE INDEX idx_shifts_user_open ON shifts (user_id) WHERE status = 'open';
+
+-- Amounts counted per tender when a shift closes
+CREATE TABLE shift_counts (
+    shift_id UUID NOT NULL REFERENCES shifts (id) ON DELETE CASCADE,
+    method   VARCHAR(20) NOT NULL CHECK (method IN ('cash', 'card', 'mobile_money', 'insurance', 'credit_account')),
+    amount   NUMERIC(12, 2) NOT NULL CHECK (amount >= 0),
+    PRIMARY KEY (shift_id, method)
+);
+
+ALTER TABLE sales
+    ADD COLUMN shift_id UUID REFERENCES shifts (id) ON DELETE SET NULL;
+CREATE INDEX idx_sales_shift_id ON sales (shift_id);
@@ -0,0 +1,4 @@
+ALTER TABLE pharmacies
+    DROP COLUMN receipt_header,
+    DROP COLUMN receipt_footer,
+    DROP COLUMN receipt_paper_width;
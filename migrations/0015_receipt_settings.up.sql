@@ -0,0 +1,5 @@
+ALTER TABLE pharmacies
+    ADD COLUMN receipt_header      VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN receipt_footer      VARCHAR(500) NOT NULL DEFAULT '',
+    -- Thermal roll width in millimetres
+    ADD COLUMN receipt_paper_width INTEGER NOT NULL DEFAULT 80 CHECK (receipt_paper_width IN (58, 80));
@@ -0,0 +1,9 @@
+ALTER TABLE receipts
+    DROP COLUMN number,
+    DROP COLUMN pharmacy_id;
+
+DROP TABLE receipt_sequences;
+
+ALTER TABLE pharmacies
+    DROP COLUMN receipt_number_prefix,
+    DROP COLUMN receipt_yearly_reset;
@@ -0,0 +1,24 @@
+ALTER TABLE pharmacies
+    ADD COLUMN receipt_number_prefix VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN receipt_yearly_reset  BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Last receipt number allocated in each pharmacy's series; the series is the
+-- year with a yearly reset and 0 otherwise. Sales lock their series' row
+-- until they commit, so numbers are allocated one after another with no gaps.
+CREATE TABLE receipt_sequences (
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id) ON DELETE CASCADE,
+    series      INTEGER NOT NULL,
+    last_number BIGINT NOT NULL CHECK (last_number > 0),
+    PRIMARY KEY (pharmacy_id, series)
+);
+
+ALTER TABLE receipts
+    ADD COLUMN pharmacy_id UUID REFERENCES pharmacies (id) ON DELETE CASCADE,
+    ADD COLUMN number      VARCHAR(50);
+
+UPDATE receipts r SET pharmacy_id = s.pharmacy_id FROM sales s WHERE s.id = r.sale_id;
+
+ALTER TABLE receipts ALTER COLUMN pharmacy_id SET NOT NULL;
+
+-- Receipts from before numbering have no number
+CREATE UNIQUE INDEX idx_receipts_pharmacy_number ON receipts (pharmacy_id, number);
@@ -0,0 +1,6 @@
+ALTER TABLE receipts
+    DROP COLUMN chain_position,
+    DROP COLUMN previous_hash,
+    DROP COLUMN hash;
+
+DROP TABLE receipt_chains;
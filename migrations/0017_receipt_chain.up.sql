@@ -0,0 +1,15 @@
+-- Last receipt chained for each pharmacy. Sales lock their pharmacy's row
+-- until they commit, so each receipt is chained to the one committed before.
+CREATE TABLE receipt_chains (
+    pharmacy_id UUID PRIMARY KEY REFERENCES pharmacies (id) ON DELETE CASCADE,
+    position    BIGINT NOT NULL DEFAULT 0,
+    hash        VARCHAR(64) NOT NULL DEFAULT ''
+);
+
+-- Receipts from before chaining have no position or hashes
+ALTER TABLE receipts
+    ADD COLUMN chain_position BIGINT,
+    ADD COLUMN previous_hash  VARCHAR(64),
+    ADD COLUMN hash           VARCHAR(64);
+
+CREATE UNIQUE INDEX idx_receipts_chain ON receipts (pharmacy_id, chain_position);
@@ -0,0 +1 @@
+DROP INDEX idx_sales_user_id;
@@ -0,0 +1,2 @@
+-- Sale history filtered by cashier
+CREATE INDEX idx_sales_user_id ON sales (user_id, sale_date DESC);
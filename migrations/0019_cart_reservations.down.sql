@@ -0,0 +1,4 @@
+DROP INDEX idx_carts_reserved_until;
+DROP INDEX idx_carts_reserved_variant;
+
+ALTER TABLE carts DROP COLUMN reserved_until;
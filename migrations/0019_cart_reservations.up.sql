@@ -0,0 +1,6 @@
+-- A cart line holds its quantity back from other carts and sales until its
+-- reservation runs out
+ALTER TABLE carts ADD COLUMN reserved_until TIMESTAMPTZ;
+
+CREATE INDEX idx_carts_reserved_variant ON carts (medicine_variant_id, reserved_until) WHERE reserved_until IS NOT NULL;
+CREATE INDEX idx_carts_reserved_until ON carts (reserved_until) WHERE reserved_until IS NOT NULL;
@@ -0,0 +1,9 @@
+DELETE FROM carts WHERE parked_cart_id IS NOT NULL;
+
+DROP INDEX idx_carts_parked_variant;
+DROP INDEX idx_carts_active_variant;
+ALTER TABLE carts ADD CONSTRAINT carts_user_id_medicine_variant_id_key UNIQUE (user_id, medicine_variant_id);
+
+ALTER TABLE carts DROP COLUMN parked_cart_id;
+
+DROP TABLE parked_carts;
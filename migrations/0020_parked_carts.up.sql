@@ -0,0 +1,19 @@
+-- A user can set their cart aside under a label and resume it later. Parked
+-- lines stay in carts, tagged with their parked cart; the untagged lines are
+-- the user's active cart.
+CREATE TABLE parked_carts (
+    id          UUID PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id),
+    label       VARCHAR(100) NOT NULL,
+    parked_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_parked_carts_user ON parked_carts (user_id, parked_at);
+
+ALTER TABLE carts ADD COLUMN parked_cart_id UUID REFERENCES parked_carts (id) ON DELETE CASCADE;
+
+-- A variant has one line in the active cart and one in each parked cart
+ALTER TABLE carts DROP CONSTRAINT carts_user_id_medicine_variant_id_key;
+CREATE UNIQUE INDEX idx_carts_active_variant ON carts (user_id, medicine_variant_id) WHERE parked_cart_id IS NULL;
+CREATE UNIQUE INDEX idx_carts_parked_variant ON carts (parked_cart_id, medicine_variant_id) WHERE parked_cart_id IS NOT NULL;
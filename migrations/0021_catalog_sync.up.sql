@@ -0,0 +1,10 @@
+-- Deleted variants are remembered so that a POS syncing its catalog can drop
+-- them
+CREATE TABLE deleted_variants (
+    variant_id  UUID PRIMARY KEY,
+    medicine_id UUID NOT NULL,
+    pharmacy_id UUID NOT NULL REFERENCES pharmacies (id) ON DELETE CASCADE,
+    deleted_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_deleted_variants_pharmacy ON deleted_variants (pharmacy_id, deleted_at, variant_id);
@@ -0,0 +1,2 @@
+ALTER TABLE sales DROP COLUMN customer_id;
+DROP TABLE customers;
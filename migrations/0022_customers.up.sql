@@ -0,0 +1,15 @@
+CREATE TABLE customers (
+    id           UUID PRIMARY KEY,
+    pharmacy_id  UUID NOT NULL REFERENCES pharmacies (id),
+    full_name    VARCHAR(100) NOT NULL,
+    phone_number VARCHAR(20) NOT NULL DEFAULT '',
+    notes        TEXT NOT NULL DEFAULT '',
+    allergies    TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_customers_pharmacy_name ON customers (pharmacy_id, full_name);
+
+-- Sales outlive the customer they were made to
+ALTER TABLE sales ADD COLUMN customer_id UUID REFERENCES customers (id) ON DELETE SET NULL;
+CREATE INDEX idx_sales_customer_date ON sales (customer_id, sale_date) WHERE customer_id IS NOT NULL;
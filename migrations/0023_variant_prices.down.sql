@@ -0,0 +1 @@
+DROP TABLE variant_prices;
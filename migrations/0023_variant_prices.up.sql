@@ -0,0 +1,13 @@
+-- Every price a variant has been sold at, so that sales made offline can be
+-- checked against the price in effect when they were made
+CREATE TABLE variant_prices (
+    variant_id     UUID NOT NULL REFERENCES medicine_variants (id) ON DELETE CASCADE,
+    price_per_unit NUMERIC(12, 2) NOT NULL CHECK (price_per_unit > 0),
+    effective_from TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (variant_id, effective_from)
+);
+
+-- Earlier prices were never kept, so each variant's current price is taken
+-- to have held since it was created
+INSERT INTO variant_prices (variant_id, price_per_unit, effective_from)
+SELECT id, price_per_unit, created_at FROM medicine_variants;
@@ -0,0 +1,3 @@
+ALTER TABLE sale_returns
+    DROP COLUMN refund_method,
+    DROP COLUMN shift_id;
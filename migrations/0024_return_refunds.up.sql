@@ -0,0 +1,8 @@
+-- Refunds are paid out through a tender during a shift, so that the shift's
+-- Z-report can take them off what it expects on hand. Earlier returns were
+-- refunded in cash outside any shift.
+ALTER TABLE sale_returns
+    ADD COLUMN shift_id UUID REFERENCES shifts (id) ON DELETE SET NULL,
+    ADD COLUMN refund_method VARCHAR(20) NOT NULL DEFAULT 'cash'
+        CHECK (refund_method IN ('cash', 'card', 'mobile_money', 'insurance', 'credit_account'));
+CREATE INDEX idx_sale_returns_shift_id ON sale_returns (shift_id);
@@ -0,0 +1,14 @@
+// Package migrations embeds the versioned SQL schema migrations.
+//
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql and
+// are applied in ascending version order by infrastructure.Migrator.
+//
+// The schema needs PostgreSQL 13 or later, where gen_random_uuid is built in.
+package migrations
+
+import "embed"
+
+// FS holds the embedded migration files
+//
+//go:embed *.sql
+var FS embed.FS
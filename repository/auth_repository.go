@@ -24,6 +24,7 @@ type AuthRepository interface {
 	GetResetToken(ctx context.Context, token string) (*uuid.UUID, error)
 	DeleteResetToken(ctx context.Context, token string) error
 	GetPharmacists(ctx context.Context, pharmacyID *uuid.UUID) ([]domain.User, error)
+	GetOwners(ctx context.Context, pharmacyID uuid.UUID) ([]domain.User, error)
 }
 
 // authRepository implements AuthRepository
@@ -248,3 +249,35 @@ func (r *authRepository) GetPharmacists(ctx context.Context, pharmacyID *uuid.UU
 
 	return pharmacists, nil
 }
+
+// GetOwners retrieves the owners of a pharmacy
+func (r *authRepository) GetOwners(ctx context.Context, pharmacyID uuid.UUID) ([]domain.User, error) {
+	query := `
+        SELECT id, phone_number, password, full_name, role, pharmacy_id, profile_picture, created_at, updated_at
+        FROM users
+        WHERE role = $1 AND pharmacy_id = $2
+        ORDER BY created_at
+    `
+	rows, err := r.db.QueryContext(ctx, query, domain.RoleOwner, pharmacyID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get owners")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owners []domain.User
+	for rows.Next() {
+		var user domain.User
+		err := rows.Scan(
+			&user.ID, &user.PhoneNumber, &user.Password, &user.FullName, &user.Role,
+			&user.PharmacyID, &user.ProfilePicture, &user.CreatedAt, &user.UpdatedAt,
+		)
+		if err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan owner")
+			return nil, err
+		}
+		owners = append(owners, user)
+	}
+
+	return owners, nil
+}
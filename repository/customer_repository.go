@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// CustomerRepository defines the interface for customer-related database operations
+type CustomerRepository interface {
+	Create(ctx context.Context, customer domain.Customer) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Customer, error)
+	Search(ctx context.Context, pharmacyID uuid.UUID, query string, limit int) ([]domain.Customer, error)
+	Update(ctx context.Context, customer domain.Customer) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// customerRepository implements CustomerRepository
+type customerRepository struct {
+	db     *sql.DB
+	logger zerolog.Logger
+}
+
+// NewCustomerRepository creates a new CustomerRepository
+func NewCustomerRepository(db *sql.DB, logger zerolog.Logger) CustomerRepository {
+	return &customerRepository{db, logger}
+}
+
+// Create inserts a new customer into the database
+func (r *customerRepository) Create(ctx context.Context, customer domain.Customer) error {
+	query := `
+        INSERT INTO customers (id, pharmacy_id, full_name, phone_number, notes, allergies, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	_, err := r.db.ExecContext(ctx, query,
+		customer.ID, customer.PharmacyID, customer.FullName, customer.PhoneNumber, customer.Notes, customer.Allergies,
+		customer.CreatedAt, customer.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create customer")
+		return err
+	}
+	return nil
+}
+
+// GetByID retrieves a customer by ID
+func (r *customerRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Customer, error) {
+	query := `
+        SELECT id, pharmacy_id, full_name, phone_number, notes, allergies, created_at, updated_at
+        FROM customers WHERE id = $1
+    `
+	var c domain.Customer
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&c.ID, &c.PharmacyID, &c.FullName, &c.PhoneNumber, &c.Notes, &c.Allergies, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("id", id.String()).Msg("Customer not found")
+		return nil, domain.ErrCustomerNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get customer by ID")
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Search retrieves up to limit of a pharmacy's customers whose name or phone
+// number contains query, ordered by name. An empty query matches everyone.
+func (r *customerRepository) Search(ctx context.Context, pharmacyID uuid.UUID, query string, limit int) ([]domain.Customer, error) {
+	sqlQuery := `
+        SELECT id, pharmacy_id, full_name, phone_number, notes, allergies, created_at, updated_at
+        FROM customers
+        WHERE pharmacy_id = $1
+        AND (full_name ILIKE $2 OR phone_number LIKE $2)
+        ORDER BY full_name, id
+        LIMIT $3
+    `
+	rows, err := r.db.QueryContext(ctx, sqlQuery, pharmacyID, "%"+query+"%", limit)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to search customers")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var customers []domain.Customer
+	for rows.Next() {
+		var c domain.Customer
+		if err := rows.Scan(&c.ID, &c.PharmacyID, &c.FullName, &c.PhoneNumber, &c.Notes, &c.Allergies, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan customer")
+			return nil, err
+		}
+		customers = append(customers, c)
+	}
+	return customers, rows.Err()
+}
+
+// Update updates a customer
+func (r *customerRepository) Update(ctx context.Context, customer domain.Customer) error {
+	query := `
+        UPDATE customers
+        SET full_name = $2, phone_number = $3, notes = $4, allergies = $5, updated_at = $6
+        WHERE id = $1
+    `
+	result, err := r.db.ExecContext(ctx, query,
+		customer.ID, customer.FullName, customer.PhoneNumber, customer.Notes, customer.Allergies, customer.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update customer")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		r.logger.Info().Str("id", customer.ID.String()).Msg("Customer not found for update")
+		return domain.ErrCustomerNotFound
+	}
+	return nil
+}
+
+// Delete deletes a customer. Their sales are kept without a customer.
+func (r *customerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM customers WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to delete customer")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		r.logger.Info().Str("id", id.String()).Msg("Customer not found for deletion")
+		return domain.ErrCustomerNotFound
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// InventoryRepository defines the interface for stock reporting and write-off database operations
+type InventoryRepository interface {
+	GetExpiring(ctx context.Context, pharmacyID uuid.UUID, before time.Time) ([]domain.ExpiringMedicine, error)
+	CreateWriteOff(ctx context.Context, writeOff domain.WriteOff) (*domain.WriteOff, error)
+	GetWriteOffs(ctx context.Context, pharmacyID uuid.UUID, from, to time.Time) ([]domain.WriteOff, error)
+}
+
+// inventoryRepository implements InventoryRepository
+type inventoryRepository struct {
+	db     *sql.DB
+	logger zerolog.Logger
+}
+
+// NewInventoryRepository creates a new InventoryRepository
+func NewInventoryRepository(db *sql.DB, logger zerolog.Logger) InventoryRepository {
+	return &inventoryRepository{db, logger}
+}
+
+// GetExpiring retrieves a pharmacy's lots with stock that expire before the
+// given time, grouped by medicine and earliest expiry first
+func (r *inventoryRepository) GetExpiring(ctx context.Context, pharmacyID uuid.UUID, before time.Time) ([]domain.ExpiringMedicine, error) {
+	query := `
+        SELECT m.id, m.name, l.id, mv.id, mv.brand, mv.unit, l.lot_number, l.expiry_date, l.quantity,
+               l.quantity * mv.cost_price, l.expiry_date <= NOW()
+        FROM medicine_lots l
+        JOIN medicine_variants mv ON l.variant_id = mv.id
+        JOIN medicines m ON mv.medicine_id = m.id
+        WHERE m.pharmacy_id = $1 AND l.quantity > 0 AND l.expiry_date < $2
+        ORDER BY m.name, m.id, l.expiry_date, mv.brand
+    `
+	rows, err := r.db.QueryContext(ctx, query, pharmacyID, before)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get expiring lots")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var medicines []domain.ExpiringMedicine
+	for rows.Next() {
+		var medicineID uuid.UUID
+		var medicineName string
+		var lot domain.ExpiringLot
+		if err := rows.Scan(&medicineID, &medicineName, &lot.LotID, &lot.VariantID, &lot.Brand, &lot.Unit, &lot.LotNumber,
+			&lot.ExpiryDate, &lot.Quantity, &lot.Value, &lot.Expired); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan expiring lot")
+			return nil, err
+		}
+		if n := len(medicines); n == 0 || medicines[n-1].MedicineID != medicineID {
+			medicines = append(medicines, domain.ExpiringMedicine{MedicineID: medicineID, MedicineName: medicineName})
+		}
+		m := &medicines[len(medicines)-1]
+		m.Lots = append(m.Lots, lot)
+		m.Quantity += lot.Quantity
+		m.Value += lot.Value
+	}
+	return medicines, nil
+}
+
+// CreateWriteOff removes expired stock from a lot of the write-off's pharmacy,
+// recording the write-off and an expiry_write_off stock movement. A zero
+// Quantity writes off everything left in the lot.
+func (r *inventoryRepository) CreateWriteOff(ctx context.Context, writeOff domain.WriteOff) (*domain.WriteOff, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	lotQuery := `
+        SELECT l.variant_id
+        FROM medicine_lots l
+        JOIN medicine_variants mv ON l.variant_id = mv.id
+        JOIN medicines m ON mv.medicine_id = m.id
+        WHERE l.id = $1 AND m.pharmacy_id = $2
+    `
+	err = tx.QueryRowContext(ctx, lotQuery, writeOff.LotID, writeOff.PharmacyID).Scan(&writeOff.VariantID)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("lot_id", writeOff.LotID.String()).Msg("Medicine lot not found")
+		return nil, domain.ErrLotNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicine lot")
+		return nil, err
+	}
+
+	onHand, err := lockVariantLots(ctx, tx, writeOff.VariantID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return nil, err
+	}
+
+	var available int
+	var expiry time.Time
+	detailQuery := `
+        SELECT l.lot_number, l.quantity, l.expiry_date, mv.cost_price
+        FROM medicine_lots l
+        JOIN medicine_variants mv ON l.variant_id = mv.id
+        WHERE l.id = $1
+    `
+	if err := tx.QueryRowContext(ctx, detailQuery, writeOff.LotID).Scan(&writeOff.LotNumber, &available, &expiry, &writeOff.UnitValue); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicine lot")
+		return nil, err
+	}
+	if expiry.After(writeOff.CreatedAt) {
+		r.logger.Info().Str("lot_id", writeOff.LotID.String()).Msg("Medicine lot has not expired")
+		return nil, domain.ErrLotNotExpired
+	}
+	if writeOff.Quantity == 0 {
+		writeOff.Quantity = available
+	}
+	if available == 0 || writeOff.Quantity > available {
+		r.logger.Info().Str("lot_id", writeOff.LotID.String()).Msg("Insufficient stock")
+		return nil, domain.ErrInsufficientStock
+	}
+	writeOff.LossValue = writeOff.UnitValue.Mul(writeOff.Quantity)
+
+	updateQuery := `UPDATE medicine_lots SET quantity = quantity - $1, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, updateQuery, writeOff.Quantity, writeOff.CreatedAt, writeOff.LotID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update lot stock")
+		return nil, err
+	}
+
+	insertQuery := `
+        INSERT INTO write_offs (id, pharmacy_id, variant_id, lot_id, quantity, unit_value, loss_value, reason, user_id, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+    `
+	if _, err := tx.ExecContext(ctx, insertQuery,
+		writeOff.ID, writeOff.PharmacyID, writeOff.VariantID, writeOff.LotID, writeOff.Quantity, writeOff.UnitValue,
+		writeOff.LossValue, writeOff.Reason, writeOff.UserID, writeOff.CreatedAt,
+	); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create write-off")
+		return nil, err
+	}
+
+	movement := domain.StockMovement{
+		ID:             uuid.New(),
+		VariantID:      writeOff.VariantID,
+		LotID:          writeOff.LotID,
+		Type:           domain.StockMovementExpiryWriteOff,
+		Quantity:       -writeOff.Quantity,
+		QuantityBefore: onHand,
+		QuantityAfter:  onHand - writeOff.Quantity,
+		Reason:         writeOff.Reason,
+		UserID:         writeOff.UserID,
+		CreatedAt:      writeOff.CreatedAt,
+	}
+	if err := insertStockMovement(ctx, tx, movement); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to record stock movement")
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+	return &writeOff, nil
+}
+
+// GetWriteOffs retrieves a pharmacy's write-offs made in [from, to), oldest first
+func (r *inventoryRepository) GetWriteOffs(ctx context.Context, pharmacyID uuid.UUID, from, to time.Time) ([]domain.WriteOff, error) {
+	query := `
+        SELECT w.id, w.pharmacy_id, w.variant_id, w.lot_id, l.lot_number, w.quantity, w.unit_value, w.loss_value,
+               w.reason, w.user_id, w.created_at
+        FROM write_offs w
+        JOIN medicine_lots l ON w.lot_id = l.id
+        WHERE w.pharmacy_id = $1 AND w.created_at >= $2 AND w.created_at < $3
+        ORDER BY w.created_at
+    `
+	rows, err := r.db.QueryContext(ctx, query, pharmacyID, from, to)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get write-offs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var writeOffs []domain.WriteOff
+	for rows.Next() {
+		var w domain.WriteOff
+		if err := rows.Scan(&w.ID, &w.PharmacyID, &w.VariantID, &w.LotID, &w.LotNumber, &w.Quantity, &w.UnitValue, &w.LossValue,
+			&w.Reason, &w.UserID, &w.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan write-off")
+			return nil, err
+		}
+		writeOffs = append(writeOffs, w)
+	}
+	return writeOffs, nil
+}
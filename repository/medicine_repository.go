@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"pharmacy-management-backend/domain"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 )
 
@@ -14,16 +16,71 @@ import (
 type MedicineRepository interface {
 	Create(ctx context.Context, medicine domain.Medicine) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Medicine, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.Medicine, error)
 	GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Medicine, error)
 	Update(ctx context.Context, medicine domain.Medicine) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	CountVariants(ctx context.Context, medicineID uuid.UUID) (int, error)
-	CreateVariant(ctx context.Context, variant domain.MedicineVariant) error
+	CreateVariant(ctx context.Context, variant domain.MedicineVariant, lots []domain.MedicineLot, userID uuid.UUID) error
 	GetVariantByID(ctx context.Context, id uuid.UUID) (*domain.MedicineVariant, error)
+	GetVariantsByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.MedicineVariant, error)
 	GetVariantsByMedicineID(ctx context.Context, medicineID uuid.UUID) ([]domain.MedicineVariant, error)
+	GetPriceHistory(ctx context.Context, variantIDs []uuid.UUID, since time.Time) (map[uuid.UUID][]domain.VariantPrice, error)
 	UpdateVariant(ctx context.Context, variant domain.MedicineVariant) error
 	DeleteVariant(ctx context.Context, id uuid.UUID) error
+	GetCatalogChanges(ctx context.Context, pharmacyID uuid.UUID, after domain.CatalogCursor, before time.Time, limit int) ([]domain.CatalogItem, error)
 	CheckBarcodeExists(ctx context.Context, barcode string) (bool, error)
+	CreateLot(ctx context.Context, lot domain.MedicineLot, userID uuid.UUID) error
+	GetLotsByVariantID(ctx context.Context, variantID uuid.UUID) ([]domain.MedicineLot, error)
+	AdjustStock(ctx context.Context, movement domain.StockMovement) (*domain.StockMovement, error)
+	GetStockMovements(ctx context.Context, variantID uuid.UUID, limit, offset int) ([]domain.StockMovement, error)
+	GetLowStock(ctx context.Context, pharmacyID uuid.UUID) ([]domain.LowStockItem, error)
+	ClaimLowStockAlert(ctx context.Context, variantID uuid.UUID) (bool, error)
+	ReleaseLowStockAlert(ctx context.Context, variantID uuid.UUID) error
+}
+
+// variantColumns and variantFrom select variant columns with stock and expiry
+// derived from the variant's unexpired lots, and availability from the cart
+// reservations still running; callers append their own WHERE clause, and may
+// select extra columns between the two
+const (
+	variantColumns = `
+        SELECT mv.id, mv.medicine_id, mv.brand, mv.barcode, mv.unit, mv.price_per_unit, mv.cost_price,
+               lots.expiry_date, COALESCE(lots.stock, 0), GREATEST(COALESCE(lots.stock, 0) - COALESCE(held.quantity, 0), 0),
+               mv.reorder_point, mv.reorder_quantity, mv.created_at, mv.updated_at`
+	variantFrom = `
+        FROM medicine_variants mv
+        LEFT JOIN LATERAL (
+            SELECT SUM(l.quantity) AS stock,
+                   MIN(l.expiry_date) FILTER (WHERE l.quantity > 0) AS expiry_date
+            FROM medicine_lots l
+            WHERE l.variant_id = mv.id AND l.expiry_date > NOW()
+        ) lots ON TRUE
+        LEFT JOIN LATERAL (
+            SELECT SUM(c.quantity) AS quantity
+            FROM carts c
+            WHERE c.medicine_variant_id = mv.id AND c.reserved_until > NOW()
+        ) held ON TRUE
+`
+	variantSelect = variantColumns + variantFrom
+)
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanVariant scans a row selected with variantColumns into v and any extra
+// selected columns into extra
+func scanVariant(row rowScanner, v *domain.MedicineVariant, extra ...interface{}) error {
+	var expiry sql.NullTime
+	dest := []interface{}{&v.ID, &v.MedicineID, &v.Brand, &v.Barcode, &v.Unit, &v.PricePerUnit, &v.CostPrice, &expiry, &v.Stock, &v.Available,
+		&v.ReorderPoint, &v.ReorderQuantity, &v.CreatedAt, &v.UpdatedAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	v.ExpiryDate = expiry.Time
+	return nil
 }
 
 // medicineRepository implements MedicineRepository
@@ -40,11 +97,12 @@ func NewMedicineRepository(db *sql.DB, logger zerolog.Logger) MedicineRepository
 // Create inserts a new medicine into the database
 func (r *medicineRepository) Create(ctx context.Context, medicine domain.Medicine) error {
 	query := `
-        INSERT INTO medicines (id, pharmacy_id, name, description, picture, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        INSERT INTO medicines (id, pharmacy_id, name, description, picture, category, tax_category, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
     `
 	_, err := r.db.ExecContext(ctx, query,
-		medicine.ID, medicine.PharmacyID, medicine.Name, medicine.Description, medicine.Picture, medicine.CreatedAt, medicine.UpdatedAt,
+		medicine.ID, medicine.PharmacyID, medicine.Name, medicine.Description, medicine.Picture, medicine.Category, medicine.TaxCategory,
+		medicine.CreatedAt, medicine.UpdatedAt,
 	)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to create medicine")
@@ -56,12 +114,12 @@ func (r *medicineRepository) Create(ctx context.Context, medicine domain.Medicin
 // GetByID retrieves a medicine by ID
 func (r *medicineRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Medicine, error) {
 	query := `
-        SELECT id, pharmacy_id, name, description, picture, created_at, updated_at
+        SELECT id, pharmacy_id, name, description, picture, category, tax_category, created_at, updated_at
         FROM medicines WHERE id = $1
     `
 	var m domain.Medicine
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&m.ID, &m.PharmacyID, &m.Name, &m.Description, &m.Picture, &m.CreatedAt, &m.UpdatedAt,
+		&m.ID, &m.PharmacyID, &m.Name, &m.Description, &m.Picture, &m.Category, &m.TaxCategory, &m.CreatedAt, &m.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		r.logger.Info().Str("id", id.String()).Msg("Medicine not found")
@@ -80,10 +138,44 @@ func (r *medicineRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain
 	return &m, nil
 }
 
+// GetByIDs retrieves the medicines with the given IDs, without their
+// variants, keyed by ID. IDs that do not exist are left out.
+func (r *medicineRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.Medicine, error) {
+	medicines := make(map[uuid.UUID]domain.Medicine, len(ids))
+	if len(ids) == 0 {
+		return medicines, nil
+	}
+	query := `
+        SELECT id, pharmacy_id, name, description, picture, category, tax_category, created_at, updated_at
+        FROM medicines WHERE id = ANY($1::uuid[])
+    `
+	rows, err := r.db.QueryContext(ctx, query, uuidArray(ids))
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicines by IDs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m domain.Medicine
+		if err := rows.Scan(&m.ID, &m.PharmacyID, &m.Name, &m.Description, &m.Picture, &m.Category, &m.TaxCategory,
+			&m.CreatedAt, &m.UpdatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan medicine")
+			return nil, err
+		}
+		medicines[m.ID] = m
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicines by IDs")
+		return nil, err
+	}
+	return medicines, nil
+}
+
 // GetAll retrieves medicines for a pharmacy (or all for Admin)
 func (r *medicineRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Medicine, error) {
 	query := `
-        SELECT id, pharmacy_id, name, description, picture, created_at, updated_at
+        SELECT id, pharmacy_id, name, description, picture, category, tax_category, created_at, updated_at
         FROM medicines
         WHERE ($1::uuid IS NULL OR pharmacy_id = $1)
     `
@@ -97,7 +189,7 @@ func (r *medicineRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID) (
 	var medicines []domain.Medicine
 	for rows.Next() {
 		var m domain.Medicine
-		if err := rows.Scan(&m.ID, &m.PharmacyID, &m.Name, &m.Description, &m.Picture, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		if err := rows.Scan(&m.ID, &m.PharmacyID, &m.Name, &m.Description, &m.Picture, &m.Category, &m.TaxCategory, &m.CreatedAt, &m.UpdatedAt); err != nil {
 			r.logger.Error().Err(err).Msg("Failed to scan medicine")
 			return nil, err
 		}
@@ -115,11 +207,11 @@ func (r *medicineRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID) (
 func (r *medicineRepository) Update(ctx context.Context, medicine domain.Medicine) error {
 	query := `
         UPDATE medicines
-        SET name = $2, description = $3, picture = $4, updated_at = $5
+        SET name = $2, description = $3, picture = $4, category = $5, tax_category = $6, updated_at = $7
         WHERE id = $1
     `
 	result, err := r.db.ExecContext(ctx, query,
-		medicine.ID, medicine.Name, medicine.Description, medicine.Picture, medicine.UpdatedAt,
+		medicine.ID, medicine.Name, medicine.Description, medicine.Picture, medicine.Category, medicine.TaxCategory, medicine.UpdatedAt,
 	)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to update medicine")
@@ -169,32 +261,58 @@ func (r *medicineRepository) CountVariants(ctx context.Context, medicineID uuid.
 	return count, nil
 }
 
-// CreateVariant inserts a new medicine variant
-func (r *medicineRepository) CreateVariant(ctx context.Context, variant domain.MedicineVariant) error {
+// CreateVariant inserts a new medicine variant together with its initial lots,
+// recording a receipt movement for each lot
+func (r *medicineRepository) CreateVariant(ctx context.Context, variant domain.MedicineVariant, lots []domain.MedicineLot, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-        INSERT INTO medicine_variants (id, medicine_id, brand, barcode, unit, price_per_unit, expiry_date, stock, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        INSERT INTO medicine_variants (id, medicine_id, brand, barcode, unit, price_per_unit, cost_price, reorder_point, reorder_quantity, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
     `
-	_, err := r.db.ExecContext(ctx, query,
-		variant.ID, variant.MedicineID, variant.Brand, variant.Barcode, variant.Unit, variant.PricePerUnit, variant.ExpiryDate, variant.Stock, variant.CreatedAt, variant.UpdatedAt,
+	_, err = tx.ExecContext(ctx, query,
+		variant.ID, variant.MedicineID, variant.Brand, variant.Barcode, variant.Unit, variant.PricePerUnit, variant.CostPrice,
+		variant.ReorderPoint, variant.ReorderQuantity, variant.CreatedAt, variant.UpdatedAt,
 	)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to create medicine variant")
 		return err
 	}
+	if err := insertVariantPrice(ctx, tx, variant.ID, variant.PricePerUnit, variant.CreatedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to record variant price")
+		return err
+	}
+
+	onHand := 0
+	for _, lot := range lots {
+		if err := insertLot(ctx, tx, lot); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to create medicine lot")
+			return err
+		}
+		if err := insertReceiptMovement(ctx, tx, lot, onHand, userID); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to record stock movement")
+			return err
+		}
+		onHand += lot.Quantity
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
 	return nil
 }
 
 // GetVariantByID retrieves a medicine variant by ID
 func (r *medicineRepository) GetVariantByID(ctx context.Context, id uuid.UUID) (*domain.MedicineVariant, error) {
-	query := `
-        SELECT id, medicine_id, brand, barcode, unit, price_per_unit, expiry_date, stock, created_at, updated_at
-        FROM medicine_variants WHERE id = $1
-    `
+	query := variantSelect + `WHERE mv.id = $1`
 	var v domain.MedicineVariant
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&v.ID, &v.MedicineID, &v.Brand, &v.Barcode, &v.Unit, &v.PricePerUnit, &v.ExpiryDate, &v.Stock, &v.CreatedAt, &v.UpdatedAt,
-	)
+	err := scanVariant(r.db.QueryRowContext(ctx, query, id), &v)
 	if err == sql.ErrNoRows {
 		r.logger.Info().Str("id", id.String()).Msg("Medicine variant not found")
 		return nil, domain.ErrVariantNotFound
@@ -206,12 +324,39 @@ func (r *medicineRepository) GetVariantByID(ctx context.Context, id uuid.UUID) (
 	return &v, nil
 }
 
+// GetVariantsByIDs retrieves the medicine variants with the given IDs, keyed
+// by ID. IDs that do not exist are left out.
+func (r *medicineRepository) GetVariantsByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.MedicineVariant, error) {
+	variants := make(map[uuid.UUID]domain.MedicineVariant, len(ids))
+	if len(ids) == 0 {
+		return variants, nil
+	}
+	query := variantSelect + `WHERE mv.id = ANY($1::uuid[])`
+	rows, err := r.db.QueryContext(ctx, query, uuidArray(ids))
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicine variants by IDs")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v domain.MedicineVariant
+		if err := scanVariant(rows, &v); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan medicine variant")
+			return nil, err
+		}
+		variants[v.ID] = v
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicine variants by IDs")
+		return nil, err
+	}
+	return variants, nil
+}
+
 // GetVariantsByMedicineID retrieves variants for a medicine
 func (r *medicineRepository) GetVariantsByMedicineID(ctx context.Context, medicineID uuid.UUID) ([]domain.MedicineVariant, error) {
-	query := `
-        SELECT id, medicine_id, brand, barcode, unit, price_per_unit, expiry_date, stock, created_at, updated_at
-        FROM medicine_variants WHERE medicine_id = $1
-    `
+	query := variantSelect + `WHERE mv.medicine_id = $1 ORDER BY mv.created_at`
 	rows, err := r.db.QueryContext(ctx, query, medicineID)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to get medicine variants")
@@ -222,7 +367,7 @@ func (r *medicineRepository) GetVariantsByMedicineID(ctx context.Context, medici
 	var variants []domain.MedicineVariant
 	for rows.Next() {
 		var v domain.MedicineVariant
-		if err := rows.Scan(&v.ID, &v.MedicineID, &v.Brand, &v.Barcode, &v.Unit, &v.PricePerUnit, &v.ExpiryDate, &v.Stock, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		if err := scanVariant(rows, &v); err != nil {
 			r.logger.Error().Err(err).Msg("Failed to scan medicine variant")
 			return nil, err
 		}
@@ -231,15 +376,26 @@ func (r *medicineRepository) GetVariantsByMedicineID(ctx context.Context, medici
 	return variants, nil
 }
 
-// UpdateVariant updates a medicine variant
+// UpdateVariant updates a medicine variant. A pending low-stock alert is
+// cleared so the new reorder point is re-evaluated on the next sale. A
+// changed price joins the variant's price history from its UpdatedAt.
 func (r *medicineRepository) UpdateVariant(ctx context.Context, variant domain.MedicineVariant) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
         UPDATE medicine_variants
-        SET brand = $2, barcode = $3, unit = $4, price_per_unit = $5, expiry_date = $6, stock = $7, updated_at = $8
+        SET brand = $2, barcode = $3, unit = $4, price_per_unit = $5, cost_price = $6, reorder_point = $7,
+            reorder_quantity = $8, low_stock_alerted_at = NULL, updated_at = $9
         WHERE id = $1
     `
-	result, err := r.db.ExecContext(ctx, query,
-		variant.ID, variant.Brand, variant.Barcode, variant.Unit, variant.PricePerUnit, variant.ExpiryDate, variant.Stock, variant.UpdatedAt,
+	result, err := tx.ExecContext(ctx, query,
+		variant.ID, variant.Brand, variant.Barcode, variant.Unit, variant.PricePerUnit, variant.CostPrice,
+		variant.ReorderPoint, variant.ReorderQuantity, variant.UpdatedAt,
 	)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to update medicine variant")
@@ -254,13 +410,88 @@ func (r *medicineRepository) UpdateVariant(ctx context.Context, variant domain.M
 		r.logger.Info().Str("id", variant.ID.String()).Msg("Medicine variant not found for update")
 		return domain.ErrVariantNotFound
 	}
+
+	var current domain.Money
+	currentQuery := `SELECT price_per_unit FROM variant_prices WHERE variant_id = $1 ORDER BY effective_from DESC LIMIT 1`
+	if err := tx.QueryRowContext(ctx, currentQuery, variant.ID).Scan(&current); err != nil && err != sql.ErrNoRows {
+		r.logger.Error().Err(err).Msg("Failed to get variant price")
+		return err
+	}
+	if current != variant.PricePerUnit {
+		if err := insertVariantPrice(ctx, tx, variant.ID, variant.PricePerUnit, variant.UpdatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to record variant price")
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
 	return nil
 }
 
-// DeleteVariant deletes a medicine variant
+// GetPriceHistory retrieves the prices of the given variants in effect at
+// since or set after it, keyed by variant ID, each variant's oldest first
+func (r *medicineRepository) GetPriceHistory(ctx context.Context, variantIDs []uuid.UUID, since time.Time) (map[uuid.UUID][]domain.VariantPrice, error) {
+	history := make(map[uuid.UUID][]domain.VariantPrice, len(variantIDs))
+	if len(variantIDs) == 0 {
+		return history, nil
+	}
+	query := `
+        SELECT p.variant_id, p.price_per_unit, p.effective_from
+        FROM variant_prices p
+        WHERE p.variant_id = ANY($1::uuid[])
+          AND p.effective_from >= COALESCE((SELECT MAX(o.effective_from) FROM variant_prices o
+                                            WHERE o.variant_id = p.variant_id AND o.effective_from <= $2), '-infinity')
+        ORDER BY p.variant_id, p.effective_from
+    `
+	rows, err := r.db.QueryContext(ctx, query, uuidArray(variantIDs), since)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get price history")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p domain.VariantPrice
+		if err := rows.Scan(&p.VariantID, &p.PricePerUnit, &p.EffectiveFrom); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan variant price")
+			return nil, err
+		}
+		history[p.VariantID] = append(history[p.VariantID], p)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get price history")
+		return nil, err
+	}
+	return history, nil
+}
+
+// DeleteVariant deletes a medicine variant that has never moved stock,
+// leaving a tombstone for catalog sync
 func (r *medicineRepository) DeleteVariant(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM medicine_variants WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+	// The ledger restricts the delete anyway; checking first gives a clear error
+	var moved bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM stock_movements WHERE variant_id = $1)`, id).Scan(&moved); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check stock movements")
+		return err
+	}
+	if moved {
+		r.logger.Info().Str("id", id.String()).Msg("Medicine variant has stock history")
+		return domain.ErrVariantHasHistory
+	}
+
+	query := `
+        WITH deleted AS (
+            DELETE FROM medicine_variants mv USING medicines m
+            WHERE mv.id = $1 AND m.id = mv.medicine_id
+            RETURNING mv.id, mv.medicine_id, m.pharmacy_id
+        )
+        INSERT INTO deleted_variants (variant_id, medicine_id, pharmacy_id, deleted_at)
+        SELECT id, medicine_id, pharmacy_id, $2 FROM deleted
+    `
+	result, err := r.db.ExecContext(ctx, query, id, time.Now())
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to delete medicine variant")
 		return err
@@ -277,6 +508,51 @@ func (r *medicineRepository) DeleteVariant(ctx context.Context, id uuid.UUID) er
 	return nil
 }
 
+// GetCatalogChanges retrieves up to limit of a pharmacy's variants changed,
+// or deleted, after the cursor and before the given time, oldest change
+// first. A change to a medicine changes each of its variants.
+func (r *medicineRepository) GetCatalogChanges(ctx context.Context, pharmacyID uuid.UUID, after domain.CatalogCursor, before time.Time, limit int) ([]domain.CatalogItem, error) {
+	query := `
+        SELECT id, medicine_id, name, brand, barcode, unit, price_per_unit, category, tax_category, deleted, changed_at
+        FROM (
+            SELECT mv.id, mv.medicine_id, m.name, mv.brand, mv.barcode, mv.unit, mv.price_per_unit, m.category, m.tax_category,
+                   FALSE AS deleted, GREATEST(mv.updated_at, m.updated_at) AS changed_at
+            FROM medicine_variants mv
+            JOIN medicines m ON m.id = mv.medicine_id
+            WHERE m.pharmacy_id = $1
+            UNION ALL
+            SELECT variant_id, medicine_id, '', '', '', '', 0, '', '', TRUE, deleted_at
+            FROM deleted_variants
+            WHERE pharmacy_id = $1
+        ) changes
+        WHERE (changed_at, id) > ($2, $3) AND changed_at < $4
+        ORDER BY changed_at, id
+        LIMIT $5
+    `
+	rows, err := r.db.QueryContext(ctx, query, pharmacyID, after.ChangedAt, after.VariantID, before, limit)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get catalog changes")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.CatalogItem
+	for rows.Next() {
+		var it domain.CatalogItem
+		if err := rows.Scan(&it.VariantID, &it.MedicineID, &it.Name, &it.Brand, &it.Barcode, &it.Unit, &it.PricePerUnit, &it.Category,
+			&it.TaxCategory, &it.Deleted, &it.ChangedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan catalog change")
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read catalog changes")
+		return nil, err
+	}
+	return items, nil
+}
+
 // CheckBarcodeExists checks if a barcode is already taken
 func (r *medicineRepository) CheckBarcodeExists(ctx context.Context, barcode string) (bool, error) {
 	query := `SELECT EXISTS (SELECT 1 FROM medicine_variants WHERE barcode = $1)`
@@ -288,3 +564,338 @@ func (r *medicineRepository) CheckBarcodeExists(ctx context.Context, barcode str
 	}
 	return exists, nil
 }
+
+// CreateLot inserts a new lot for a variant and records its receipt movement
+func (r *medicineRepository) CreateLot(ctx context.Context, lot domain.MedicineLot, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	onHand, err := lockVariantLots(ctx, tx, lot.VariantID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return err
+	}
+	if err := insertLot(ctx, tx, lot); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create medicine lot")
+		return err
+	}
+	if err := insertReceiptMovement(ctx, tx, lot, onHand, userID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to record stock movement")
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE medicine_variants SET low_stock_alerted_at = NULL, updated_at = $1 WHERE id = $2`, lot.CreatedAt, lot.VariantID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update medicine variant")
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+// GetLotsByVariantID retrieves all lots of a variant, earliest expiry first
+func (r *medicineRepository) GetLotsByVariantID(ctx context.Context, variantID uuid.UUID) ([]domain.MedicineLot, error) {
+	query := `
+        SELECT id, variant_id, lot_number, expiry_date, quantity, quarantined, received_at, created_at, updated_at
+        FROM medicine_lots WHERE variant_id = $1
+        ORDER BY expiry_date, received_at
+    `
+	rows, err := r.db.QueryContext(ctx, query, variantID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicine lots")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []domain.MedicineLot
+	for rows.Next() {
+		var l domain.MedicineLot
+		if err := rows.Scan(&l.ID, &l.VariantID, &l.LotNumber, &l.ExpiryDate, &l.Quantity, &l.Quarantined, &l.ReceivedAt, &l.CreatedAt, &l.UpdatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan medicine lot")
+			return nil, err
+		}
+		lots = append(lots, l)
+	}
+	return lots, nil
+}
+
+// GetLowStock retrieves a pharmacy's variants whose stock is below their
+// reorder point
+func (r *medicineRepository) GetLowStock(ctx context.Context, pharmacyID uuid.UUID) ([]domain.LowStockItem, error) {
+	query := variantColumns + `, m.name` + variantFrom + `
+        JOIN medicines m ON mv.medicine_id = m.id
+        WHERE m.pharmacy_id = $1 AND mv.reorder_point > 0 AND COALESCE(lots.stock, 0) < mv.reorder_point
+        ORDER BY m.name, mv.brand
+    `
+	rows, err := r.db.QueryContext(ctx, query, pharmacyID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get low stock variants")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []domain.LowStockItem
+	for rows.Next() {
+		var item domain.LowStockItem
+		if err := scanVariant(rows, &item.MedicineVariant, &item.MedicineName); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan low stock variant")
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// ClaimLowStockAlert marks a variant as alerted and reports whether this call
+// did so, so that only one caller notifies until the variant is restocked
+func (r *medicineRepository) ClaimLowStockAlert(ctx context.Context, variantID uuid.UUID) (bool, error) {
+	query := `UPDATE medicine_variants SET low_stock_alerted_at = NOW() WHERE id = $1 AND low_stock_alerted_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, variantID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to claim low stock alert")
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// ReleaseLowStockAlert gives up a claimed alert whose notification could not
+// be sent, so that the next check claims it again
+func (r *medicineRepository) ReleaseLowStockAlert(ctx context.Context, variantID uuid.UUID) error {
+	query := `UPDATE medicine_variants SET low_stock_alerted_at = NULL WHERE id = $1`
+	if _, err := r.db.ExecContext(ctx, query, variantID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to release low stock alert")
+		return err
+	}
+	return nil
+}
+
+// AdjustStock applies movement.Quantity to a lot of movement.VariantID and
+// records the movement with the variant's on-hand quantity before and after
+func (r *medicineRepository) AdjustStock(ctx context.Context, movement domain.StockMovement) (*domain.StockMovement, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	onHand, err := lockVariantLots(ctx, tx, movement.VariantID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return nil, err
+	}
+
+	var quantity int
+	query := `SELECT lot_number, quantity FROM medicine_lots WHERE id = $1 AND variant_id = $2`
+	err = tx.QueryRowContext(ctx, query, movement.LotID, movement.VariantID).Scan(&movement.LotNumber, &quantity)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("lot_id", movement.LotID.String()).Msg("Medicine lot not found")
+		return nil, domain.ErrLotNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicine lot")
+		return nil, err
+	}
+	if quantity+movement.Quantity < 0 {
+		r.logger.Info().Str("lot_id", movement.LotID.String()).Msg("Insufficient stock")
+		return nil, domain.ErrInsufficientStock
+	}
+
+	updateQuery := `UPDATE medicine_lots SET quantity = quantity + $1, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, updateQuery, movement.Quantity, movement.CreatedAt, movement.LotID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update lot stock")
+		return nil, err
+	}
+	// Restocking re-arms the low-stock alert
+	variantQuery := `
+        UPDATE medicine_variants
+        SET low_stock_alerted_at = CASE WHEN $1 > 0 THEN NULL ELSE low_stock_alerted_at END, updated_at = $2
+        WHERE id = $3
+    `
+	if _, err := tx.ExecContext(ctx, variantQuery, movement.Quantity, movement.CreatedAt, movement.VariantID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update medicine variant")
+		return nil, err
+	}
+
+	movement.QuantityBefore = onHand
+	movement.QuantityAfter = onHand + movement.Quantity
+	if err := insertStockMovement(ctx, tx, movement); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to record stock movement")
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// GetStockMovements retrieves a variant's stock movements, newest first
+func (r *medicineRepository) GetStockMovements(ctx context.Context, variantID uuid.UUID, limit, offset int) ([]domain.StockMovement, error) {
+	query := `
+        SELECT sm.id, sm.variant_id, sm.lot_id, l.lot_number, sm.type, sm.quantity, sm.quantity_before,
+               sm.quantity_after, sm.reason, sm.user_id, sm.sale_id, sm.created_at
+        FROM stock_movements sm
+        JOIN medicine_lots l ON sm.lot_id = l.id
+        WHERE sm.variant_id = $1
+        ORDER BY sm.created_at DESC, sm.seq DESC
+        LIMIT $2 OFFSET $3
+    `
+	rows, err := r.db.QueryContext(ctx, query, variantID, limit, offset)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get stock movements")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movements []domain.StockMovement
+	for rows.Next() {
+		var m domain.StockMovement
+		var saleID uuid.NullUUID
+		if err := rows.Scan(&m.ID, &m.VariantID, &m.LotID, &m.LotNumber, &m.Type, &m.Quantity, &m.QuantityBefore,
+			&m.QuantityAfter, &m.Reason, &m.UserID, &saleID, &m.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan stock movement")
+			return nil, err
+		}
+		if saleID.Valid {
+			m.SaleID = &saleID.UUID
+		}
+		movements = append(movements, m)
+	}
+	return movements, nil
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// uuidArray passes ids as a Postgres array parameter
+func uuidArray(ids []uuid.UUID) interface{} {
+	s := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		s[i] = id.String()
+	}
+	return s
+}
+
+// insertLot inserts a lot using db or an open transaction
+func insertLot(ctx context.Context, db execer, lot domain.MedicineLot) error {
+	query := `
+        INSERT INTO medicine_lots (id, variant_id, lot_number, expiry_date, quantity, received_at, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	_, err := db.ExecContext(ctx, query,
+		lot.ID, lot.VariantID, lot.LotNumber, lot.ExpiryDate, lot.Quantity, lot.ReceivedAt, lot.CreatedAt, lot.UpdatedAt,
+	)
+	return err
+}
+
+// insertVariantPrice adds a price to a variant's history, replacing one set
+// at the same time
+func insertVariantPrice(ctx context.Context, db execer, variantID uuid.UUID, price domain.Money, effectiveFrom time.Time) error {
+	query := `
+        INSERT INTO variant_prices (variant_id, price_per_unit, effective_from)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (variant_id, effective_from) DO UPDATE SET price_per_unit = EXCLUDED.price_per_unit
+    `
+	_, err := db.ExecContext(ctx, query, variantID, price, effectiveFrom)
+	return err
+}
+
+// lockVariantLots locks every lot of a variant, in a fixed order so that
+// concurrent writers cannot deadlock, and returns their total quantity
+func lockVariantLots(ctx context.Context, tx *sql.Tx, variantID uuid.UUID) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT quantity FROM medicine_lots WHERE variant_id = $1 ORDER BY id FOR UPDATE`, variantID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	onHand := 0
+	for rows.Next() {
+		var quantity int
+		if err := rows.Scan(&quantity); err != nil {
+			return 0, err
+		}
+		onHand += quantity
+	}
+	return onHand, rows.Err()
+}
+
+// lockLots locks every lot of the given variants, variant by variant in ID
+// order and in lockVariantLots' order within each, so that concurrent writers
+// touching several variants cannot deadlock, and returns each variant's total
+// quantity
+func lockLots(ctx context.Context, tx *sql.Tx, variantIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	onHand := make(map[uuid.UUID]int, len(variantIDs))
+	for _, id := range variantIDs {
+		onHand[id] = 0
+	}
+	if len(variantIDs) == 0 {
+		return onHand, nil
+	}
+	query := `
+        SELECT variant_id, quantity FROM medicine_lots
+        WHERE variant_id = ANY($1::uuid[])
+        ORDER BY variant_id, id
+        FOR UPDATE
+    `
+	rows, err := tx.QueryContext(ctx, query, uuidArray(variantIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var variantID uuid.UUID
+		var quantity int
+		if err := rows.Scan(&variantID, &quantity); err != nil {
+			return nil, err
+		}
+		onHand[variantID] += quantity
+	}
+	return onHand, rows.Err()
+}
+
+// insertStockMovement appends a movement to the ledger
+func insertStockMovement(ctx context.Context, db execer, m domain.StockMovement) error {
+	query := `
+        INSERT INTO stock_movements (id, variant_id, lot_id, type, quantity, quantity_before, quantity_after, reason, user_id, sale_id, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    `
+	_, err := db.ExecContext(ctx, query,
+		m.ID, m.VariantID, m.LotID, m.Type, m.Quantity, m.QuantityBefore, m.QuantityAfter, m.Reason, m.UserID, m.SaleID, m.CreatedAt,
+	)
+	return err
+}
+
+// insertReceiptMovement records the arrival of a new lot on top of onHand
+func insertReceiptMovement(ctx context.Context, db execer, lot domain.MedicineLot, onHand int, userID uuid.UUID) error {
+	if lot.Quantity == 0 {
+		return nil
+	}
+	return insertStockMovement(ctx, db, domain.StockMovement{
+		ID:             uuid.New(),
+		VariantID:      lot.VariantID,
+		LotID:          lot.ID,
+		Type:           domain.StockMovementReceipt,
+		Quantity:       lot.Quantity,
+		QuantityBefore: onHand,
+		QuantityAfter:  onHand + lot.Quantity,
+		UserID:         userID,
+		CreatedAt:      lot.CreatedAt,
+	})
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// authRepository implements repository.AuthRepository
+type authRepository struct {
+	store *Store
+}
+
+// NewAuthRepository creates a new in-memory AuthRepository
+func NewAuthRepository(store *Store) repository.AuthRepository {
+	return &authRepository{store}
+}
+
+// Create stores a new user
+func (r *authRepository) Create(ctx context.Context, user domain.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[user.ID]; ok {
+		return errUniqueViolation
+	}
+	for _, u := range r.store.users {
+		if u.PhoneNumber == user.PhoneNumber {
+			return errUniqueViolation
+		}
+	}
+	r.store.users[user.ID] = user
+	return nil
+}
+
+// GetByPhone retrieves a user by phone number
+func (r *authRepository) GetByPhone(ctx context.Context, phoneNumber string) (*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, u := range r.store.users {
+		if u.PhoneNumber == phoneNumber {
+			return &u, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+// GetByID retrieves a user by ID
+func (r *authRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	u, ok := r.store.users[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &u, nil
+}
+
+// Update replaces a stored user
+func (r *authRepository) Update(ctx context.Context, user domain.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.users[user.ID]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	for _, u := range r.store.users {
+		if u.ID != user.ID && u.PhoneNumber == user.PhoneNumber {
+			return errUniqueViolation
+		}
+	}
+	user.CreatedAt = existing.CreatedAt
+	r.store.users[user.ID] = user
+	return nil
+}
+
+// SaveRefreshToken saves a refresh token
+func (r *authRepository) SaveRefreshToken(ctx context.Context, userID uuid.UUID, t string, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[userID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.refreshTokens[t]; ok {
+		return errUniqueViolation
+	}
+	r.store.refreshTokens[t] = token{userID, expiresAt}
+	return nil
+}
+
+// GetRefreshToken retrieves a user ID by refresh token
+func (r *authRepository) GetRefreshToken(ctx context.Context, t string) (*uuid.UUID, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	stored, ok := r.store.refreshTokens[t]
+	if !ok || !stored.expiresAt.After(time.Now()) {
+		return nil, domain.ErrInvalidToken
+	}
+	return &stored.userID, nil
+}
+
+// DeleteRefreshToken deletes a refresh token
+func (r *authRepository) DeleteRefreshToken(ctx context.Context, t string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.refreshTokens, t)
+	return nil
+}
+
+// SaveResetToken saves a password reset token
+func (r *authRepository) SaveResetToken(ctx context.Context, userID uuid.UUID, t string, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[userID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.resetTokens[t]; ok {
+		return errUniqueViolation
+	}
+	r.store.resetTokens[t] = token{userID, expiresAt}
+	return nil
+}
+
+// GetResetToken retrieves a user ID by reset token
+func (r *authRepository) GetResetToken(ctx context.Context, t string) (*uuid.UUID, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	stored, ok := r.store.resetTokens[t]
+	if !ok || !stored.expiresAt.After(time.Now()) {
+		return nil, domain.ErrInvalidResetToken
+	}
+	return &stored.userID, nil
+}
+
+// DeleteResetToken deletes a reset token
+func (r *authRepository) DeleteResetToken(ctx context.Context, t string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.resetTokens, t)
+	return nil
+}
+
+// GetPharmacists retrieves all pharmacists, optionally filtered by pharmacy_id
+func (r *authRepository) GetPharmacists(ctx context.Context, pharmacyID *uuid.UUID) ([]domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var pharmacists []domain.User
+	for _, u := range r.store.users {
+		if u.Role != domain.RolePharmacist {
+			continue
+		}
+		if pharmacyID != nil && u.PharmacyID != *pharmacyID {
+			continue
+		}
+		pharmacists = append(pharmacists, u)
+	}
+	sort.SliceStable(pharmacists, func(i, j int) bool {
+		return pharmacists[i].CreatedAt.After(pharmacists[j].CreatedAt)
+	})
+	return pharmacists, nil
+}
+
+// GetOwners retrieves the owners of a pharmacy
+func (r *authRepository) GetOwners(ctx context.Context, pharmacyID uuid.UUID) ([]domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var owners []domain.User
+	for _, u := range r.store.users {
+		if u.Role == domain.RoleOwner && u.PharmacyID == pharmacyID {
+			owners = append(owners, u)
+		}
+	}
+	sort.SliceStable(owners, func(i, j int) bool {
+		return owners[i].CreatedAt.Before(owners[j].CreatedAt)
+	})
+	return owners, nil
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// customerRepository implements repository.CustomerRepository
+type customerRepository struct {
+	store *Store
+}
+
+// NewCustomerRepository creates a new in-memory CustomerRepository
+func NewCustomerRepository(store *Store) repository.CustomerRepository {
+	return &customerRepository{store}
+}
+
+// Create stores a new customer
+func (r *customerRepository) Create(ctx context.Context, customer domain.Customer) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.pharmacies[customer.PharmacyID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.customers[customer.ID]; ok {
+		return errUniqueViolation
+	}
+	r.store.customers[customer.ID] = customer
+	return nil
+}
+
+// GetByID retrieves a customer by ID
+func (r *customerRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Customer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	c, ok := r.store.customers[id]
+	if !ok {
+		return nil, domain.ErrCustomerNotFound
+	}
+	return &c, nil
+}
+
+// Search retrieves up to limit of a pharmacy's customers whose name or phone
+// number contains query, ordered by name. An empty query matches everyone.
+func (r *customerRepository) Search(ctx context.Context, pharmacyID uuid.UUID, query string, limit int) ([]domain.Customer, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	query = strings.ToLower(query)
+	var customers []domain.Customer
+	for _, c := range r.store.customers {
+		if c.PharmacyID == pharmacyID && (strings.Contains(strings.ToLower(c.FullName), query) || strings.Contains(c.PhoneNumber, query)) {
+			customers = append(customers, c)
+		}
+	}
+	sort.Slice(customers, func(i, j int) bool {
+		if customers[i].FullName != customers[j].FullName {
+			return customers[i].FullName < customers[j].FullName
+		}
+		return customers[i].ID.String() < customers[j].ID.String()
+	})
+	if len(customers) > limit {
+		customers = customers[:limit]
+	}
+	return customers, nil
+}
+
+// Update updates a customer
+func (r *customerRepository) Update(ctx context.Context, customer domain.Customer) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.customers[customer.ID]
+	if !ok {
+		return domain.ErrCustomerNotFound
+	}
+	existing.FullName = customer.FullName
+	existing.PhoneNumber = customer.PhoneNumber
+	existing.Notes = customer.Notes
+	existing.Allergies = customer.Allergies
+	existing.UpdatedAt = customer.UpdatedAt
+	r.store.customers[customer.ID] = existing
+	return nil
+}
+
+// Delete deletes a customer. Their sales are kept without a customer.
+func (r *customerRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.customers[id]; !ok {
+		return domain.ErrCustomerNotFound
+	}
+	for saleID, s := range r.store.sales {
+		if s.CustomerID != nil && *s.CustomerID == id {
+			s.CustomerID = nil
+			r.store.sales[saleID] = s
+		}
+	}
+	delete(r.store.customers, id)
+	return nil
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// inventoryRepository implements repository.InventoryRepository
+type inventoryRepository struct {
+	store *Store
+}
+
+// NewInventoryRepository creates a new in-memory InventoryRepository
+func NewInventoryRepository(store *Store) repository.InventoryRepository {
+	return &inventoryRepository{store}
+}
+
+// GetExpiring retrieves a pharmacy's lots with stock that expire before the
+// given time, grouped by medicine and earliest expiry first
+func (r *inventoryRepository) GetExpiring(ctx context.Context, pharmacyID uuid.UUID, before time.Time) ([]domain.ExpiringMedicine, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	now := time.Now()
+	byMedicine := make(map[uuid.UUID]*domain.ExpiringMedicine)
+	for _, l := range r.store.lots {
+		if l.Quantity == 0 || !l.ExpiryDate.Before(before) {
+			continue
+		}
+		v := r.store.variants[l.VariantID]
+		m, ok := r.store.medicines[v.MedicineID]
+		if !ok || m.PharmacyID != pharmacyID {
+			continue
+		}
+		lot := domain.ExpiringLot{
+			LotID:      l.ID,
+			VariantID:  v.ID,
+			Brand:      v.Brand,
+			Unit:       v.Unit,
+			LotNumber:  l.LotNumber,
+			ExpiryDate: l.ExpiryDate,
+			Quantity:   l.Quantity,
+			Value:      v.CostPrice.Mul(l.Quantity),
+			Expired:    !l.ExpiryDate.After(now),
+		}
+		em, ok := byMedicine[m.ID]
+		if !ok {
+			em = &domain.ExpiringMedicine{MedicineID: m.ID, MedicineName: m.Name}
+			byMedicine[m.ID] = em
+		}
+		em.Lots = append(em.Lots, lot)
+		em.Quantity += lot.Quantity
+		em.Value += lot.Value
+	}
+
+	var medicines []domain.ExpiringMedicine
+	for _, em := range byMedicine {
+		sort.SliceStable(em.Lots, func(i, j int) bool {
+			if !em.Lots[i].ExpiryDate.Equal(em.Lots[j].ExpiryDate) {
+				return em.Lots[i].ExpiryDate.Before(em.Lots[j].ExpiryDate)
+			}
+			return em.Lots[i].Brand < em.Lots[j].Brand
+		})
+		medicines = append(medicines, *em)
+	}
+	sort.SliceStable(medicines, func(i, j int) bool {
+		if medicines[i].MedicineName != medicines[j].MedicineName {
+			return medicines[i].MedicineName < medicines[j].MedicineName
+		}
+		return medicines[i].MedicineID.String() < medicines[j].MedicineID.String()
+	})
+	return medicines, nil
+}
+
+// CreateWriteOff removes expired stock from a lot of the write-off's pharmacy,
+// recording the write-off and an expiry_write_off stock movement. A zero
+// Quantity writes off everything left in the lot.
+func (r *inventoryRepository) CreateWriteOff(ctx context.Context, writeOff domain.WriteOff) (*domain.WriteOff, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	lot, ok := r.store.lots[writeOff.LotID]
+	if !ok {
+		return nil, domain.ErrLotNotFound
+	}
+	v := r.store.variants[lot.VariantID]
+	if m, ok := r.store.medicines[v.MedicineID]; !ok || m.PharmacyID != writeOff.PharmacyID {
+		return nil, domain.ErrLotNotFound
+	}
+	if _, ok := r.store.users[writeOff.UserID]; !ok {
+		return nil, errForeignKeyViolation
+	}
+	if lot.ExpiryDate.After(writeOff.CreatedAt) {
+		return nil, domain.ErrLotNotExpired
+	}
+	if writeOff.Quantity == 0 {
+		writeOff.Quantity = lot.Quantity
+	}
+	if lot.Quantity == 0 || writeOff.Quantity > lot.Quantity {
+		return nil, domain.ErrInsufficientStock
+	}
+
+	writeOff.VariantID = v.ID
+	writeOff.LotNumber = lot.LotNumber
+	writeOff.UnitValue = v.CostPrice
+	writeOff.LossValue = writeOff.UnitValue.Mul(writeOff.Quantity)
+
+	onHand := r.store.onHand(v.ID)
+	lot.Quantity -= writeOff.Quantity
+	lot.UpdatedAt = writeOff.CreatedAt
+	r.store.lots[lot.ID] = lot
+	r.store.writeOffs = append(r.store.writeOffs, writeOff)
+	r.store.movements = append(r.store.movements, domain.StockMovement{
+		ID:             uuid.New(),
+		VariantID:      v.ID,
+		LotID:          lot.ID,
+		Type:           domain.StockMovementExpiryWriteOff,
+		Quantity:       -writeOff.Quantity,
+		QuantityBefore: onHand,
+		QuantityAfter:  onHand - writeOff.Quantity,
+		Reason:         writeOff.Reason,
+		UserID:         writeOff.UserID,
+		CreatedAt:      writeOff.CreatedAt,
+	})
+	return &writeOff, nil
+}
+
+// GetWriteOffs retrieves a pharmacy's write-offs made in [from, to), oldest first
+func (r *inventoryRepository) GetWriteOffs(ctx context.Context, pharmacyID uuid.UUID, from, to time.Time) ([]domain.WriteOff, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var writeOffs []domain.WriteOff
+	for _, w := range r.store.writeOffs {
+		if w.PharmacyID == pharmacyID && !w.CreatedAt.Before(from) && w.CreatedAt.Before(to) {
+			writeOffs = append(writeOffs, w)
+		}
+	}
+	sort.SliceStable(writeOffs, func(i, j int) bool {
+		return writeOffs[i].CreatedAt.Before(writeOffs[j].CreatedAt)
+	})
+	return writeOffs, nil
+}
@@ -0,0 +1,535 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// medicineRepository implements repository.MedicineRepository
+type medicineRepository struct {
+	store *Store
+}
+
+// NewMedicineRepository creates a new in-memory MedicineRepository
+func NewMedicineRepository(store *Store) repository.MedicineRepository {
+	return &medicineRepository{store}
+}
+
+// Create stores a new medicine
+func (r *medicineRepository) Create(ctx context.Context, medicine domain.Medicine) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.medicines[medicine.ID]; ok {
+		return errUniqueViolation
+	}
+	if _, ok := r.store.pharmacies[medicine.PharmacyID]; !ok {
+		return errForeignKeyViolation
+	}
+	medicine.Variants = nil
+	r.store.medicines[medicine.ID] = medicine
+	return nil
+}
+
+// GetByID retrieves a medicine with its variants
+func (r *medicineRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Medicine, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	m, ok := r.store.medicines[id]
+	if !ok {
+		return nil, domain.ErrMedicineNotFound
+	}
+	m.Variants = r.store.variantsOf(id)
+	return &m, nil
+}
+
+// GetByIDs retrieves the medicines with the given IDs, without their
+// variants, keyed by ID
+func (r *medicineRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.Medicine, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	medicines := make(map[uuid.UUID]domain.Medicine, len(ids))
+	for _, id := range ids {
+		if m, ok := r.store.medicines[id]; ok {
+			medicines[id] = m
+		}
+	}
+	return medicines, nil
+}
+
+// GetAll retrieves medicines for a pharmacy
+func (r *medicineRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Medicine, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var medicines []domain.Medicine
+	for _, m := range r.store.medicines {
+		if m.PharmacyID != pharmacyID {
+			continue
+		}
+		m.Variants = r.store.variantsOf(m.ID)
+		medicines = append(medicines, m)
+	}
+	sort.SliceStable(medicines, func(i, j int) bool {
+		return medicines[i].CreatedAt.Before(medicines[j].CreatedAt)
+	})
+	return medicines, nil
+}
+
+// Update updates a medicine's editable fields
+func (r *medicineRepository) Update(ctx context.Context, medicine domain.Medicine) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.medicines[medicine.ID]
+	if !ok {
+		return domain.ErrMedicineNotFound
+	}
+	existing.Name = medicine.Name
+	existing.Description = medicine.Description
+	existing.Picture = medicine.Picture
+	existing.Category = medicine.Category
+	existing.TaxCategory = medicine.TaxCategory
+	existing.UpdatedAt = medicine.UpdatedAt
+	r.store.medicines[medicine.ID] = existing
+	return nil
+}
+
+// Delete deletes a medicine that has no variants
+func (r *medicineRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.medicines[id]; !ok {
+		return domain.ErrMedicineNotFound
+	}
+	for _, v := range r.store.variants {
+		if v.MedicineID == id {
+			return errForeignKeyViolation
+		}
+	}
+	delete(r.store.medicines, id)
+	return nil
+}
+
+// CountVariants counts variants for a medicine
+func (r *medicineRepository) CountVariants(ctx context.Context, medicineID uuid.UUID) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, v := range r.store.variants {
+		if v.MedicineID == medicineID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CreateVariant stores a new medicine variant together with its initial lots,
+// recording a receipt movement for each lot
+func (r *medicineRepository) CreateVariant(ctx context.Context, variant domain.MedicineVariant, lots []domain.MedicineLot, userID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.variants[variant.ID]; ok {
+		return errUniqueViolation
+	}
+	if _, ok := r.store.medicines[variant.MedicineID]; !ok {
+		return errForeignKeyViolation
+	}
+	if r.store.barcodeTaken(variant.Barcode, uuid.Nil) {
+		return errUniqueViolation
+	}
+	seen := make(map[string]bool)
+	for _, lot := range lots {
+		if _, ok := r.store.lots[lot.ID]; ok || seen[lot.LotNumber] || lot.VariantID != variant.ID {
+			return errUniqueViolation
+		}
+		seen[lot.LotNumber] = true
+	}
+
+	variant.Stock = 0
+	variant.ExpiryDate = time.Time{}
+	r.store.variants[variant.ID] = variant
+	r.store.setPrice(variant.ID, variant.PricePerUnit, variant.CreatedAt)
+	for _, lot := range lots {
+		r.store.receiveLot(lot, userID)
+	}
+	return nil
+}
+
+// GetVariantByID retrieves a medicine variant by ID
+func (r *medicineRepository) GetVariantByID(ctx context.Context, id uuid.UUID) (*domain.MedicineVariant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	v, ok := r.store.variant(id)
+	if !ok {
+		return nil, domain.ErrVariantNotFound
+	}
+	return &v, nil
+}
+
+// GetVariantsByIDs retrieves the medicine variants with the given IDs, keyed
+// by ID
+func (r *medicineRepository) GetVariantsByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.MedicineVariant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	variants := make(map[uuid.UUID]domain.MedicineVariant, len(ids))
+	for _, id := range ids {
+		if v, ok := r.store.variant(id); ok {
+			variants[id] = v
+		}
+	}
+	return variants, nil
+}
+
+// GetVariantsByMedicineID retrieves variants for a medicine
+func (r *medicineRepository) GetVariantsByMedicineID(ctx context.Context, medicineID uuid.UUID) ([]domain.MedicineVariant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.variantsOf(medicineID), nil
+}
+
+// GetPriceHistory retrieves the prices of the given variants in effect at
+// since or set after it, keyed by variant ID, each variant's oldest first
+func (r *medicineRepository) GetPriceHistory(ctx context.Context, variantIDs []uuid.UUID, since time.Time) (map[uuid.UUID][]domain.VariantPrice, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	history := make(map[uuid.UUID][]domain.VariantPrice, len(variantIDs))
+	for _, id := range variantIDs {
+		prices := r.store.variantPrices[id]
+		first := 0
+		for i, p := range prices {
+			if !p.EffectiveFrom.After(since) {
+				first = i
+			}
+		}
+		if len(prices) > 0 {
+			history[id] = append([]domain.VariantPrice(nil), prices[first:]...)
+		}
+	}
+	return history, nil
+}
+
+// UpdateVariant updates a medicine variant, clearing any pending low-stock
+// alert
+func (r *medicineRepository) UpdateVariant(ctx context.Context, variant domain.MedicineVariant) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.variants[variant.ID]
+	if !ok {
+		return domain.ErrVariantNotFound
+	}
+	if r.store.barcodeTaken(variant.Barcode, variant.ID) {
+		return errUniqueViolation
+	}
+	if existing.PricePerUnit != variant.PricePerUnit {
+		r.store.setPrice(variant.ID, variant.PricePerUnit, variant.UpdatedAt)
+	}
+	existing.Brand = variant.Brand
+	existing.Barcode = variant.Barcode
+	existing.Unit = variant.Unit
+	existing.PricePerUnit = variant.PricePerUnit
+	existing.CostPrice = variant.CostPrice
+	existing.ReorderPoint = variant.ReorderPoint
+	existing.ReorderQuantity = variant.ReorderQuantity
+	existing.UpdatedAt = variant.UpdatedAt
+	r.store.variants[variant.ID] = existing
+	delete(r.store.lowStockAlerts, variant.ID)
+	return nil
+}
+
+// DeleteVariant deletes a medicine variant that has never moved stock,
+// leaving a tombstone for catalog sync
+func (r *medicineRepository) DeleteVariant(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	v, ok := r.store.variants[id]
+	if !ok {
+		return domain.ErrVariantNotFound
+	}
+	for _, item := range r.store.saleItems {
+		if item.MedicineVariantID == id {
+			return errForeignKeyViolation
+		}
+	}
+	for _, item := range r.store.orderItems {
+		if item.MedicineVariantID == id {
+			return errForeignKeyViolation
+		}
+	}
+	for _, po := range r.store.purchaseOrders {
+		for _, item := range po.Items {
+			if item.VariantID == id {
+				return errForeignKeyViolation
+			}
+		}
+	}
+	for _, m := range r.store.movements {
+		if m.VariantID == id {
+			return domain.ErrVariantHasHistory
+		}
+	}
+	for cartID, c := range r.store.carts {
+		if c.MedicineVariantID == id {
+			delete(r.store.carts, cartID)
+		}
+	}
+	for lotID, l := range r.store.lots {
+		if l.VariantID == id {
+			delete(r.store.lots, lotID)
+		}
+	}
+	delete(r.store.lowStockAlerts, id)
+	delete(r.store.variants, id)
+	delete(r.store.variantPrices, id)
+	if m, ok := r.store.medicines[v.MedicineID]; ok {
+		r.store.deletedVariants = append(r.store.deletedVariants, deletedVariant{m.PharmacyID, domain.CatalogItem{
+			VariantID:  id,
+			MedicineID: v.MedicineID,
+			Deleted:    true,
+			ChangedAt:  time.Now(),
+		}})
+	}
+	return nil
+}
+
+// GetCatalogChanges retrieves up to limit of a pharmacy's variants changed,
+// or deleted, after the cursor and before the given time, oldest change
+// first. A change to a medicine changes each of its variants.
+func (r *medicineRepository) GetCatalogChanges(ctx context.Context, pharmacyID uuid.UUID, after domain.CatalogCursor, before time.Time, limit int) ([]domain.CatalogItem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var items []domain.CatalogItem
+	for _, v := range r.store.variants {
+		m, ok := r.store.medicines[v.MedicineID]
+		if !ok || m.PharmacyID != pharmacyID {
+			continue
+		}
+		changedAt := v.UpdatedAt
+		if m.UpdatedAt.After(changedAt) {
+			changedAt = m.UpdatedAt
+		}
+		items = append(items, domain.CatalogItem{
+			VariantID:    v.ID,
+			MedicineID:   m.ID,
+			Name:         m.Name,
+			Brand:        v.Brand,
+			Barcode:      v.Barcode,
+			Unit:         v.Unit,
+			PricePerUnit: v.PricePerUnit,
+			Category:     m.Category,
+			TaxCategory:  m.TaxCategory,
+			ChangedAt:    changedAt,
+		})
+	}
+	for _, d := range r.store.deletedVariants {
+		if d.pharmacyID == pharmacyID {
+			items = append(items, d.item)
+		}
+	}
+
+	changes := items[:0]
+	for _, it := range items {
+		if it.ChangedAt.Before(before) && catalogAfter(it, after) {
+			changes = append(changes, it)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return catalogAfter(changes[j], domain.CatalogCursor{ChangedAt: changes[i].ChangedAt, VariantID: changes[i].VariantID})
+	})
+	return paginate(changes, limit, 0), nil
+}
+
+// catalogAfter reports whether a catalog change comes after the cursor
+func catalogAfter(it domain.CatalogItem, c domain.CatalogCursor) bool {
+	if !it.ChangedAt.Equal(c.ChangedAt) {
+		return it.ChangedAt.After(c.ChangedAt)
+	}
+	return bytes.Compare(it.VariantID[:], c.VariantID[:]) > 0
+}
+
+// CheckBarcodeExists checks if a barcode is already taken
+func (r *medicineRepository) CheckBarcodeExists(ctx context.Context, barcode string) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.barcodeTaken(barcode, uuid.Nil), nil
+}
+
+// CreateLot stores a new lot for a variant and records its receipt movement
+func (r *medicineRepository) CreateLot(ctx context.Context, lot domain.MedicineLot, userID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.variants[lot.VariantID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.lots[lot.ID]; ok {
+		return errUniqueViolation
+	}
+	for _, l := range r.store.lots {
+		if l.VariantID == lot.VariantID && l.LotNumber == lot.LotNumber {
+			return errUniqueViolation
+		}
+	}
+	r.store.receiveLot(lot, userID)
+	return nil
+}
+
+// GetLotsByVariantID retrieves all lots of a variant, earliest expiry first
+func (r *medicineRepository) GetLotsByVariantID(ctx context.Context, variantID uuid.UUID) ([]domain.MedicineLot, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var lots []domain.MedicineLot
+	for _, l := range r.store.lots {
+		if l.VariantID == variantID {
+			lots = append(lots, l)
+		}
+	}
+	sortLots(lots)
+	return lots, nil
+}
+
+// GetLowStock retrieves a pharmacy's variants whose stock is below their
+// reorder point
+func (r *medicineRepository) GetLowStock(ctx context.Context, pharmacyID uuid.UUID) ([]domain.LowStockItem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var items []domain.LowStockItem
+	for id := range r.store.variants {
+		v, _ := r.store.variant(id)
+		m, ok := r.store.medicines[v.MedicineID]
+		if !ok || m.PharmacyID != pharmacyID || v.ReorderPoint == 0 || v.Stock >= v.ReorderPoint {
+			continue
+		}
+		items = append(items, domain.LowStockItem{MedicineVariant: v, MedicineName: m.Name})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].MedicineName != items[j].MedicineName {
+			return items[i].MedicineName < items[j].MedicineName
+		}
+		return items[i].Brand < items[j].Brand
+	})
+	return items, nil
+}
+
+// ClaimLowStockAlert marks a variant as alerted and reports whether this call
+// did so, so that only one caller notifies until the variant is restocked
+func (r *medicineRepository) ClaimLowStockAlert(ctx context.Context, variantID uuid.UUID) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.variants[variantID]; !ok || r.store.lowStockAlerts[variantID] {
+		return false, nil
+	}
+	r.store.lowStockAlerts[variantID] = true
+	return true, nil
+}
+
+// ReleaseLowStockAlert gives up a claimed alert whose notification could not
+// be sent, so that the next check claims it again
+func (r *medicineRepository) ReleaseLowStockAlert(ctx context.Context, variantID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.lowStockAlerts, variantID)
+	return nil
+}
+
+// AdjustStock applies movement.Quantity to a lot of movement.VariantID and
+// records the movement with the variant's on-hand quantity before and after
+func (r *medicineRepository) AdjustStock(ctx context.Context, movement domain.StockMovement) (*domain.StockMovement, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	lot, ok := r.store.lots[movement.LotID]
+	if !ok || lot.VariantID != movement.VariantID {
+		return nil, domain.ErrLotNotFound
+	}
+	if lot.Quantity+movement.Quantity < 0 {
+		return nil, domain.ErrInsufficientStock
+	}
+
+	movement.LotNumber = lot.LotNumber
+	movement.QuantityBefore = r.store.onHand(movement.VariantID)
+	movement.QuantityAfter = movement.QuantityBefore + movement.Quantity
+
+	lot.Quantity += movement.Quantity
+	lot.UpdatedAt = movement.CreatedAt
+	r.store.lots[lot.ID] = lot
+	v := r.store.variants[lot.VariantID]
+	v.UpdatedAt = movement.CreatedAt
+	r.store.variants[lot.VariantID] = v
+	r.store.movements = append(r.store.movements, movement)
+	if movement.Quantity > 0 {
+		delete(r.store.lowStockAlerts, movement.VariantID)
+	}
+	return &movement, nil
+}
+
+// GetStockMovements retrieves a variant's stock movements, newest first
+func (r *medicineRepository) GetStockMovements(ctx context.Context, variantID uuid.UUID, limit, offset int) ([]domain.StockMovement, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var movements []domain.StockMovement
+	for i := len(r.store.movements) - 1; i >= 0; i-- {
+		m := r.store.movements[i]
+		if m.VariantID != variantID {
+			continue
+		}
+		m.LotNumber = r.store.lots[m.LotID].LotNumber
+		movements = append(movements, m)
+	}
+	sort.SliceStable(movements, func(i, j int) bool {
+		return movements[i].CreatedAt.After(movements[j].CreatedAt)
+	})
+	return paginate(movements, limit, offset), nil
+}
+
+// variantsOf returns a medicine's variants ordered by creation time. The
+// caller must hold the lock.
+func (s *Store) variantsOf(medicineID uuid.UUID) []domain.MedicineVariant {
+	var variants []domain.MedicineVariant
+	for id, v := range s.variants {
+		if v.MedicineID == medicineID {
+			v, _ = s.variant(id)
+			variants = append(variants, v)
+		}
+	}
+	sort.SliceStable(variants, func(i, j int) bool {
+		return variants[i].CreatedAt.Before(variants[j].CreatedAt)
+	})
+	return variants
+}
+
+// barcodeTaken reports whether a variant other than except uses barcode. The
+// caller must hold the lock.
+func (s *Store) barcodeTaken(barcode string, except uuid.UUID) bool {
+	for _, v := range s.variants {
+		if v.Barcode == barcode && v.ID != except {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package memory_test
+
+import (
+	"testing"
+
+	"pharmacy-management-backend/repository/memory"
+	"pharmacy-management-backend/repository/repositorytest"
+)
+
+func TestMemoryContract(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Harness {
+		store := memory.NewStore()
+		return repositorytest.Harness{
+			Auth:          memory.NewAuthRepository(store),
+			Pharmacy:      memory.NewPharmacyRepository(store),
+			Medicine:      memory.NewMedicineRepository(store),
+			Sale:          memory.NewSaleRepository(store),
+			Order:         memory.NewOrderRepository(store),
+			Inventory:     memory.NewInventoryRepository(store),
+			Supplier:      memory.NewSupplierRepository(store),
+			PurchaseOrder: memory.NewPurchaseOrderRepository(store),
+			Promotion:     memory.NewPromotionRepository(store),
+			Shift:         memory.NewShiftRepository(store),
+			Customer:      memory.NewCustomerRepository(store),
+			SeedOrder:     store.SeedOrder,
+		}
+	})
+}
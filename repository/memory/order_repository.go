@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// orderRepository implements repository.OrderRepository
+type orderRepository struct {
+	store *Store
+}
+
+// NewOrderRepository creates a new in-memory OrderRepository
+func NewOrderRepository(store *Store) repository.OrderRepository {
+	return &orderRepository{store}
+}
+
+// ListOrders retrieves orders for a pharmacy with hospital and patient names
+func (r *orderRepository) ListOrders(ctx context.Context, pharmacyID uuid.UUID, limit, offset int) ([]domain.OrderResponse, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var orders []domain.OrderResponse
+	for _, o := range r.store.orders {
+		if o.PharmacyID != pharmacyID {
+			continue
+		}
+		h, ok := r.store.hospitals[o.HospitalID]
+		if !ok {
+			continue
+		}
+		p, ok := r.store.patients[o.PatientID]
+		if !ok {
+			continue
+		}
+		orders = append(orders, domain.OrderResponse{
+			ID:           o.ID,
+			HospitalName: h.Name,
+			PatientName:  p.FullName,
+			OrderDate:    o.OrderDate,
+		})
+	}
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].OrderDate.After(orders[j].OrderDate)
+	})
+	return paginate(orders, limit, offset), nil
+}
+
+// GetOrderDetails retrieves order details including patient and items
+func (r *orderRepository) GetOrderDetails(ctx context.Context, orderID uuid.UUID) (*domain.Order, []domain.OrderItem, *domain.Patient, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	order, ok := r.store.orders[orderID]
+	if !ok {
+		return nil, nil, nil, domain.ErrOrderNotFound
+	}
+	patient, ok := r.store.patients[order.PatientID]
+	if !ok {
+		return nil, nil, nil, domain.ErrNotFound
+	}
+
+	var items []domain.OrderItem
+	for _, item := range r.store.orderItems {
+		if item.OrderID != orderID {
+			continue
+		}
+		v, ok := r.store.variants[item.MedicineVariantID]
+		if !ok {
+			continue
+		}
+		m, ok := r.store.medicines[v.MedicineID]
+		if !ok {
+			continue
+		}
+		item.MedicineName = m.Name
+		item.Unit = v.Unit
+		items = append(items, item)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+	return &order, items, &patient, nil
+}
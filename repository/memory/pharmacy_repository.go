@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// pharmacyRepository implements repository.PharmacyRepository
+type pharmacyRepository struct {
+	store *Store
+}
+
+// NewPharmacyRepository creates a new in-memory PharmacyRepository
+func NewPharmacyRepository(store *Store) repository.PharmacyRepository {
+	return &pharmacyRepository{store}
+}
+
+// Create stores a new pharmacy
+func (r *pharmacyRepository) Create(ctx context.Context, pharmacy domain.Pharmacy) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.pharmacies[pharmacy.ID]; ok {
+		return errUniqueViolation
+	}
+	r.store.pharmacies[pharmacy.ID] = pharmacy
+	return nil
+}
+
+// GetAll retrieves all pharmacies
+func (r *pharmacyRepository) GetAll(ctx context.Context) ([]domain.Pharmacy, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var pharmacies []domain.Pharmacy
+	for _, p := range r.store.pharmacies {
+		pharmacies = append(pharmacies, p)
+	}
+	sort.SliceStable(pharmacies, func(i, j int) bool {
+		return pharmacies[i].CreatedAt.Before(pharmacies[j].CreatedAt)
+	})
+	return pharmacies, nil
+}
+
+// GetByID retrieves a pharmacy by ID
+func (r *pharmacyRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Pharmacy, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.store.pharmacies[id]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &p, nil
+}
+
+// Update updates a pharmacy's name and address
+func (r *pharmacyRepository) Update(ctx context.Context, pharmacy domain.Pharmacy) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.pharmacies[pharmacy.ID]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	existing.Name = pharmacy.Name
+	existing.Address = pharmacy.Address
+	existing.UpdatedAt = pharmacy.UpdatedAt
+	r.store.pharmacies[pharmacy.ID] = existing
+	return nil
+}
+
+// UpdateTaxSettings replaces a pharmacy's VAT configuration
+func (r *pharmacyRepository) UpdateTaxSettings(ctx context.Context, id uuid.UUID, settings domain.TaxSettings, updatedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.pharmacies[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	existing.TaxSettings = settings
+	existing.UpdatedAt = updatedAt
+	r.store.pharmacies[id] = existing
+	return nil
+}
+
+// UpdateReceiptSettings replaces how a pharmacy's receipts are printed and
+// numbered
+func (r *pharmacyRepository) UpdateReceiptSettings(ctx context.Context, id uuid.UUID, settings domain.ReceiptSettings, updatedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.pharmacies[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	existing.ReceiptSettings = settings
+	existing.UpdatedAt = updatedAt
+	r.store.pharmacies[id] = existing
+	return nil
+}
+
+// Delete deletes a pharmacy that nothing references
+func (r *pharmacyRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.pharmacies[id]; !ok {
+		return domain.ErrNotFound
+	}
+	for _, m := range r.store.medicines {
+		if m.PharmacyID == id {
+			return errForeignKeyViolation
+		}
+	}
+	for _, s := range r.store.sales {
+		if s.PharmacyID == id {
+			return errForeignKeyViolation
+		}
+	}
+	for _, c := range r.store.carts {
+		if c.PharmacyID == id {
+			return errForeignKeyViolation
+		}
+	}
+	for _, p := range r.store.parkedCarts {
+		if p.PharmacyID == id {
+			return errForeignKeyViolation
+		}
+	}
+	for _, o := range r.store.orders {
+		if o.PharmacyID == id {
+			return errForeignKeyViolation
+		}
+	}
+	for _, sup := range r.store.suppliers {
+		if sup.PharmacyID == id {
+			return errForeignKeyViolation
+		}
+	}
+	for _, c := range r.store.customers {
+		if c.PharmacyID == id {
+			return errForeignKeyViolation
+		}
+	}
+	deleted := r.store.deletedVariants[:0]
+	for _, d := range r.store.deletedVariants {
+		if d.pharmacyID != id {
+			deleted = append(deleted, d)
+		}
+	}
+	r.store.deletedVariants = deleted
+	delete(r.store.pharmacies, id)
+	return nil
+}
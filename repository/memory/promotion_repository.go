@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// promotionRepository implements repository.PromotionRepository
+type promotionRepository struct {
+	store *Store
+}
+
+// NewPromotionRepository creates a new in-memory PromotionRepository
+func NewPromotionRepository(store *Store) repository.PromotionRepository {
+	return &promotionRepository{store}
+}
+
+// Create stores a new promotion
+func (r *promotionRepository) Create(ctx context.Context, promotion domain.Promotion) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.pharmacies[promotion.PharmacyID]; !ok {
+		return errForeignKeyViolation
+	}
+	if promotion.MedicineID != nil {
+		if _, ok := r.store.medicines[*promotion.MedicineID]; !ok {
+			return errForeignKeyViolation
+		}
+	}
+	if _, ok := r.store.promotions[promotion.ID]; ok {
+		return errUniqueViolation
+	}
+	r.store.promotions[promotion.ID] = promotion
+	return nil
+}
+
+// GetByID retrieves a promotion by ID
+func (r *promotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Promotion, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	p, ok := r.store.promotions[id]
+	if !ok {
+		return nil, domain.ErrPromotionNotFound
+	}
+	return &p, nil
+}
+
+// GetAll retrieves a pharmacy's promotions, latest starting first
+func (r *promotionRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Promotion, error) {
+	return r.list(pharmacyID, func(p domain.Promotion) bool { return true }), nil
+}
+
+// GetRunning retrieves a pharmacy's active promotions whose date range
+// includes at
+func (r *promotionRepository) GetRunning(ctx context.Context, pharmacyID uuid.UUID, at time.Time) ([]domain.Promotion, error) {
+	return r.list(pharmacyID, func(p domain.Promotion) bool { return p.RunsAt(at) }), nil
+}
+
+func (r *promotionRepository) list(pharmacyID uuid.UUID, keep func(domain.Promotion) bool) []domain.Promotion {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var promotions []domain.Promotion
+	for _, p := range r.store.promotions {
+		if p.PharmacyID == pharmacyID && keep(p) {
+			promotions = append(promotions, p)
+		}
+	}
+	sort.SliceStable(promotions, func(i, j int) bool {
+		if !promotions[i].StartsAt.Equal(promotions[j].StartsAt) {
+			return promotions[i].StartsAt.After(promotions[j].StartsAt)
+		}
+		return promotions[i].ID.String() < promotions[j].ID.String()
+	})
+	return promotions
+}
+
+// Update updates a promotion
+func (r *promotionRepository) Update(ctx context.Context, promotion domain.Promotion) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.promotions[promotion.ID]
+	if !ok {
+		return domain.ErrPromotionNotFound
+	}
+	if promotion.MedicineID != nil {
+		if _, ok := r.store.medicines[*promotion.MedicineID]; !ok {
+			return errForeignKeyViolation
+		}
+	}
+	promotion.PharmacyID = existing.PharmacyID
+	promotion.CreatedAt = existing.CreatedAt
+	r.store.promotions[promotion.ID] = promotion
+	return nil
+}
+
+// Delete deletes a promotion. Sales it was applied to keep its name.
+func (r *promotionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.promotions[id]; !ok {
+		return domain.ErrPromotionNotFound
+	}
+	delete(r.store.promotions, id)
+	for saleID, sale := range r.store.sales {
+		discounts := append([]domain.AppliedDiscount(nil), sale.Discounts...)
+		for i, d := range discounts {
+			if d.PromotionID != nil && *d.PromotionID == id {
+				discounts[i].PromotionID = nil
+			}
+		}
+		sale.Discounts = discounts
+		r.store.sales[saleID] = sale
+	}
+	return nil
+}
+
+// CreateManualDiscount stores a manual discount
+func (r *promotionRepository) CreateManualDiscount(ctx context.Context, discount domain.ManualDiscount) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.pharmacies[discount.PharmacyID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.users[discount.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.manualDiscounts[discount.ID]; ok {
+		return errUniqueViolation
+	}
+	discount.SaleID = nil
+	r.store.manualDiscounts[discount.ID] = discount
+	return nil
+}
+
+// GetManualDiscountByID retrieves a manual discount by ID
+func (r *promotionRepository) GetManualDiscountByID(ctx context.Context, id uuid.UUID) (*domain.ManualDiscount, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	d, ok := r.store.manualDiscounts[id]
+	if !ok {
+		return nil, domain.ErrManualDiscountNotFound
+	}
+	return &d, nil
+}
+
+// GetManualDiscounts retrieves a pharmacy's manual discounts in a status, or
+// in any status when status is empty, oldest first
+func (r *promotionRepository) GetManualDiscounts(ctx context.Context, pharmacyID uuid.UUID, status domain.ManualDiscountStatus) ([]domain.ManualDiscount, error) {
+	return r.listManualDiscounts(func(d domain.ManualDiscount) bool {
+		return d.PharmacyID == pharmacyID && (status == "" || d.Status == status)
+	}), nil
+}
+
+// GetApprovedManualDiscounts retrieves a user's approved manual discounts not
+// yet used on a sale, oldest first
+func (r *promotionRepository) GetApprovedManualDiscounts(ctx context.Context, userID uuid.UUID) ([]domain.ManualDiscount, error) {
+	return r.listManualDiscounts(func(d domain.ManualDiscount) bool {
+		return d.UserID == userID && d.Status == domain.ManualDiscountApproved && d.SaleID == nil
+	}), nil
+}
+
+func (r *promotionRepository) listManualDiscounts(keep func(domain.ManualDiscount) bool) []domain.ManualDiscount {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var discounts []domain.ManualDiscount
+	for _, d := range r.store.manualDiscounts {
+		if keep(d) {
+			discounts = append(discounts, d)
+		}
+	}
+	sort.SliceStable(discounts, func(i, j int) bool {
+		if !discounts[i].CreatedAt.Equal(discounts[j].CreatedAt) {
+			return discounts[i].CreatedAt.Before(discounts[j].CreatedAt)
+		}
+		return discounts[i].ID.String() < discounts[j].ID.String()
+	})
+	return discounts
+}
+
+// ReviewManualDiscount approves or rejects a pending manual discount
+func (r *promotionRepository) ReviewManualDiscount(ctx context.Context, id uuid.UUID, status domain.ManualDiscountStatus, reviewerID uuid.UUID, reviewedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	d, ok := r.store.manualDiscounts[id]
+	if !ok {
+		return domain.ErrManualDiscountNotFound
+	}
+	if d.Status != domain.ManualDiscountPending {
+		return domain.ErrManualDiscountReviewed
+	}
+	d.Status = status
+	d.ReviewedBy = &reviewerID
+	d.ReviewedAt = &reviewedAt
+	r.store.manualDiscounts[id] = d
+	return nil
+}
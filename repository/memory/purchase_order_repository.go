@@ -0,0 +1,284 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// purchaseOrderRepository implements repository.PurchaseOrderRepository
+type purchaseOrderRepository struct {
+	store *Store
+}
+
+// NewPurchaseOrderRepository creates a new in-memory PurchaseOrderRepository
+func NewPurchaseOrderRepository(store *Store) repository.PurchaseOrderRepository {
+	return &purchaseOrderRepository{store}
+}
+
+// Create stores a purchase order with its items
+func (r *purchaseOrderRepository) Create(ctx context.Context, order domain.PurchaseOrder) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if err := r.checkReferences(order); err != nil {
+		return err
+	}
+	if _, ok := r.store.purchaseOrders[order.ID]; ok {
+		return errUniqueViolation
+	}
+	order.SupplierName = ""
+	order.TotalCost = 0
+	order.Items = storedItems(order.Items)
+	r.store.purchaseOrders[order.ID] = order
+	return nil
+}
+
+// GetByID retrieves a purchase order with its items
+func (r *purchaseOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PurchaseOrder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	po, ok := r.store.purchaseOrders[id]
+	if !ok {
+		return nil, domain.ErrPurchaseOrderNotFound
+	}
+	po = r.store.purchaseOrder(po)
+	items := make([]domain.PurchaseOrderItem, len(po.Items))
+	for i, item := range po.Items {
+		v := r.store.variants[item.VariantID]
+		item.MedicineName = r.store.medicines[v.MedicineID].Name
+		item.Brand = v.Brand
+		item.Unit = v.Unit
+		items[i] = item
+	}
+	po.Items = items
+	return &po, nil
+}
+
+// GetAll retrieves a pharmacy's purchase order headers, newest first,
+// optionally filtered by status
+func (r *purchaseOrderRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID, status domain.PurchaseOrderStatus, limit, offset int) ([]domain.PurchaseOrder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var orders []domain.PurchaseOrder
+	for _, po := range r.store.purchaseOrders {
+		if po.PharmacyID != pharmacyID || (status != "" && po.Status != status) {
+			continue
+		}
+		po = r.store.purchaseOrder(po)
+		po.Items = nil
+		orders = append(orders, po)
+	}
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].CreatedAt.After(orders[j].CreatedAt)
+	})
+	return paginate(orders, limit, offset), nil
+}
+
+// Update replaces the supplier, notes and items of a draft purchase order
+func (r *purchaseOrderRepository) Update(ctx context.Context, order domain.PurchaseOrder) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.purchaseOrders[order.ID]
+	if !ok {
+		return domain.ErrPurchaseOrderNotFound
+	}
+	if existing.Status != domain.PurchaseOrderDraft {
+		return domain.ErrInvalidPurchaseOrderStatus
+	}
+	if err := r.checkReferences(order); err != nil {
+		return err
+	}
+	existing.SupplierID = order.SupplierID
+	existing.Notes = order.Notes
+	existing.Items = storedItems(order.Items)
+	existing.UpdatedAt = order.UpdatedAt
+	r.store.purchaseOrders[order.ID] = existing
+	return nil
+}
+
+// UpdateStatus moves a purchase order from one status to another, failing if
+// it is no longer in the expected status
+func (r *purchaseOrderRepository) UpdateStatus(ctx context.Context, id uuid.UUID, from, to domain.PurchaseOrderStatus, updatedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	po, ok := r.store.purchaseOrders[id]
+	if !ok {
+		return domain.ErrPurchaseOrderNotFound
+	}
+	if po.Status != from {
+		return domain.ErrInvalidPurchaseOrderStatus
+	}
+	po.Status = to
+	po.UpdatedAt = updatedAt
+	r.store.purchaseOrders[id] = po
+	return nil
+}
+
+// Receive records a delivery against a sent or partially received purchase
+// order: each line is added to its lot (created if the lot number is new for
+// the variant), recorded as a receipt movement, averaged into the variant's
+// cost price and counted against its order line, and the order's status is
+// advanced
+func (r *purchaseOrderRepository) Receive(ctx context.Context, receipt domain.GoodsReceipt) (*domain.GoodsReceipt, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	po, ok := r.store.purchaseOrders[receipt.PurchaseOrderID]
+	if !ok {
+		return nil, domain.ErrPurchaseOrderNotFound
+	}
+	if po.Status != domain.PurchaseOrderSent && po.Status != domain.PurchaseOrderPartiallyReceived {
+		return nil, domain.ErrInvalidPurchaseOrderStatus
+	}
+
+	items := append([]domain.PurchaseOrderItem(nil), po.Items...)
+	lines := append([]domain.GoodsReceiptLine(nil), receipt.Lines...)
+	for i, l := range lines {
+		idx := -1
+		for j, item := range items {
+			if item.ID == l.PurchaseOrderItemID {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, domain.ErrPurchaseOrderItemNotFound
+		}
+		items[idx].QuantityReceived += l.Quantity
+		if items[idx].QuantityReceived > items[idx].QuantityOrdered {
+			return nil, domain.ErrOverReceipt
+		}
+		lines[i].VariantID = items[idx].VariantID
+	}
+
+	// Resolve every lot before changing anything so a clash leaves no trace
+	lotIDs := make([]uuid.UUID, len(lines))
+	newLots := make(map[string]uuid.UUID)
+	for i, l := range lines {
+		if id, ok := newLots[l.VariantID.String()+"/"+l.LotNumber]; ok {
+			lotIDs[i] = id
+			continue
+		}
+		for _, lot := range r.store.lots {
+			if lot.VariantID == l.VariantID && lot.LotNumber == l.LotNumber {
+				if !lot.ExpiryDate.Equal(l.ExpiryDate) {
+					return nil, domain.ErrLotNumberTaken
+				}
+				lotIDs[i] = lot.ID
+			}
+		}
+		if lotIDs[i] == uuid.Nil {
+			lotIDs[i] = uuid.New()
+			newLots[l.VariantID.String()+"/"+l.LotNumber] = lotIDs[i]
+		}
+	}
+
+	for i, l := range lines {
+		before := r.store.onHand(l.VariantID)
+		lot, ok := r.store.lots[lotIDs[i]]
+		if !ok {
+			lot = domain.MedicineLot{
+				ID:         lotIDs[i],
+				VariantID:  l.VariantID,
+				LotNumber:  l.LotNumber,
+				ExpiryDate: l.ExpiryDate,
+				ReceivedAt: receipt.ReceivedAt,
+				CreatedAt:  receipt.ReceivedAt,
+			}
+		}
+		lot.Quantity += l.Quantity
+		lot.UpdatedAt = receipt.ReceivedAt
+		r.store.lots[lot.ID] = lot
+		lines[i].LotID = lot.ID
+
+		r.store.movements = append(r.store.movements, domain.StockMovement{
+			ID:             uuid.New(),
+			VariantID:      l.VariantID,
+			LotID:          lot.ID,
+			Type:           domain.StockMovementReceipt,
+			Quantity:       l.Quantity,
+			QuantityBefore: before,
+			QuantityAfter:  before + l.Quantity,
+			Reason:         "Purchase order " + po.ID.String(),
+			UserID:         receipt.ReceivedBy,
+			CreatedAt:      receipt.ReceivedAt,
+		})
+		v := r.store.variants[l.VariantID]
+		if before <= 0 {
+			v.CostPrice = l.UnitCost
+		} else {
+			v.CostPrice = (v.CostPrice.Mul(before) + l.UnitCost.Mul(l.Quantity)).Div(before + l.Quantity)
+		}
+		v.UpdatedAt = receipt.ReceivedAt
+		r.store.variants[l.VariantID] = v
+		delete(r.store.lowStockAlerts, l.VariantID)
+	}
+
+	po.Status = domain.PurchaseOrderReceived
+	for _, item := range items {
+		if item.QuantityReceived < item.QuantityOrdered {
+			po.Status = domain.PurchaseOrderPartiallyReceived
+			break
+		}
+	}
+	po.Items = items
+	po.UpdatedAt = receipt.ReceivedAt
+	r.store.purchaseOrders[po.ID] = po
+
+	receipt.Lines = lines
+	r.store.goodsReceipts = append(r.store.goodsReceipts, receipt)
+	return &receipt, nil
+}
+
+// checkReferences mirrors the purchase order foreign keys. The caller must
+// hold the lock.
+func (r *purchaseOrderRepository) checkReferences(order domain.PurchaseOrder) error {
+	if _, ok := r.store.pharmacies[order.PharmacyID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.suppliers[order.SupplierID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.users[order.CreatedBy]; !ok {
+		return errForeignKeyViolation
+	}
+	for _, item := range order.Items {
+		if _, ok := r.store.variants[item.VariantID]; !ok {
+			return errForeignKeyViolation
+		}
+	}
+	return nil
+}
+
+// purchaseOrder fills in a stored purchase order's supplier name and total
+// cost. The caller must hold the lock.
+func (s *Store) purchaseOrder(po domain.PurchaseOrder) domain.PurchaseOrder {
+	po.SupplierName = s.suppliers[po.SupplierID].Name
+	po.TotalCost = 0
+	for _, item := range po.Items {
+		po.TotalCost += item.UnitCost.Mul(item.QuantityOrdered)
+	}
+	return po
+}
+
+// storedItems copies items without their joined variant details
+func storedItems(items []domain.PurchaseOrderItem) []domain.PurchaseOrderItem {
+	stored := make([]domain.PurchaseOrderItem, len(items))
+	for i, item := range items {
+		item.MedicineName = ""
+		item.Brand = ""
+		item.Unit = ""
+		stored[i] = item
+	}
+	return stored
+}
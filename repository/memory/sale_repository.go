@@ -0,0 +1,937 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// saleRepository implements repository.SaleRepository
+type saleRepository struct {
+	store *Store
+}
+
+// NewSaleRepository creates a new in-memory SaleRepository
+func NewSaleRepository(store *Store) repository.SaleRepository {
+	return &saleRepository{store}
+}
+
+// SearchMedicines searches for variants with unexpired stock by name, brand or barcode
+func (r *saleRepository) SearchMedicines(ctx context.Context, pharmacyID uuid.UUID, query string) ([]domain.MedicineVariant, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	needle := strings.ToLower(query)
+	var variants []domain.MedicineVariant
+	for id := range r.store.variants {
+		v, _ := r.store.variant(id)
+		m, ok := r.store.medicines[v.MedicineID]
+		if !ok || m.PharmacyID != pharmacyID {
+			continue
+		}
+		matches := strings.Contains(strings.ToLower(m.Name), needle) ||
+			strings.Contains(strings.ToLower(v.Brand), needle) ||
+			v.Barcode == query
+		if !matches || v.Stock <= 0 {
+			continue
+		}
+		variants = append(variants, v)
+	}
+	sort.SliceStable(variants, func(i, j int) bool {
+		return variants[i].CreatedAt.Before(variants[j].CreatedAt)
+	})
+	return variants, nil
+}
+
+// AddToCart adds an item to the active cart, adding to the quantity if the
+// variant is already in it. The line's whole quantity is reserved until the
+// item's ReservedUntil, and must be available to it, as Store.available
+// counts.
+func (r *saleRepository) AddToCart(ctx context.Context, cart domain.Cart) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[cart.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	v, ok := r.store.variant(cart.MedicineVariantID)
+	if !ok {
+		return errForeignKeyViolation
+	}
+
+	for id, existing := range r.store.carts {
+		if existing.UserID == cart.UserID && existing.ParkedCartID == nil && existing.MedicineVariantID == cart.MedicineVariantID {
+			if existing.Quantity+cart.Quantity > r.store.available(v.ID, id) {
+				return domain.ErrInsufficientStock
+			}
+			existing.Quantity += cart.Quantity
+			existing.ReservedUntil = stripCart(cart).ReservedUntil
+			existing.CreatedAt = cart.CreatedAt
+			r.store.carts[id] = existing
+			return nil
+		}
+	}
+	if cart.Quantity > r.store.available(v.ID, uuid.Nil) {
+		return domain.ErrInsufficientStock
+	}
+	if _, ok := r.store.carts[cart.ID]; ok {
+		return errUniqueViolation
+	}
+	r.store.carts[cart.ID] = stripCart(cart)
+	return nil
+}
+
+// GetCart retrieves the lines of a user's active cart with medicine details
+func (r *saleRepository) GetCart(ctx context.Context, userID uuid.UUID) ([]domain.Cart, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var carts []domain.Cart
+	for _, c := range r.store.carts {
+		if c.UserID != userID || c.ParkedCartID != nil {
+			continue
+		}
+		v, ok := r.store.variants[c.MedicineVariantID]
+		if !ok {
+			continue
+		}
+		m, ok := r.store.medicines[v.MedicineID]
+		if !ok {
+			continue
+		}
+		c.MedicineName = m.Name
+		c.PricePerUnit = v.PricePerUnit
+		c.Unit = v.Unit
+		c.ImageURL = m.Picture
+		carts = append(carts, c)
+	}
+	sort.SliceStable(carts, func(i, j int) bool {
+		return carts[i].CreatedAt.Before(carts[j].CreatedAt)
+	})
+	return carts, nil
+}
+
+// RemoveFromCart removes an item from the cart
+func (r *saleRepository) RemoveFromCart(ctx context.Context, cartID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.carts[cartID]; !ok {
+		return domain.ErrCartItemNotFound
+	}
+	delete(r.store.carts, cartID)
+	return nil
+}
+
+// UpdateCartItem sets the quantity of a line in the user's active cart,
+// reserving it until the item's ReservedUntil. Like AddToCart, the whole
+// quantity must be available to the line.
+func (r *saleRepository) UpdateCartItem(ctx context.Context, cart domain.Cart) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	line, ok := r.store.carts[cart.ID]
+	if !ok || line.UserID != cart.UserID || line.ParkedCartID != nil {
+		return domain.ErrCartItemNotFound
+	}
+	if cart.Quantity > r.store.available(line.MedicineVariantID, line.ID) {
+		return domain.ErrInsufficientStock
+	}
+	line.Quantity = cart.Quantity
+	line.ReservedUntil = stripCart(cart).ReservedUntil
+	r.store.carts[line.ID] = line
+	return nil
+}
+
+// ClearCart clears the user's active cart, leaving parked carts alone
+func (r *saleRepository) ClearCart(ctx context.Context, userID uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, c := range r.store.carts {
+		if c.UserID == userID && c.ParkedCartID == nil {
+			delete(r.store.carts, id)
+		}
+	}
+	return nil
+}
+
+// ParkCart moves the lines of the user's active cart into a new parked cart,
+// keeping their reservations, and returns it with its lines counted. An
+// empty cart cannot be parked.
+func (r *saleRepository) ParkCart(ctx context.Context, parked domain.ParkedCart) (*domain.ParkedCart, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.parkedCarts[parked.ID]; ok {
+		return nil, errUniqueViolation
+	}
+	if _, ok := r.store.users[parked.UserID]; !ok {
+		return nil, errForeignKeyViolation
+	}
+	if _, ok := r.store.pharmacies[parked.PharmacyID]; !ok {
+		return nil, errForeignKeyViolation
+	}
+	parked.Lines, parked.Quantity = 0, 0
+	for id, c := range r.store.carts {
+		if c.UserID == parked.UserID && c.ParkedCartID == nil {
+			parkedCartID := parked.ID
+			c.ParkedCartID = &parkedCartID
+			r.store.carts[id] = c
+			parked.Lines++
+			parked.Quantity += c.Quantity
+		}
+	}
+	if parked.Lines == 0 {
+		return nil, domain.ErrCartEmpty
+	}
+	stored := parked
+	stored.Lines, stored.Quantity = 0, 0
+	r.store.parkedCarts[parked.ID] = stored
+	return &parked, nil
+}
+
+// GetParkedCarts retrieves a user's parked carts, oldest first
+func (r *saleRepository) GetParkedCarts(ctx context.Context, userID uuid.UUID) ([]domain.ParkedCart, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var parked []domain.ParkedCart
+	for _, p := range r.store.parkedCarts {
+		if p.UserID != userID {
+			continue
+		}
+		for _, c := range r.store.carts {
+			if c.ParkedCartID != nil && *c.ParkedCartID == p.ID {
+				p.Lines++
+				p.Quantity += c.Quantity
+			}
+		}
+		parked = append(parked, p)
+	}
+	sort.Slice(parked, func(i, j int) bool {
+		if !parked[i].ParkedAt.Equal(parked[j].ParkedAt) {
+			return parked[i].ParkedAt.Before(parked[j].ParkedAt)
+		}
+		return parked[i].ID.String() < parked[j].ID.String()
+	})
+	return parked, nil
+}
+
+// ResumeCart makes one of the user's parked carts their active cart again,
+// which must be empty. Each line is reserved afresh until reservedUntil if
+// its quantity is still available to it, and otherwise left unreserved for
+// checkout to settle.
+func (r *saleRepository) ResumeCart(ctx context.Context, userID, parkedCartID uuid.UUID, reservedUntil time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var lines []domain.Cart
+	for _, c := range r.store.carts {
+		if c.UserID == userID && c.ParkedCartID == nil {
+			return domain.ErrCartNotEmpty
+		}
+		if c.ParkedCartID != nil && *c.ParkedCartID == parkedCartID {
+			lines = append(lines, c)
+		}
+	}
+	if p, ok := r.store.parkedCarts[parkedCartID]; !ok || p.UserID != userID {
+		return domain.ErrParkedCartNotFound
+	}
+
+	// Decide every reservation before moving any line, as one statement would
+	reserved := make(map[uuid.UUID]bool, len(lines))
+	for _, c := range lines {
+		reserved[c.ID] = c.Quantity <= r.store.available(c.MedicineVariantID, c.ID)
+	}
+	for _, c := range lines {
+		c.ParkedCartID = nil
+		c.ReservedUntil = nil
+		if reserved[c.ID] {
+			until := reservedUntil
+			c.ReservedUntil = &until
+		}
+		r.store.carts[c.ID] = c
+	}
+	delete(r.store.parkedCarts, parkedCartID)
+	return nil
+}
+
+// ReleaseExpiredReservations releases the cart reservations that ran out by
+// now, leaving the lines in their carts
+func (r *saleRepository) ReleaseExpiredReservations(ctx context.Context, now time.Time) (int, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	released := 0
+	for id, c := range r.store.carts {
+		if c.ReservedUntil != nil && !c.ReservedUntil.After(now) {
+			c.ReservedUntil = nil
+			r.store.carts[id] = c
+			released++
+		}
+	}
+	return released, nil
+}
+
+// CreateSale records a sale, its items and receipt, deducting stock from the
+// earliest-expiring unexpired lots other carts do not hold. The sale's shift
+// must still be open, and a sale whose ID is taken is refused with
+// ErrSaleExists.
+func (r *saleRepository) CreateSale(ctx context.Context, sale domain.Sale, items []domain.SaleItem, receipt *domain.Receipt) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.createSale(sale, items, receipt)
+}
+
+// CheckoutCart sells the user's active cart as the sale checkout builds from
+// its lines and empties the cart with it, all under the store's lock. An
+// empty cart is refused with ErrCartEmpty.
+func (r *saleRepository) CheckoutCart(ctx context.Context, userID uuid.UUID, checkout repository.CheckoutFunc) (*domain.Sale, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var lines []domain.CheckoutLine
+	for _, c := range r.store.carts {
+		if c.UserID != userID || c.ParkedCartID != nil {
+			continue
+		}
+		v, ok := r.store.variant(c.MedicineVariantID)
+		if !ok {
+			return nil, domain.ErrVariantNotFound
+		}
+		lines = append(lines, domain.CheckoutLine{Cart: c, Variant: v, Medicine: r.store.medicines[v.MedicineID]})
+	}
+	if len(lines) == 0 {
+		return nil, domain.ErrCartEmpty
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		a, b := lines[i].Cart, lines[j].Cart
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+
+	sale, items, receipt, err := checkout(lines)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.store.createSale(sale, items, receipt); err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		delete(r.store.carts, line.Cart.ID)
+	}
+	sale.ReceiptNumber = receipt.Number
+	return &sale, nil
+}
+
+// createSale records a sale for CreateSale and CheckoutCart. The caller must
+// hold the lock.
+func (s *Store) createSale(sale domain.Sale, items []domain.SaleItem, receipt *domain.Receipt) error {
+	if _, ok := s.sales[sale.ID]; ok {
+		return domain.ErrSaleExists
+	}
+	if _, ok := s.users[sale.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	pharmacy, ok := s.pharmacies[sale.PharmacyID]
+	if !ok {
+		return errForeignKeyViolation
+	}
+	if sale.CustomerID != nil {
+		if _, ok := s.customers[*sale.CustomerID]; !ok {
+			return errForeignKeyViolation
+		}
+	}
+	if sale.ShiftID != nil {
+		shift, ok := s.shifts[*sale.ShiftID]
+		if !ok {
+			return domain.ErrShiftNotFound
+		}
+		if shift.Status != domain.ShiftOpen {
+			return domain.ErrShiftClosed
+		}
+	}
+	for _, d := range sale.Discounts {
+		if d.ManualDiscountID == nil {
+			continue
+		}
+		md, ok := s.manualDiscounts[*d.ManualDiscountID]
+		if !ok || md.Status != domain.ManualDiscountApproved || md.SaleID != nil {
+			return domain.ErrManualDiscountUsed
+		}
+	}
+	settings := pharmacy.ReceiptSettings
+	sequence := receiptSequence{sale.PharmacyID, settings.NumberSeries(sale.SaleDate)}
+	n := s.receiptSequences[sequence] + 1
+	sale.ReceiptNumber = settings.ReceiptNumber(sequence.series, n)
+	var response []byte
+	if sale.IdempotencyKey != "" {
+		var err error
+		if response, err = json.Marshal(sale); err != nil {
+			return err
+		}
+	}
+
+	// Allocate against a working copy of lot quantities before touching anything
+	remaining := make(map[uuid.UUID]int)
+	allocations := make([][]domain.LotAllocation, len(items))
+	for i, item := range items {
+		need, left := item.Quantity, 0
+		for _, lot := range s.sellableLots(item.MedicineVariantID) {
+			available, seen := remaining[lot.ID]
+			if !seen {
+				available = lot.Quantity
+			}
+			take := min(available, need)
+			left += available - take
+			if take == 0 {
+				continue
+			}
+			remaining[lot.ID] = available - take
+			need -= take
+			allocations[i] = append(allocations[i], domain.LotAllocation{
+				LotID:      lot.ID,
+				LotNumber:  lot.LotNumber,
+				ExpiryDate: lot.ExpiryDate,
+				Quantity:   take,
+			})
+		}
+		if need > 0 || left < s.reserved(item.MedicineVariantID, sale.UserID) {
+			return domain.ErrInsufficientStock
+		}
+	}
+
+	for i, item := range items {
+		onHand := s.onHand(item.MedicineVariantID)
+		for _, allocation := range allocations[i] {
+			s.movements = append(s.movements, domain.StockMovement{
+				ID:             uuid.New(),
+				VariantID:      item.MedicineVariantID,
+				LotID:          allocation.LotID,
+				Type:           domain.StockMovementSale,
+				Quantity:       -allocation.Quantity,
+				QuantityBefore: onHand,
+				QuantityAfter:  onHand - allocation.Quantity,
+				UserID:         sale.UserID,
+				SaleID:         &sale.ID,
+				CreatedAt:      sale.CreatedAt,
+			})
+			onHand -= allocation.Quantity
+		}
+	}
+
+	now := time.Now()
+	for lotID, quantity := range remaining {
+		lot := s.lots[lotID]
+		lot.Quantity = quantity
+		lot.UpdatedAt = now
+		s.lots[lotID] = lot
+		v := s.variants[lot.VariantID]
+		v.UpdatedAt = now
+		s.variants[lot.VariantID] = v
+	}
+	sale.Discounts = append([]domain.AppliedDiscount(nil), sale.Discounts...)
+	sale.Payments = append([]domain.Payment(nil), sale.Payments...)
+	sale.Change = domain.TotalChange(sale.Payments)
+	s.sales[sale.ID] = sale
+	for _, d := range sale.Discounts {
+		if d.ManualDiscountID != nil {
+			md := s.manualDiscounts[*d.ManualDiscountID]
+			md.SaleID = &sale.ID
+			s.manualDiscounts[md.ID] = md
+		}
+	}
+	for i, item := range items {
+		items[i].Lots = allocations[i]
+		if i < len(receipt.Content.Items) {
+			receipt.Content.Items[i].Lots = allocations[i]
+		}
+		item.Lots = allocations[i]
+		s.saleItems[item.ID] = stripSaleItem(item)
+	}
+	receipt.Number = sale.ReceiptNumber
+	head := s.receiptChains[sale.PharmacyID]
+	receipt.ChainPosition = head.Position + 1
+	receipt.PreviousHash = head.Hash
+	hash, err := receipt.ComputeHash()
+	if err != nil {
+		return err
+	}
+	receipt.Hash = hash
+	s.receiptSequences[sequence] = n
+	s.receiptChains[sale.PharmacyID] = domain.ReceiptChainHead{PharmacyID: sale.PharmacyID, Position: receipt.ChainPosition, Hash: hash}
+	stored := *receipt
+	stored.Content.Items = append([]domain.ReceiptItem(nil), receipt.Content.Items...)
+	s.receipts[receipt.SaleID] = stored
+
+	id := idempotencyKeyID(sale.UserID, sale.IdempotencyKey)
+	if key, ok := s.idempotencyKeys[id]; ok && sale.IdempotencyKey != "" && key.SaleID == nil {
+		key.SaleID = &sale.ID
+		key.Response = response
+		s.idempotencyKeys[id] = key
+	}
+	return nil
+}
+
+// GetSales retrieves a page of sale headers matching a filter, with the
+// number of sales matched
+func (r *saleRepository) GetSales(ctx context.Context, filter domain.SaleFilter) (*domain.SalePage, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	headers := make(map[uuid.UUID]*domain.SaleHeader)
+	soldMedicine := make(map[uuid.UUID]bool)
+	for _, si := range r.store.saleItems {
+		if h, ok := headers[si.SaleID]; ok {
+			h.ItemCount++
+			h.UnitCount += si.Quantity
+		} else {
+			headers[si.SaleID] = &domain.SaleHeader{ItemCount: 1, UnitCount: si.Quantity}
+		}
+		if v, ok := r.store.variants[si.MedicineVariantID]; ok && v.MedicineID == filter.MedicineID {
+			soldMedicine[si.SaleID] = true
+		}
+	}
+
+	var sales []domain.SaleHeader
+	for _, s := range r.store.sales {
+		switch {
+		case filter.PharmacyID != uuid.Nil && s.PharmacyID != filter.PharmacyID,
+			!filter.From.IsZero() && s.SaleDate.Before(filter.From),
+			!filter.To.IsZero() && !s.SaleDate.Before(filter.To),
+			filter.UserID != uuid.Nil && s.UserID != filter.UserID,
+			filter.CustomerID != uuid.Nil && (s.CustomerID == nil || *s.CustomerID != filter.CustomerID),
+			filter.MedicineID != uuid.Nil && !soldMedicine[s.ID],
+			filter.MinTotal != nil && s.TotalPrice < *filter.MinTotal,
+			filter.MaxTotal != nil && s.TotalPrice > *filter.MaxTotal:
+			continue
+		}
+		h := domain.SaleHeader{}
+		if counted, ok := headers[s.ID]; ok {
+			h = *counted
+		}
+		h.ID = s.ID
+		h.ReceiptNumber = r.store.receipts[s.ID].Number
+		h.PharmacyID = s.PharmacyID
+		h.UserID = s.UserID
+		h.Cashier = r.store.users[s.UserID].FullName
+		h.CustomerID = s.CustomerID
+		h.TotalPrice = s.TotalPrice
+		h.TotalRefunded = s.TotalRefunded
+		h.SaleDate = s.SaleDate
+		sales = append(sales, h)
+	}
+
+	sort.Slice(sales, func(i, j int) bool {
+		a, b := sales[i], sales[j]
+		switch filter.Sort {
+		case domain.SaleSortDate:
+			if !a.SaleDate.Equal(b.SaleDate) {
+				return a.SaleDate.Before(b.SaleDate)
+			}
+		case domain.SaleSortTotal:
+			if a.TotalPrice != b.TotalPrice {
+				return a.TotalPrice < b.TotalPrice
+			}
+		case domain.SaleSortTotalDesc:
+			if a.TotalPrice != b.TotalPrice {
+				return a.TotalPrice > b.TotalPrice
+			}
+		default:
+			if !a.SaleDate.Equal(b.SaleDate) {
+				return a.SaleDate.After(b.SaleDate)
+			}
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return &domain.SalePage{
+		Sales:  append([]domain.SaleHeader{}, paginate(sales, filter.Limit, filter.Offset)...),
+		Total:  len(sales),
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}, nil
+}
+
+// GetSaleByID retrieves a sale by ID
+func (r *saleRepository) GetSaleByID(ctx context.Context, saleID uuid.UUID) (*domain.Sale, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s, ok := r.store.sales[saleID]
+	if !ok {
+		return nil, domain.ErrSaleNotFound
+	}
+	s.Cashier = r.store.users[s.UserID].FullName
+	s.Discounts = append([]domain.AppliedDiscount(nil), s.Discounts...)
+	s.Payments = append([]domain.Payment(nil), s.Payments...)
+	return &s, nil
+}
+
+// GetReceiptBySaleID retrieves a receipt by sale ID
+func (r *saleRepository) GetReceiptBySaleID(ctx context.Context, saleID uuid.UUID) (*domain.Receipt, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	receipt, ok := r.store.receipts[saleID]
+	if !ok {
+		return nil, domain.ErrSaleNotFound
+	}
+	receipt.Content.Items = append([]domain.ReceiptItem(nil), receipt.Content.Items...)
+	return &receipt, nil
+}
+
+// GetReceiptByNumber retrieves one of a pharmacy's receipts by its number
+func (r *saleRepository) GetReceiptByNumber(ctx context.Context, pharmacyID uuid.UUID, number string) (*domain.Receipt, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, receipt := range r.store.receipts {
+		if receipt.Number != number || r.store.sales[receipt.SaleID].PharmacyID != pharmacyID {
+			continue
+		}
+		receipt.Content.Items = append([]domain.ReceiptItem(nil), receipt.Content.Items...)
+		return &receipt, nil
+	}
+	return nil, domain.ErrReceiptNotFound
+}
+
+// GetReceiptChain retrieves up to limit of a pharmacy's chained receipts
+// after the given position, in chain order
+func (r *saleRepository) GetReceiptChain(ctx context.Context, pharmacyID uuid.UUID, afterPosition int64, limit int) ([]domain.Receipt, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var receipts []domain.Receipt
+	for _, receipt := range r.store.receipts {
+		if receipt.ChainPosition <= afterPosition || r.store.sales[receipt.SaleID].PharmacyID != pharmacyID {
+			continue
+		}
+		receipt.Content.Items = append([]domain.ReceiptItem(nil), receipt.Content.Items...)
+		receipts = append(receipts, receipt)
+	}
+	sort.Slice(receipts, func(i, j int) bool {
+		return receipts[i].ChainPosition < receipts[j].ChainPosition
+	})
+	return paginate(receipts, limit, 0), nil
+}
+
+// GetReceiptChainHead retrieves the last receipt chained for a pharmacy,
+// position 0 if it has none
+func (r *saleRepository) GetReceiptChainHead(ctx context.Context, pharmacyID uuid.UUID) (*domain.ReceiptChainHead, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	head := r.store.receiptChains[pharmacyID]
+	head.PharmacyID = pharmacyID
+	return &head, nil
+}
+
+// GetSaleItems retrieves a sale's items in the order they were sold
+func (r *saleRepository) GetSaleItems(ctx context.Context, saleID uuid.UUID) ([]domain.SaleItem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.saleItemsOf(saleID), nil
+}
+
+// GetSaleItemsBySaleIDs retrieves the items of the given sales, keyed by sale
+// ID, each sale's in the order they were sold
+func (r *saleRepository) GetSaleItemsBySaleIDs(ctx context.Context, saleIDs []uuid.UUID) (map[uuid.UUID][]domain.SaleItem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	saleItems := make(map[uuid.UUID][]domain.SaleItem)
+	for _, id := range saleIDs {
+		if items := r.store.saleItemsOf(id); len(items) > 0 {
+			saleItems[id] = items
+		}
+	}
+	return saleItems, nil
+}
+
+// saleItemsOf returns a sale's items in the order they were sold, with their
+// medicine's details. The caller must hold the lock.
+func (s *Store) saleItemsOf(saleID uuid.UUID) []domain.SaleItem {
+	var saleItems []domain.SaleItem
+	for _, si := range s.saleItems {
+		if si.SaleID != saleID {
+			continue
+		}
+		v := s.variants[si.MedicineVariantID]
+		m := s.medicines[v.MedicineID]
+		si.MedicineName = m.Name
+		si.Unit = v.Unit
+		si.ImageURL = m.Picture
+		si.Lots = nil
+		saleItems = append(saleItems, si)
+	}
+	sort.Slice(saleItems, func(i, j int) bool {
+		if !saleItems[i].CreatedAt.Equal(saleItems[j].CreatedAt) {
+			return saleItems[i].CreatedAt.Before(saleItems[j].CreatedAt)
+		}
+		return saleItems[i].ID.String() < saleItems[j].ID.String()
+	})
+	return saleItems
+}
+
+// CreateReturn records the return build makes of a sale's items, under the
+// store's lock. Each item of the return is checked against the quantity sold
+// less earlier returns, then put back into the lots it was sold from, latest
+// expiry first, either as stock with a return movement or into the lot's
+// quarantined quantity. A refund paid in a shift needs the shift still open.
+// Nothing is written unless every item can be returned.
+func (r *saleRepository) CreateReturn(ctx context.Context, saleID uuid.UUID, build repository.ReturnFunc) (*domain.SaleReturn, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sale, ok := r.store.sales[saleID]
+	if !ok {
+		return nil, domain.ErrSaleNotFound
+	}
+	saleReturn, err := build(r.store.saleItemsOf(saleID))
+	if err != nil {
+		return nil, err
+	}
+	saleReturn.SaleID = saleID
+	if _, ok := r.store.users[saleReturn.UserID]; !ok {
+		return nil, errForeignKeyViolation
+	}
+	if saleReturn.ShiftID != nil {
+		shift, ok := r.store.shifts[*saleReturn.ShiftID]
+		if !ok {
+			return nil, domain.ErrShiftNotFound
+		}
+		if shift.Status != domain.ShiftOpen {
+			return nil, domain.ErrShiftClosed
+		}
+	}
+
+	// Lot quantities already returned per sale item, from earlier returns
+	returned := make(map[uuid.UUID]map[uuid.UUID]int)
+	for _, sr := range r.store.saleReturns {
+		for _, item := range sr.Items {
+			for _, a := range item.Lots {
+				if returned[item.SaleItemID] == nil {
+					returned[item.SaleItemID] = make(map[uuid.UUID]int)
+				}
+				returned[item.SaleItemID][a.LotID] += a.Quantity
+			}
+		}
+	}
+
+	items := append([]domain.SaleReturnItem(nil), saleReturn.Items...)
+	left := make(map[uuid.UUID]int)
+	for i, item := range items {
+		si, ok := r.store.saleItems[item.SaleItemID]
+		if !ok || si.SaleID != sale.ID {
+			return nil, domain.ErrSaleItemNotFound
+		}
+		if _, seen := left[si.ID]; !seen {
+			left[si.ID] = si.Quantity - si.ReturnedQuantity
+		}
+		if item.Quantity > left[si.ID] {
+			return nil, domain.ErrReturnExceedsSold
+		}
+		left[si.ID] -= item.Quantity
+
+		lots := make([]domain.LotAllocation, len(si.Lots))
+		copy(lots, si.Lots)
+		sort.SliceStable(lots, func(a, b int) bool {
+			return lots[a].ExpiryDate.After(lots[b].ExpiryDate)
+		})
+		need := item.Quantity
+		var allocations []domain.LotAllocation
+		for _, a := range lots {
+			if need == 0 {
+				break
+			}
+			available := a.Quantity - returned[si.ID][a.LotID]
+			if available <= 0 {
+				continue
+			}
+			a.Quantity = min(available, need)
+			need -= a.Quantity
+			if returned[si.ID] == nil {
+				returned[si.ID] = make(map[uuid.UUID]int)
+			}
+			returned[si.ID][a.LotID] += a.Quantity
+			allocations = append(allocations, a)
+		}
+		if need > 0 {
+			return nil, domain.ErrLotNotFound
+		}
+		items[i].ReturnID = saleReturn.ID
+		items[i].MedicineVariantID = si.MedicineVariantID
+		items[i].Lots = allocations
+	}
+
+	for _, item := range items {
+		onHand := r.store.onHand(item.MedicineVariantID)
+		for _, a := range item.Lots {
+			lot := r.store.lots[a.LotID]
+			lot.UpdatedAt = saleReturn.CreatedAt
+			if saleReturn.Quarantine {
+				lot.Quarantined += a.Quantity
+				r.store.lots[lot.ID] = lot
+				continue
+			}
+			lot.Quantity += a.Quantity
+			r.store.lots[lot.ID] = lot
+			r.store.movements = append(r.store.movements, domain.StockMovement{
+				ID:             uuid.New(),
+				VariantID:      item.MedicineVariantID,
+				LotID:          a.LotID,
+				Type:           domain.StockMovementReturn,
+				Quantity:       a.Quantity,
+				QuantityBefore: onHand,
+				QuantityAfter:  onHand + a.Quantity,
+				Reason:         saleReturn.Reason,
+				UserID:         saleReturn.UserID,
+				SaleID:         &sale.ID,
+				CreatedAt:      saleReturn.CreatedAt,
+			})
+			onHand += a.Quantity
+		}
+		if !saleReturn.Quarantine {
+			v := r.store.variants[item.MedicineVariantID]
+			v.UpdatedAt = saleReturn.CreatedAt
+			r.store.variants[v.ID] = v
+			delete(r.store.lowStockAlerts, v.ID)
+		}
+
+		si := r.store.saleItems[item.SaleItemID]
+		si.ReturnedQuantity += item.Quantity
+		r.store.saleItems[si.ID] = si
+	}
+
+	sale.TotalRefunded += saleReturn.TotalRefund
+	sale.UpdatedAt = saleReturn.CreatedAt
+	r.store.sales[sale.ID] = sale
+
+	saleReturn.Items = items
+	saleReturn.CreditNote.ReturnID = saleReturn.ID
+	noteItems := append([]domain.ReceiptItem(nil), saleReturn.CreditNote.Content.Items...)
+	for i := range noteItems {
+		if i < len(items) {
+			noteItems[i].Lots = items[i].Lots
+		}
+	}
+	saleReturn.CreditNote.Content.Items = noteItems
+	r.store.saleReturns = append(r.store.saleReturns, saleReturn)
+	return &saleReturn, nil
+}
+
+// GetReturns retrieves a sale's returns with their items and credit notes,
+// oldest first
+func (r *saleRepository) GetReturns(ctx context.Context, saleID uuid.UUID) ([]domain.SaleReturn, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var returns []domain.SaleReturn
+	for _, sr := range r.store.saleReturns {
+		if sr.SaleID != saleID {
+			continue
+		}
+		sr.Items = append([]domain.SaleReturnItem(nil), sr.Items...)
+		sr.CreditNote.Content.Items = append([]domain.ReceiptItem(nil), sr.CreditNote.Content.Items...)
+		returns = append(returns, sr)
+	}
+	return returns, nil
+}
+
+// ReserveIdempotencyKey claims a key for the user's request. It returns nil
+// once the key is reserved, or the unexpired key already held, which carries
+// the sale and response if that request completed. Expired keys are purged
+// first so they can be reused.
+func (r *saleRepository) ReserveIdempotencyKey(ctx context.Context, key domain.IdempotencyKey) (*domain.IdempotencyKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, k := range r.store.idempotencyKeys {
+		if !k.ExpiresAt.After(key.CreatedAt) {
+			delete(r.store.idempotencyKeys, id)
+		}
+	}
+	if _, ok := r.store.users[key.UserID]; !ok {
+		return nil, errForeignKeyViolation
+	}
+
+	id := idempotencyKeyID(key.UserID, key.Key)
+	if existing, ok := r.store.idempotencyKeys[id]; ok {
+		existing.Response = append([]byte(nil), existing.Response...)
+		return &existing, nil
+	}
+	key.SaleID = nil
+	key.Response = nil
+	r.store.idempotencyKeys[id] = key
+	return nil, nil
+}
+
+// ReleaseIdempotencyKey gives up a reserved key whose request failed, so the
+// client can retry with it. Completed keys are kept.
+func (r *saleRepository) ReleaseIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	id := idempotencyKeyID(userID, key)
+	if k, ok := r.store.idempotencyKeys[id]; ok && k.SaleID == nil {
+		delete(r.store.idempotencyKeys, id)
+	}
+	return nil
+}
+
+// idempotencyKeyID is the store key for a user's idempotency key
+func idempotencyKeyID(userID uuid.UUID, key string) string {
+	return userID.String() + "/" + key
+}
+
+// stripCart clears the joined response-only fields before storing a cart item
+func stripCart(c domain.Cart) domain.Cart {
+	if c.ReservedUntil != nil {
+		until := *c.ReservedUntil
+		c.ReservedUntil = &until
+	}
+	if c.ParkedCartID != nil {
+		parkedCartID := *c.ParkedCartID
+		c.ParkedCartID = &parkedCartID
+	}
+	c.MedicineName = ""
+	c.PricePerUnit = 0
+	c.Unit = ""
+	c.ImageURL = ""
+	return c
+}
+
+// stripSaleItem clears the joined response-only fields before storing a sale item
+func stripSaleItem(si domain.SaleItem) domain.SaleItem {
+	si.MedicineName = ""
+	si.Unit = ""
+	si.ImageURL = ""
+	return si
+}
+
+// paginate applies LIMIT/OFFSET semantics to a slice
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
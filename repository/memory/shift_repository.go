@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// shiftRepository implements repository.ShiftRepository
+type shiftRepository struct {
+	store *Store
+}
+
+// NewShiftRepository creates a new in-memory ShiftRepository
+func NewShiftRepository(store *Store) repository.ShiftRepository {
+	return &shiftRepository{store}
+}
+
+// Create opens a shift. It fails with ErrShiftAlreadyOpen if the user has
+// one open already.
+func (r *shiftRepository) Create(ctx context.Context, shift domain.Shift) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.pharmacies[shift.PharmacyID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.users[shift.UserID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.shifts[shift.ID]; ok {
+		return errUniqueViolation
+	}
+	for _, s := range r.store.shifts {
+		if s.UserID == shift.UserID && s.Status == domain.ShiftOpen {
+			return domain.ErrShiftAlreadyOpen
+		}
+	}
+	shift.Counts = nil
+	r.store.shifts[shift.ID] = shift
+	return nil
+}
+
+// GetByID retrieves a shift by ID with its counts
+func (r *shiftRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Shift, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s, ok := r.store.shifts[id]
+	if !ok {
+		return nil, domain.ErrShiftNotFound
+	}
+	s.Counts = append([]domain.TenderCount(nil), s.Counts...)
+	return &s, nil
+}
+
+// GetOpen retrieves the user's open shift
+func (r *shiftRepository) GetOpen(ctx context.Context, userID uuid.UUID) (*domain.Shift, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, s := range r.store.shifts {
+		if s.UserID == userID && s.Status == domain.ShiftOpen {
+			return &s, nil
+		}
+	}
+	return nil, domain.ErrNoOpenShift
+}
+
+// GetAll retrieves a pharmacy's shifts, latest opened first, without their
+// counts
+func (r *shiftRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID, limit, offset int) ([]domain.Shift, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var shifts []domain.Shift
+	for _, s := range r.store.shifts {
+		if s.PharmacyID == pharmacyID {
+			s.Counts = nil
+			shifts = append(shifts, s)
+		}
+	}
+	sort.SliceStable(shifts, func(i, j int) bool {
+		if !shifts[i].OpenedAt.Equal(shifts[j].OpenedAt) {
+			return shifts[i].OpenedAt.After(shifts[j].OpenedAt)
+		}
+		return shifts[i].ID.String() < shifts[j].ID.String()
+	})
+	return paginate(shifts, limit, offset), nil
+}
+
+// Close closes an open shift and records its counts
+func (r *shiftRepository) Close(ctx context.Context, id uuid.UUID, counts []domain.TenderCount, closedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	s, ok := r.store.shifts[id]
+	if !ok {
+		return domain.ErrShiftNotFound
+	}
+	if s.Status != domain.ShiftOpen {
+		return domain.ErrShiftClosed
+	}
+	s.Status = domain.ShiftClosed
+	s.ClosedAt = &closedAt
+	s.Counts = append([]domain.TenderCount(nil), counts...)
+	sort.SliceStable(s.Counts, func(i, j int) bool { return s.Counts[i].Method < s.Counts[j].Method })
+	r.store.shifts[id] = s
+	return nil
+}
+
+// GetTotals retrieves what a shift's sales took and its returns refunded, by
+// tender
+func (r *shiftRepository) GetTotals(ctx context.Context, id uuid.UUID) (*domain.ShiftTotals, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var totals domain.ShiftTotals
+	byMethod := make(map[domain.PaymentMethod]*domain.TenderTotal)
+	for _, sale := range r.store.sales {
+		if sale.ShiftID == nil || *sale.ShiftID != id {
+			continue
+		}
+		totals.SaleCount++
+		totals.TotalSales += sale.TotalPrice
+		for _, p := range sale.Payments {
+			t, ok := byMethod[p.Method]
+			if !ok {
+				t = &domain.TenderTotal{Method: p.Method}
+				byMethod[p.Method] = t
+			}
+			t.Count++
+			t.Amount += p.Amount
+			t.Change += p.Change
+		}
+	}
+	for _, t := range byMethod {
+		totals.Tenders = append(totals.Tenders, *t)
+	}
+	sort.Slice(totals.Tenders, func(i, j int) bool { return totals.Tenders[i].Method < totals.Tenders[j].Method })
+
+	refunds := make(map[domain.PaymentMethod]*domain.TenderTotal)
+	for _, sr := range r.store.saleReturns {
+		if sr.ShiftID == nil || *sr.ShiftID != id {
+			continue
+		}
+		t, ok := refunds[sr.RefundMethod]
+		if !ok {
+			t = &domain.TenderTotal{Method: sr.RefundMethod}
+			refunds[sr.RefundMethod] = t
+		}
+		t.Count++
+		t.Amount += sr.TotalRefund
+	}
+	for _, t := range refunds {
+		totals.Refunds = append(totals.Refunds, *t)
+	}
+	sort.Slice(totals.Refunds, func(i, j int) bool { return totals.Refunds[i].Method < totals.Refunds[j].Method })
+	return &totals, nil
+}
@@ -0,0 +1,285 @@
+// Package memory provides in-memory implementations of the repository
+// interfaces for tests and local demos.
+//
+// All repositories created from the same Store share its data, so a sale
+// recorded through the SaleRepository is visible to the MedicineRepository
+// exactly as it would be with Postgres. A single mutex guards the Store,
+// which makes every repository method atomic.
+package memory
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// errUniqueViolation mirrors a Postgres unique constraint failure
+	errUniqueViolation = errors.New("duplicate key value violates unique constraint")
+	// errForeignKeyViolation mirrors a Postgres foreign key constraint failure
+	errForeignKeyViolation = errors.New("violates foreign key constraint")
+)
+
+// token is a stored refresh or password reset token
+type token struct {
+	userID    uuid.UUID
+	expiresAt time.Time
+}
+
+// receiptSequence is a pharmacy's receipt number series, see
+// domain.ReceiptSettings.NumberSeries
+type receiptSequence struct {
+	pharmacyID uuid.UUID
+	series     int
+}
+
+// Store holds the shared in-memory state backing every repository
+type Store struct {
+	mu sync.RWMutex
+
+	users         map[uuid.UUID]domain.User
+	refreshTokens map[string]token
+	resetTokens   map[string]token
+
+	pharmacies map[uuid.UUID]domain.Pharmacy
+	medicines  map[uuid.UUID]domain.Medicine
+	variants   map[uuid.UUID]domain.MedicineVariant
+	lots       map[uuid.UUID]domain.MedicineLot
+	movements  []domain.StockMovement
+	// variantPrices holds each variant's prices, oldest first
+	variantPrices map[uuid.UUID][]domain.VariantPrice
+	// lowStockAlerts holds variants whose owner was told they are low
+	lowStockAlerts map[uuid.UUID]bool
+	writeOffs      []domain.WriteOff
+	// deletedVariants are kept in the order they were deleted
+	deletedVariants []deletedVariant
+
+	suppliers      map[uuid.UUID]domain.Supplier
+	purchaseOrders map[uuid.UUID]domain.PurchaseOrder
+	goodsReceipts  []domain.GoodsReceipt
+
+	customers map[uuid.UUID]domain.Customer
+
+	carts     map[uuid.UUID]domain.Cart
+	sales     map[uuid.UUID]domain.Sale
+	saleItems map[uuid.UUID]domain.SaleItem
+	receipts  map[uuid.UUID]domain.Receipt
+	// receiptSequences holds the last receipt number of each series
+	receiptSequences map[receiptSequence]int64
+	// receiptChains holds each pharmacy's last chained receipt
+	receiptChains map[uuid.UUID]domain.ReceiptChainHead
+	// saleReturns are kept in the order they were made
+	saleReturns []domain.SaleReturn
+	// idempotencyKeys is keyed by user ID and key, see idempotencyKeyID
+	idempotencyKeys map[string]domain.IdempotencyKey
+	// parkedCarts are stored without their line counts
+	parkedCarts map[uuid.UUID]domain.ParkedCart
+
+	promotions      map[uuid.UUID]domain.Promotion
+	manualDiscounts map[uuid.UUID]domain.ManualDiscount
+
+	shifts map[uuid.UUID]domain.Shift
+
+	hospitals  map[uuid.UUID]domain.Hospital
+	patients   map[uuid.UUID]domain.Patient
+	orders     map[uuid.UUID]domain.Order
+	orderItems map[uuid.UUID]domain.OrderItem
+}
+
+// deletedVariant is the tombstone of a deleted variant, kept for catalog sync
+type deletedVariant struct {
+	pharmacyID uuid.UUID
+	item       domain.CatalogItem
+}
+
+// NewStore creates an empty Store
+func NewStore() *Store {
+	return &Store{
+		users:            make(map[uuid.UUID]domain.User),
+		refreshTokens:    make(map[string]token),
+		resetTokens:      make(map[string]token),
+		pharmacies:       make(map[uuid.UUID]domain.Pharmacy),
+		medicines:        make(map[uuid.UUID]domain.Medicine),
+		variants:         make(map[uuid.UUID]domain.MedicineVariant),
+		lots:             make(map[uuid.UUID]domain.MedicineLot),
+		variantPrices:    make(map[uuid.UUID][]domain.VariantPrice),
+		lowStockAlerts:   make(map[uuid.UUID]bool),
+		suppliers:        make(map[uuid.UUID]domain.Supplier),
+		purchaseOrders:   make(map[uuid.UUID]domain.PurchaseOrder),
+		customers:        make(map[uuid.UUID]domain.Customer),
+		carts:            make(map[uuid.UUID]domain.Cart),
+		parkedCarts:      make(map[uuid.UUID]domain.ParkedCart),
+		sales:            make(map[uuid.UUID]domain.Sale),
+		saleItems:        make(map[uuid.UUID]domain.SaleItem),
+		receipts:         make(map[uuid.UUID]domain.Receipt),
+		receiptSequences: make(map[receiptSequence]int64),
+		receiptChains:    make(map[uuid.UUID]domain.ReceiptChainHead),
+		idempotencyKeys:  make(map[string]domain.IdempotencyKey),
+		promotions:       make(map[uuid.UUID]domain.Promotion),
+		manualDiscounts:  make(map[uuid.UUID]domain.ManualDiscount),
+		shifts:           make(map[uuid.UUID]domain.Shift),
+		hospitals:        make(map[uuid.UUID]domain.Hospital),
+		patients:         make(map[uuid.UUID]domain.Patient),
+		orders:           make(map[uuid.UUID]domain.Order),
+		orderItems:       make(map[uuid.UUID]domain.OrderItem),
+	}
+}
+
+// SeedOrder stores an order with its hospital, patient and items. Orders are
+// created outside this service, so OrderRepository has no write methods.
+func (s *Store) SeedOrder(hospital domain.Hospital, patient domain.Patient, order domain.Order, items []domain.OrderItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.pharmacies[order.PharmacyID]; !ok {
+		return errForeignKeyViolation
+	}
+	for _, item := range items {
+		if _, ok := s.variants[item.MedicineVariantID]; !ok {
+			return errForeignKeyViolation
+		}
+	}
+
+	s.hospitals[hospital.ID] = hospital
+	s.patients[patient.ID] = patient
+	s.orders[order.ID] = order
+	for _, item := range items {
+		item.MedicineName = ""
+		item.Unit = ""
+		s.orderItems[item.ID] = item
+	}
+	return nil
+}
+
+// variant returns a stored variant with Stock and ExpiryDate derived from its
+// unexpired lots, and Available from the carts' reservations. The caller must
+// hold the lock.
+func (s *Store) variant(id uuid.UUID) (domain.MedicineVariant, bool) {
+	v, ok := s.variants[id]
+	if !ok {
+		return v, false
+	}
+	v.Stock = 0
+	v.ExpiryDate = time.Time{}
+	for _, l := range s.sellableLots(id) {
+		v.Stock += l.Quantity
+		if v.ExpiryDate.IsZero() || l.ExpiryDate.Before(v.ExpiryDate) {
+			v.ExpiryDate = l.ExpiryDate
+		}
+	}
+	v.Available = max(v.Stock-s.reserved(id, uuid.Nil), 0)
+	return v, true
+}
+
+// reserved returns how much of a variant carts other than the active cart of
+// exceptUserID have reserved. The caller must hold the lock.
+func (s *Store) reserved(variantID, exceptUserID uuid.UUID) int {
+	now := time.Now()
+	total := 0
+	for _, c := range s.carts {
+		active := c.UserID == exceptUserID && c.ParkedCartID == nil
+		if c.MedicineVariantID == variantID && !active && c.Reserved(now) {
+			total += c.Quantity
+		}
+	}
+	return total
+}
+
+// available returns how much of a variant a cart line can hold: the
+// variant's unexpired stock less what every other line holds under a running
+// reservation, whether that line is active or parked and whoever's cart it is
+// in. The line's own quantity is left out, being what is asked for; a line
+// not yet in a cart is uuid.Nil. The caller must hold the lock.
+func (s *Store) available(variantID, lineID uuid.UUID) int {
+	now := time.Now()
+	total := 0
+	for _, l := range s.sellableLots(variantID) {
+		total += l.Quantity
+	}
+	for _, c := range s.carts {
+		if c.MedicineVariantID == variantID && c.ID != lineID && c.Reserved(now) {
+			total -= c.Quantity
+		}
+	}
+	return total
+}
+
+// setPrice adds a price to a variant's history, replacing one set at the
+// same time. The caller must hold the lock.
+func (s *Store) setPrice(variantID uuid.UUID, price domain.Money, effectiveFrom time.Time) {
+	prices := s.variantPrices[variantID]
+	i := sort.Search(len(prices), func(i int) bool { return !prices[i].EffectiveFrom.Before(effectiveFrom) })
+	p := domain.VariantPrice{VariantID: variantID, PricePerUnit: price, EffectiveFrom: effectiveFrom}
+	if i < len(prices) && prices[i].EffectiveFrom.Equal(effectiveFrom) {
+		prices[i] = p
+		return
+	}
+	s.variantPrices[variantID] = slices.Insert(prices, i, p)
+}
+
+// sellableLots returns a variant's unexpired lots holding stock, earliest
+// expiry first. The caller must hold the lock.
+func (s *Store) sellableLots(variantID uuid.UUID) []domain.MedicineLot {
+	now := time.Now()
+	var lots []domain.MedicineLot
+	for _, l := range s.lots {
+		if l.VariantID == variantID && l.ExpiryDate.After(now) && l.Quantity > 0 {
+			lots = append(lots, l)
+		}
+	}
+	sortLots(lots)
+	return lots
+}
+
+// sortLots orders lots first-expiry-first-out
+func sortLots(lots []domain.MedicineLot) {
+	sort.SliceStable(lots, func(i, j int) bool {
+		if !lots[i].ExpiryDate.Equal(lots[j].ExpiryDate) {
+			return lots[i].ExpiryDate.Before(lots[j].ExpiryDate)
+		}
+		if !lots[i].ReceivedAt.Equal(lots[j].ReceivedAt) {
+			return lots[i].ReceivedAt.Before(lots[j].ReceivedAt)
+		}
+		return lots[i].ID.String() < lots[j].ID.String()
+	})
+}
+
+// onHand returns the total quantity across all of a variant's lots, expired
+// ones included. The caller must hold the lock.
+func (s *Store) onHand(variantID uuid.UUID) int {
+	total := 0
+	for _, l := range s.lots {
+		if l.VariantID == variantID {
+			total += l.Quantity
+		}
+	}
+	return total
+}
+
+// receiveLot stores a new lot, appends its receipt movement and re-arms the
+// variant's low-stock alert. The caller must hold the lock.
+func (s *Store) receiveLot(lot domain.MedicineLot, userID uuid.UUID) {
+	before := s.onHand(lot.VariantID)
+	s.lots[lot.ID] = lot
+	delete(s.lowStockAlerts, lot.VariantID)
+	if lot.Quantity == 0 {
+		return
+	}
+	s.movements = append(s.movements, domain.StockMovement{
+		ID:             uuid.New(),
+		VariantID:      lot.VariantID,
+		LotID:          lot.ID,
+		Type:           domain.StockMovementReceipt,
+		Quantity:       lot.Quantity,
+		QuantityBefore: before,
+		QuantityAfter:  before + lot.Quantity,
+		UserID:         userID,
+		CreatedAt:      lot.CreatedAt,
+	})
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// supplierRepository implements repository.SupplierRepository
+type supplierRepository struct {
+	store *Store
+}
+
+// NewSupplierRepository creates a new in-memory SupplierRepository
+func NewSupplierRepository(store *Store) repository.SupplierRepository {
+	return &supplierRepository{store}
+}
+
+// Create stores a new supplier
+func (r *supplierRepository) Create(ctx context.Context, supplier domain.Supplier) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.pharmacies[supplier.PharmacyID]; !ok {
+		return errForeignKeyViolation
+	}
+	if _, ok := r.store.suppliers[supplier.ID]; ok {
+		return errUniqueViolation
+	}
+	r.store.suppliers[supplier.ID] = supplier
+	return nil
+}
+
+// GetByID retrieves a supplier by ID
+func (r *supplierRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Supplier, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s, ok := r.store.suppliers[id]
+	if !ok {
+		return nil, domain.ErrSupplierNotFound
+	}
+	return &s, nil
+}
+
+// GetAll retrieves a pharmacy's suppliers ordered by name
+func (r *supplierRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Supplier, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var suppliers []domain.Supplier
+	for _, s := range r.store.suppliers {
+		if s.PharmacyID == pharmacyID {
+			suppliers = append(suppliers, s)
+		}
+	}
+	sort.SliceStable(suppliers, func(i, j int) bool {
+		return suppliers[i].Name < suppliers[j].Name
+	})
+	return suppliers, nil
+}
+
+// Update updates a supplier
+func (r *supplierRepository) Update(ctx context.Context, supplier domain.Supplier) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, ok := r.store.suppliers[supplier.ID]
+	if !ok {
+		return domain.ErrSupplierNotFound
+	}
+	existing.Name = supplier.Name
+	existing.ContactName = supplier.ContactName
+	existing.PhoneNumber = supplier.PhoneNumber
+	existing.Email = supplier.Email
+	existing.Address = supplier.Address
+	existing.UpdatedAt = supplier.UpdatedAt
+	r.store.suppliers[supplier.ID] = existing
+	return nil
+}
+
+// Delete deletes a supplier that no purchase order references
+func (r *supplierRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.suppliers[id]; !ok {
+		return domain.ErrSupplierNotFound
+	}
+	for _, po := range r.store.purchaseOrders {
+		if po.SupplierID == id {
+			return errForeignKeyViolation
+		}
+	}
+	delete(r.store.suppliers, id)
+	return nil
+}
+
+// CountPurchaseOrders counts purchase orders placed with a supplier
+func (r *supplierRepository) CountPurchaseOrders(ctx context.Context, supplierID uuid.UUID) (int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	count := 0
+	for _, po := range r.store.purchaseOrders {
+		if po.SupplierID == supplierID {
+			count++
+		}
+	}
+	return count, nil
+}
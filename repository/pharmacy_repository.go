@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"pharmacy-management-backend/domain"
 
@@ -16,6 +17,8 @@ type PharmacyRepository interface {
 	GetAll(ctx context.Context) ([]domain.Pharmacy, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Pharmacy, error)
 	Update(ctx context.Context, pharmacy domain.Pharmacy) error
+	UpdateTaxSettings(ctx context.Context, id uuid.UUID, settings domain.TaxSettings, updatedAt time.Time) error
+	UpdateReceiptSettings(ctx context.Context, id uuid.UUID, settings domain.ReceiptSettings, updatedAt time.Time) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
@@ -30,14 +33,26 @@ func NewPharmacyRepository(db *sql.DB, logger zerolog.Logger) PharmacyRepository
 	return &pharmacyRepository{db, logger}
 }
 
+const pharmacyColumns = `id, name, address, vat_rate, prices_include_tax, receipt_header, receipt_footer, receipt_paper_width,
+               receipt_number_prefix, receipt_yearly_reset, created_at, updated_at`
+
+func scanPharmacy(row rowScanner, p *domain.Pharmacy) error {
+	return row.Scan(&p.ID, &p.Name, &p.Address, &p.TaxSettings.VATRate, &p.TaxSettings.PricesIncludeTax,
+		&p.ReceiptSettings.Header, &p.ReceiptSettings.Footer, &p.ReceiptSettings.PaperWidth,
+		&p.ReceiptSettings.NumberPrefix, &p.ReceiptSettings.YearlyReset, &p.CreatedAt, &p.UpdatedAt)
+}
+
 // Create inserts a new pharmacy into the database
 func (r *pharmacyRepository) Create(ctx context.Context, pharmacy domain.Pharmacy) error {
 	query := `
-        INSERT INTO pharmacies (id, name, address, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5)
+        INSERT INTO pharmacies (id, name, address, vat_rate, prices_include_tax, receipt_header, receipt_footer, receipt_paper_width,
+                                receipt_number_prefix, receipt_yearly_reset, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
     `
 	_, err := r.db.ExecContext(ctx, query,
-		pharmacy.ID, pharmacy.Name, pharmacy.Address, pharmacy.CreatedAt, pharmacy.UpdatedAt,
+		pharmacy.ID, pharmacy.Name, pharmacy.Address, pharmacy.TaxSettings.VATRate, pharmacy.TaxSettings.PricesIncludeTax,
+		pharmacy.ReceiptSettings.Header, pharmacy.ReceiptSettings.Footer, pharmacy.ReceiptSettings.PaperWidth,
+		pharmacy.ReceiptSettings.NumberPrefix, pharmacy.ReceiptSettings.YearlyReset, pharmacy.CreatedAt, pharmacy.UpdatedAt,
 	)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to create pharmacy")
@@ -49,7 +64,7 @@ func (r *pharmacyRepository) Create(ctx context.Context, pharmacy domain.Pharmac
 // GetAll retrieves all pharmacies
 func (r *pharmacyRepository) GetAll(ctx context.Context) ([]domain.Pharmacy, error) {
 	query := `
-        SELECT id, name, address, created_at, updated_at
+        SELECT ` + pharmacyColumns + `
         FROM pharmacies
     `
 	rows, err := r.db.QueryContext(ctx, query)
@@ -62,7 +77,7 @@ func (r *pharmacyRepository) GetAll(ctx context.Context) ([]domain.Pharmacy, err
 	var pharmacies []domain.Pharmacy
 	for rows.Next() {
 		var p domain.Pharmacy
-		if err := rows.Scan(&p.ID, &p.Name, &p.Address, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		if err := scanPharmacy(rows, &p); err != nil {
 			r.logger.Error().Err(err).Msg("Failed to scan pharmacy")
 			return nil, err
 		}
@@ -74,13 +89,11 @@ func (r *pharmacyRepository) GetAll(ctx context.Context) ([]domain.Pharmacy, err
 // GetByID retrieves a pharmacy by ID
 func (r *pharmacyRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Pharmacy, error) {
 	query := `
-        SELECT id, name, address, created_at, updated_at
+        SELECT ` + pharmacyColumns + `
         FROM pharmacies WHERE id = $1
     `
 	var p domain.Pharmacy
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&p.ID, &p.Name, &p.Address, &p.CreatedAt, &p.UpdatedAt,
-	)
+	err := scanPharmacy(r.db.QueryRowContext(ctx, query, id), &p)
 	if err == sql.ErrNoRows {
 		r.logger.Info().Str("id", id.String()).Msg("Pharmacy not found")
 		return nil, domain.ErrNotFound
@@ -118,6 +131,57 @@ func (r *pharmacyRepository) Update(ctx context.Context, pharmacy domain.Pharmac
 	return nil
 }
 
+// UpdateTaxSettings replaces a pharmacy's VAT configuration
+func (r *pharmacyRepository) UpdateTaxSettings(ctx context.Context, id uuid.UUID, settings domain.TaxSettings, updatedAt time.Time) error {
+	query := `
+        UPDATE pharmacies
+        SET vat_rate = $2, prices_include_tax = $3, updated_at = $4
+        WHERE id = $1
+    `
+	result, err := r.db.ExecContext(ctx, query, id, settings.VATRate, settings.PricesIncludeTax, updatedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update tax settings")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		r.logger.Info().Str("id", id.String()).Msg("Pharmacy not found for tax settings update")
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// UpdateReceiptSettings replaces how a pharmacy's receipts are printed and
+// numbered
+func (r *pharmacyRepository) UpdateReceiptSettings(ctx context.Context, id uuid.UUID, settings domain.ReceiptSettings, updatedAt time.Time) error {
+	query := `
+        UPDATE pharmacies
+        SET receipt_header = $2, receipt_footer = $3, receipt_paper_width = $4, receipt_number_prefix = $5,
+            receipt_yearly_reset = $6, updated_at = $7
+        WHERE id = $1
+    `
+	result, err := r.db.ExecContext(ctx, query, id, settings.Header, settings.Footer, settings.PaperWidth, settings.NumberPrefix,
+		settings.YearlyReset, updatedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update receipt settings")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		r.logger.Info().Str("id", id.String()).Msg("Pharmacy not found for receipt settings update")
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
 // Delete deletes a pharmacy
 func (r *pharmacyRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM pharmacies WHERE id = $1`
@@ -0,0 +1,119 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/infrastructure"
+	"pharmacy-management-backend/migrations"
+	"pharmacy-management-backend/repository"
+	"pharmacy-management-backend/repository/repositorytest"
+
+	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// openTestDB connects to TEST_DATABASE_URL and migrates it, skipping the test
+// when no database is configured. The database is wiped between subtests.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set; skipping Postgres tests")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := infrastructure.NewMigrator(db, migrations.FS, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// truncateAll empties every table except schema_migrations
+func truncateAll(t *testing.T, db *sql.DB) {
+	t.Helper()
+	rows, err := db.Query(`SELECT tablename FROM pg_tables WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'`)
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("scan table name: %v", err)
+		}
+		tables = append(tables, name)
+	}
+	if len(tables) == 0 {
+		return
+	}
+	if _, err := db.Exec(`TRUNCATE ` + strings.Join(tables, ", ") + ` CASCADE`); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+}
+
+// seedOrder inserts an order with its hospital, patient and items
+func seedOrder(db *sql.DB) func(domain.Hospital, domain.Patient, domain.Order, []domain.OrderItem) error {
+	return func(h domain.Hospital, p domain.Patient, o domain.Order, items []domain.OrderItem) error {
+		ctx := context.Background()
+		if _, err := db.ExecContext(ctx, `
+            INSERT INTO hospitals (id, name, created_at, updated_at) VALUES ($1, $2, $3, $4)
+            ON CONFLICT (id) DO NOTHING`, h.ID, h.Name, h.CreatedAt, h.UpdatedAt); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `
+            INSERT INTO patients (id, full_name, phone_number, emergency_phone_number, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6)
+            ON CONFLICT (id) DO NOTHING`, p.ID, p.FullName, p.PhoneNumber, p.EmergencyPhoneNumber, p.CreatedAt, p.UpdatedAt); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, `
+            INSERT INTO orders (id, hospital_id, patient_id, pharmacy_id, order_date, created_at, updated_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)`, o.ID, o.HospitalID, o.PatientID, o.PharmacyID, o.OrderDate, o.CreatedAt, o.UpdatedAt); err != nil {
+			return err
+		}
+		for _, item := range items {
+			if _, err := db.ExecContext(ctx, `
+                INSERT INTO order_items (id, order_id, medicine_variant_id, quantity, price_per_unit, created_at)
+                VALUES ($1, $2, $3, $4, $5, $6)`, item.ID, item.OrderID, item.MedicineVariantID, item.Quantity, item.PricePerUnit, item.CreatedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestPostgresContract(t *testing.T) {
+	db := openTestDB(t)
+	logger := zerolog.Nop()
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Harness {
+		truncateAll(t, db)
+		return repositorytest.Harness{
+			Auth:          repository.NewAuthRepository(db, logger),
+			Pharmacy:      repository.NewPharmacyRepository(db, logger),
+			Medicine:      repository.NewMedicineRepository(db, logger),
+			Sale:          repository.NewSaleRepository(db, logger),
+			Order:         repository.NewOrderRepository(db, logger),
+			Inventory:     repository.NewInventoryRepository(db, logger),
+			Supplier:      repository.NewSupplierRepository(db, logger),
+			PurchaseOrder: repository.NewPurchaseOrderRepository(db, logger),
+			Promotion:     repository.NewPromotionRepository(db, logger),
+			Shift:         repository.NewShiftRepository(db, logger),
+			Customer:      repository.NewCustomerRepository(db, logger),
+			SeedOrder:     seedOrder(db),
+		}
+	})
+}
@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// PromotionRepository defines the interface for promotion and manual
+// discount database operations
+type PromotionRepository interface {
+	Create(ctx context.Context, promotion domain.Promotion) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Promotion, error)
+	GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Promotion, error)
+	GetRunning(ctx context.Context, pharmacyID uuid.UUID, at time.Time) ([]domain.Promotion, error)
+	Update(ctx context.Context, promotion domain.Promotion) error
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	CreateManualDiscount(ctx context.Context, discount domain.ManualDiscount) error
+	GetManualDiscountByID(ctx context.Context, id uuid.UUID) (*domain.ManualDiscount, error)
+	GetManualDiscounts(ctx context.Context, pharmacyID uuid.UUID, status domain.ManualDiscountStatus) ([]domain.ManualDiscount, error)
+	GetApprovedManualDiscounts(ctx context.Context, userID uuid.UUID) ([]domain.ManualDiscount, error)
+	ReviewManualDiscount(ctx context.Context, id uuid.UUID, status domain.ManualDiscountStatus, reviewerID uuid.UUID, reviewedAt time.Time) error
+}
+
+// promotionRepository implements PromotionRepository
+type promotionRepository struct {
+	db     *sql.DB
+	logger zerolog.Logger
+}
+
+// NewPromotionRepository creates a new PromotionRepository
+func NewPromotionRepository(db *sql.DB, logger zerolog.Logger) PromotionRepository {
+	return &promotionRepository{db, logger}
+}
+
+const promotionColumns = `id, pharmacy_id, name, type, scope, rate, amount, buy_quantity, get_quantity, medicine_id, category,
+               min_subtotal, active, starts_at, ends_at, created_at, updated_at`
+
+func scanPromotion(row rowScanner, p *domain.Promotion) error {
+	return row.Scan(&p.ID, &p.PharmacyID, &p.Name, &p.Type, &p.Scope, &p.Rate, &p.Amount, &p.BuyQuantity, &p.GetQuantity,
+		&p.MedicineID, &p.Category, &p.MinSubtotal, &p.Active, &p.StartsAt, &p.EndsAt, &p.CreatedAt, &p.UpdatedAt)
+}
+
+// Create inserts a new promotion into the database
+func (r *promotionRepository) Create(ctx context.Context, promotion domain.Promotion) error {
+	query := `
+        INSERT INTO promotions (id, pharmacy_id, name, type, scope, rate, amount, buy_quantity, get_quantity, medicine_id, category,
+                                min_subtotal, active, starts_at, ends_at, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+    `
+	_, err := r.db.ExecContext(ctx, query,
+		promotion.ID, promotion.PharmacyID, promotion.Name, promotion.Type, promotion.Scope, promotion.Rate, promotion.Amount,
+		promotion.BuyQuantity, promotion.GetQuantity, promotion.MedicineID, promotion.Category, promotion.MinSubtotal,
+		promotion.Active, promotion.StartsAt, promotion.EndsAt, promotion.CreatedAt, promotion.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create promotion")
+		return err
+	}
+	return nil
+}
+
+// GetByID retrieves a promotion by ID
+func (r *promotionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Promotion, error) {
+	query := `SELECT ` + promotionColumns + ` FROM promotions WHERE id = $1`
+	var p domain.Promotion
+	err := scanPromotion(r.db.QueryRowContext(ctx, query, id), &p)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("id", id.String()).Msg("Promotion not found")
+		return nil, domain.ErrPromotionNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get promotion by ID")
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetAll retrieves a pharmacy's promotions, latest starting first
+func (r *promotionRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Promotion, error) {
+	query := `
+        SELECT ` + promotionColumns + `
+        FROM promotions
+        WHERE pharmacy_id = $1
+        ORDER BY starts_at DESC, id
+    `
+	return r.query(ctx, query, pharmacyID)
+}
+
+// GetRunning retrieves a pharmacy's active promotions whose date range
+// includes at
+func (r *promotionRepository) GetRunning(ctx context.Context, pharmacyID uuid.UUID, at time.Time) ([]domain.Promotion, error) {
+	query := `
+        SELECT ` + promotionColumns + `
+        FROM promotions
+        WHERE pharmacy_id = $1 AND active AND starts_at <= $2 AND ends_at > $2
+        ORDER BY starts_at DESC, id
+    `
+	return r.query(ctx, query, pharmacyID, at)
+}
+
+func (r *promotionRepository) query(ctx context.Context, query string, args ...any) ([]domain.Promotion, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get promotions")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var promotions []domain.Promotion
+	for rows.Next() {
+		var p domain.Promotion
+		if err := scanPromotion(rows, &p); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan promotion")
+			return nil, err
+		}
+		promotions = append(promotions, p)
+	}
+	return promotions, nil
+}
+
+// Update updates a promotion
+func (r *promotionRepository) Update(ctx context.Context, promotion domain.Promotion) error {
+	query := `
+        UPDATE promotions
+        SET name = $2, type = $3, scope = $4, rate = $5, amount = $6, buy_quantity = $7, get_quantity = $8, medicine_id = $9,
+            category = $10, min_subtotal = $11, active = $12, starts_at = $13, ends_at = $14, updated_at = $15
+        WHERE id = $1
+    `
+	result, err := r.db.ExecContext(ctx, query,
+		promotion.ID, promotion.Name, promotion.Type, promotion.Scope, promotion.Rate, promotion.Amount, promotion.BuyQuantity,
+		promotion.GetQuantity, promotion.MedicineID, promotion.Category, promotion.MinSubtotal, promotion.Active,
+		promotion.StartsAt, promotion.EndsAt, promotion.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update promotion")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		r.logger.Info().Str("id", promotion.ID.String()).Msg("Promotion not found for update")
+		return domain.ErrPromotionNotFound
+	}
+	return nil
+}
+
+// Delete deletes a promotion. Sales it was applied to keep its name.
+func (r *promotionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM promotions WHERE id = $1`, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to delete promotion")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		r.logger.Info().Str("id", id.String()).Msg("Promotion not found for deletion")
+		return domain.ErrPromotionNotFound
+	}
+	return nil
+}
+
+const manualDiscountColumns = `id, pharmacy_id, user_id, cart_id, rate, amount, reason, status, reviewed_by, reviewed_at, sale_id, created_at`
+
+func scanManualDiscount(row rowScanner, d *domain.ManualDiscount) error {
+	return row.Scan(&d.ID, &d.PharmacyID, &d.UserID, &d.CartID, &d.Rate, &d.Amount, &d.Reason, &d.Status, &d.ReviewedBy,
+		&d.ReviewedAt, &d.SaleID, &d.CreatedAt)
+}
+
+// CreateManualDiscount inserts a manual discount
+func (r *promotionRepository) CreateManualDiscount(ctx context.Context, discount domain.ManualDiscount) error {
+	query := `
+        INSERT INTO manual_discounts (id, pharmacy_id, user_id, cart_id, rate, amount, reason, status, reviewed_by, reviewed_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+    `
+	_, err := r.db.ExecContext(ctx, query,
+		discount.ID, discount.PharmacyID, discount.UserID, discount.CartID, discount.Rate, discount.Amount, discount.Reason,
+		discount.Status, discount.ReviewedBy, discount.ReviewedAt, discount.CreatedAt,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create manual discount")
+		return err
+	}
+	return nil
+}
+
+// GetManualDiscountByID retrieves a manual discount by ID
+func (r *promotionRepository) GetManualDiscountByID(ctx context.Context, id uuid.UUID) (*domain.ManualDiscount, error) {
+	query := `SELECT ` + manualDiscountColumns + ` FROM manual_discounts WHERE id = $1`
+	var d domain.ManualDiscount
+	err := scanManualDiscount(r.db.QueryRowContext(ctx, query, id), &d)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("id", id.String()).Msg("Manual discount not found")
+		return nil, domain.ErrManualDiscountNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get manual discount by ID")
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetManualDiscounts retrieves a pharmacy's manual discounts in a status, or
+// in any status when status is empty, oldest first
+func (r *promotionRepository) GetManualDiscounts(ctx context.Context, pharmacyID uuid.UUID, status domain.ManualDiscountStatus) ([]domain.ManualDiscount, error) {
+	query := `
+        SELECT ` + manualDiscountColumns + `
+        FROM manual_discounts
+        WHERE pharmacy_id = $1 AND ($2::varchar = '' OR status = $2)
+        ORDER BY created_at, id
+    `
+	return r.queryManualDiscounts(ctx, query, pharmacyID, status)
+}
+
+// GetApprovedManualDiscounts retrieves a user's approved manual discounts not
+// yet used on a sale, oldest first
+func (r *promotionRepository) GetApprovedManualDiscounts(ctx context.Context, userID uuid.UUID) ([]domain.ManualDiscount, error) {
+	query := `
+        SELECT ` + manualDiscountColumns + `
+        FROM manual_discounts
+        WHERE user_id = $1 AND status = 'approved' AND sale_id IS NULL
+        ORDER BY created_at, id
+    `
+	return r.queryManualDiscounts(ctx, query, userID)
+}
+
+func (r *promotionRepository) queryManualDiscounts(ctx context.Context, query string, args ...any) ([]domain.ManualDiscount, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get manual discounts")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var discounts []domain.ManualDiscount
+	for rows.Next() {
+		var d domain.ManualDiscount
+		if err := scanManualDiscount(rows, &d); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan manual discount")
+			return nil, err
+		}
+		discounts = append(discounts, d)
+	}
+	return discounts, nil
+}
+
+// ReviewManualDiscount approves or rejects a pending manual discount
+func (r *promotionRepository) ReviewManualDiscount(ctx context.Context, id uuid.UUID, status domain.ManualDiscountStatus, reviewerID uuid.UUID, reviewedAt time.Time) error {
+	query := `
+        UPDATE manual_discounts
+        SET status = $2, reviewed_by = $3, reviewed_at = $4
+        WHERE id = $1 AND status = 'pending'
+    `
+	result, err := r.db.ExecContext(ctx, query, id, status, reviewerID, reviewedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to review manual discount")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.GetManualDiscountByID(ctx, id); err != nil {
+			return err
+		}
+		return domain.ErrManualDiscountReviewed
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// PurchaseOrderRepository defines the interface for purchase order database operations
+type PurchaseOrderRepository interface {
+	Create(ctx context.Context, order domain.PurchaseOrder) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.PurchaseOrder, error)
+	GetAll(ctx context.Context, pharmacyID uuid.UUID, status domain.PurchaseOrderStatus, limit, offset int) ([]domain.PurchaseOrder, error)
+	Update(ctx context.Context, order domain.PurchaseOrder) error
+	UpdateStatus(ctx context.Context, id uuid.UUID, from, to domain.PurchaseOrderStatus, updatedAt time.Time) error
+	Receive(ctx context.Context, receipt domain.GoodsReceipt) (*domain.GoodsReceipt, error)
+}
+
+// purchaseOrderRepository implements PurchaseOrderRepository
+type purchaseOrderRepository struct {
+	db     *sql.DB
+	logger zerolog.Logger
+}
+
+// NewPurchaseOrderRepository creates a new PurchaseOrderRepository
+func NewPurchaseOrderRepository(db *sql.DB, logger zerolog.Logger) PurchaseOrderRepository {
+	return &purchaseOrderRepository{db, logger}
+}
+
+// purchaseOrderSelect selects purchase order headers with supplier name and
+// total cost; callers append their own WHERE clause
+const purchaseOrderSelect = `
+        SELECT po.id, po.pharmacy_id, po.supplier_id, s.name, po.status, po.notes,
+               COALESCE((SELECT SUM(i.quantity_ordered * i.unit_cost) FROM purchase_order_items i WHERE i.purchase_order_id = po.id), 0),
+               po.created_by, po.created_at, po.updated_at
+        FROM purchase_orders po
+        JOIN suppliers s ON po.supplier_id = s.id
+`
+
+// scanPurchaseOrder scans a row selected with purchaseOrderSelect
+func scanPurchaseOrder(row rowScanner, po *domain.PurchaseOrder) error {
+	return row.Scan(&po.ID, &po.PharmacyID, &po.SupplierID, &po.SupplierName, &po.Status, &po.Notes,
+		&po.TotalCost, &po.CreatedBy, &po.CreatedAt, &po.UpdatedAt)
+}
+
+// Create inserts a purchase order with its items
+func (r *purchaseOrderRepository) Create(ctx context.Context, order domain.PurchaseOrder) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        INSERT INTO purchase_orders (id, pharmacy_id, supplier_id, status, notes, created_by, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	if _, err := tx.ExecContext(ctx, query,
+		order.ID, order.PharmacyID, order.SupplierID, order.Status, order.Notes, order.CreatedBy, order.CreatedAt, order.UpdatedAt,
+	); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create purchase order")
+		return err
+	}
+	if err := insertPurchaseOrderItems(ctx, tx, order.Items); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create purchase order item")
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+// GetByID retrieves a purchase order with its items
+func (r *purchaseOrderRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PurchaseOrder, error) {
+	var po domain.PurchaseOrder
+	err := scanPurchaseOrder(r.db.QueryRowContext(ctx, purchaseOrderSelect+`WHERE po.id = $1`, id), &po)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("id", id.String()).Msg("Purchase order not found")
+		return nil, domain.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get purchase order by ID")
+		return nil, err
+	}
+
+	query := `
+        SELECT i.id, i.purchase_order_id, i.variant_id, m.name, mv.brand, mv.unit,
+               i.quantity_ordered, i.quantity_received, i.unit_cost
+        FROM purchase_order_items i
+        JOIN medicine_variants mv ON i.variant_id = mv.id
+        JOIN medicines m ON mv.medicine_id = m.id
+        WHERE i.purchase_order_id = $1
+        ORDER BY i.position
+    `
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get purchase order items")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item domain.PurchaseOrderItem
+		if err := rows.Scan(&item.ID, &item.PurchaseOrderID, &item.VariantID, &item.MedicineName, &item.Brand, &item.Unit,
+			&item.QuantityOrdered, &item.QuantityReceived, &item.UnitCost); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan purchase order item")
+			return nil, err
+		}
+		po.Items = append(po.Items, item)
+	}
+	return &po, nil
+}
+
+// GetAll retrieves a pharmacy's purchase order headers, newest first,
+// optionally filtered by status
+func (r *purchaseOrderRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID, status domain.PurchaseOrderStatus, limit, offset int) ([]domain.PurchaseOrder, error) {
+	query := purchaseOrderSelect + `
+        WHERE po.pharmacy_id = $1 AND ($2::text = '' OR po.status = $2)
+        ORDER BY po.created_at DESC
+        LIMIT $3 OFFSET $4
+    `
+	rows, err := r.db.QueryContext(ctx, query, pharmacyID, status, limit, offset)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get purchase orders")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []domain.PurchaseOrder
+	for rows.Next() {
+		var po domain.PurchaseOrder
+		if err := scanPurchaseOrder(rows, &po); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan purchase order")
+			return nil, err
+		}
+		orders = append(orders, po)
+	}
+	return orders, nil
+}
+
+// Update replaces the supplier, notes and items of a draft purchase order
+func (r *purchaseOrderRepository) Update(ctx context.Context, order domain.PurchaseOrder) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.lockStatus(ctx, tx, order.ID, domain.PurchaseOrderDraft); err != nil {
+		return err
+	}
+
+	query := `UPDATE purchase_orders SET supplier_id = $2, notes = $3, updated_at = $4 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, order.ID, order.SupplierID, order.Notes, order.UpdatedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update purchase order")
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM purchase_order_items WHERE purchase_order_id = $1`, order.ID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to delete purchase order items")
+		return err
+	}
+	if err := insertPurchaseOrderItems(ctx, tx, order.Items); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create purchase order item")
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+// UpdateStatus moves a purchase order from one status to another, failing if
+// it is no longer in the expected status
+func (r *purchaseOrderRepository) UpdateStatus(ctx context.Context, id uuid.UUID, from, to domain.PurchaseOrderStatus, updatedAt time.Time) error {
+	query := `UPDATE purchase_orders SET status = $3, updated_at = $4 WHERE id = $1 AND status = $2`
+	result, err := r.db.ExecContext(ctx, query, id, from, to, updatedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update purchase order status")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM purchase_orders WHERE id = $1)`, id).Scan(&exists); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to check purchase order existence")
+			return err
+		}
+		if !exists {
+			r.logger.Info().Str("id", id.String()).Msg("Purchase order not found for status update")
+			return domain.ErrPurchaseOrderNotFound
+		}
+		return domain.ErrInvalidPurchaseOrderStatus
+	}
+	return nil
+}
+
+// Receive records a delivery against a sent or partially received purchase
+// order in one transaction: each line is added to its lot (created if the
+// lot number is new for the variant), recorded as a receipt movement,
+// averaged into the variant's cost price and counted against its order line,
+// and the order's status is advanced.
+func (r *purchaseOrderRepository) Receive(ctx context.Context, receipt domain.GoodsReceipt) (*domain.GoodsReceipt, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := r.lockStatus(ctx, tx, receipt.PurchaseOrderID, domain.PurchaseOrderSent, domain.PurchaseOrderPartiallyReceived); err != nil {
+		return nil, err
+	}
+
+	// Order lines are only modified under the purchase order's lock
+	type orderLine struct {
+		variantID uuid.UUID
+		ordered   int
+		received  int
+	}
+	lines := make(map[uuid.UUID]*orderLine)
+	rows, err := tx.QueryContext(ctx, `SELECT id, variant_id, quantity_ordered, quantity_received FROM purchase_order_items WHERE purchase_order_id = $1`, receipt.PurchaseOrderID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get purchase order items")
+		return nil, err
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var line orderLine
+		if err := rows.Scan(&id, &line.variantID, &line.ordered, &line.received); err != nil {
+			rows.Close()
+			r.logger.Error().Err(err).Msg("Failed to scan purchase order item")
+			return nil, err
+		}
+		lines[id] = &line
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read purchase order items")
+		return nil, err
+	}
+
+	for i, l := range receipt.Lines {
+		line, ok := lines[l.PurchaseOrderItemID]
+		if !ok {
+			r.logger.Info().Str("item_id", l.PurchaseOrderItemID.String()).Msg("Purchase order item not found")
+			return nil, domain.ErrPurchaseOrderItemNotFound
+		}
+		line.received += l.Quantity
+		if line.received > line.ordered {
+			r.logger.Info().Str("item_id", l.PurchaseOrderItemID.String()).Msg("Over-receipt")
+			return nil, domain.ErrOverReceipt
+		}
+		receipt.Lines[i].VariantID = line.variantID
+	}
+
+	// Lock every affected variant's lots in a fixed order
+	variantIDs := make([]uuid.UUID, len(receipt.Lines))
+	for i, l := range receipt.Lines {
+		variantIDs[i] = l.VariantID
+	}
+	onHand, err := lockLots(ctx, tx, variantIDs)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return nil, err
+	}
+
+	receiptQuery := `
+        INSERT INTO goods_receipts (id, purchase_order_id, received_by, received_at)
+        VALUES ($1, $2, $3, $4)
+    `
+	if _, err := tx.ExecContext(ctx, receiptQuery, receipt.ID, receipt.PurchaseOrderID, receipt.ReceivedBy, receipt.ReceivedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create goods receipt")
+		return nil, err
+	}
+
+	for i, l := range receipt.Lines {
+		lotID, err := r.receiveIntoLot(ctx, tx, l, receipt.ReceivedAt)
+		if err != nil {
+			return nil, err
+		}
+		receipt.Lines[i].LotID = lotID
+
+		movement := domain.StockMovement{
+			ID:             uuid.New(),
+			VariantID:      l.VariantID,
+			LotID:          lotID,
+			Type:           domain.StockMovementReceipt,
+			Quantity:       l.Quantity,
+			QuantityBefore: onHand[l.VariantID],
+			QuantityAfter:  onHand[l.VariantID] + l.Quantity,
+			Reason:         "Purchase order " + receipt.PurchaseOrderID.String(),
+			UserID:         receipt.ReceivedBy,
+			CreatedAt:      receipt.ReceivedAt,
+		}
+		if err := insertStockMovement(ctx, tx, movement); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to record stock movement")
+			return nil, err
+		}
+
+		// Blend the delivery into the variant's weighted-average cost
+		costQuery := `
+            UPDATE medicine_variants
+            SET cost_price = CASE WHEN $2::integer <= 0 THEN $3::numeric
+                                  ELSE ROUND((cost_price * $2 + $3 * $4::integer) / ($2 + $4), 2) END
+            WHERE id = $1
+        `
+		if _, err := tx.ExecContext(ctx, costQuery, l.VariantID, onHand[l.VariantID], l.UnitCost, l.Quantity); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to update cost price")
+			return nil, err
+		}
+		onHand[l.VariantID] = movement.QuantityAfter
+
+		lineQuery := `
+            INSERT INTO goods_receipt_lines (id, goods_receipt_id, purchase_order_item_id, lot_id, quantity, unit_cost)
+            VALUES ($1, $2, $3, $4, $5, $6)
+        `
+		if _, err := tx.ExecContext(ctx, lineQuery, l.ID, receipt.ID, l.PurchaseOrderItemID, lotID, l.Quantity, l.UnitCost); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to create goods receipt line")
+			return nil, err
+		}
+		itemQuery := `UPDATE purchase_order_items SET quantity_received = quantity_received + $1 WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, itemQuery, l.Quantity, l.PurchaseOrderItemID); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to update purchase order item")
+			return nil, err
+		}
+	}
+
+	// Restocking re-arms each variant's low-stock alert
+	for _, variantID := range variantIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE medicine_variants SET low_stock_alerted_at = NULL, updated_at = $1 WHERE id = $2`, receipt.ReceivedAt, variantID); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to update medicine variant")
+			return nil, err
+		}
+	}
+
+	status := domain.PurchaseOrderReceived
+	for _, line := range lines {
+		if line.received < line.ordered {
+			status = domain.PurchaseOrderPartiallyReceived
+			break
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE purchase_orders SET status = $2, updated_at = $3 WHERE id = $1`, receipt.PurchaseOrderID, status, receipt.ReceivedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update purchase order status")
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// lockStatus locks a purchase order and checks it is in one of the allowed statuses
+func (r *purchaseOrderRepository) lockStatus(ctx context.Context, tx *sql.Tx, id uuid.UUID, allowed ...domain.PurchaseOrderStatus) error {
+	var status domain.PurchaseOrderStatus
+	err := tx.QueryRowContext(ctx, `SELECT status FROM purchase_orders WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("id", id.String()).Msg("Purchase order not found")
+		return domain.ErrPurchaseOrderNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock purchase order")
+		return err
+	}
+	for _, s := range allowed {
+		if status == s {
+			return nil
+		}
+	}
+	r.logger.Info().Str("id", id.String()).Str("status", string(status)).Msg("Invalid purchase order status")
+	return domain.ErrInvalidPurchaseOrderStatus
+}
+
+// receiveIntoLot adds a receipt line to the variant's lot with the same lot
+// number and expiry, creating the lot if the number is new. The caller must
+// hold the variant's lot locks.
+func (r *purchaseOrderRepository) receiveIntoLot(ctx context.Context, tx *sql.Tx, line domain.GoodsReceiptLine, receivedAt time.Time) (uuid.UUID, error) {
+	var lotID uuid.UUID
+	var expiry time.Time
+	query := `SELECT id, expiry_date FROM medicine_lots WHERE variant_id = $1 AND lot_number = $2`
+	err := tx.QueryRowContext(ctx, query, line.VariantID, line.LotNumber).Scan(&lotID, &expiry)
+	if err == sql.ErrNoRows {
+		lot := domain.MedicineLot{
+			ID:         uuid.New(),
+			VariantID:  line.VariantID,
+			LotNumber:  line.LotNumber,
+			ExpiryDate: line.ExpiryDate,
+			Quantity:   line.Quantity,
+			ReceivedAt: receivedAt,
+			CreatedAt:  receivedAt,
+			UpdatedAt:  receivedAt,
+		}
+		if err := insertLot(ctx, tx, lot); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to create medicine lot")
+			return uuid.Nil, err
+		}
+		return lot.ID, nil
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicine lot")
+		return uuid.Nil, err
+	}
+	if !expiry.Equal(line.ExpiryDate.Truncate(time.Microsecond)) {
+		r.logger.Info().Str("lot_number", line.LotNumber).Msg("Lot number already used with a different expiry")
+		return uuid.Nil, domain.ErrLotNumberTaken
+	}
+	updateQuery := `UPDATE medicine_lots SET quantity = quantity + $1, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, updateQuery, line.Quantity, receivedAt, lotID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update lot stock")
+		return uuid.Nil, err
+	}
+	return lotID, nil
+}
+
+// insertPurchaseOrderItems inserts items keeping their order
+func insertPurchaseOrderItems(ctx context.Context, tx *sql.Tx, items []domain.PurchaseOrderItem) error {
+	query := `
+        INSERT INTO purchase_order_items (id, purchase_order_id, variant_id, quantity_ordered, quantity_received, unit_cost, position)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	for i, item := range items {
+		if _, err := tx.ExecContext(ctx, query,
+			item.ID, item.PurchaseOrderID, item.VariantID, item.QuantityOrdered, item.QuantityReceived, item.UnitCost, i,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
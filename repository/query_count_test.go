@@ -0,0 +1,175 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+	"pharmacy-management-backend/usecase"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+// countingConnector opens lib/pq connections that count the statements sent
+// through them
+type countingConnector struct {
+	driver.Connector
+	queries *atomic.Int64
+}
+
+func (c countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return countingConn{conn, c.queries}, nil
+}
+
+// countingConn counts queries and execs, including those run through prepared
+// statements, and passes them on to lib/pq
+type countingConn struct {
+	driver.Conn
+	queries *atomic.Int64
+}
+
+func (c countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.queries.Add(1)
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries.Add(1)
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	c.queries.Add(1)
+	return c.Conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+// openCountingDB migrates and empties the test database like openTestDB, and
+// connects to it through a driver that counts every statement sent
+func openCountingDB(t *testing.T) (*sql.DB, *atomic.Int64) {
+	t.Helper()
+	truncateAll(t, openTestDB(t))
+	connector, err := pq.NewConnector(os.Getenv("TEST_DATABASE_URL"))
+	if err != nil {
+		t.Fatalf("open connector: %v", err)
+	}
+	queries := new(atomic.Int64)
+	db := sql.OpenDB(countingConnector{connector, queries})
+	t.Cleanup(func() { db.Close() })
+	return db, queries
+}
+
+// TestSaleUsecaseQueryCounts checks that reading a cart or a page of sales
+// costs the same number of queries however many lines or rows it has. The
+// usecase tests check the same repository calls against the memory store.
+func TestSaleUsecaseQueryCounts(t *testing.T) {
+	db, queries := openCountingDB(t)
+	ctx := context.Background()
+	logger := zerolog.Nop()
+	pharmacyRepo := repository.NewPharmacyRepository(db, logger)
+	authRepo := repository.NewAuthRepository(db, logger)
+	medicineRepo := repository.NewMedicineRepository(db, logger)
+	saleRepo := repository.NewSaleRepository(db, logger)
+	sales := usecase.NewSaleUsecase(saleRepo, medicineRepo, pharmacyRepo, repository.NewPromotionRepository(db, logger),
+		repository.NewShiftRepository(db, logger), repository.NewCustomerRepository(db, logger), nil, time.Hour, 15*time.Minute, "")
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	fail := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := domain.Pharmacy{ID: uuid.New(), Name: "Central Pharmacy", Address: "1 Main St", ReceiptSettings: domain.ReceiptSettings{PaperWidth: 80},
+		CreatedAt: now, UpdatedAt: now}
+	fail(pharmacyRepo.Create(ctx, p))
+	u := domain.User{ID: uuid.New(), PhoneNumber: "+2519" + uuid.NewString()[:8], Password: "hashed", FullName: "Test User",
+		Role: domain.RolePharmacist, PharmacyID: p.ID, CreatedAt: now, UpdatedAt: now}
+	fail(authRepo.Create(ctx, u))
+
+	// Every line is a different medicine, so nothing is looked up twice
+	variants := make([]domain.MedicineVariant, 50)
+	for i := range variants {
+		m := domain.Medicine{ID: uuid.New(), PharmacyID: p.ID, Name: fmt.Sprintf("Medicine %02d", i), TaxCategory: domain.TaxStandard,
+			CreatedAt: now, UpdatedAt: now}
+		fail(medicineRepo.Create(ctx, m))
+		v := domain.MedicineVariant{ID: uuid.New(), MedicineID: m.ID, Brand: m.Name, Barcode: "BC" + uuid.NewString()[:8], Unit: "box",
+			PricePerUnit: 250, CreatedAt: now, UpdatedAt: now}
+		lot := domain.MedicineLot{ID: uuid.New(), VariantID: v.ID, LotNumber: "L1", ExpiryDate: now.AddDate(1, 0, 0), Quantity: 200,
+			ReceivedAt: now, CreatedAt: now, UpdatedAt: now}
+		fail(medicineRepo.CreateVariant(ctx, v, []domain.MedicineLot{lot}, u.ID))
+		variants[i] = v
+	}
+
+	addToCart := func(v domain.MedicineVariant) {
+		t.Helper()
+		fail(saleRepo.AddToCart(ctx, domain.Cart{ID: uuid.New(), UserID: u.ID, PharmacyID: p.ID, MedicineVariantID: v.ID, Quantity: 1,
+			CreatedAt: now}))
+	}
+	countCart := func(lines int) int64 {
+		t.Helper()
+		queries.Store(0)
+		cart, err := sales.GetCart(ctx, string(domain.RolePharmacist), u.ID, p.ID)
+		fail(err)
+		if len(cart.Items) != lines {
+			t.Fatalf("GetCart returned %d lines, want %d", len(cart.Items), lines)
+		}
+		return queries.Load()
+	}
+	addToCart(variants[0])
+	one := countCart(1)
+	for _, v := range variants[1:] {
+		addToCart(v)
+	}
+	if fifty := countCart(50); fifty != one {
+		t.Fatalf("GetCart ran %d queries for 50 lines and %d for 1", fifty, one)
+	}
+
+	createSale := func(i int) {
+		t.Helper()
+		v := variants[i%len(variants)]
+		sale := domain.Sale{ID: uuid.New(), UserID: u.ID, PharmacyID: p.ID, TotalPrice: 250, TotalNet: 250, SaleDate: now.Add(-time.Duration(i) * time.Minute),
+			CreatedAt: now, UpdatedAt: now}
+		item := domain.SaleItem{ID: uuid.New(), SaleID: sale.ID, MedicineVariantID: v.ID, Quantity: 1, PricePerUnit: 250,
+			TaxCategory: domain.TaxStandard, Net: 250, Gross: 250, CreatedAt: now}
+		receipt := domain.Receipt{ID: uuid.New(), SaleID: sale.ID, CreatedAt: now, Content: domain.ReceiptContent{
+			Items: []domain.ReceiptItem{{Brand: v.Brand, MedicineName: v.Brand, PricePerUnit: 250, Quantity: 1, Subtotal: 250,
+				TaxCategory: domain.TaxStandard, Net: 250, Gross: 250}},
+			PharmacyID: p.ID, SaleDate: sale.SaleDate, TotalPrice: 250}}
+		fail(saleRepo.CreateSale(ctx, sale, []domain.SaleItem{item}, &receipt))
+	}
+	countSales := func(rows int) int64 {
+		t.Helper()
+		queries.Store(0)
+		page, err := sales.GetSales(ctx, string(domain.RolePharmacist), p.ID, domain.SaleFilter{Limit: 100})
+		fail(err)
+		if len(page.Sales) != rows {
+			t.Fatalf("GetSales returned %d rows, want %d", len(page.Sales), rows)
+		}
+		return queries.Load()
+	}
+	createSale(0)
+	one = countSales(1)
+	for i := 1; i < 100; i++ {
+		createSale(i)
+	}
+	if hundred := countSales(100); hundred != one {
+		t.Fatalf("GetSales ran %d queries for 100 rows and %d for 1", hundred, one)
+	}
+}
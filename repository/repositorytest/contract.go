@@ -0,0 +1,91 @@
+// Package repositorytest holds the behavioral contract shared by every
+// implementation of the repository interfaces.
+//
+// Each implementation's tests call Run with a constructor for a fresh, empty
+// set of repositories, so the in-memory and Postgres implementations are held
+// to exactly the same expectations.
+//
+// The Postgres run needs TEST_DATABASE_URL and is skipped without it. The
+// concurrency tests, such as CreateSaleConcurrent and CheckoutCartConcurrent,
+// then only run against the in-memory store, which serializes every call
+// under one lock; they check Postgres's row locking only when a database is
+// configured.
+package repositorytest
+
+import (
+	"testing"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+)
+
+// Harness bundles one set of repositories sharing a single backing store
+type Harness struct {
+	Auth          repository.AuthRepository
+	Pharmacy      repository.PharmacyRepository
+	Medicine      repository.MedicineRepository
+	Sale          repository.SaleRepository
+	Order         repository.OrderRepository
+	Inventory     repository.InventoryRepository
+	Supplier      repository.SupplierRepository
+	PurchaseOrder repository.PurchaseOrderRepository
+	Promotion     repository.PromotionRepository
+	Shift         repository.ShiftRepository
+	Customer      repository.CustomerRepository
+	// SeedOrder stores an order; OrderRepository itself is read-only
+	SeedOrder func(hospital domain.Hospital, patient domain.Patient, order domain.Order, items []domain.OrderItem) error
+}
+
+// Run runs the contract suite. newHarness must return repositories over an
+// empty store and is called once per subtest.
+func Run(t *testing.T, newHarness func(t *testing.T) Harness) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, h Harness)
+	}{
+		{"AuthUsers", testAuthUsers},
+		{"AuthTokens", testAuthTokens},
+		{"AuthPharmacists", testAuthPharmacists},
+		{"Pharmacies", testPharmacies},
+		{"Medicines", testMedicines},
+		{"Variants", testVariants},
+		{"PriceHistory", testPriceHistory},
+		{"BatchLookups", testBatchLookups},
+		{"Lots", testLots},
+		{"StockMovements", testStockMovements},
+		{"LowStock", testLowStock},
+		{"ExpiringAndWriteOffs", testExpiringAndWriteOffs},
+		{"Suppliers", testSuppliers},
+		{"Customers", testCustomers},
+		{"CustomerPurchases", testCustomerPurchases},
+		{"PurchaseOrders", testPurchaseOrders},
+		{"ReceiveGoods", testReceiveGoods},
+		{"CatalogChanges", testCatalogChanges},
+		{"SearchMedicines", testSearchMedicines},
+		{"Cart", testCart},
+		{"CartReservations", testCartReservations},
+		{"ParkedCarts", testParkedCarts},
+		{"CartAvailability", testCartAvailability},
+		{"CreateSale", testCreateSale},
+		{"CreateSaleFirstExpiryFirstOut", testCreateSaleFEFO},
+		{"CreateSaleInsufficientStock", testCreateSaleInsufficientStock},
+		{"CreateSaleConcurrent", testCreateSaleConcurrent},
+		{"CheckoutCart", testCheckoutCart},
+		{"CheckoutCartConcurrent", testCheckoutCartConcurrent},
+		{"ReceiptNumbers", testReceiptNumbers},
+		{"ReceiptChain", testReceiptChain},
+		{"SaleHistory", testSaleHistory},
+		{"SaleReturns", testSaleReturns},
+		{"IdempotencyKeys", testIdempotencyKeys},
+		{"Promotions", testPromotions},
+		{"ManualDiscounts", testManualDiscounts},
+		{"SaleDiscounts", testSaleDiscounts},
+		{"Shifts", testShifts},
+		{"Orders", testOrders},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newHarness(t))
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testAuthUsers(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RoleOwner)
+
+	got, err := h.Auth.GetByPhone(ctx, u.PhoneNumber)
+	mustNoErr(t, err)
+	if got.ID != u.ID || got.FullName != u.FullName || got.Role != u.Role || got.PharmacyID != p.ID {
+		t.Fatalf("GetByPhone returned %+v, want %+v", got, u)
+	}
+	got, err = h.Auth.GetByID(ctx, u.ID)
+	mustNoErr(t, err)
+	if got.PhoneNumber != u.PhoneNumber {
+		t.Fatalf("GetByID returned phone %q, want %q", got.PhoneNumber, u.PhoneNumber)
+	}
+
+	_, err = h.Auth.GetByPhone(ctx, "+000000000000")
+	mustErrIs(t, err, domain.ErrNotFound)
+	_, err = h.Auth.GetByID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrNotFound)
+
+	dup := u
+	dup.ID = uuid.New()
+	if err := h.Auth.Create(ctx, dup); err == nil {
+		t.Fatal("expected duplicate phone number to be rejected")
+	}
+
+	u.FullName = "Renamed"
+	u.UpdatedAt = now()
+	mustNoErr(t, h.Auth.Update(ctx, u))
+	got, err = h.Auth.GetByID(ctx, u.ID)
+	mustNoErr(t, err)
+	if got.FullName != "Renamed" {
+		t.Fatalf("Update did not persist, got name %q", got.FullName)
+	}
+
+	missing := u
+	missing.ID = uuid.New()
+	mustErrIs(t, h.Auth.Update(ctx, missing), domain.ErrNotFound)
+}
+
+func testAuthTokens(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+
+	mustNoErr(t, h.Auth.SaveRefreshToken(ctx, u.ID, "refresh-valid", time.Now().Add(time.Hour)))
+	mustNoErr(t, h.Auth.SaveRefreshToken(ctx, u.ID, "refresh-expired", time.Now().Add(-time.Hour)))
+	userID, err := h.Auth.GetRefreshToken(ctx, "refresh-valid")
+	mustNoErr(t, err)
+	if *userID != u.ID {
+		t.Fatalf("GetRefreshToken returned %v, want %v", *userID, u.ID)
+	}
+	_, err = h.Auth.GetRefreshToken(ctx, "refresh-expired")
+	mustErrIs(t, err, domain.ErrInvalidToken)
+	mustNoErr(t, h.Auth.DeleteRefreshToken(ctx, "refresh-valid"))
+	_, err = h.Auth.GetRefreshToken(ctx, "refresh-valid")
+	mustErrIs(t, err, domain.ErrInvalidToken)
+
+	mustNoErr(t, h.Auth.SaveResetToken(ctx, u.ID, "123456", time.Now().Add(time.Hour)))
+	userID, err = h.Auth.GetResetToken(ctx, "123456")
+	mustNoErr(t, err)
+	if *userID != u.ID {
+		t.Fatalf("GetResetToken returned %v, want %v", *userID, u.ID)
+	}
+	mustNoErr(t, h.Auth.DeleteResetToken(ctx, "123456"))
+	_, err = h.Auth.GetResetToken(ctx, "123456")
+	mustErrIs(t, err, domain.ErrInvalidResetToken)
+}
+
+func testAuthPharmacists(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p1 := newPharmacy(t, h)
+	p2 := newPharmacy(t, h)
+	newUser(t, h, p1.ID, domain.RoleOwner)
+	a := newUser(t, h, p1.ID, domain.RolePharmacist)
+	newUser(t, h, p2.ID, domain.RolePharmacist)
+
+	all, err := h.Auth.GetPharmacists(ctx, nil)
+	mustNoErr(t, err)
+	if len(all) != 2 {
+		t.Fatalf("expected 2 pharmacists, got %d", len(all))
+	}
+	scoped, err := h.Auth.GetPharmacists(ctx, &p1.ID)
+	mustNoErr(t, err)
+	if len(scoped) != 1 || scoped[0].ID != a.ID {
+		t.Fatalf("expected only pharmacist %v, got %+v", a.ID, scoped)
+	}
+}
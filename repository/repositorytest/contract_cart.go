@@ -0,0 +1,520 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testSearchMedicines(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	other := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Paracetamol")
+	inStock := newVariant(t, h, m.ID, "Panadol", 200, 5, now().AddDate(1, 0, 0))
+	newVariant(t, h, m.ID, "Emptydol", 200, 0, now().AddDate(1, 0, 0))
+	newVariant(t, h, m.ID, "Expiredol", 200, 5, now().AddDate(0, 0, -1))
+	otherMedicine := newMedicine(t, h, other.ID, "Paracetamol")
+	newVariant(t, h, otherMedicine.ID, "Panadol", 200, 5, now().AddDate(1, 0, 0))
+
+	byName, err := h.Sale.SearchMedicines(ctx, p.ID, "paracet")
+	mustNoErr(t, err)
+	if len(byName) != 1 || byName[0].ID != inStock.ID {
+		t.Fatalf("search by name returned %+v", byName)
+	}
+	byBrand, err := h.Sale.SearchMedicines(ctx, p.ID, "PANA")
+	mustNoErr(t, err)
+	if len(byBrand) != 1 || byBrand[0].ID != inStock.ID {
+		t.Fatalf("search by brand returned %+v", byBrand)
+	}
+	byBarcode, err := h.Sale.SearchMedicines(ctx, p.ID, inStock.Barcode)
+	mustNoErr(t, err)
+	if len(byBarcode) != 1 || byBarcode[0].ID != inStock.ID {
+		t.Fatalf("search by barcode returned %+v", byBarcode)
+	}
+	none, err := h.Sale.SearchMedicines(ctx, p.ID, "zzz")
+	mustNoErr(t, err)
+	if len(none) != 0 {
+		t.Fatalf("expected no results, got %+v", none)
+	}
+}
+
+func testCart(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Cetirizine")
+	v := newVariant(t, h, m.ID, "Zyrtec", 300, 10, now().AddDate(1, 0, 0))
+
+	first := domain.Cart{ID: uuid.New(), UserID: u.ID, PharmacyID: p.ID, MedicineVariantID: v.ID, Quantity: 2, CreatedAt: now()}
+	mustNoErr(t, h.Sale.AddToCart(ctx, first))
+	second := domain.Cart{ID: uuid.New(), UserID: u.ID, PharmacyID: p.ID, MedicineVariantID: v.ID, Quantity: 3, CreatedAt: now()}
+	mustNoErr(t, h.Sale.AddToCart(ctx, second))
+
+	cart, err := h.Sale.GetCart(ctx, u.ID)
+	mustNoErr(t, err)
+	if len(cart) != 1 {
+		t.Fatalf("expected cart upsert to keep one line, got %d", len(cart))
+	}
+	line := cart[0]
+	if line.ID != first.ID || line.Quantity != 5 {
+		t.Fatalf("expected line %v with quantity 5, got %v with %d", first.ID, line.ID, line.Quantity)
+	}
+	if line.MedicineName != "Cetirizine" || line.PricePerUnit != 300 || line.Unit != "box" {
+		t.Fatalf("GetCart did not join medicine details: %+v", line)
+	}
+
+	mustErrIs(t, h.Sale.RemoveFromCart(ctx, uuid.New()), domain.ErrCartItemNotFound)
+	mustNoErr(t, h.Sale.RemoveFromCart(ctx, line.ID))
+	cart, err = h.Sale.GetCart(ctx, u.ID)
+	mustNoErr(t, err)
+	if len(cart) != 0 {
+		t.Fatalf("expected empty cart, got %+v", cart)
+	}
+
+	mustNoErr(t, h.Sale.AddToCart(ctx, domain.Cart{ID: uuid.New(), UserID: u.ID, PharmacyID: p.ID, MedicineVariantID: v.ID, Quantity: 1, CreatedAt: now()}))
+	mustNoErr(t, h.Sale.ClearCart(ctx, u.ID))
+	cart, err = h.Sale.GetCart(ctx, u.ID)
+	mustNoErr(t, err)
+	if len(cart) != 0 {
+		t.Fatalf("expected cleared cart, got %+v", cart)
+	}
+}
+
+func testCartReservations(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Amlodipine")
+	v := newVariant(t, h, m.ID, "Norvasc", 700, 5, now().AddDate(1, 0, 0))
+	a := newUser(t, h, p.ID, domain.RolePharmacist)
+	b := newUser(t, h, p.ID, domain.RolePharmacist)
+	until := now().Add(time.Hour)
+	reserve := func(userID uuid.UUID, quantity int) error {
+		return h.Sale.AddToCart(ctx, domain.Cart{ID: uuid.New(), UserID: userID, PharmacyID: p.ID, MedicineVariantID: v.ID, Quantity: quantity,
+			ReservedUntil: &until, CreatedAt: now()})
+	}
+	available := func(want int) {
+		t.Helper()
+		got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+		mustNoErr(t, err)
+		if got.Stock != 5 || got.Available != want {
+			t.Fatalf("expected stock 5 with %d available, got %d with %d", want, got.Stock, got.Available)
+		}
+		found, err := h.Sale.SearchMedicines(ctx, p.ID, "Norvasc")
+		mustNoErr(t, err)
+		if len(found) != 1 || found[0].Stock != 5 || found[0].Available != want {
+			t.Fatalf("SearchMedicines returned %+v, want stock 5 with %d available", found, want)
+		}
+	}
+
+	// Reservations hold stock back from other carts but not from its own
+	mustNoErr(t, reserve(a.ID, 3))
+	available(2)
+	mustErrIs(t, reserve(b.ID, 3), domain.ErrInsufficientStock)
+	mustNoErr(t, reserve(b.ID, 2))
+	available(0)
+	mustErrIs(t, reserve(a.ID, 1), domain.ErrInsufficientStock)
+	cart, err := h.Sale.GetCart(ctx, a.ID)
+	mustNoErr(t, err)
+	if len(cart) != 1 || cart[0].Quantity != 3 || cart[0].ReservedUntil == nil || !cart[0].ReservedUntil.Equal(until) {
+		t.Fatalf("expected a's line of 3 reserved until %v, got %+v", until, cart)
+	}
+
+	// Nor can reserved stock be sold outside the carts holding it
+	sale, items, receipt := newSale(p.ID, newUser(t, h, p.ID, domain.RolePharmacist).ID, map[uuid.UUID]int{v.ID: 1}, 700)
+	mustErrIs(t, h.Sale.CreateSale(ctx, sale, items, &receipt), domain.ErrInsufficientStock)
+
+	// Expired reservations are released, leaving the lines in their carts
+	released, err := h.Sale.ReleaseExpiredReservations(ctx, now())
+	mustNoErr(t, err)
+	if released != 0 {
+		t.Fatalf("released %d reservations before they ran out", released)
+	}
+	released, err = h.Sale.ReleaseExpiredReservations(ctx, until)
+	mustNoErr(t, err)
+	if released != 2 {
+		t.Fatalf("expected 2 reservations released, got %d", released)
+	}
+	available(5)
+	cart, err = h.Sale.GetCart(ctx, a.ID)
+	mustNoErr(t, err)
+	if len(cart) != 1 || cart[0].ReservedUntil != nil {
+		t.Fatalf("expected a's line kept without a reservation, got %+v", cart)
+	}
+
+	// Checking out turns a cart's reservation into the sale
+	mustNoErr(t, h.Sale.ClearCart(ctx, b.ID))
+	mustNoErr(t, reserve(b.ID, 2))
+	_, err = h.Sale.CheckoutCart(ctx, b.ID, checkout(p.ID, b.ID, 700))
+	mustNoErr(t, err)
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 3 || got.Available != 3 {
+		t.Fatalf("expected 3 in stock and available after checkout, got %d and %d", got.Stock, got.Available)
+	}
+}
+
+func testParkedCarts(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Metformin")
+	v1 := newVariant(t, h, m.ID, "Glucophage", 400, 5, now().AddDate(1, 0, 0))
+	v2 := newVariant(t, h, m.ID, "Glumet", 300, 10, now().AddDate(1, 0, 0))
+	a := newUser(t, h, p.ID, domain.RolePharmacist)
+	b := newUser(t, h, p.ID, domain.RolePharmacist)
+	until := now().Add(time.Hour)
+	reserve := func(userID, variantID uuid.UUID, quantity int) error {
+		return h.Sale.AddToCart(ctx, domain.Cart{ID: uuid.New(), UserID: userID, PharmacyID: p.ID, MedicineVariantID: variantID,
+			Quantity: quantity, ReservedUntil: &until, CreatedAt: now()})
+	}
+	lines := func(userID uuid.UUID) map[uuid.UUID]domain.Cart {
+		t.Helper()
+		cart, err := h.Sale.GetCart(ctx, userID)
+		mustNoErr(t, err)
+		byVariant := make(map[uuid.UUID]domain.Cart)
+		for _, c := range cart {
+			byVariant[c.MedicineVariantID] = c
+		}
+		return byVariant
+	}
+
+	// A line's quantity can be set to anything available
+	mustNoErr(t, reserve(a.ID, v1.ID, 2))
+	mustNoErr(t, reserve(a.ID, v2.ID, 1))
+	mustNoErr(t, reserve(b.ID, v2.ID, 1))
+	line := lines(a.ID)[v1.ID]
+	line.Quantity = 4
+	mustNoErr(t, h.Sale.UpdateCartItem(ctx, line))
+	line.Quantity = 6
+	mustErrIs(t, h.Sale.UpdateCartItem(ctx, line), domain.ErrInsufficientStock)
+	mustErrIs(t, h.Sale.UpdateCartItem(ctx, domain.Cart{ID: uuid.New(), UserID: a.ID, Quantity: 1, ReservedUntil: &until}),
+		domain.ErrCartItemNotFound)
+	other := lines(b.ID)[v2.ID]
+	other.UserID = a.ID
+	mustErrIs(t, h.Sale.UpdateCartItem(ctx, other), domain.ErrCartItemNotFound)
+	if got := lines(a.ID)[v1.ID]; got.Quantity != 4 {
+		t.Fatalf("expected the line set to 4, got %d", got.Quantity)
+	}
+	mustNoErr(t, h.Sale.ClearCart(ctx, b.ID))
+
+	// Parking moves the lines aside, still reserved, leaving the cart empty
+	parked, err := h.Sale.ParkCart(ctx, domain.ParkedCart{ID: uuid.New(), UserID: a.ID, PharmacyID: p.ID, Label: "Abebe", ParkedAt: now()})
+	mustNoErr(t, err)
+	if parked.Lines != 2 || parked.Quantity != 5 {
+		t.Fatalf("expected 2 lines of 5 units parked, got %+v", parked)
+	}
+	parkedLines := lines(a.ID)
+	if len(parkedLines) != 0 {
+		t.Fatalf("expected an empty cart after parking, got %+v", parkedLines)
+	}
+	_, err = h.Sale.ParkCart(ctx, domain.ParkedCart{ID: uuid.New(), UserID: a.ID, PharmacyID: p.ID, Label: "Empty", ParkedAt: now()})
+	mustErrIs(t, err, domain.ErrCartEmpty)
+	got, err := h.Medicine.GetVariantByID(ctx, v1.ID)
+	mustNoErr(t, err)
+	if got.Available != 1 {
+		t.Fatalf("expected parked lines to keep 4 of 5 reserved, got %d available", got.Available)
+	}
+	mustErrIs(t, reserve(a.ID, v1.ID, 2), domain.ErrInsufficientStock)
+
+	// The next customer's cart is checked out without touching the parked one
+	mustNoErr(t, reserve(a.ID, v1.ID, 1))
+	mustErrIs(t, h.Sale.ResumeCart(ctx, a.ID, parked.ID, until), domain.ErrCartNotEmpty)
+	_, err = h.Sale.CheckoutCart(ctx, a.ID, checkout(p.ID, a.ID, 400))
+	mustNoErr(t, err)
+	list, err := h.Sale.GetParkedCarts(ctx, a.ID)
+	mustNoErr(t, err)
+	if len(list) != 1 || list[0].ID != parked.ID || list[0].Label != "Abebe" || list[0].Lines != 2 || list[0].Quantity != 5 ||
+		!list[0].ParkedAt.Equal(parked.ParkedAt) {
+		t.Fatalf("expected the parked cart listed unchanged, got %+v", list)
+	}
+	list, err = h.Sale.GetParkedCarts(ctx, b.ID)
+	mustNoErr(t, err)
+	if len(list) != 0 {
+		t.Fatalf("expected no parked carts for another user, got %+v", list)
+	}
+	mustErrIs(t, h.Sale.ResumeCart(ctx, b.ID, parked.ID, until), domain.ErrParkedCartNotFound)
+
+	// Resuming reserves the lines afresh where the stock is still there
+	_, err = h.Sale.ReleaseExpiredReservations(ctx, until)
+	mustNoErr(t, err)
+	mustNoErr(t, reserve(b.ID, v1.ID, 3))
+	later := now().Add(2 * time.Hour)
+	mustNoErr(t, h.Sale.ResumeCart(ctx, a.ID, parked.ID, later))
+	resumed := lines(a.ID)
+	if len(resumed) != 2 || resumed[v1.ID].ID != line.ID || resumed[v1.ID].Quantity != 4 || resumed[v2.ID].Quantity != 1 {
+		t.Fatalf("expected the parked lines back in the cart, got %+v", resumed)
+	}
+	if resumed[v1.ID].ReservedUntil != nil {
+		t.Fatalf("expected the line of 4 unreserved with 1 available, got reserved until %v", resumed[v1.ID].ReservedUntil)
+	}
+	if r := resumed[v2.ID].ReservedUntil; r == nil || !r.Equal(later) {
+		t.Fatalf("expected the line of 1 reserved until %v, got %v", later, r)
+	}
+	list, err = h.Sale.GetParkedCarts(ctx, a.ID)
+	mustNoErr(t, err)
+	if len(list) != 0 {
+		t.Fatalf("expected no parked carts after resuming, got %+v", list)
+	}
+	mustNoErr(t, h.Sale.ClearCart(ctx, a.ID))
+	mustErrIs(t, h.Sale.ResumeCart(ctx, a.ID, parked.ID, later), domain.ErrParkedCartNotFound)
+}
+
+func testCartAvailability(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	v := newVariant(t, h, newMedicine(t, h, p.ID, "Salbutamol").ID, "Ventolin", 600, 10, now().AddDate(1, 0, 0))
+	mustNoErr(t, h.Medicine.CreateLot(ctx, newLot(v.ID, "L0", now().AddDate(0, 0, -1), 5), uuid.Nil))
+	a := newUser(t, h, p.ID, domain.RolePharmacist)
+	b := newUser(t, h, p.ID, domain.RolePharmacist)
+	until, past := now().Add(time.Hour), now().Add(-time.Minute)
+	add := func(userID uuid.UUID, quantity int, reservedUntil *time.Time) error {
+		return h.Sale.AddToCart(ctx, domain.Cart{ID: uuid.New(), UserID: userID, PharmacyID: p.ID, MedicineVariantID: v.ID, Quantity: quantity,
+			ReservedUntil: reservedUntil, CreatedAt: now()})
+	}
+	line := func(userID uuid.UUID) domain.Cart {
+		t.Helper()
+		cart, err := h.Sale.GetCart(ctx, userID)
+		mustNoErr(t, err)
+		if len(cart) != 1 {
+			t.Fatalf("expected one cart line, got %+v", cart)
+		}
+		return cart[0]
+	}
+	park := func() domain.ParkedCart {
+		t.Helper()
+		parked, err := h.Sale.ParkCart(ctx, domain.ParkedCart{ID: uuid.New(), UserID: a.ID, PharmacyID: p.ID, ParkedAt: now()})
+		mustNoErr(t, err)
+		return *parked
+	}
+
+	// Of the 10 unexpired units a's own parked line holds 2 and b's line 3.
+	// An expired lot, an expired reservation and an unreserved line hold
+	// nothing back.
+	mustNoErr(t, add(a.ID, 2, &until))
+	park()
+	mustNoErr(t, add(b.ID, 3, &until))
+	mustNoErr(t, add(newUser(t, h, p.ID, domain.RolePharmacist).ID, 4, &past))
+	mustNoErr(t, add(newUser(t, h, p.ID, domain.RolePharmacist).ID, 1, nil))
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 10 || got.Available != 5 {
+		t.Fatalf("expected 10 in stock with 5 available, got %d with %d", got.Stock, got.Available)
+	}
+
+	// Adding, updating and resuming all hold a line to the same 5 units
+	mustErrIs(t, add(a.ID, 6, &until), domain.ErrInsufficientStock)
+	mustNoErr(t, add(a.ID, 2, &until))
+	mustErrIs(t, add(a.ID, 4, &until), domain.ErrInsufficientStock)
+	mustNoErr(t, add(a.ID, 3, &until))
+	l := line(a.ID)
+	l.Quantity = 6
+	mustErrIs(t, h.Sale.UpdateCartItem(ctx, l), domain.ErrInsufficientStock)
+	l.Quantity, l.ReservedUntil = 5, &past
+	mustNoErr(t, h.Sale.UpdateCartItem(ctx, l))
+
+	// Parked unreserved, the line of 5 is not reserved on resuming while b
+	// holds 4, but is once b is back down to 3
+	parked := park()
+	mustNoErr(t, add(b.ID, 1, &until))
+	later := now().Add(2 * time.Hour)
+	mustNoErr(t, h.Sale.ResumeCart(ctx, a.ID, parked.ID, later))
+	if l := line(a.ID); l.Quantity != 5 || l.ReservedUntil != nil {
+		t.Fatalf("expected the line of 5 unreserved with 4 available, got %+v", l)
+	}
+	parked = park()
+	l = line(b.ID)
+	l.Quantity, l.ReservedUntil = 3, &until
+	mustNoErr(t, h.Sale.UpdateCartItem(ctx, l))
+	mustNoErr(t, h.Sale.ResumeCart(ctx, a.ID, parked.ID, later))
+	if l := line(a.ID); l.Quantity != 5 || l.ReservedUntil == nil || !l.ReservedUntil.Equal(later) {
+		t.Fatalf("expected the line of 5 reserved until %v with 5 available, got %+v", later, l)
+	}
+	got, err = h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Available != 0 {
+		t.Fatalf("expected nothing left available, got %d", got.Available)
+	}
+}
+
+func testCheckoutCart(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Omeprazole")
+	v := newVariant(t, h, m.ID, "Losec", 500, 3, now().AddDate(1, 0, 0))
+	cartLines := func() []domain.Cart {
+		t.Helper()
+		cart, err := h.Sale.GetCart(ctx, u.ID)
+		mustNoErr(t, err)
+		return cart
+	}
+
+	_, err := h.Sale.CheckoutCart(ctx, u.ID, checkout(p.ID, u.ID, 500))
+	mustErrIs(t, err, domain.ErrCartEmpty)
+
+	// A failed checkout leaves the cart as it was. This line holds no
+	// reservation, and another cart has since reserved the stock it needs.
+	line := domain.Cart{ID: uuid.New(), UserID: u.ID, PharmacyID: p.ID, MedicineVariantID: v.ID, Quantity: 2, CreatedAt: now()}
+	mustNoErr(t, h.Sale.AddToCart(ctx, line))
+	until := now().Add(time.Hour)
+	other := domain.Cart{ID: uuid.New(), UserID: newUser(t, h, p.ID, domain.RolePharmacist).ID, PharmacyID: p.ID, MedicineVariantID: v.ID,
+		Quantity: 2, ReservedUntil: &until, CreatedAt: now()}
+	mustNoErr(t, h.Sale.AddToCart(ctx, other))
+	_, err = h.Sale.CheckoutCart(ctx, u.ID, checkout(p.ID, u.ID, 500))
+	mustErrIs(t, err, domain.ErrInsufficientStock)
+	if cart := cartLines(); len(cart) != 1 || cart[0].Quantity != 2 {
+		t.Fatalf("failed checkout changed the cart: %+v", cart)
+	}
+	mustNoErr(t, h.Sale.RemoveFromCart(ctx, other.ID))
+
+	// So does a checkout refused by the caller
+	refused := errors.New("refused")
+	_, err = h.Sale.CheckoutCart(ctx, u.ID, func([]domain.CheckoutLine) (domain.Sale, []domain.SaleItem, *domain.Receipt, error) {
+		return domain.Sale{}, nil, nil, refused
+	})
+	mustErrIs(t, err, refused)
+
+	// Checkout is handed the cart's lines with their variant and medicine as
+	// they stand, and empties the cart with the sale, so it cannot be sold
+	// twice
+	var lines []domain.CheckoutLine
+	build := checkout(p.ID, u.ID, 500)
+	sale, err := h.Sale.CheckoutCart(ctx, u.ID, func(l []domain.CheckoutLine) (domain.Sale, []domain.SaleItem, *domain.Receipt, error) {
+		lines = l
+		return build(l)
+	})
+	mustNoErr(t, err)
+	if len(lines) != 1 || lines[0].Cart.ID != line.ID || lines[0].Cart.Quantity != 2 || lines[0].Variant.ID != v.ID ||
+		lines[0].Variant.PricePerUnit != 500 || lines[0].Variant.Stock != 3 || lines[0].Medicine.ID != m.ID || lines[0].Medicine.Name != "Omeprazole" {
+		t.Fatalf("unexpected checkout lines %+v", lines)
+	}
+	if sale.ReceiptNumber == "" {
+		t.Fatalf("expected the sale numbered, got %+v", sale)
+	}
+	if cart := cartLines(); len(cart) != 0 {
+		t.Fatalf("expected checkout to empty the cart, got %+v", cart)
+	}
+	_, err = h.Sale.CheckoutCart(ctx, u.ID, checkout(p.ID, u.ID, 500))
+	mustErrIs(t, err, domain.ErrCartEmpty)
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 1 {
+		t.Fatalf("expected stock 1 after one checkout, got %d", got.Stock)
+	}
+}
+
+func testCheckoutCartConcurrent(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	scarce := newVariant(t, h, newMedicine(t, h, p.ID, "Insulin").ID, "Lantus", 900, 5, now().AddDate(1, 0, 0))
+	plenty := newVariant(t, h, newMedicine(t, h, p.ID, "Paracetamol").ID, "Panadol", 100, 100, now().AddDate(1, 0, 0))
+
+	// Many cashiers check out carts holding the last units of one variant
+	// without reservations
+	const attempts = 20
+	users := make([]domain.User, attempts)
+	for i := range users {
+		users[i] = newUser(t, h, p.ID, domain.RolePharmacist)
+		for _, v := range []domain.MedicineVariant{scarce, plenty} {
+			line := domain.Cart{ID: uuid.New(), UserID: users[i].ID, PharmacyID: p.ID, MedicineVariantID: v.ID, Quantity: 1, CreatedAt: now()}
+			mustNoErr(t, h.Sale.AddToCart(ctx, line))
+		}
+	}
+	var wg sync.WaitGroup
+	sold := make([]bool, attempts)
+	for i := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := h.Sale.CheckoutCart(ctx, users[i].ID, checkout(p.ID, users[i].ID, 500))
+			if err != nil && !errors.Is(err, domain.ErrInsufficientStock) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			sold[i] = err == nil
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for i, u := range users {
+		cart, err := h.Sale.GetCart(ctx, u.ID)
+		mustNoErr(t, err)
+		want := 2
+		if sold[i] {
+			succeeded++
+			want = 0
+		}
+		if len(cart) != want {
+			t.Fatalf("user %d (sold %v) has %d cart lines, want %d", i, sold[i], len(cart), want)
+		}
+	}
+	if succeeded != 5 {
+		t.Fatalf("expected exactly 5 checkouts to succeed, got %d", succeeded)
+	}
+	for v, want := range map[uuid.UUID]int{scarce.ID: 0, plenty.ID: 95} {
+		got, err := h.Medicine.GetVariantByID(ctx, v)
+		mustNoErr(t, err)
+		if got.Stock != want {
+			t.Fatalf("expected stock %d for %s, got %d", want, got.Brand, got.Stock)
+		}
+	}
+
+	// The same cart checked out from several terminals at once sells once
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	line := domain.Cart{ID: uuid.New(), UserID: u.ID, PharmacyID: p.ID, MedicineVariantID: plenty.ID, Quantity: 1, CreatedAt: now()}
+	mustNoErr(t, h.Sale.AddToCart(ctx, line))
+	var mu sync.Mutex
+	succeeded = 0
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := h.Sale.CheckoutCart(ctx, u.ID, checkout(p.ID, u.ID, 100))
+			if err != nil && !errors.Is(err, domain.ErrCartEmpty) {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("expected the cart to sell exactly once, got %d sales", succeeded)
+	}
+
+	// A line changed while checkout prices the cart waits for the sale, and
+	// then finds the cart gone
+	line = domain.Cart{ID: uuid.New(), UserID: u.ID, PharmacyID: p.ID, MedicineVariantID: plenty.ID, Quantity: 2, CreatedAt: now()}
+	mustNoErr(t, h.Sale.AddToCart(ctx, line))
+	updated := make(chan error, 1)
+	build := checkout(p.ID, u.ID, 100)
+	_, err := h.Sale.CheckoutCart(ctx, u.ID, func(lines []domain.CheckoutLine) (domain.Sale, []domain.SaleItem, *domain.Receipt, error) {
+		go func() {
+			change := line
+			change.Quantity = 1
+			updated <- h.Sale.UpdateCartItem(ctx, change)
+		}()
+		select {
+		case err := <-updated:
+			t.Errorf("cart line updated during checkout: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+		return build(lines)
+	})
+	mustNoErr(t, err)
+	mustErrIs(t, <-updated, domain.ErrCartItemNotFound)
+	got, err := h.Medicine.GetVariantByID(ctx, plenty.ID)
+	mustNoErr(t, err)
+	if got.Stock != 92 {
+		t.Fatalf("expected the line sold as priced, leaving 92, got %d", got.Stock)
+	}
+}
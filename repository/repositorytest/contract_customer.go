@@ -0,0 +1,121 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testCustomers(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	other := newPharmacy(t, h)
+	selam := newCustomer(t, h, p.ID, "Selam Tesfaye", "+251911223344")
+	abebe := newCustomer(t, h, p.ID, "Abebe Kebede", "+251922556677")
+	newCustomer(t, h, other.ID, "Abebe Bikila", "+251911223344")
+
+	all, err := h.Customer.Search(ctx, p.ID, "", 10)
+	mustNoErr(t, err)
+	if len(all) != 2 || all[0].ID != abebe.ID || all[1].ID != selam.ID {
+		t.Fatalf("expected Abebe then Selam, got %+v", all)
+	}
+	byName, err := h.Customer.Search(ctx, p.ID, "abebe", 10)
+	mustNoErr(t, err)
+	if len(byName) != 1 || byName[0].ID != abebe.ID {
+		t.Fatalf("search by name returned %+v", byName)
+	}
+	byPhone, err := h.Customer.Search(ctx, p.ID, "0911223", 10)
+	mustNoErr(t, err)
+	if len(byPhone) != 0 {
+		t.Fatalf("expected no match for a local number, got %+v", byPhone)
+	}
+	byPhone, err = h.Customer.Search(ctx, p.ID, "911223", 10)
+	mustNoErr(t, err)
+	if len(byPhone) != 1 || byPhone[0].ID != selam.ID {
+		t.Fatalf("search by phone returned %+v", byPhone)
+	}
+	limited, err := h.Customer.Search(ctx, p.ID, "", 1)
+	mustNoErr(t, err)
+	if len(limited) != 1 || limited[0].ID != abebe.ID {
+		t.Fatalf("expected the first customer only, got %+v", limited)
+	}
+
+	selam.Notes = "Prefers generics"
+	selam.Allergies = "Penicillin"
+	selam.UpdatedAt = now().Add(time.Minute)
+	mustNoErr(t, h.Customer.Update(ctx, selam))
+	got, err := h.Customer.GetByID(ctx, selam.ID)
+	mustNoErr(t, err)
+	if got.Notes != "Prefers generics" || got.Allergies != "Penicillin" || got.PharmacyID != p.ID || !got.UpdatedAt.Equal(selam.UpdatedAt) {
+		t.Fatalf("update not applied: %+v", got)
+	}
+	mustErrIs(t, h.Customer.Update(ctx, domain.Customer{ID: uuid.New(), FullName: "x"}), domain.ErrCustomerNotFound)
+
+	mustNoErr(t, h.Customer.Delete(ctx, abebe.ID))
+	_, err = h.Customer.GetByID(ctx, abebe.ID)
+	mustErrIs(t, err, domain.ErrCustomerNotFound)
+	mustErrIs(t, h.Customer.Delete(ctx, abebe.ID), domain.ErrCustomerNotFound)
+}
+
+func testCustomerPurchases(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	customer := newCustomer(t, h, p.ID, "Selam Tesfaye", "+251911223344")
+	m := newMedicine(t, h, p.ID, "Paracetamol")
+	panadol := newVariant(t, h, m.ID, "Panadol", 200, 20, now().AddDate(1, 0, 0))
+	tylenol := newVariant(t, h, m.ID, "Tylenol", 300, 20, now().AddDate(1, 0, 0))
+
+	first, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{panadol.ID: 2}, 200)
+	first.CustomerID = &customer.ID
+	first.SaleDate = now().Add(-time.Hour)
+	mustNoErr(t, h.Sale.CreateSale(ctx, first, items, &receipt))
+	second, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{panadol.ID: 1, tylenol.ID: 3}, 300)
+	second.CustomerID = &customer.ID
+	mustNoErr(t, h.Sale.CreateSale(ctx, second, items, &receipt))
+	walkIn, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{tylenol.ID: 1}, 300)
+	mustNoErr(t, h.Sale.CreateSale(ctx, walkIn, items, &receipt))
+
+	unknown, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{tylenol.ID: 1}, 300)
+	stranger := uuid.New()
+	unknown.CustomerID = &stranger
+	if err := h.Sale.CreateSale(ctx, unknown, items, &receipt); err == nil {
+		t.Fatal("expected a sale to an unknown customer to fail")
+	}
+
+	page, err := h.Sale.GetSales(ctx, domain.SaleFilter{PharmacyID: p.ID, CustomerID: customer.ID, Limit: 10})
+	mustNoErr(t, err)
+	if page.Total != 2 || len(page.Sales) != 2 || page.Sales[0].ID != second.ID || page.Sales[1].ID != first.ID ||
+		page.Sales[0].CustomerID == nil || *page.Sales[0].CustomerID != customer.ID {
+		t.Fatalf("expected the customer's sales, latest first, got %+v", page)
+	}
+	sale, err := h.Sale.GetSaleByID(ctx, first.ID)
+	mustNoErr(t, err)
+	if sale.CustomerID == nil || *sale.CustomerID != customer.ID {
+		t.Fatalf("expected the sale's customer, got %+v", sale.CustomerID)
+	}
+
+	saleItems, err := h.Sale.GetSaleItemsBySaleIDs(ctx, []uuid.UUID{first.ID, second.ID, uuid.New()})
+	mustNoErr(t, err)
+	if len(saleItems) != 2 || len(saleItems[first.ID]) != 1 || len(saleItems[second.ID]) != 2 ||
+		saleItems[first.ID][0].MedicineName != "Paracetamol" || saleItems[first.ID][0].Quantity != 2 {
+		t.Fatalf("GetSaleItemsBySaleIDs returned %+v", saleItems)
+	}
+	none, err := h.Sale.GetSaleItemsBySaleIDs(ctx, nil)
+	mustNoErr(t, err)
+	if len(none) != 0 {
+		t.Fatalf("expected no items for no sales, got %+v", none)
+	}
+
+	// Sales outlive the customer they were made to
+	mustNoErr(t, h.Customer.Delete(ctx, customer.ID))
+	sale, err = h.Sale.GetSaleByID(ctx, first.ID)
+	mustNoErr(t, err)
+	if sale.CustomerID != nil {
+		t.Fatalf("expected the deleted customer to be cleared from the sale, got %v", *sale.CustomerID)
+	}
+}
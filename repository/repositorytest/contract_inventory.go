@@ -0,0 +1,213 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testStockMovements(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	owner := newUser(t, h, p.ID, domain.RoleOwner)
+	m := newMedicine(t, h, p.ID, "Cetirizine")
+	v := newVariant(t, h, m.ID, "Zyrtec", 200, 10, now().AddDate(1, 0, 0))
+	other := newVariant(t, h, m.ID, "Reactine", 200, 5, now().AddDate(1, 0, 0))
+
+	lots, err := h.Medicine.GetLotsByVariantID(ctx, v.ID)
+	mustNoErr(t, err)
+	lot := lots[0]
+
+	adjust := func(lotID uuid.UUID, typ domain.StockMovementType, quantity int) (*domain.StockMovement, error) {
+		return h.Medicine.AdjustStock(ctx, domain.StockMovement{
+			ID: uuid.New(), VariantID: v.ID, LotID: lotID, Type: typ, Quantity: quantity,
+			Reason: "shelf count", UserID: owner.ID, CreatedAt: now(),
+		})
+	}
+
+	damaged, err := adjust(lot.ID, domain.StockMovementDamage, -3)
+	mustNoErr(t, err)
+	if damaged.QuantityBefore != 10 || damaged.QuantityAfter != 7 || damaged.LotNumber != "L1" {
+		t.Fatalf("unexpected damage movement %+v", damaged)
+	}
+	_, err = adjust(lot.ID, domain.StockMovementAdjustment, -8)
+	mustErrIs(t, err, domain.ErrInsufficientStock)
+	otherLots, err := h.Medicine.GetLotsByVariantID(ctx, other.ID)
+	mustNoErr(t, err)
+	_, err = adjust(otherLots[0].ID, domain.StockMovementAdjustment, 1)
+	mustErrIs(t, err, domain.ErrLotNotFound)
+
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 7 {
+		t.Fatalf("expected stock 7 after damage, got %d", got.Stock)
+	}
+
+	sale, items, receipt := newSale(p.ID, owner.ID, map[uuid.UUID]int{v.ID: 2}, 200)
+	sale.CreatedAt = now().Add(time.Second)
+	mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &receipt))
+
+	movements, err := h.Medicine.GetStockMovements(ctx, v.ID, 10, 0)
+	mustNoErr(t, err)
+	if len(movements) != 3 {
+		t.Fatalf("expected receipt, damage and sale movements, got %+v", movements)
+	}
+	sold, received := movements[0], movements[2]
+	if sold.Type != domain.StockMovementSale || sold.Quantity != -2 || sold.QuantityBefore != 7 || sold.QuantityAfter != 5 ||
+		sold.SaleID == nil || *sold.SaleID != sale.ID || sold.UserID != owner.ID {
+		t.Fatalf("unexpected sale movement %+v", sold)
+	}
+	if movements[1].ID != damaged.ID || movements[1].Reason != "shelf count" {
+		t.Fatalf("unexpected damage movement in history %+v", movements[1])
+	}
+	if received.Type != domain.StockMovementReceipt || received.Quantity != 10 || received.QuantityBefore != 0 || received.SaleID != nil {
+		t.Fatalf("unexpected receipt movement %+v", received)
+	}
+
+	page, err := h.Medicine.GetStockMovements(ctx, v.ID, 1, 1)
+	mustNoErr(t, err)
+	if len(page) != 1 || page[0].ID != damaged.ID {
+		t.Fatalf("expected second movement on page 2, got %+v", page)
+	}
+}
+
+func testLowStock(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	owner := newUser(t, h, p.ID, domain.RoleOwner)
+	newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Omeprazole")
+	low := newVariant(t, h, m.ID, "Losec", 400, 3, now().AddDate(1, 0, 0))
+	newVariant(t, h, m.ID, "Prilosec", 400, 30, now().AddDate(1, 0, 0))
+	newVariant(t, h, m.ID, "Zegerid", 400, 1, now().AddDate(1, 0, 0))
+
+	owners, err := h.Auth.GetOwners(ctx, p.ID)
+	mustNoErr(t, err)
+	if len(owners) != 1 || owners[0].ID != owner.ID {
+		t.Fatalf("expected only the owner, got %+v", owners)
+	}
+
+	low.ReorderPoint = 5
+	low.ReorderQuantity = 40
+	low.UpdatedAt = now()
+	mustNoErr(t, h.Medicine.UpdateVariant(ctx, low))
+
+	items, err := h.Medicine.GetLowStock(ctx, p.ID)
+	mustNoErr(t, err)
+	if len(items) != 1 || items[0].ID != low.ID || items[0].MedicineName != "Omeprazole" ||
+		items[0].Stock != 3 || items[0].ReorderQuantity != 40 {
+		t.Fatalf("expected only Losec to be low, got %+v", items)
+	}
+	other, err := h.Medicine.GetLowStock(ctx, newPharmacy(t, h).ID)
+	mustNoErr(t, err)
+	if len(other) != 0 {
+		t.Fatalf("low stock leaked across pharmacies: %+v", other)
+	}
+
+	claimed, err := h.Medicine.ClaimLowStockAlert(ctx, low.ID)
+	mustNoErr(t, err)
+	again, err := h.Medicine.ClaimLowStockAlert(ctx, low.ID)
+	mustNoErr(t, err)
+	if !claimed || again {
+		t.Fatalf("expected exactly one claim, got %v then %v", claimed, again)
+	}
+
+	// A released claim can be claimed again
+	mustNoErr(t, h.Medicine.ReleaseLowStockAlert(ctx, low.ID))
+	claimed, err = h.Medicine.ClaimLowStockAlert(ctx, low.ID)
+	mustNoErr(t, err)
+	if !claimed {
+		t.Fatal("expected a released alert to be claimable")
+	}
+
+	// Restocking re-arms the alert
+	mustNoErr(t, h.Medicine.CreateLot(ctx, newLot(low.ID, "L2", now().AddDate(1, 0, 0), 1), owner.ID))
+	claimed, err = h.Medicine.ClaimLowStockAlert(ctx, low.ID)
+	mustNoErr(t, err)
+	if !claimed {
+		t.Fatal("expected alert to be claimable after restock")
+	}
+}
+
+func testExpiringAndWriteOffs(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	owner := newUser(t, h, p.ID, domain.RoleOwner)
+	ibuprofen := newMedicine(t, h, p.ID, "Ibuprofen")
+	aspirin := newMedicine(t, h, p.ID, "Aspirin")
+	advil := newVariant(t, h, ibuprofen.ID, "Advil", 200, 0, time.Time{})
+	bayer := newVariant(t, h, aspirin.ID, "Bayer", 100, 0, time.Time{})
+	// Stock is valued at cost, not at its retail price
+	advil.CostPrice = 150
+	mustNoErr(t, h.Medicine.UpdateVariant(ctx, advil))
+
+	expired := newLot(advil.ID, "EXP", now().AddDate(0, 0, -2), 6)
+	soon := newLot(advil.ID, "SOON", now().AddDate(0, 1, 0), 4)
+	later := newLot(advil.ID, "LATER", now().AddDate(2, 0, 0), 9)
+	aspirinSoon := newLot(bayer.ID, "ASOON", now().AddDate(0, 0, 10), 5)
+	for _, lot := range []domain.MedicineLot{expired, soon, later, aspirinSoon} {
+		mustNoErr(t, h.Medicine.CreateLot(ctx, lot, owner.ID))
+	}
+
+	report, err := h.Inventory.GetExpiring(ctx, p.ID, now().AddDate(0, 0, 90))
+	mustNoErr(t, err)
+	if len(report) != 2 || report[0].MedicineName != "Aspirin" || report[1].MedicineName != "Ibuprofen" {
+		t.Fatalf("expected Aspirin then Ibuprofen, got %+v", report)
+	}
+	ib := report[1]
+	if len(ib.Lots) != 2 || ib.Lots[0].LotID != expired.ID || !ib.Lots[0].Expired || ib.Lots[1].Expired ||
+		ib.Quantity != 10 || ib.Value != 1500 {
+		t.Fatalf("unexpected Ibuprofen group %+v", ib)
+	}
+
+	writeOff := func(lotID uuid.UUID, quantity int) (*domain.WriteOff, error) {
+		return h.Inventory.CreateWriteOff(ctx, domain.WriteOff{
+			ID: uuid.New(), PharmacyID: p.ID, LotID: lotID, Quantity: quantity,
+			Reason: "expired on shelf", UserID: owner.ID, CreatedAt: now(),
+		})
+	}
+	_, err = writeOff(soon.ID, 0)
+	mustErrIs(t, err, domain.ErrLotNotExpired)
+	_, err = writeOff(expired.ID, 7)
+	mustErrIs(t, err, domain.ErrInsufficientStock)
+	_, err = h.Inventory.CreateWriteOff(ctx, domain.WriteOff{
+		ID: uuid.New(), PharmacyID: newPharmacy(t, h).ID, LotID: expired.ID, Reason: "not mine", UserID: owner.ID, CreatedAt: now(),
+	})
+	mustErrIs(t, err, domain.ErrLotNotFound)
+
+	w, err := writeOff(expired.ID, 0)
+	mustNoErr(t, err)
+	if w.Quantity != 6 || w.UnitValue != 150 || w.LossValue != 900 || w.VariantID != advil.ID || w.LotNumber != "EXP" {
+		t.Fatalf("unexpected write-off %+v", w)
+	}
+	_, err = writeOff(expired.ID, 0)
+	mustErrIs(t, err, domain.ErrInsufficientStock)
+
+	movements, err := h.Medicine.GetStockMovements(ctx, advil.ID, 1, 0)
+	mustNoErr(t, err)
+	if len(movements) != 1 || movements[0].Type != domain.StockMovementExpiryWriteOff || movements[0].Quantity != -6 ||
+		movements[0].QuantityBefore != 19 || movements[0].QuantityAfter != 13 {
+		t.Fatalf("expected write-off movement, got %+v", movements)
+	}
+
+	report, err = h.Inventory.GetExpiring(ctx, p.ID, now().AddDate(0, 0, 90))
+	mustNoErr(t, err)
+	if len(report[1].Lots) != 1 || report[1].Lots[0].LotID != soon.ID {
+		t.Fatalf("written-off lot still reported: %+v", report[1])
+	}
+
+	writeOffs, err := h.Inventory.GetWriteOffs(ctx, p.ID, now().Add(-time.Hour), now().Add(time.Hour))
+	mustNoErr(t, err)
+	if len(writeOffs) != 1 || writeOffs[0].ID != w.ID || writeOffs[0].LossValue != 900 || writeOffs[0].LotNumber != "EXP" {
+		t.Fatalf("unexpected write-offs %+v", writeOffs)
+	}
+	writeOffs, err = h.Inventory.GetWriteOffs(ctx, p.ID, now().Add(time.Hour), now().Add(2*time.Hour))
+	mustNoErr(t, err)
+	if len(writeOffs) != 0 {
+		t.Fatalf("expected no write-offs outside the period, got %+v", writeOffs)
+	}
+}
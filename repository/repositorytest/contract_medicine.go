@@ -0,0 +1,323 @@
+package repositorytest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testMedicines(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p1 := newPharmacy(t, h)
+	p2 := newPharmacy(t, h)
+	m := newMedicine(t, h, p1.ID, "Paracetamol")
+	newMedicine(t, h, p2.ID, "Ibuprofen")
+	v := newVariant(t, h, m.ID, "Panadol", 250, 10, now().AddDate(1, 0, 0))
+
+	got, err := h.Medicine.GetByID(ctx, m.ID)
+	mustNoErr(t, err)
+	if got.Name != m.Name || len(got.Variants) != 1 || got.Variants[0].ID != v.ID {
+		t.Fatalf("GetByID returned %+v", got)
+	}
+	all, err := h.Medicine.GetAll(ctx, p1.ID)
+	mustNoErr(t, err)
+	if len(all) != 1 || all[0].ID != m.ID || len(all[0].Variants) != 1 {
+		t.Fatalf("GetAll returned %+v", all)
+	}
+
+	m.Name = "Acetaminophen"
+	m.TaxCategory = domain.TaxZeroRated
+	m.UpdatedAt = now()
+	mustNoErr(t, h.Medicine.Update(ctx, m))
+	got, err = h.Medicine.GetByID(ctx, m.ID)
+	mustNoErr(t, err)
+	if got.Name != "Acetaminophen" || got.TaxCategory != domain.TaxZeroRated {
+		t.Fatalf("Update did not persist, got %+v", got)
+	}
+
+	count, err := h.Medicine.CountVariants(ctx, m.ID)
+	mustNoErr(t, err)
+	if count != 1 {
+		t.Fatalf("CountVariants = %d, want 1", count)
+	}
+	if err := h.Medicine.Delete(ctx, m.ID); err == nil {
+		t.Fatal("expected deleting a medicine with variants to fail")
+	}
+
+	_, err = h.Medicine.GetByID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrMedicineNotFound)
+	mustErrIs(t, h.Medicine.Update(ctx, domain.Medicine{ID: uuid.New(), Name: "x"}), domain.ErrMedicineNotFound)
+	mustErrIs(t, h.Medicine.Delete(ctx, uuid.New()), domain.ErrMedicineNotFound)
+
+	// Stock history is kept, so a variant that has held stock stays
+	mustErrIs(t, h.Medicine.DeleteVariant(ctx, v.ID), domain.ErrVariantHasHistory)
+	movements, err := h.Medicine.GetStockMovements(ctx, v.ID, 10, 0)
+	mustNoErr(t, err)
+	if len(movements) != 1 {
+		t.Fatalf("expected the variant's movement to be kept, got %+v", movements)
+	}
+
+	unstocked := newMedicine(t, h, p1.ID, "Aspirin")
+	empty := newVariant(t, h, unstocked.ID, "Bayer", 150, 0, time.Time{})
+	mustNoErr(t, h.Medicine.DeleteVariant(ctx, empty.ID))
+	mustNoErr(t, h.Medicine.Delete(ctx, unstocked.ID))
+	_, err = h.Medicine.GetByID(ctx, unstocked.ID)
+	mustErrIs(t, err, domain.ErrMedicineNotFound)
+}
+
+func testVariants(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Amoxicillin")
+	v := newVariant(t, h, m.ID, "Amoxil", 400, 20, now().AddDate(1, 0, 0))
+
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Barcode != v.Barcode || got.Stock != 20 || got.PricePerUnit != 400 || !got.ExpiryDate.Equal(v.ExpiryDate) {
+		t.Fatalf("GetVariantByID returned %+v, want %+v", got, v)
+	}
+
+	exists, err := h.Medicine.CheckBarcodeExists(ctx, v.Barcode)
+	mustNoErr(t, err)
+	if !exists {
+		t.Fatal("expected barcode to exist")
+	}
+	exists, err = h.Medicine.CheckBarcodeExists(ctx, "UNUSED0001")
+	mustNoErr(t, err)
+	if exists {
+		t.Fatal("expected barcode not to exist")
+	}
+
+	dup := v
+	dup.ID = uuid.New()
+	if err := h.Medicine.CreateVariant(ctx, dup, nil, uuid.Nil); err == nil {
+		t.Fatal("expected duplicate barcode to be rejected")
+	}
+
+	v.Brand = "Amoxil Forte"
+	v.UpdatedAt = now()
+	mustNoErr(t, h.Medicine.UpdateVariant(ctx, v))
+	variants, err := h.Medicine.GetVariantsByMedicineID(ctx, m.ID)
+	mustNoErr(t, err)
+	if len(variants) != 1 || variants[0].Brand != "Amoxil Forte" || variants[0].Stock != 20 {
+		t.Fatalf("GetVariantsByMedicineID returned %+v", variants)
+	}
+
+	_, err = h.Medicine.GetVariantByID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrVariantNotFound)
+	missing := v
+	missing.ID = uuid.New()
+	missing.Barcode = "UNUSED0002"
+	mustErrIs(t, h.Medicine.UpdateVariant(ctx, missing), domain.ErrVariantNotFound)
+	mustErrIs(t, h.Medicine.DeleteVariant(ctx, uuid.New()), domain.ErrVariantNotFound)
+}
+
+func testPriceHistory(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Metformin")
+	v := newVariant(t, h, m.ID, "Glucophage", 400, 10, now().AddDate(1, 0, 0))
+	created := v.CreatedAt
+
+	// Only changes of price join the history
+	update := func(price domain.Money, at time.Time) {
+		t.Helper()
+		v.PricePerUnit, v.UpdatedAt = price, at
+		mustNoErr(t, h.Medicine.UpdateVariant(ctx, v))
+	}
+	update(450, created.Add(time.Hour))
+	v.Brand = "Glucophage XR"
+	update(450, created.Add(2*time.Hour))
+	update(500, created.Add(3*time.Hour))
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []domain.Money
+	}{
+		{"before creation", created.Add(-time.Hour), []domain.Money{400, 450, 500}},
+		{"at creation", created, []domain.Money{400, 450, 500}},
+		{"between changes", created.Add(90 * time.Minute), []domain.Money{450, 500}},
+		{"at a change", created.Add(3 * time.Hour), []domain.Money{500}},
+		{"after the last change", created.Add(4 * time.Hour), []domain.Money{500}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := h.Medicine.GetPriceHistory(ctx, []uuid.UUID{v.ID, uuid.New()}, tt.since)
+			mustNoErr(t, err)
+			if len(history) != 1 || len(history[v.ID]) != len(tt.want) {
+				t.Fatalf("GetPriceHistory returned %+v, want prices %v", history, tt.want)
+			}
+			for i, p := range history[v.ID] {
+				if p.VariantID != v.ID || p.PricePerUnit != tt.want[i] {
+					t.Fatalf("GetPriceHistory returned %+v, want prices %v", history[v.ID], tt.want)
+				}
+			}
+		})
+	}
+
+	history, err := h.Medicine.GetPriceHistory(ctx, []uuid.UUID{v.ID}, created)
+	mustNoErr(t, err)
+	for _, tt := range []struct {
+		at   time.Time
+		want domain.Money
+	}{
+		{created.Add(-time.Minute), 400},
+		{created.Add(time.Hour - time.Second), 400},
+		{created.Add(time.Hour), 450},
+		{created.Add(5 * time.Hour), 500},
+	} {
+		if got, ok := domain.PriceAt(history[v.ID], tt.at); !ok || got != tt.want {
+			t.Fatalf("PriceAt(%s) = %d, want %d", tt.at, got, tt.want)
+		}
+	}
+}
+
+func testBatchLookups(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	m1 := newMedicine(t, h, p.ID, "Cetirizine")
+	m2 := newMedicine(t, h, p.ID, "Loratadine")
+	v1 := newVariant(t, h, m1.ID, "Zyrtec", 300, 12, now().AddDate(1, 0, 0))
+	v2 := newVariant(t, h, m2.ID, "Claritin", 350, 0, time.Time{})
+	unknown := uuid.New()
+
+	variants, err := h.Medicine.GetVariantsByIDs(ctx, []uuid.UUID{v1.ID, v2.ID, v1.ID, unknown})
+	mustNoErr(t, err)
+	if len(variants) != 2 {
+		t.Fatalf("GetVariantsByIDs returned %d variants, want 2", len(variants))
+	}
+	if got := variants[v1.ID]; got.Brand != "Zyrtec" || got.Stock != 12 || !got.ExpiryDate.Equal(v1.ExpiryDate) {
+		t.Fatalf("GetVariantsByIDs returned %+v for %s", got, v1.ID)
+	}
+	if got := variants[v2.ID]; got.MedicineID != m2.ID || got.Stock != 0 || !got.ExpiryDate.IsZero() {
+		t.Fatalf("GetVariantsByIDs returned %+v for %s", got, v2.ID)
+	}
+
+	medicines, err := h.Medicine.GetByIDs(ctx, []uuid.UUID{m1.ID, m2.ID, unknown})
+	mustNoErr(t, err)
+	if len(medicines) != 2 || medicines[m1.ID].Name != "Cetirizine" || medicines[m2.ID].PharmacyID != p.ID {
+		t.Fatalf("GetByIDs returned %+v", medicines)
+	}
+
+	variants, err = h.Medicine.GetVariantsByIDs(ctx, nil)
+	mustNoErr(t, err)
+	medicines, err = h.Medicine.GetByIDs(ctx, nil)
+	mustNoErr(t, err)
+	if len(variants) != 0 || len(medicines) != 0 {
+		t.Fatalf("empty lookups returned %d variants and %d medicines", len(variants), len(medicines))
+	}
+}
+
+func testLots(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Azithromycin")
+	v := newVariant(t, h, m.ID, "Zithromax", 600, 0, time.Time{})
+
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 0 || !got.ExpiryDate.IsZero() {
+		t.Fatalf("variant without lots should have no stock or expiry, got %+v", got)
+	}
+
+	late := newLot(v.ID, "LATE", now().AddDate(1, 0, 0), 4)
+	early := newLot(v.ID, "EARLY", now().AddDate(0, 2, 0), 3)
+	expired := newLot(v.ID, "OLD", now().AddDate(0, 0, -1), 9)
+	for _, lot := range []domain.MedicineLot{late, early, expired} {
+		mustNoErr(t, h.Medicine.CreateLot(ctx, lot, uuid.Nil))
+	}
+	if err := h.Medicine.CreateLot(ctx, newLot(v.ID, "LATE", now().AddDate(1, 0, 0), 1), uuid.Nil); err == nil {
+		t.Fatal("expected duplicate lot number to be rejected")
+	}
+
+	got, err = h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 7 {
+		t.Fatalf("expected stock from unexpired lots only (7), got %d", got.Stock)
+	}
+	if !got.ExpiryDate.Equal(early.ExpiryDate) {
+		t.Fatalf("expected expiry of earliest sellable lot %v, got %v", early.ExpiryDate, got.ExpiryDate)
+	}
+
+	lots, err := h.Medicine.GetLotsByVariantID(ctx, v.ID)
+	mustNoErr(t, err)
+	if len(lots) != 3 || lots[0].ID != expired.ID || lots[1].ID != early.ID || lots[2].ID != late.ID {
+		t.Fatalf("expected lots ordered by expiry, got %+v", lots)
+	}
+}
+
+func testCatalogChanges(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	other := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Paracetamol")
+	a := newVariant(t, h, m.ID, "Panadol", 200, 5, now().AddDate(1, 0, 0))
+	b := newVariant(t, h, m.ID, "Tylenol", 300, 0, now().AddDate(1, 0, 0))
+	otherMedicine := newMedicine(t, h, other.ID, "Paracetamol")
+	newVariant(t, h, otherMedicine.ID, "Panadol", 200, 5, now().AddDate(1, 0, 0))
+	later := now().Add(time.Hour)
+
+	all, err := h.Medicine.GetCatalogChanges(ctx, p.ID, domain.CatalogCursor{}, later, 10)
+	mustNoErr(t, err)
+	if len(all) != 2 || all[0].Name != "Paracetamol" || all[0].Deleted || all[1].Deleted {
+		t.Fatalf("expected both of the pharmacy's variants, got %+v", all)
+	}
+	if c := all[0].ChangedAt.Compare(all[1].ChangedAt); c > 0 || c == 0 && bytes.Compare(all[0].VariantID[:], all[1].VariantID[:]) > 0 {
+		t.Fatalf("expected changes in cursor order, got %+v", all)
+	}
+	for _, it := range all {
+		if it.VariantID == b.ID && (it.Brand != "Tylenol" || it.PricePerUnit != 300 || it.Barcode != b.Barcode) {
+			t.Fatalf("catalog item does not match its variant: %+v", it)
+		}
+	}
+
+	cursor := domain.CatalogCursor{ChangedAt: all[0].ChangedAt, VariantID: all[0].VariantID}
+	page, err := h.Medicine.GetCatalogChanges(ctx, p.ID, cursor, later, 10)
+	mustNoErr(t, err)
+	if len(page) != 1 || page[0].VariantID != all[1].VariantID {
+		t.Fatalf("expected the changes after the cursor, got %+v", page)
+	}
+	settled, err := h.Medicine.GetCatalogChanges(ctx, p.ID, domain.CatalogCursor{}, now().Add(-time.Minute), 10)
+	mustNoErr(t, err)
+	if len(settled) != 0 {
+		t.Fatalf("expected changes at or after before to be held back, got %+v", settled)
+	}
+
+	// Renaming the medicine changes all of its variants
+	cursor = domain.CatalogCursor{ChangedAt: all[1].ChangedAt, VariantID: all[1].VariantID}
+	m.Name = "Acetaminophen"
+	m.UpdatedAt = now().Add(time.Minute)
+	mustNoErr(t, h.Medicine.Update(ctx, m))
+	renamed, err := h.Medicine.GetCatalogChanges(ctx, p.ID, cursor, later, 10)
+	mustNoErr(t, err)
+	if len(renamed) != 2 || renamed[0].Name != "Acetaminophen" || renamed[1].Name != "Acetaminophen" || !renamed[0].ChangedAt.Equal(m.UpdatedAt) {
+		t.Fatalf("expected both variants changed by the rename, got %+v", renamed)
+	}
+
+	mustNoErr(t, h.Medicine.DeleteVariant(ctx, b.ID))
+	all, err = h.Medicine.GetCatalogChanges(ctx, p.ID, domain.CatalogCursor{}, later, 10)
+	mustNoErr(t, err)
+	if len(all) != 2 {
+		t.Fatalf("expected a variant and a tombstone, got %+v", all)
+	}
+	for _, it := range all {
+		switch it.VariantID {
+		case a.ID:
+			if it.Deleted {
+				t.Fatalf("expected %s to be live, got %+v", a.ID, it)
+			}
+		case b.ID:
+			if !it.Deleted || it.MedicineID != m.ID {
+				t.Fatalf("expected a tombstone for %s, got %+v", b.ID, it)
+			}
+		default:
+			t.Fatalf("unexpected catalog item %+v", it)
+		}
+	}
+}
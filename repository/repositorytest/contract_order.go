@@ -0,0 +1,44 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testOrders(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Insulin")
+	v := newVariant(t, h, m.ID, "Lantus", 3000, 10, now().AddDate(1, 0, 0))
+
+	hospital := domain.Hospital{ID: uuid.New(), Name: "General Hospital", CreatedAt: now(), UpdatedAt: now()}
+	patient := domain.Patient{ID: uuid.New(), FullName: "Abebe Kebede", PhoneNumber: "+251911000000", CreatedAt: now(), UpdatedAt: now()}
+	older := domain.Order{ID: uuid.New(), HospitalID: hospital.ID, PatientID: patient.ID, PharmacyID: p.ID, OrderDate: now().Add(-time.Hour), CreatedAt: now(), UpdatedAt: now()}
+	newer := domain.Order{ID: uuid.New(), HospitalID: hospital.ID, PatientID: patient.ID, PharmacyID: p.ID, OrderDate: now(), CreatedAt: now(), UpdatedAt: now()}
+	items := []domain.OrderItem{{ID: uuid.New(), OrderID: newer.ID, MedicineVariantID: v.ID, Quantity: 2, PricePerUnit: 3000, CreatedAt: now()}}
+	mustNoErr(t, h.SeedOrder(hospital, patient, older, nil))
+	mustNoErr(t, h.SeedOrder(hospital, patient, newer, items))
+
+	orders, err := h.Order.ListOrders(ctx, p.ID, 10, 0)
+	mustNoErr(t, err)
+	if len(orders) != 2 || orders[0].ID != newer.ID || orders[0].HospitalName != hospital.Name || orders[0].PatientName != patient.FullName {
+		t.Fatalf("ListOrders returned %+v", orders)
+	}
+
+	order, gotItems, gotPatient, err := h.Order.GetOrderDetails(ctx, newer.ID)
+	mustNoErr(t, err)
+	if order.ID != newer.ID || gotPatient.ID != patient.ID {
+		t.Fatalf("GetOrderDetails returned order %+v patient %+v", order, gotPatient)
+	}
+	if len(gotItems) != 1 || gotItems[0].MedicineName != "Insulin" || gotItems[0].Unit != "box" {
+		t.Fatalf("GetOrderDetails returned items %+v", gotItems)
+	}
+
+	_, _, _, err = h.Order.GetOrderDetails(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrOrderNotFound)
+}
@@ -0,0 +1,69 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testPharmacies(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+
+	got, err := h.Pharmacy.GetByID(ctx, p.ID)
+	mustNoErr(t, err)
+	if got.Name != p.Name || got.Address != p.Address {
+		t.Fatalf("GetByID returned %+v, want %+v", got, p)
+	}
+	all, err := h.Pharmacy.GetAll(ctx)
+	mustNoErr(t, err)
+	if len(all) != 1 {
+		t.Fatalf("expected 1 pharmacy, got %d", len(all))
+	}
+
+	p.Name = "Renamed Pharmacy"
+	p.UpdatedAt = now()
+	mustNoErr(t, h.Pharmacy.Update(ctx, p))
+	got, err = h.Pharmacy.GetByID(ctx, p.ID)
+	mustNoErr(t, err)
+	if got.Name != "Renamed Pharmacy" {
+		t.Fatalf("Update did not persist, got name %q", got.Name)
+	}
+
+	settings := domain.TaxSettings{VATRate: 1500, PricesIncludeTax: true}
+	mustNoErr(t, h.Pharmacy.UpdateTaxSettings(ctx, p.ID, settings, now()))
+	got, err = h.Pharmacy.GetByID(ctx, p.ID)
+	mustNoErr(t, err)
+	if got.TaxSettings != settings {
+		t.Fatalf("UpdateTaxSettings did not persist, got %+v", got.TaxSettings)
+	}
+	mustNoErr(t, h.Pharmacy.Update(ctx, p))
+	all, err = h.Pharmacy.GetAll(ctx)
+	mustNoErr(t, err)
+	if len(all) != 1 || all[0].TaxSettings != settings {
+		t.Fatalf("expected Update to keep tax settings, got %+v", all)
+	}
+
+	receiptSettings := domain.ReceiptSettings{Header: "Open 24 hours\nTIN 0012345", Footer: "Thank you", PaperWidth: 58, NumberPrefix: "MAIN",
+		YearlyReset: true}
+	mustNoErr(t, h.Pharmacy.UpdateReceiptSettings(ctx, p.ID, receiptSettings, now()))
+	got, err = h.Pharmacy.GetByID(ctx, p.ID)
+	mustNoErr(t, err)
+	if got.ReceiptSettings != receiptSettings || got.TaxSettings != settings {
+		t.Fatalf("UpdateReceiptSettings did not persist, got %+v", got)
+	}
+
+	_, err = h.Pharmacy.GetByID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrNotFound)
+	mustErrIs(t, h.Pharmacy.Update(ctx, domain.Pharmacy{ID: uuid.New(), Name: "x"}), domain.ErrNotFound)
+	mustErrIs(t, h.Pharmacy.UpdateTaxSettings(ctx, uuid.New(), settings, now()), domain.ErrNotFound)
+	mustErrIs(t, h.Pharmacy.UpdateReceiptSettings(ctx, uuid.New(), receiptSettings, now()), domain.ErrNotFound)
+	mustErrIs(t, h.Pharmacy.Delete(ctx, uuid.New()), domain.ErrNotFound)
+
+	mustNoErr(t, h.Pharmacy.Delete(ctx, p.ID))
+	_, err = h.Pharmacy.GetByID(ctx, p.ID)
+	mustErrIs(t, err, domain.ErrNotFound)
+}
@@ -0,0 +1,187 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testPromotions(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	other := newPharmacy(t, h)
+	m := newMedicine(t, h, p.ID, "Vitamin C")
+	m.Category = "vitamins"
+	mustNoErr(t, h.Medicine.Update(ctx, m))
+	gotMedicine, err := h.Medicine.GetByID(ctx, m.ID)
+	mustNoErr(t, err)
+	if gotMedicine.Category != "vitamins" {
+		t.Fatalf("expected category to persist, got %q", gotMedicine.Category)
+	}
+
+	running := newPromotion(t, h, p.ID, "Running", domain.PromotionScopeLine, now().Add(-time.Hour), now().Add(time.Hour))
+	future := newPromotion(t, h, p.ID, "Future", domain.PromotionScopeBasket, now().Add(time.Hour), now().Add(2*time.Hour))
+	newPromotion(t, h, other.ID, "Elsewhere", domain.PromotionScopeLine, now().Add(-time.Hour), now().Add(time.Hour))
+
+	got, err := h.Promotion.GetByID(ctx, running.ID)
+	mustNoErr(t, err)
+	if got.Name != "Running" || got.Rate != 1000 || got.Scope != domain.PromotionScopeLine || !got.StartsAt.Equal(running.StartsAt) || got.MedicineID != nil {
+		t.Fatalf("GetByID returned %+v", got)
+	}
+
+	all, err := h.Promotion.GetAll(ctx, p.ID)
+	mustNoErr(t, err)
+	if len(all) != 2 || all[0].ID != future.ID || all[1].ID != running.ID {
+		t.Fatalf("expected both of the pharmacy's promotions, latest starting first, got %+v", all)
+	}
+
+	runs, err := h.Promotion.GetRunning(ctx, p.ID, now())
+	mustNoErr(t, err)
+	if len(runs) != 1 || runs[0].ID != running.ID {
+		t.Fatalf("expected only the running promotion, got %+v", runs)
+	}
+
+	running.Active = false
+	running.Type = domain.PromotionFixed
+	running.Amount = 250
+	running.MedicineID = &m.ID
+	running.Category = "vitamins"
+	mustNoErr(t, h.Promotion.Update(ctx, running))
+	got, err = h.Promotion.GetByID(ctx, running.ID)
+	mustNoErr(t, err)
+	if got.Active || got.Type != domain.PromotionFixed || got.Amount != 250 || got.MedicineID == nil || *got.MedicineID != m.ID || got.Category != "vitamins" {
+		t.Fatalf("Update did not persist, got %+v", got)
+	}
+	runs, err = h.Promotion.GetRunning(ctx, p.ID, now())
+	mustNoErr(t, err)
+	if len(runs) != 0 {
+		t.Fatalf("expected inactive promotions not to run, got %+v", runs)
+	}
+	runs, err = h.Promotion.GetRunning(ctx, p.ID, future.StartsAt)
+	mustNoErr(t, err)
+	if len(runs) != 1 || runs[0].ID != future.ID {
+		t.Fatalf("expected the future promotion to run from its start, got %+v", runs)
+	}
+
+	mustNoErr(t, h.Promotion.Delete(ctx, running.ID))
+	_, err = h.Promotion.GetByID(ctx, running.ID)
+	mustErrIs(t, err, domain.ErrPromotionNotFound)
+	mustErrIs(t, h.Promotion.Delete(ctx, running.ID), domain.ErrPromotionNotFound)
+	mustErrIs(t, h.Promotion.Update(ctx, running), domain.ErrPromotionNotFound)
+}
+
+func testManualDiscounts(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	owner := newUser(t, h, p.ID, domain.RoleOwner)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+
+	older := newManualDiscount(t, h, p.ID, u.ID, domain.ManualDiscountPending, now().Add(-time.Hour))
+	newer := newManualDiscount(t, h, p.ID, u.ID, domain.ManualDiscountPending, now())
+
+	pending, err := h.Promotion.GetManualDiscounts(ctx, p.ID, domain.ManualDiscountPending)
+	mustNoErr(t, err)
+	if len(pending) != 2 || pending[0].ID != older.ID || pending[1].ID != newer.ID {
+		t.Fatalf("expected both pending discounts, oldest first, got %+v", pending)
+	}
+	approved, err := h.Promotion.GetApprovedManualDiscounts(ctx, u.ID)
+	mustNoErr(t, err)
+	if len(approved) != 0 {
+		t.Fatalf("expected no approved discounts yet, got %+v", approved)
+	}
+
+	reviewedAt := now()
+	mustNoErr(t, h.Promotion.ReviewManualDiscount(ctx, older.ID, domain.ManualDiscountApproved, owner.ID, reviewedAt))
+	mustNoErr(t, h.Promotion.ReviewManualDiscount(ctx, newer.ID, domain.ManualDiscountRejected, owner.ID, reviewedAt))
+	mustErrIs(t, h.Promotion.ReviewManualDiscount(ctx, older.ID, domain.ManualDiscountRejected, owner.ID, reviewedAt), domain.ErrManualDiscountReviewed)
+	mustErrIs(t, h.Promotion.ReviewManualDiscount(ctx, uuid.New(), domain.ManualDiscountApproved, owner.ID, reviewedAt), domain.ErrManualDiscountNotFound)
+
+	got, err := h.Promotion.GetManualDiscountByID(ctx, older.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.ManualDiscountApproved || got.ReviewedBy == nil || *got.ReviewedBy != owner.ID || got.ReviewedAt == nil ||
+		!got.ReviewedAt.Equal(reviewedAt) || got.Amount != 100 || got.SaleID != nil {
+		t.Fatalf("GetManualDiscountByID returned %+v", got)
+	}
+	_, err = h.Promotion.GetManualDiscountByID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrManualDiscountNotFound)
+
+	all, err := h.Promotion.GetManualDiscounts(ctx, p.ID, "")
+	mustNoErr(t, err)
+	if len(all) != 2 {
+		t.Fatalf("expected every discount with no status filter, got %+v", all)
+	}
+	approved, err = h.Promotion.GetApprovedManualDiscounts(ctx, u.ID)
+	mustNoErr(t, err)
+	if len(approved) != 1 || approved[0].ID != older.ID {
+		t.Fatalf("expected only the approved discount, got %+v", approved)
+	}
+}
+
+func testSaleDiscounts(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RoleOwner)
+	m := newMedicine(t, h, p.ID, "Zinc")
+	v := newVariant(t, h, m.ID, "Zincovit", 500, 10, now().AddDate(1, 0, 0))
+
+	promotion := newPromotion(t, h, p.ID, "Weekend", domain.PromotionScopeLine, now().Add(-time.Hour), now().Add(time.Hour))
+	manual := newManualDiscount(t, h, p.ID, u.ID, domain.ManualDiscountApproved, now())
+	pending := newManualDiscount(t, h, p.ID, u.ID, domain.ManualDiscountPending, now())
+
+	sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 2}, 500)
+	items[0].Discount = 200
+	items[0].Net, items[0].Gross = 800, 800
+	sale.TotalDiscount, sale.TotalNet, sale.TotalPrice = 200, 800, 800
+	sale.Discounts = []domain.AppliedDiscount{
+		{PromotionID: &promotion.ID, Name: promotion.Name, Scope: domain.PromotionScopeLine, Amount: 100},
+		{ManualDiscountID: &manual.ID, Name: manual.Reason, Scope: domain.PromotionScopeBasket, Amount: 100},
+	}
+	mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &receipt))
+
+	stored, err := h.Sale.GetSaleByID(ctx, sale.ID)
+	mustNoErr(t, err)
+	if stored.TotalDiscount != 200 || len(stored.Discounts) != 2 || stored.Discounts[0].PromotionID == nil || *stored.Discounts[0].PromotionID != promotion.ID ||
+		stored.Discounts[1].ManualDiscountID == nil || *stored.Discounts[1].ManualDiscountID != manual.ID || stored.Discounts[1].Amount != 100 {
+		t.Fatalf("GetSaleByID returned %+v", stored)
+	}
+	saleItems, err := h.Sale.GetSaleItems(ctx, sale.ID)
+	mustNoErr(t, err)
+	if len(saleItems) != 1 || saleItems[0].Discount != 200 {
+		t.Fatalf("GetSaleItems returned %+v", saleItems)
+	}
+
+	used, err := h.Promotion.GetManualDiscountByID(ctx, manual.ID)
+	mustNoErr(t, err)
+	if used.SaleID == nil || *used.SaleID != sale.ID {
+		t.Fatalf("expected the manual discount to be marked used, got %+v", used)
+	}
+	approved, err := h.Promotion.GetApprovedManualDiscounts(ctx, u.ID)
+	mustNoErr(t, err)
+	if len(approved) != 0 {
+		t.Fatalf("expected used discounts not to be offered again, got %+v", approved)
+	}
+
+	// Manual discounts are single use and must be approved
+	for _, d := range []domain.ManualDiscount{manual, pending} {
+		again, againItems, againReceipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 1}, 500)
+		again.Discounts = []domain.AppliedDiscount{{ManualDiscountID: &d.ID, Name: d.Reason, Scope: domain.PromotionScopeBasket, Amount: 100}}
+		mustErrIs(t, h.Sale.CreateSale(ctx, again, againItems, &againReceipt), domain.ErrManualDiscountUsed)
+	}
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 8 {
+		t.Fatalf("expected rejected sales to leave stock alone, got %d", got.Stock)
+	}
+
+	// Deleting a promotion keeps what it gave on past sales
+	mustNoErr(t, h.Promotion.Delete(ctx, promotion.ID))
+	stored, err = h.Sale.GetSaleByID(ctx, sale.ID)
+	mustNoErr(t, err)
+	if len(stored.Discounts) != 2 || stored.Discounts[0].PromotionID != nil || stored.Discounts[0].Name != "Weekend" {
+		t.Fatalf("expected the deleted promotion's discount to remain, got %+v", stored.Discounts)
+	}
+}
@@ -0,0 +1,169 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testPurchaseOrders(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	owner := newUser(t, h, p.ID, domain.RoleOwner)
+	supplier := newSupplier(t, h, p.ID, "Alpha Distributors")
+	m := newMedicine(t, h, p.ID, "Amoxicillin")
+	amoxil := newVariant(t, h, m.ID, "Amoxil", 300, 0, time.Time{})
+	moxatag := newVariant(t, h, m.ID, "Moxatag", 400, 0, time.Time{})
+
+	po := newPurchaseOrder(t, h, p.ID, supplier.ID, owner.ID, 10, 150, amoxil.ID, moxatag.ID)
+	got, err := h.PurchaseOrder.GetByID(ctx, po.ID)
+	mustNoErr(t, err)
+	if got.SupplierName != "Alpha Distributors" || got.Status != domain.PurchaseOrderDraft || got.TotalCost != 3000 ||
+		len(got.Items) != 2 || got.Items[0].VariantID != amoxil.ID || got.Items[1].VariantID != moxatag.ID ||
+		got.Items[0].MedicineName != "Amoxicillin" || got.Items[0].Brand != "Amoxil" || got.Items[0].Unit != "box" {
+		t.Fatalf("unexpected purchase order %+v", got)
+	}
+	_, err = h.PurchaseOrder.GetByID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrPurchaseOrderNotFound)
+
+	po.Notes = "urgent"
+	po.Items = []domain.PurchaseOrderItem{{ID: uuid.New(), PurchaseOrderID: po.ID, VariantID: moxatag.ID, QuantityOrdered: 4, UnitCost: 200}}
+	mustNoErr(t, h.PurchaseOrder.Update(ctx, po))
+	got, err = h.PurchaseOrder.GetByID(ctx, po.ID)
+	mustNoErr(t, err)
+	if got.Notes != "urgent" || len(got.Items) != 1 || got.Items[0].VariantID != moxatag.ID || got.TotalCost != 800 {
+		t.Fatalf("update not applied: %+v", got)
+	}
+	if err := h.Medicine.DeleteVariant(ctx, moxatag.ID); err == nil {
+		t.Fatal("expected deleting an ordered variant to fail")
+	}
+
+	mustNoErr(t, h.PurchaseOrder.UpdateStatus(ctx, po.ID, domain.PurchaseOrderDraft, domain.PurchaseOrderSent, now()))
+	mustErrIs(t, h.PurchaseOrder.UpdateStatus(ctx, po.ID, domain.PurchaseOrderDraft, domain.PurchaseOrderSent, now()),
+		domain.ErrInvalidPurchaseOrderStatus)
+	mustErrIs(t, h.PurchaseOrder.UpdateStatus(ctx, uuid.New(), domain.PurchaseOrderDraft, domain.PurchaseOrderSent, now()),
+		domain.ErrPurchaseOrderNotFound)
+	mustErrIs(t, h.PurchaseOrder.Update(ctx, po), domain.ErrInvalidPurchaseOrderStatus)
+
+	newPurchaseOrder(t, h, p.ID, supplier.ID, owner.ID, 1, 1, amoxil.ID)
+	other := newPharmacy(t, h)
+	newPurchaseOrder(t, h, other.ID, newSupplier(t, h, other.ID, "Other").ID, owner.ID, 1, 1, amoxil.ID)
+
+	orders, err := h.PurchaseOrder.GetAll(ctx, p.ID, "", 10, 0)
+	mustNoErr(t, err)
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 purchase orders, got %+v", orders)
+	}
+	orders, err = h.PurchaseOrder.GetAll(ctx, p.ID, domain.PurchaseOrderSent, 10, 0)
+	mustNoErr(t, err)
+	if len(orders) != 1 || orders[0].ID != po.ID || orders[0].TotalCost != 800 || orders[0].SupplierName != "Alpha Distributors" {
+		t.Fatalf("expected only the sent order, got %+v", orders)
+	}
+	orders, err = h.PurchaseOrder.GetAll(ctx, p.ID, "", 1, 1)
+	mustNoErr(t, err)
+	if len(orders) != 1 {
+		t.Fatalf("expected one paginated order, got %+v", orders)
+	}
+}
+
+func testReceiveGoods(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	owner := newUser(t, h, p.ID, domain.RoleOwner)
+	supplier := newSupplier(t, h, p.ID, "Alpha Distributors")
+	m := newMedicine(t, h, p.ID, "Amoxicillin")
+	expiry := now().AddDate(1, 0, 0)
+	amoxil := newVariant(t, h, m.ID, "Amoxil", 300, 5, expiry)
+	moxatag := newVariant(t, h, m.ID, "Moxatag", 400, 0, time.Time{})
+
+	amoxil.CostPrice = 100
+	mustNoErr(t, h.Medicine.UpdateVariant(ctx, amoxil))
+
+	po := newPurchaseOrder(t, h, p.ID, supplier.ID, owner.ID, 10, 150, amoxil.ID, moxatag.ID)
+	amoxilItem, moxatagItem := po.Items[0].ID, po.Items[1].ID
+	receive := func(lines ...domain.GoodsReceiptLine) (*domain.GoodsReceipt, error) {
+		for i := range lines {
+			lines[i].ID = uuid.New()
+			if lines[i].UnitCost == 0 {
+				lines[i].UnitCost = 150
+			}
+		}
+		return h.PurchaseOrder.Receive(ctx, domain.GoodsReceipt{
+			ID: uuid.New(), PurchaseOrderID: po.ID, ReceivedBy: owner.ID, ReceivedAt: now(), Lines: lines,
+		})
+	}
+
+	_, err := receive(domain.GoodsReceiptLine{PurchaseOrderItemID: amoxilItem, LotNumber: "L1", ExpiryDate: expiry, Quantity: 1})
+	mustErrIs(t, err, domain.ErrInvalidPurchaseOrderStatus)
+	mustNoErr(t, h.PurchaseOrder.UpdateStatus(ctx, po.ID, domain.PurchaseOrderDraft, domain.PurchaseOrderSent, now()))
+
+	_, err = receive(domain.GoodsReceiptLine{PurchaseOrderItemID: uuid.New(), LotNumber: "L1", ExpiryDate: expiry, Quantity: 1})
+	mustErrIs(t, err, domain.ErrPurchaseOrderItemNotFound)
+	_, err = receive(domain.GoodsReceiptLine{PurchaseOrderItemID: amoxilItem, LotNumber: "L1", ExpiryDate: expiry, Quantity: 11})
+	mustErrIs(t, err, domain.ErrOverReceipt)
+	_, err = receive(
+		domain.GoodsReceiptLine{PurchaseOrderItemID: moxatagItem, LotNumber: "M1", ExpiryDate: expiry, Quantity: 2},
+		domain.GoodsReceiptLine{PurchaseOrderItemID: amoxilItem, LotNumber: "L1", ExpiryDate: expiry.AddDate(0, 1, 0), Quantity: 1},
+	)
+	mustErrIs(t, err, domain.ErrLotNumberTaken)
+	lots, err := h.Medicine.GetLotsByVariantID(ctx, moxatag.ID)
+	mustNoErr(t, err)
+	if len(lots) != 0 {
+		t.Fatalf("failed receipt left lots behind: %+v", lots)
+	}
+
+	// An existing lot number tops up that lot; a new one creates a lot
+	receipt, err := receive(
+		domain.GoodsReceiptLine{PurchaseOrderItemID: amoxilItem, LotNumber: "L1", ExpiryDate: expiry, Quantity: 4},
+		domain.GoodsReceiptLine{PurchaseOrderItemID: moxatagItem, LotNumber: "M1", ExpiryDate: expiry, Quantity: 10, UnitCost: 125},
+	)
+	mustNoErr(t, err)
+	if len(receipt.Lines) != 2 || receipt.Lines[0].VariantID != amoxil.ID || receipt.Lines[1].VariantID != moxatag.ID ||
+		receipt.Lines[0].LotID == uuid.Nil || receipt.Lines[1].UnitCost != 125 {
+		t.Fatalf("unexpected receipt %+v", receipt)
+	}
+	lots, err = h.Medicine.GetLotsByVariantID(ctx, amoxil.ID)
+	mustNoErr(t, err)
+	if len(lots) != 1 || lots[0].Quantity != 9 || lots[0].ID != receipt.Lines[0].LotID {
+		t.Fatalf("expected existing lot topped up to 9, got %+v", lots)
+	}
+	v, err := h.Medicine.GetVariantByID(ctx, moxatag.ID)
+	mustNoErr(t, err)
+	if v.Stock != 10 || !v.ExpiryDate.Equal(expiry) || v.CostPrice != 125 {
+		t.Fatalf("expected 10 received into a new lot at 1.25, got %+v", v)
+	}
+	// 5 on hand at 1 plus 4 received at 1.5
+	v, err = h.Medicine.GetVariantByID(ctx, amoxil.ID)
+	mustNoErr(t, err)
+	if v.CostPrice != 122 {
+		t.Fatalf("expected weighted-average cost 11.00/9 rounded to 1.22, got %v", v.CostPrice)
+	}
+	movements, err := h.Medicine.GetStockMovements(ctx, amoxil.ID, 1, 0)
+	mustNoErr(t, err)
+	if len(movements) != 1 || movements[0].Type != domain.StockMovementReceipt || movements[0].Quantity != 4 ||
+		movements[0].QuantityBefore != 5 || movements[0].QuantityAfter != 9 || movements[0].UserID != owner.ID {
+		t.Fatalf("expected receipt movement, got %+v", movements)
+	}
+
+	got, err := h.PurchaseOrder.GetByID(ctx, po.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.PurchaseOrderPartiallyReceived || got.Items[0].QuantityReceived != 4 || got.Items[1].QuantityReceived != 10 {
+		t.Fatalf("expected partially received, got %+v", got)
+	}
+	_, err = receive(domain.GoodsReceiptLine{PurchaseOrderItemID: moxatagItem, LotNumber: "M2", ExpiryDate: expiry, Quantity: 1})
+	mustErrIs(t, err, domain.ErrOverReceipt)
+
+	_, err = receive(domain.GoodsReceiptLine{PurchaseOrderItemID: amoxilItem, LotNumber: "L2", ExpiryDate: expiry.AddDate(0, 6, 0), Quantity: 6})
+	mustNoErr(t, err)
+	got, err = h.PurchaseOrder.GetByID(ctx, po.ID)
+	mustNoErr(t, err)
+	if got.Status != domain.PurchaseOrderReceived {
+		t.Fatalf("expected received, got %s", got.Status)
+	}
+	_, err = receive(domain.GoodsReceiptLine{PurchaseOrderItemID: amoxilItem, LotNumber: "L3", ExpiryDate: expiry, Quantity: 1})
+	mustErrIs(t, err, domain.ErrInvalidPurchaseOrderStatus)
+}
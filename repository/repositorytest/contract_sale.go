@@ -0,0 +1,646 @@
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testCreateSale(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Omeprazole")
+	v := newVariant(t, h, m.ID, "Losec", 500, 10, now().AddDate(1, 0, 0))
+
+	sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 4}, 500)
+	items[0].UnitCost = 300
+	// 15% VAT added on top of the 20.00 line
+	items[0].TaxRate, items[0].Tax, items[0].Gross = 1500, 300, 2300
+	sale.TotalTax, sale.TotalPrice = 300, 2300
+	sale.Payments = []domain.Payment{
+		{ID: uuid.New(), SaleID: sale.ID, Method: domain.PaymentCard, Amount: 1000, Reference: "slip-1", CreatedAt: now()},
+		{ID: uuid.New(), SaleID: sale.ID, Method: domain.PaymentCash, Amount: 1500, Change: 200, CreatedAt: now()},
+	}
+	receipt.Content.TotalCost = 1200
+	receipt.Content.Margin = 800
+	mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &receipt))
+
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 6 {
+		t.Fatalf("expected stock 6 after sale, got %d", got.Stock)
+	}
+
+	// Recording the same sale again changes nothing
+	mustErrIs(t, h.Sale.CreateSale(ctx, sale, items, &receipt), domain.ErrSaleExists)
+	got, err = h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 6 {
+		t.Fatalf("expected stock 6 after duplicate sale, got %d", got.Stock)
+	}
+
+	storedSale, err := h.Sale.GetSaleByID(ctx, sale.ID)
+	mustNoErr(t, err)
+	if storedSale.TotalPrice != 2300 || storedSale.TotalNet != 2000 || storedSale.TotalTax != 300 || storedSale.UserID != u.ID || storedSale.PharmacyID != p.ID {
+		t.Fatalf("GetSaleByID returned %+v", storedSale)
+	}
+	if len(storedSale.Payments) != 2 || storedSale.Payments[0].Method != domain.PaymentCard || storedSale.Payments[0].Reference != "slip-1" ||
+		storedSale.Payments[1].Method != domain.PaymentCash || storedSale.Payments[1].Amount != 1500 || storedSale.Payments[1].Change != 200 ||
+		storedSale.Change != 200 {
+		t.Fatalf("expected payments in the order tendered, got %+v", storedSale.Payments)
+	}
+	storedReceipt, err := h.Sale.GetReceiptBySaleID(ctx, sale.ID)
+	mustNoErr(t, err)
+	if storedReceipt.ID != receipt.ID || len(storedReceipt.Content.Items) != 1 || storedReceipt.Content.TotalPrice != 2000 ||
+		storedReceipt.Content.Margin != 800 {
+		t.Fatalf("GetReceiptBySaleID returned %+v", storedReceipt)
+	}
+
+	saleItems, err := h.Sale.GetSaleItems(ctx, sale.ID)
+	mustNoErr(t, err)
+	if len(saleItems) != 1 || saleItems[0].Quantity != 4 || saleItems[0].MedicineName != "Omeprazole" || saleItems[0].UnitCost != 300 ||
+		saleItems[0].TaxCategory != domain.TaxStandard || saleItems[0].TaxRate != 1500 || saleItems[0].Net != 2000 || saleItems[0].Tax != 300 ||
+		saleItems[0].Gross != 2300 {
+		t.Fatalf("GetSaleItems returned %+v", saleItems)
+	}
+
+	_, err = h.Sale.GetSaleByID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrSaleNotFound)
+	_, err = h.Sale.GetReceiptBySaleID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrSaleNotFound)
+}
+
+func testCreateSaleFEFO(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Losartan")
+	v := newVariant(t, h, m.ID, "Cozaar", 300, 0, time.Time{})
+
+	late := newLot(v.ID, "LATE", now().AddDate(1, 0, 0), 10)
+	early := newLot(v.ID, "EARLY", now().AddDate(0, 1, 0), 2)
+	expired := newLot(v.ID, "OLD", now().AddDate(0, 0, -1), 50)
+	for _, lot := range []domain.MedicineLot{late, early, expired} {
+		mustNoErr(t, h.Medicine.CreateLot(ctx, lot, uuid.Nil))
+	}
+
+	sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 5}, 300)
+	mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &receipt))
+
+	allocations := items[0].Lots
+	if len(allocations) != 2 ||
+		allocations[0].LotID != early.ID || allocations[0].Quantity != 2 ||
+		allocations[1].LotID != late.ID || allocations[1].Quantity != 3 {
+		t.Fatalf("expected 2 from EARLY then 3 from LATE, got %+v", allocations)
+	}
+
+	lots, err := h.Medicine.GetLotsByVariantID(ctx, v.ID)
+	mustNoErr(t, err)
+	quantities := map[uuid.UUID]int{}
+	for _, l := range lots {
+		quantities[l.ID] = l.Quantity
+	}
+	if quantities[expired.ID] != 50 || quantities[early.ID] != 0 || quantities[late.ID] != 7 {
+		t.Fatalf("unexpected lot quantities after sale: %+v", quantities)
+	}
+
+	stored, err := h.Sale.GetReceiptBySaleID(ctx, sale.ID)
+	mustNoErr(t, err)
+	if len(stored.Content.Items) != 1 || len(stored.Content.Items[0].Lots) != 2 || stored.Content.Items[0].Lots[0].LotNumber != "EARLY" {
+		t.Fatalf("receipt does not trace lots: %+v", stored.Content.Items)
+	}
+
+	// Expired lots are never sold, even when they would cover the request
+	sale, items, receipt = newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 8}, 300)
+	mustErrIs(t, h.Sale.CreateSale(ctx, sale, items, &receipt), domain.ErrInsufficientStock)
+}
+
+func testCreateSaleInsufficientStock(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Metformin")
+	plenty := newVariant(t, h, m.ID, "Glucophage", 100, 10, now().AddDate(1, 0, 0))
+	scarce := newVariant(t, h, m.ID, "Glumetza", 100, 1, now().AddDate(1, 0, 0))
+
+	sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{plenty.ID: 3, scarce.ID: 2}, 100)
+	mustErrIs(t, h.Sale.CreateSale(ctx, sale, items, &receipt), domain.ErrInsufficientStock)
+
+	got, err := h.Medicine.GetVariantByID(ctx, plenty.ID)
+	mustNoErr(t, err)
+	if got.Stock != 10 {
+		t.Fatalf("failed sale must not deduct stock, got %d", got.Stock)
+	}
+	_, err = h.Sale.GetSaleByID(ctx, sale.ID)
+	mustErrIs(t, err, domain.ErrSaleNotFound)
+}
+
+func testCreateSaleConcurrent(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Salbutamol")
+	v := newVariant(t, h, m.ID, "Ventolin", 800, 5, now().AddDate(1, 0, 0))
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 1}, 800)
+			err := h.Sale.CreateSale(ctx, sale, items, &receipt)
+			if err != nil && !errors.Is(err, domain.ErrInsufficientStock) {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 5 {
+		t.Fatalf("expected exactly 5 sales to succeed, got %d", succeeded)
+	}
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 0 {
+		t.Fatalf("expected stock 0, got %d", got.Stock)
+	}
+}
+
+func testReceiptNumbers(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	other := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	otherUser := newUser(t, h, other.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Cetirizine")
+	v := newVariant(t, h, m.ID, "Zyrtec", 250, 100, now().AddDate(2, 0, 0))
+	otherMedicine := newMedicine(t, h, other.ID, "Cetirizine")
+	otherVariant := newVariant(t, h, otherMedicine.ID, "Zyrtec", 250, 100, now().AddDate(2, 0, 0))
+
+	sell := func(pharmacyID, userID, variantID uuid.UUID, quantity int, saleDate time.Time) (domain.Sale, domain.Receipt, error) {
+		sale, items, receipt := newSale(pharmacyID, userID, map[uuid.UUID]int{variantID: quantity}, 250)
+		sale.SaleDate, receipt.Content.SaleDate = saleDate, saleDate
+		err := h.Sale.CreateSale(ctx, sale, items, &receipt)
+		return sale, receipt, err
+	}
+	expectNumber := func(pharmacyID, userID, variantID uuid.UUID, saleDate time.Time, want string) domain.Sale {
+		t.Helper()
+		sale, receipt, err := sell(pharmacyID, userID, variantID, 1, saleDate)
+		mustNoErr(t, err)
+		if receipt.Number != want {
+			t.Fatalf("expected receipt number %q, got %q", want, receipt.Number)
+		}
+		stored, err := h.Sale.GetSaleByID(ctx, sale.ID)
+		mustNoErr(t, err)
+		if stored.ReceiptNumber != want {
+			t.Fatalf("expected sale to carry receipt number %q, got %q", want, stored.ReceiptNumber)
+		}
+		return sale
+	}
+
+	first := expectNumber(p.ID, u.ID, v.ID, now(), "000001")
+	expectNumber(p.ID, u.ID, v.ID, now(), "000002")
+	// A sale that fails does not use up a number
+	_, _, err := sell(p.ID, u.ID, v.ID, 1000, now())
+	mustErrIs(t, err, domain.ErrInsufficientStock)
+	expectNumber(p.ID, u.ID, v.ID, now(), "000003")
+	// Each pharmacy numbers its own receipts
+	expectNumber(other.ID, otherUser.ID, otherVariant.ID, now(), "000001")
+
+	found, err := h.Sale.GetReceiptByNumber(ctx, p.ID, "000001")
+	mustNoErr(t, err)
+	if found.SaleID != first.ID || found.Number != "000001" || len(found.Content.Items) != 1 {
+		t.Fatalf("GetReceiptByNumber returned %+v", found)
+	}
+	bySale, err := h.Sale.GetReceiptBySaleID(ctx, first.ID)
+	mustNoErr(t, err)
+	if bySale.Number != "000001" {
+		t.Fatalf("expected GetReceiptBySaleID to return the number, got %q", bySale.Number)
+	}
+	_, err = h.Sale.GetReceiptByNumber(ctx, other.ID, "000002")
+	mustErrIs(t, err, domain.ErrReceiptNotFound)
+	_, err = h.Sale.GetReceiptByNumber(ctx, p.ID, "000099")
+	mustErrIs(t, err, domain.ErrReceiptNotFound)
+
+	// With a yearly reset each year numbers from 1 again
+	settings := domain.ReceiptSettings{PaperWidth: 80, NumberPrefix: "MAIN", YearlyReset: true}
+	mustNoErr(t, h.Pharmacy.UpdateReceiptSettings(ctx, p.ID, settings, now()))
+	thisYear := now().UTC().Year()
+	nextYear := time.Date(thisYear+1, time.January, 1, 9, 0, 0, 0, time.UTC)
+	expectNumber(p.ID, u.ID, v.ID, now(), fmt.Sprintf("MAIN-%d-000001", thisYear))
+	expectNumber(p.ID, u.ID, v.ID, nextYear, fmt.Sprintf("MAIN-%d-000001", thisYear+1))
+	expectNumber(p.ID, u.ID, v.ID, now(), fmt.Sprintf("MAIN-%d-000002", thisYear))
+	// Without one the original series carries on
+	settings.YearlyReset = false
+	mustNoErr(t, h.Pharmacy.UpdateReceiptSettings(ctx, p.ID, settings, now()))
+	expectNumber(p.ID, u.ID, v.ID, now(), "MAIN-000004")
+
+	// Concurrent checkouts take consecutive numbers, even when some fail
+	q := newPharmacy(t, h)
+	qUser := newUser(t, h, q.ID, domain.RolePharmacist)
+	qMedicine := newMedicine(t, h, q.ID, "Loratadine")
+	qVariant := newVariant(t, h, qMedicine.ID, "Claritin", 250, 15, now().AddDate(2, 0, 0))
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	numbers := map[string]bool{}
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, receipt, err := sell(q.ID, qUser.ID, qVariant.ID, 1, now())
+			if err != nil && !errors.Is(err, domain.ErrInsufficientStock) {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				numbers[receipt.Number] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(numbers) != 15 {
+		t.Fatalf("expected 15 distinct receipt numbers, got %d: %v", len(numbers), numbers)
+	}
+	for n := 1; n <= 15; n++ {
+		if !numbers[fmt.Sprintf("%06d", n)] {
+			t.Fatalf("receipt number %06d missing from %v", n, numbers)
+		}
+	}
+	// and are chained in the order they committed
+	chain, err := h.Sale.GetReceiptChain(ctx, q.ID, 0, 100)
+	mustNoErr(t, err)
+	head, err := h.Sale.GetReceiptChainHead(ctx, q.ID)
+	mustNoErr(t, err)
+	report := domain.ChainReport{PharmacyID: q.ID}
+	for _, r := range chain {
+		mustNoErr(t, report.Check(r))
+	}
+	report.Finish(*head)
+	if !report.Valid || report.Checked != 15 {
+		t.Fatalf("expected a valid chain of 15 receipts, got %+v", report)
+	}
+}
+
+func testReceiptChain(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	other := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	otherUser := newUser(t, h, other.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Paracetamol")
+	v := newVariant(t, h, m.ID, "Panadol", 150, 100, now().AddDate(2, 0, 0))
+	otherMedicine := newMedicine(t, h, other.ID, "Paracetamol")
+	otherVariant := newVariant(t, h, otherMedicine.ID, "Panadol", 150, 100, now().AddDate(2, 0, 0))
+
+	head, err := h.Sale.GetReceiptChainHead(ctx, p.ID)
+	mustNoErr(t, err)
+	if head.Position != 0 || head.Hash != "" {
+		t.Fatalf("expected an empty chain, got %+v", head)
+	}
+
+	var receipts []domain.Receipt
+	for i := 0; i < 3; i++ {
+		sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 1 + i}, 150)
+		mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &receipt))
+		receipts = append(receipts, receipt)
+	}
+	sale, items, otherReceipt := newSale(other.ID, otherUser.ID, map[uuid.UUID]int{otherVariant.ID: 1}, 150)
+	mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &otherReceipt))
+
+	previous := ""
+	for i, r := range receipts {
+		if r.ChainPosition != int64(i+1) || r.PreviousHash != previous || len(r.Hash) != 64 {
+			t.Fatalf("receipt %d not chained to the one before: %+v", i, r)
+		}
+		previous = r.Hash
+		// The hash still matches once the receipt has been stored and read back
+		stored, err := h.Sale.GetReceiptBySaleID(ctx, r.SaleID)
+		mustNoErr(t, err)
+		hash, err := stored.ComputeHash()
+		mustNoErr(t, err)
+		if stored.Hash != r.Hash || hash != r.Hash || stored.PreviousHash != r.PreviousHash || stored.ChainPosition != r.ChainPosition {
+			t.Fatalf("stored receipt %d does not verify: %+v", i, stored)
+		}
+	}
+	if otherReceipt.ChainPosition != 1 || otherReceipt.PreviousHash != "" {
+		t.Fatalf("expected each pharmacy to have its own chain, got %+v", otherReceipt)
+	}
+
+	page, err := h.Sale.GetReceiptChain(ctx, p.ID, 0, 2)
+	mustNoErr(t, err)
+	if len(page) != 2 || page[0].ID != receipts[0].ID || page[1].ID != receipts[1].ID {
+		t.Fatalf("expected the first two receipts in chain order, got %+v", page)
+	}
+	page, err = h.Sale.GetReceiptChain(ctx, p.ID, 2, 10)
+	mustNoErr(t, err)
+	if len(page) != 1 || page[0].ID != receipts[2].ID {
+		t.Fatalf("expected the receipt after position 2, got %+v", page)
+	}
+	head, err = h.Sale.GetReceiptChainHead(ctx, p.ID)
+	mustNoErr(t, err)
+	if head.Position != 3 || head.Hash != receipts[2].Hash {
+		t.Fatalf("expected the head at the last receipt, got %+v", head)
+	}
+
+	// A walk over the chain spots a receipt changed after the fact
+	report := domain.ChainReport{PharmacyID: p.ID}
+	all, err := h.Sale.GetReceiptChain(ctx, p.ID, 0, 10)
+	mustNoErr(t, err)
+	all[1].Content.TotalPrice++
+	for _, r := range all {
+		mustNoErr(t, report.Check(r))
+	}
+	report.Finish(*head)
+	if report.Valid || len(report.Breaks) != 1 || report.Breaks[0].ReceiptID != receipts[1].ID || report.Breaks[0].Reason != domain.ChainBreakAltered {
+		t.Fatalf("expected the altered receipt to break the chain, got %+v", report)
+	}
+}
+
+func testSaleHistory(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	other := newPharmacy(t, h)
+	alice := newUser(t, h, p.ID, domain.RolePharmacist)
+	bob := newUser(t, h, p.ID, domain.RoleOwner)
+	otherUser := newUser(t, h, other.ID, domain.RolePharmacist)
+	ibuprofen := newMedicine(t, h, p.ID, "Ibuprofen")
+	advil := newVariant(t, h, ibuprofen.ID, "Advil", 500, 50, now().AddDate(1, 0, 0))
+	aspirin := newMedicine(t, h, p.ID, "Aspirin")
+	bayer := newVariant(t, h, aspirin.ID, "Bayer", 300, 50, now().AddDate(1, 0, 0))
+	otherMedicine := newMedicine(t, h, other.ID, "Ibuprofen")
+	otherVariant := newVariant(t, h, otherMedicine.ID, "Advil", 500, 50, now().AddDate(1, 0, 0))
+
+	sell := func(pharmacyID, userID uuid.UUID, lines map[uuid.UUID]int, price domain.Money, daysAgo int) domain.Sale {
+		sale, items, receipt := newSale(pharmacyID, userID, lines, price)
+		sale.SaleDate = now().AddDate(0, 0, -daysAgo)
+		mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &receipt))
+		return sale
+	}
+	s1 := sell(p.ID, alice.ID, map[uuid.UUID]int{advil.ID: 2}, 500, 3)
+	s2 := sell(p.ID, bob.ID, map[uuid.UUID]int{bayer.ID: 1}, 300, 2)
+	s3 := sell(p.ID, alice.ID, map[uuid.UUID]int{advil.ID: 1, bayer.ID: 3}, 400, 1)
+	sell(other.ID, otherUser.ID, map[uuid.UUID]int{otherVariant.ID: 1}, 500, 4)
+
+	list := func(filter domain.SaleFilter, total int, want ...domain.Sale) *domain.SalePage {
+		t.Helper()
+		if filter.Limit == 0 {
+			filter.Limit = 10
+		}
+		page, err := h.Sale.GetSales(ctx, filter)
+		mustNoErr(t, err)
+		if page.Total != total || len(page.Sales) != len(want) {
+			t.Fatalf("expected %d of %d sales, got %+v", len(want), total, page)
+		}
+		for i, s := range want {
+			if page.Sales[i].ID != s.ID {
+				t.Fatalf("expected sale %d to be %v, got %+v", i, s.ID, page.Sales)
+			}
+		}
+		return page
+	}
+	minTotal, maxTotal, exact := domain.Money(1000), domain.Money(999), domain.Money(1000)
+
+	page := list(domain.SaleFilter{PharmacyID: p.ID}, 3, s3, s2, s1)
+	if h := page.Sales[0]; h.ReceiptNumber != "000003" || h.UserID != alice.ID || h.Cashier != alice.FullName || h.TotalPrice != 1600 ||
+		h.ItemCount != 2 || h.UnitCount != 4 || h.PharmacyID != p.ID || !h.SaleDate.Equal(s3.SaleDate) {
+		t.Fatalf("unexpected sale header %+v", h)
+	}
+	list(domain.SaleFilter{PharmacyID: p.ID, Sort: domain.SaleSortDate}, 3, s1, s2, s3)
+	list(domain.SaleFilter{PharmacyID: p.ID, Sort: domain.SaleSortTotal}, 3, s2, s1, s3)
+	list(domain.SaleFilter{PharmacyID: p.ID, Sort: domain.SaleSortTotalDesc}, 3, s3, s1, s2)
+	list(domain.SaleFilter{PharmacyID: p.ID, From: s2.SaleDate, To: s3.SaleDate}, 1, s2)
+	list(domain.SaleFilter{PharmacyID: p.ID, UserID: alice.ID}, 2, s3, s1)
+	list(domain.SaleFilter{PharmacyID: p.ID, MedicineID: aspirin.ID}, 2, s3, s2)
+	list(domain.SaleFilter{PharmacyID: p.ID, MinTotal: &minTotal}, 2, s3, s1)
+	list(domain.SaleFilter{PharmacyID: p.ID, MaxTotal: &maxTotal}, 1, s2)
+	list(domain.SaleFilter{PharmacyID: p.ID, MinTotal: &exact, MaxTotal: &exact}, 1, s1)
+	list(domain.SaleFilter{PharmacyID: p.ID, Limit: 2, Offset: 2}, 3, s1)
+	page = list(domain.SaleFilter{PharmacyID: p.ID, Offset: 5}, 3)
+	if page.Sales == nil {
+		t.Fatalf("expected an empty page to list no sales rather than null")
+	}
+	list(domain.SaleFilter{Limit: 1}, 4, s3)
+
+	stored, err := h.Sale.GetSaleByID(ctx, s2.ID)
+	mustNoErr(t, err)
+	if stored.Cashier != bob.FullName || stored.ReceiptNumber != "000002" {
+		t.Fatalf("GetSaleByID returned %+v", stored)
+	}
+}
+
+func testSaleReturns(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Amoxicillin")
+	v := newVariant(t, h, m.ID, "Amoxil", 400, 0, time.Time{})
+
+	early := newLot(v.ID, "EARLY", now().AddDate(0, 2, 0), 2)
+	late := newLot(v.ID, "LATE", now().AddDate(1, 0, 0), 10)
+	for _, lot := range []domain.MedicineLot{early, late} {
+		mustNoErr(t, h.Medicine.CreateLot(ctx, lot, uuid.Nil))
+	}
+
+	sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 5}, 400)
+	items[0].UnitCost = 100
+	mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &receipt))
+
+	newReturn := func(quantity int, quarantine bool) domain.SaleReturn {
+		ret := domain.SaleReturn{
+			ID: uuid.New(), SaleID: sale.ID, UserID: u.ID, Reason: "wrong strength", Quarantine: quarantine,
+			RefundMethod: domain.PaymentCard, TotalRefund: domain.Money(400).Mul(quantity), CreatedAt: now().Add(time.Second),
+		}
+		ret.Items = []domain.SaleReturnItem{{
+			ID: uuid.New(), ReturnID: ret.ID, SaleItemID: items[0].ID, Quantity: quantity,
+			PricePerUnit: 400, UnitCost: 100, Refund: ret.TotalRefund,
+		}}
+		ret.CreditNote = domain.CreditNote{
+			ID: uuid.New(), ReturnID: ret.ID, ReceiptID: receipt.ID, CreatedAt: ret.CreatedAt,
+			Content: domain.CreditNoteContent{
+				Items:       []domain.ReceiptItem{{Brand: "Amoxil", MedicineName: "Amoxicillin", PricePerUnit: 400, Quantity: quantity, Subtotal: ret.TotalRefund}},
+				PharmacyID:  p.ID,
+				SaleID:      sale.ID,
+				ReceiptID:   receipt.ID,
+				TotalRefund: ret.TotalRefund,
+			},
+		}
+		return ret
+	}
+
+	// 2 came from EARLY and 3 from LATE; returns go back latest expiry first
+	restocked, err := h.Sale.CreateReturn(ctx, sale.ID, returning(newReturn(4, false)))
+	mustNoErr(t, err)
+	if len(restocked.Items) != 1 || restocked.Items[0].MedicineVariantID != v.ID || len(restocked.Items[0].Lots) != 2 ||
+		restocked.Items[0].Lots[0].LotID != late.ID || restocked.Items[0].Lots[0].Quantity != 3 ||
+		restocked.Items[0].Lots[1].LotID != early.ID || restocked.Items[0].Lots[1].Quantity != 1 {
+		t.Fatalf("expected 3 back to LATE then 1 to EARLY, got %+v", restocked.Items)
+	}
+	got, err := h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 11 {
+		t.Fatalf("expected stock 11 after restocking, got %d", got.Stock)
+	}
+	movements, err := h.Medicine.GetStockMovements(ctx, v.ID, 10, 0)
+	mustNoErr(t, err)
+	returned := 0
+	for _, mv := range movements {
+		if mv.Type == domain.StockMovementReturn {
+			if mv.SaleID == nil || *mv.SaleID != sale.ID || mv.Reason != "wrong strength" {
+				t.Fatalf("unexpected return movement %+v", mv)
+			}
+			returned += mv.Quantity
+		}
+	}
+	if returned != 4 {
+		t.Fatalf("expected return movements for 4 units, got %d", returned)
+	}
+
+	// Only one unit is left to return
+	_, err = h.Sale.CreateReturn(ctx, sale.ID, returning(newReturn(2, true)))
+	mustErrIs(t, err, domain.ErrReturnExceedsSold)
+	unknown := newReturn(1, true)
+	unknown.Items[0].SaleItemID = uuid.New()
+	_, err = h.Sale.CreateReturn(ctx, sale.ID, returning(unknown))
+	mustErrIs(t, err, domain.ErrSaleItemNotFound)
+	_, err = h.Sale.CreateReturn(ctx, uuid.New(), returning(newReturn(1, true)))
+	mustErrIs(t, err, domain.ErrSaleNotFound)
+
+	// The return is built from the sale's items as earlier returns left them
+	quarantined, err := h.Sale.CreateReturn(ctx, sale.ID, func(sold []domain.SaleItem) (domain.SaleReturn, error) {
+		if len(sold) != 1 || sold[0].ID != items[0].ID || sold[0].ReturnedQuantity != 4 || sold[0].Gross != items[0].Gross {
+			t.Errorf("expected the sale item with 4 returned, got %+v", sold)
+		}
+		return newReturn(1, true), nil
+	})
+	mustNoErr(t, err)
+	if len(quarantined.Items[0].Lots) != 1 || quarantined.Items[0].Lots[0].LotID != early.ID {
+		t.Fatalf("expected the last unit back to EARLY, got %+v", quarantined.Items[0].Lots)
+	}
+	lots, err := h.Medicine.GetLotsByVariantID(ctx, v.ID)
+	mustNoErr(t, err)
+	for _, l := range lots {
+		if l.ID == early.ID && (l.Quantity != 1 || l.Quarantined != 1) {
+			t.Fatalf("expected EARLY to hold 1 sellable and 1 quarantined unit, got %+v", l)
+		}
+	}
+	got, err = h.Medicine.GetVariantByID(ctx, v.ID)
+	mustNoErr(t, err)
+	if got.Stock != 11 {
+		t.Fatalf("quarantined units must not be sellable, got stock %d", got.Stock)
+	}
+
+	storedSale, err := h.Sale.GetSaleByID(ctx, sale.ID)
+	mustNoErr(t, err)
+	if storedSale.TotalRefunded != 2000 {
+		t.Fatalf("expected 20.00 refunded, got %v", storedSale.TotalRefunded)
+	}
+	saleItems, err := h.Sale.GetSaleItems(ctx, sale.ID)
+	mustNoErr(t, err)
+	if len(saleItems) != 1 || saleItems[0].ReturnedQuantity != 5 {
+		t.Fatalf("GetSaleItems returned %+v", saleItems)
+	}
+
+	returns, err := h.Sale.GetReturns(ctx, sale.ID)
+	mustNoErr(t, err)
+	if len(returns) != 2 || returns[0].ID != restocked.ID || returns[1].ID != quarantined.ID || !returns[1].Quarantine ||
+		returns[0].RefundMethod != domain.PaymentCard || returns[0].ShiftID != nil {
+		t.Fatalf("GetReturns returned %+v", returns)
+	}
+	note := returns[0].CreditNote
+	if note.ID != restocked.CreditNote.ID || note.ReceiptID != receipt.ID || note.Content.TotalRefund != 1600 ||
+		len(note.Content.Items) != 1 || len(note.Content.Items[0].Lots) != 2 {
+		t.Fatalf("unexpected credit note %+v", note)
+	}
+	if len(returns[0].Items) != 1 || returns[0].Items[0].Quantity != 4 || len(returns[0].Items[0].Lots) != 2 {
+		t.Fatalf("unexpected return items %+v", returns[0].Items)
+	}
+}
+
+func testIdempotencyKeys(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	other := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Ibuprofen")
+	v := newVariant(t, h, m.ID, "Advil", 200, 10, now().AddDate(1, 0, 0))
+
+	reserve := func(userID uuid.UUID, key string, at time.Time) (*domain.IdempotencyKey, error) {
+		return h.Sale.ReserveIdempotencyKey(ctx, domain.IdempotencyKey{Key: key, UserID: userID, RequestHash: strings.Repeat("a", 64),
+			CreatedAt: at, ExpiresAt: at.Add(time.Hour)})
+	}
+
+	existing, err := reserve(u.ID, "retry-1", now())
+	mustNoErr(t, err)
+	if existing != nil {
+		t.Fatalf("expected a fresh reservation, got %+v", existing)
+	}
+	existing, err = reserve(u.ID, "retry-1", now())
+	mustNoErr(t, err)
+	if existing == nil || existing.SaleID != nil || existing.RequestHash != strings.Repeat("a", 64) {
+		t.Fatalf("expected the in-progress key back, got %+v", existing)
+	}
+	// Keys are scoped to the user
+	existing, err = reserve(other.ID, "retry-1", now())
+	mustNoErr(t, err)
+	if existing != nil {
+		t.Fatalf("another user's key must not clash, got %+v", existing)
+	}
+
+	sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 1}, 200)
+	sale.IdempotencyKey = "retry-1"
+	mustNoErr(t, h.Sale.CreateSale(ctx, sale, items, &receipt))
+
+	existing, err = reserve(u.ID, "retry-1", now())
+	mustNoErr(t, err)
+	if existing == nil || existing.SaleID == nil || *existing.SaleID != sale.ID {
+		t.Fatalf("expected the key completed with the sale, got %+v", existing)
+	}
+	var replayed domain.Sale
+	mustNoErr(t, json.Unmarshal(existing.Response, &replayed))
+	if replayed.ID != sale.ID || replayed.TotalPrice != 200 {
+		t.Fatalf("unexpected stored response %+v", replayed)
+	}
+
+	// Completed keys survive a release; in-progress ones do not
+	mustNoErr(t, h.Sale.ReleaseIdempotencyKey(ctx, u.ID, "retry-1"))
+	mustNoErr(t, h.Sale.ReleaseIdempotencyKey(ctx, other.ID, "retry-1"))
+	existing, err = reserve(u.ID, "retry-1", now())
+	mustNoErr(t, err)
+	if existing == nil || existing.SaleID == nil {
+		t.Fatalf("release must keep a completed key, got %+v", existing)
+	}
+	existing, err = reserve(other.ID, "retry-1", now())
+	mustNoErr(t, err)
+	if existing != nil {
+		t.Fatalf("expected the released key to be reserved again, got %+v", existing)
+	}
+
+	// Expired keys can be reused
+	existing, err = reserve(u.ID, "retry-1", now().Add(2*time.Hour))
+	mustNoErr(t, err)
+	if existing != nil {
+		t.Fatalf("expected the expired key to be reserved again, got %+v", existing)
+	}
+}
@@ -0,0 +1,103 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testShifts(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	u := newUser(t, h, p.ID, domain.RolePharmacist)
+	other := newUser(t, h, p.ID, domain.RolePharmacist)
+	m := newMedicine(t, h, p.ID, "Cetirizine")
+	v := newVariant(t, h, m.ID, "Zyrtec", 500, 10, now().AddDate(1, 0, 0))
+
+	shift := domain.Shift{ID: uuid.New(), PharmacyID: p.ID, UserID: u.ID, OpeningFloat: 5000, Status: domain.ShiftOpen, OpenedAt: now().Add(-time.Hour)}
+	mustNoErr(t, h.Shift.Create(ctx, shift))
+	second := shift
+	second.ID = uuid.New()
+	mustErrIs(t, h.Shift.Create(ctx, second), domain.ErrShiftAlreadyOpen)
+	otherShift := domain.Shift{ID: uuid.New(), PharmacyID: p.ID, UserID: other.ID, Status: domain.ShiftOpen, OpenedAt: now()}
+	mustNoErr(t, h.Shift.Create(ctx, otherShift))
+
+	open, err := h.Shift.GetOpen(ctx, u.ID)
+	mustNoErr(t, err)
+	if open.ID != shift.ID || open.OpeningFloat != 5000 || open.Status != domain.ShiftOpen || open.ClosedAt != nil {
+		t.Fatalf("GetOpen returned %+v", open)
+	}
+
+	sell := func(shiftID uuid.UUID, qty int, payments ...domain.Payment) error {
+		sale, items, receipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: qty}, 500)
+		sale.ShiftID = &shiftID
+		for i := range payments {
+			payments[i].ID, payments[i].SaleID, payments[i].CreatedAt = uuid.New(), sale.ID, now()
+		}
+		sale.Payments = payments
+		return h.Sale.CreateSale(ctx, sale, items, &receipt)
+	}
+	mustNoErr(t, sell(shift.ID, 2, domain.Payment{Method: domain.PaymentCash, Amount: 2000, Change: 1000}))
+	mustNoErr(t, sell(shift.ID, 3, domain.Payment{Method: domain.PaymentCard, Amount: 500}, domain.Payment{Method: domain.PaymentCash, Amount: 1000}))
+	mustNoErr(t, sell(otherShift.ID, 1, domain.Payment{Method: domain.PaymentCash, Amount: 500}))
+
+	// Refund one unit of an earlier sale, made outside any shift
+	earlier, earlierItems, earlierReceipt := newSale(p.ID, u.ID, map[uuid.UUID]int{v.ID: 1}, 500)
+	mustNoErr(t, h.Sale.CreateSale(ctx, earlier, earlierItems, &earlierReceipt))
+	refund := func(shiftID uuid.UUID) error {
+		ret := domain.SaleReturn{ID: uuid.New(), UserID: u.ID, ShiftID: &shiftID, Reason: "changed mind",
+			RefundMethod: domain.PaymentCash, TotalRefund: 500, CreatedAt: now()}
+		ret.Items = []domain.SaleReturnItem{{ID: uuid.New(), ReturnID: ret.ID, SaleItemID: earlierItems[0].ID, Quantity: 1,
+			PricePerUnit: 500, Refund: 500}}
+		ret.CreditNote = domain.CreditNote{ID: uuid.New(), ReturnID: ret.ID, ReceiptID: earlierReceipt.ID, CreatedAt: now()}
+		_, err := h.Sale.CreateReturn(ctx, earlier.ID, returning(ret))
+		return err
+	}
+	mustNoErr(t, refund(shift.ID))
+
+	totals, err := h.Shift.GetTotals(ctx, shift.ID)
+	mustNoErr(t, err)
+	if totals.SaleCount != 2 || totals.TotalSales != 2500 || len(totals.Tenders) != 2 ||
+		totals.Tenders[0] != (domain.TenderTotal{Method: domain.PaymentCard, Count: 1, Amount: 500}) ||
+		totals.Tenders[1] != (domain.TenderTotal{Method: domain.PaymentCash, Count: 2, Amount: 3000, Change: 1000}) ||
+		len(totals.Refunds) != 1 || totals.Refunds[0] != (domain.TenderTotal{Method: domain.PaymentCash, Count: 1, Amount: 500}) {
+		t.Fatalf("GetTotals returned %+v", totals)
+	}
+
+	counts := []domain.TenderCount{{Method: domain.PaymentCash, Amount: 6900}, {Method: domain.PaymentCard, Amount: 500}}
+	mustNoErr(t, h.Shift.Close(ctx, shift.ID, counts, now()))
+	mustErrIs(t, h.Shift.Close(ctx, shift.ID, counts, now()), domain.ErrShiftClosed)
+	mustErrIs(t, h.Shift.Close(ctx, uuid.New(), counts, now()), domain.ErrShiftNotFound)
+	mustErrIs(t, sell(shift.ID, 1, domain.Payment{Method: domain.PaymentCash, Amount: 500}), domain.ErrShiftClosed)
+	mustErrIs(t, refund(shift.ID), domain.ErrShiftClosed)
+
+	closed, err := h.Shift.GetByID(ctx, shift.ID)
+	mustNoErr(t, err)
+	if closed.Status != domain.ShiftClosed || closed.ClosedAt == nil || len(closed.Counts) != 2 ||
+		closed.Counts[0] != (domain.TenderCount{Method: domain.PaymentCard, Amount: 500}) || closed.Counts[1].Amount != 6900 {
+		t.Fatalf("GetByID returned %+v", closed)
+	}
+	_, err = h.Shift.GetByID(ctx, uuid.New())
+	mustErrIs(t, err, domain.ErrShiftNotFound)
+	_, err = h.Shift.GetOpen(ctx, u.ID)
+	mustErrIs(t, err, domain.ErrNoOpenShift)
+
+	// A closed shift no longer stops the user opening another
+	second.OpenedAt = now().Add(time.Minute)
+	mustNoErr(t, h.Shift.Create(ctx, second))
+
+	shifts, err := h.Shift.GetAll(ctx, p.ID, 10, 0)
+	mustNoErr(t, err)
+	if len(shifts) != 3 || shifts[0].ID != second.ID || shifts[1].ID != otherShift.ID || shifts[2].ID != shift.ID {
+		t.Fatalf("expected shifts latest opened first, got %+v", shifts)
+	}
+	paged, err := h.Shift.GetAll(ctx, p.ID, 1, 2)
+	mustNoErr(t, err)
+	if len(paged) != 1 || paged[0].ID != shift.ID {
+		t.Fatalf("expected the oldest shift on the last page, got %+v", paged)
+	}
+}
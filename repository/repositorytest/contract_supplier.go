@@ -0,0 +1,53 @@
+package repositorytest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+)
+
+func testSuppliers(t *testing.T, h Harness) {
+	ctx := context.Background()
+	p := newPharmacy(t, h)
+	other := newPharmacy(t, h)
+	zeta := newSupplier(t, h, p.ID, "Zeta Pharma")
+	alpha := newSupplier(t, h, p.ID, "Alpha Distributors")
+	newSupplier(t, h, other.ID, "Elsewhere")
+
+	suppliers, err := h.Supplier.GetAll(ctx, p.ID)
+	mustNoErr(t, err)
+	if len(suppliers) != 2 || suppliers[0].ID != alpha.ID || suppliers[1].ID != zeta.ID {
+		t.Fatalf("expected Alpha then Zeta, got %+v", suppliers)
+	}
+
+	zeta.ContactName = "Abebe"
+	zeta.Email = "orders@zeta.example"
+	mustNoErr(t, h.Supplier.Update(ctx, zeta))
+	got, err := h.Supplier.GetByID(ctx, zeta.ID)
+	mustNoErr(t, err)
+	if got.ContactName != "Abebe" || got.Email != "orders@zeta.example" || got.PharmacyID != p.ID {
+		t.Fatalf("update not applied: %+v", got)
+	}
+	mustErrIs(t, h.Supplier.Update(ctx, domain.Supplier{ID: uuid.New(), Name: "x"}), domain.ErrSupplierNotFound)
+
+	owner := newUser(t, h, p.ID, domain.RoleOwner)
+	v := newVariant(t, h, newMedicine(t, h, p.ID, "Amoxicillin").ID, "Amoxil", 300, 0, time.Time{})
+	newPurchaseOrder(t, h, p.ID, zeta.ID, owner.ID, 10, 1, v.ID)
+	count, err := h.Supplier.CountPurchaseOrders(ctx, zeta.ID)
+	mustNoErr(t, err)
+	if count != 1 {
+		t.Fatalf("expected 1 purchase order, got %d", count)
+	}
+	if err := h.Supplier.Delete(ctx, zeta.ID); err == nil {
+		t.Fatal("expected deleting a supplier with purchase orders to fail")
+	}
+
+	mustNoErr(t, h.Supplier.Delete(ctx, alpha.ID))
+	_, err = h.Supplier.GetByID(ctx, alpha.ID)
+	mustErrIs(t, err, domain.ErrSupplierNotFound)
+	mustErrIs(t, h.Supplier.Delete(ctx, alpha.ID), domain.ErrSupplierNotFound)
+}
@@ -0,0 +1,210 @@
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// now returns the current time at a precision every backend preserves
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func mustNoErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func mustErrIs(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected error %v, got %v", target, err)
+	}
+}
+
+func newPharmacy(t *testing.T, h Harness) domain.Pharmacy {
+	t.Helper()
+	p := domain.Pharmacy{ID: uuid.New(), Name: "Central Pharmacy", Address: "1 Main St", ReceiptSettings: domain.ReceiptSettings{PaperWidth: 80},
+		CreatedAt: now(), UpdatedAt: now()}
+	mustNoErr(t, h.Pharmacy.Create(context.Background(), p))
+	return p
+}
+
+func newUser(t *testing.T, h Harness, pharmacyID uuid.UUID, role domain.Role) domain.User {
+	t.Helper()
+	u := domain.User{
+		ID:          uuid.New(),
+		PhoneNumber: "+2519" + uuid.NewString()[:8],
+		Password:    "hashed",
+		FullName:    "Test User",
+		Role:        role,
+		PharmacyID:  pharmacyID,
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+	}
+	mustNoErr(t, h.Auth.Create(context.Background(), u))
+	return u
+}
+
+func newMedicine(t *testing.T, h Harness, pharmacyID uuid.UUID, name string) domain.Medicine {
+	t.Helper()
+	m := domain.Medicine{ID: uuid.New(), PharmacyID: pharmacyID, Name: name, Description: "desc", TaxCategory: domain.TaxStandard, CreatedAt: now(), UpdatedAt: now()}
+	mustNoErr(t, h.Medicine.Create(context.Background(), m))
+	return m
+}
+
+// newVariant creates a variant holding stock units in a single lot
+func newVariant(t *testing.T, h Harness, medicineID uuid.UUID, brand string, price domain.Money, stock int, expiry time.Time) domain.MedicineVariant {
+	t.Helper()
+	v := domain.MedicineVariant{
+		ID:           uuid.New(),
+		MedicineID:   medicineID,
+		Brand:        brand,
+		Barcode:      "BC" + uuid.NewString()[:8],
+		Unit:         "box",
+		PricePerUnit: price,
+		ExpiryDate:   expiry,
+		Stock:        stock,
+		CreatedAt:    now(),
+		UpdatedAt:    now(),
+	}
+	var lots []domain.MedicineLot
+	if stock > 0 {
+		lots = append(lots, newLot(v.ID, "L1", expiry, stock))
+	}
+	mustNoErr(t, h.Medicine.CreateVariant(context.Background(), v, lots, uuid.Nil))
+	return v
+}
+
+func newLot(variantID uuid.UUID, number string, expiry time.Time, quantity int) domain.MedicineLot {
+	return domain.MedicineLot{
+		ID:         uuid.New(),
+		VariantID:  variantID,
+		LotNumber:  number,
+		ExpiryDate: expiry,
+		Quantity:   quantity,
+		ReceivedAt: now(),
+		CreatedAt:  now(),
+		UpdatedAt:  now(),
+	}
+}
+
+func newSale(pharmacyID, userID uuid.UUID, lines map[uuid.UUID]int, price domain.Money) (domain.Sale, []domain.SaleItem, domain.Receipt) {
+	sale := domain.Sale{ID: uuid.New(), UserID: userID, PharmacyID: pharmacyID, SaleDate: now(), CreatedAt: now(), UpdatedAt: now()}
+	var items []domain.SaleItem
+	var receiptItems []domain.ReceiptItem
+	for variantID, qty := range lines {
+		subtotal := price.Mul(qty)
+		items = append(items, domain.SaleItem{ID: uuid.New(), SaleID: sale.ID, MedicineVariantID: variantID, Quantity: qty, PricePerUnit: price,
+			TaxCategory: domain.TaxStandard, Net: subtotal, Gross: subtotal, CreatedAt: now()})
+		receiptItems = append(receiptItems, domain.ReceiptItem{Brand: "b", MedicineName: "m", PricePerUnit: price, Quantity: qty, Subtotal: subtotal,
+			TaxCategory: domain.TaxStandard, Net: subtotal, Gross: subtotal})
+		sale.TotalPrice += subtotal
+		sale.TotalNet += subtotal
+	}
+	receipt := domain.Receipt{
+		ID:        uuid.New(),
+		SaleID:    sale.ID,
+		Content:   domain.ReceiptContent{Items: receiptItems, PharmacyID: pharmacyID, SaleDate: sale.SaleDate, TotalPrice: sale.TotalPrice},
+		CreatedAt: now(),
+	}
+	return sale, items, receipt
+}
+
+// checkout builds a sale of the cart lines being checked out at price, its
+// items in the lines' order
+func checkout(pharmacyID, userID uuid.UUID, price domain.Money) repository.CheckoutFunc {
+	return func(lines []domain.CheckoutLine) (domain.Sale, []domain.SaleItem, *domain.Receipt, error) {
+		sale, items, receipt := newSale(pharmacyID, userID, nil, price)
+		for _, line := range lines {
+			subtotal := price.Mul(line.Cart.Quantity)
+			items = append(items, domain.SaleItem{ID: uuid.New(), SaleID: sale.ID, MedicineVariantID: line.Cart.MedicineVariantID,
+				Quantity: line.Cart.Quantity, PricePerUnit: price, TaxCategory: domain.TaxStandard, Net: subtotal, Gross: subtotal, CreatedAt: now()})
+			sale.TotalPrice += subtotal
+			sale.TotalNet += subtotal
+		}
+		receipt.Content.TotalPrice = sale.TotalPrice
+		return sale, items, &receipt, nil
+	}
+}
+
+// returning records saleReturn as it is, whatever the sale's items
+func returning(saleReturn domain.SaleReturn) repository.ReturnFunc {
+	return func([]domain.SaleItem) (domain.SaleReturn, error) {
+		return saleReturn, nil
+	}
+}
+
+func newSupplier(t *testing.T, h Harness, pharmacyID uuid.UUID, name string) domain.Supplier {
+	t.Helper()
+	s := domain.Supplier{ID: uuid.New(), PharmacyID: pharmacyID, Name: name, PhoneNumber: "+251911000000", CreatedAt: now(), UpdatedAt: now()}
+	mustNoErr(t, h.Supplier.Create(context.Background(), s))
+	return s
+}
+
+// newPurchaseOrder creates a purchase order with one item per variant, each
+// ordering quantity units at cost
+func newPurchaseOrder(t *testing.T, h Harness, pharmacyID, supplierID, userID uuid.UUID, quantity int, cost domain.Money, variantIDs ...uuid.UUID) domain.PurchaseOrder {
+	t.Helper()
+	po := domain.PurchaseOrder{
+		ID: uuid.New(), PharmacyID: pharmacyID, SupplierID: supplierID, Status: domain.PurchaseOrderDraft,
+		CreatedBy: userID, CreatedAt: now(), UpdatedAt: now(),
+	}
+	for _, variantID := range variantIDs {
+		po.Items = append(po.Items, domain.PurchaseOrderItem{
+			ID: uuid.New(), PurchaseOrderID: po.ID, VariantID: variantID, QuantityOrdered: quantity, UnitCost: cost,
+		})
+	}
+	mustNoErr(t, h.PurchaseOrder.Create(context.Background(), po))
+	return po
+}
+
+func newCustomer(t *testing.T, h Harness, pharmacyID uuid.UUID, name, phone string) domain.Customer {
+	t.Helper()
+	c := domain.Customer{ID: uuid.New(), PharmacyID: pharmacyID, FullName: name, PhoneNumber: phone, CreatedAt: now(), UpdatedAt: now()}
+	mustNoErr(t, h.Customer.Create(context.Background(), c))
+	return c
+}
+
+func newPromotion(t *testing.T, h Harness, pharmacyID uuid.UUID, name string, scope domain.PromotionScope, startsAt, endsAt time.Time) domain.Promotion {
+	t.Helper()
+	p := domain.Promotion{
+		ID:         uuid.New(),
+		PharmacyID: pharmacyID,
+		Name:       name,
+		Type:       domain.PromotionPercentage,
+		Scope:      scope,
+		Rate:       1000,
+		Active:     true,
+		StartsAt:   startsAt,
+		EndsAt:     endsAt,
+		CreatedAt:  now(),
+		UpdatedAt:  now(),
+	}
+	mustNoErr(t, h.Promotion.Create(context.Background(), p))
+	return p
+}
+
+func newManualDiscount(t *testing.T, h Harness, pharmacyID, userID uuid.UUID, status domain.ManualDiscountStatus, createdAt time.Time) domain.ManualDiscount {
+	t.Helper()
+	d := domain.ManualDiscount{
+		ID:         uuid.New(),
+		PharmacyID: pharmacyID,
+		UserID:     userID,
+		Amount:     100,
+		Reason:     "Loyal customer",
+		Status:     status,
+		CreatedAt:  createdAt,
+	}
+	mustNoErr(t, h.Promotion.CreateManualDiscount(context.Background(), d))
+	return d
+}
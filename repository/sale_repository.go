@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"pharmacy-management-backend/domain"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,13 +20,36 @@ type SaleRepository interface {
 	AddToCart(ctx context.Context, cart domain.Cart) error
 	GetCart(ctx context.Context, userID uuid.UUID) ([]domain.Cart, error)
 	RemoveFromCart(ctx context.Context, cartID uuid.UUID) error
+	UpdateCartItem(ctx context.Context, cart domain.Cart) error
 	ClearCart(ctx context.Context, userID uuid.UUID) error
-	CreateSale(ctx context.Context, sale domain.Sale, items []domain.SaleItem, receipt domain.Receipt) error
-	GetSales(ctx context.Context, pharmacyID uuid.UUID, limit, offset int) ([]domain.SaleItem, error)
+	ParkCart(ctx context.Context, parked domain.ParkedCart) (*domain.ParkedCart, error)
+	GetParkedCarts(ctx context.Context, userID uuid.UUID) ([]domain.ParkedCart, error)
+	ResumeCart(ctx context.Context, userID, parkedCartID uuid.UUID, reservedUntil time.Time) error
+	ReleaseExpiredReservations(ctx context.Context, now time.Time) (int, error)
+	CreateSale(ctx context.Context, sale domain.Sale, items []domain.SaleItem, receipt *domain.Receipt) error
+	CheckoutCart(ctx context.Context, userID uuid.UUID, checkout CheckoutFunc) (*domain.Sale, error)
+	GetSales(ctx context.Context, filter domain.SaleFilter) (*domain.SalePage, error)
 	GetSaleByID(ctx context.Context, saleID uuid.UUID) (*domain.Sale, error)
 	GetReceiptBySaleID(ctx context.Context, saleID uuid.UUID) (*domain.Receipt, error)
+	GetReceiptByNumber(ctx context.Context, pharmacyID uuid.UUID, number string) (*domain.Receipt, error)
+	GetReceiptChain(ctx context.Context, pharmacyID uuid.UUID, afterPosition int64, limit int) ([]domain.Receipt, error)
+	GetReceiptChainHead(ctx context.Context, pharmacyID uuid.UUID) (*domain.ReceiptChainHead, error)
+	GetSaleItems(ctx context.Context, saleID uuid.UUID) ([]domain.SaleItem, error)
+	GetSaleItemsBySaleIDs(ctx context.Context, saleIDs []uuid.UUID) (map[uuid.UUID][]domain.SaleItem, error)
+	CreateReturn(ctx context.Context, saleID uuid.UUID, build ReturnFunc) (*domain.SaleReturn, error)
+	GetReturns(ctx context.Context, saleID uuid.UUID) ([]domain.SaleReturn, error)
+	ReserveIdempotencyKey(ctx context.Context, key domain.IdempotencyKey) (*domain.IdempotencyKey, error)
+	ReleaseIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) error
 }
 
+// CheckoutFunc turns the lines of a cart being checked out into the sale to
+// record, with its items and receipt
+type CheckoutFunc func(lines []domain.CheckoutLine) (domain.Sale, []domain.SaleItem, *domain.Receipt, error)
+
+// ReturnFunc turns the items of a sale being returned against, with what
+// earlier returns took back, into the return to record
+type ReturnFunc func(sold []domain.SaleItem) (domain.SaleReturn, error)
+
 // saleRepository implements SaleRepository
 type saleRepository struct {
 	db     *sql.DB
@@ -35,16 +61,13 @@ func NewSaleRepository(db *sql.DB, logger zerolog.Logger) SaleRepository {
 	return &saleRepository{db, logger}
 }
 
-// SearchMedicines searches for medicine variants by name or barcode
+// SearchMedicines searches for in-stock medicine variants by name or barcode
 func (r *saleRepository) SearchMedicines(ctx context.Context, pharmacyID uuid.UUID, query string) ([]domain.MedicineVariant, error) {
-	sqlQuery := `
-        SELECT mv.id, mv.medicine_id, mv.brand, mv.barcode, mv.unit, mv.price_per_unit, mv.expiry_date, mv.stock, mv.created_at, mv.updated_at
-        FROM medicine_variants mv
+	sqlQuery := variantSelect + `
         JOIN medicines m ON mv.medicine_id = m.id
         WHERE m.pharmacy_id = $1
         AND (m.name ILIKE $2 OR mv.brand ILIKE $2 OR mv.barcode = $3)
-        AND mv.expiry_date > NOW()
-        AND mv.stock > 0
+        AND lots.stock > 0
     `
 	rows, err := r.db.QueryContext(ctx, sqlQuery, pharmacyID, "%"+query+"%", query)
 	if err != nil {
@@ -56,7 +79,7 @@ func (r *saleRepository) SearchMedicines(ctx context.Context, pharmacyID uuid.UU
 	var variants []domain.MedicineVariant
 	for rows.Next() {
 		var v domain.MedicineVariant
-		if err := rows.Scan(&v.ID, &v.MedicineID, &v.Brand, &v.Barcode, &v.Unit, &v.PricePerUnit, &v.ExpiryDate, &v.Stock, &v.CreatedAt, &v.UpdatedAt); err != nil {
+		if err := scanVariant(rows, &v); err != nil {
 			r.logger.Error().Err(err).Msg("Failed to scan medicine variant")
 			return nil, err
 		}
@@ -65,31 +88,73 @@ func (r *saleRepository) SearchMedicines(ctx context.Context, pharmacyID uuid.UU
 	return variants, nil
 }
 
-// AddToCart adds an item to the cart
+// AddToCart adds an item to the active cart, merging it into the user's line
+// for the same variant. The line's whole quantity is reserved until the
+// item's ReservedUntil, and must be available to it, as availableStock
+// counts.
 func (r *saleRepository) AddToCart(ctx context.Context, cart domain.Cart) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	// Take locks in checkout's order: the cart, then the variant's stock
+	lines, err := r.lockCart(ctx, tx, cart.UserID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock cart")
+		return err
+	}
+	if _, err := lockLots(ctx, tx, []uuid.UUID{cart.MedicineVariantID}); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return err
+	}
+
+	// The item joins the line already holding the variant, if there is one
+	lineID, quantity := uuid.Nil, cart.Quantity
+	if i := slices.IndexFunc(lines, func(c domain.Cart) bool { return c.MedicineVariantID == cart.MedicineVariantID }); i >= 0 {
+		lineID, quantity = lines[i].ID, lines[i].Quantity+cart.Quantity
+	}
+	var available int
+	if err := tx.QueryRowContext(ctx, `SELECT `+availableStock("$1", "$2"), cart.MedicineVariantID, lineID).Scan(&available); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get available stock")
+		return err
+	}
+	if quantity > available {
+		r.logger.Info().Str("variant_id", cart.MedicineVariantID.String()).Msg("Insufficient stock to reserve")
+		return domain.ErrInsufficientStock
+	}
+
 	query := `
-        INSERT INTO carts (id, user_id, pharmacy_id, medicine_variant_id, quantity, created_at)
-        VALUES ($1, $2, $3, $4, $5, $6)
-        ON CONFLICT (user_id, medicine_variant_id)
-        DO UPDATE SET quantity = carts.quantity + EXCLUDED.quantity, created_at = EXCLUDED.created_at
+        INSERT INTO carts (id, user_id, pharmacy_id, medicine_variant_id, quantity, reserved_until, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (user_id, medicine_variant_id) WHERE parked_cart_id IS NULL
+        DO UPDATE SET quantity = carts.quantity + EXCLUDED.quantity, reserved_until = EXCLUDED.reserved_until,
+                      created_at = EXCLUDED.created_at
     `
-	_, err := r.db.ExecContext(ctx, query, cart.ID, cart.UserID, cart.PharmacyID, cart.MedicineVariantID, cart.Quantity, cart.CreatedAt)
-	if err != nil {
+	if _, err := tx.ExecContext(ctx, query, cart.ID, cart.UserID, cart.PharmacyID, cart.MedicineVariantID, cart.Quantity,
+		cart.ReservedUntil, cart.CreatedAt); err != nil {
 		r.logger.Error().Err(err).Msg("Failed to add to cart")
 		return err
 	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
 	return nil
 }
 
-// GetCart retrieves cart items for a user with medicine details
+// GetCart retrieves the lines of a user's active cart with medicine details
 func (r *saleRepository) GetCart(ctx context.Context, userID uuid.UUID) ([]domain.Cart, error) {
 	query := `
-        SELECT c.id, c.user_id, c.pharmacy_id, c.medicine_variant_id, c.quantity, c.created_at,
+        SELECT c.id, c.user_id, c.pharmacy_id, c.medicine_variant_id, c.quantity, c.reserved_until, c.created_at,
                m.name, mv.price_per_unit, mv.unit, m.picture
         FROM carts c
         JOIN medicine_variants mv ON c.medicine_variant_id = mv.id
         JOIN medicines m ON mv.medicine_id = m.id
-        WHERE c.user_id = $1
+        WHERE c.user_id = $1 AND c.parked_cart_id IS NULL
     `
 	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
@@ -102,8 +167,8 @@ func (r *saleRepository) GetCart(ctx context.Context, userID uuid.UUID) ([]domai
 	for rows.Next() {
 		var c domain.Cart
 		var name, unit, picture string
-		var pricePerUnit float64
-		if err := rows.Scan(&c.ID, &c.UserID, &c.PharmacyID, &c.MedicineVariantID, &c.Quantity, &c.CreatedAt,
+		var pricePerUnit domain.Money
+		if err := rows.Scan(&c.ID, &c.UserID, &c.PharmacyID, &c.MedicineVariantID, &c.Quantity, &c.ReservedUntil, &c.CreatedAt,
 			&name, &pricePerUnit, &unit, &picture); err != nil {
 			r.logger.Error().Err(err).Msg("Failed to scan cart item")
 			return nil, err
@@ -138,9 +203,59 @@ func (r *saleRepository) RemoveFromCart(ctx context.Context, cartID uuid.UUID) e
 	return nil
 }
 
-// ClearCart clears all cart items for a user
+// UpdateCartItem sets the quantity of a line in the user's active cart,
+// reserving it until the item's ReservedUntil. Like AddToCart, the whole
+// quantity must be available to the line.
+func (r *saleRepository) UpdateCartItem(ctx context.Context, cart domain.Cart) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	lines, err := r.lockCart(ctx, tx, cart.UserID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock cart")
+		return err
+	}
+	i := slices.IndexFunc(lines, func(c domain.Cart) bool { return c.ID == cart.ID })
+	if i < 0 {
+		r.logger.Info().Str("cart_id", cart.ID.String()).Msg("Cart item not found")
+		return domain.ErrCartItemNotFound
+	}
+	variantID := lines[i].MedicineVariantID
+	if _, err := lockLots(ctx, tx, []uuid.UUID{variantID}); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return err
+	}
+
+	var available int
+	if err := tx.QueryRowContext(ctx, `SELECT `+availableStock("$1", "$2"), variantID, cart.ID).Scan(&available); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get available stock")
+		return err
+	}
+	if cart.Quantity > available {
+		r.logger.Info().Str("variant_id", variantID.String()).Msg("Insufficient stock to reserve")
+		return domain.ErrInsufficientStock
+	}
+
+	query := `UPDATE carts SET quantity = $1, reserved_until = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, query, cart.Quantity, cart.ReservedUntil, cart.ID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update cart item")
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+// ClearCart clears the user's active cart, leaving parked carts alone
 func (r *saleRepository) ClearCart(ctx context.Context, userID uuid.UUID) error {
-	query := `DELETE FROM carts WHERE user_id = $1`
+	query := `DELETE FROM carts WHERE user_id = $1 AND parked_cart_id IS NULL`
 	_, err := r.db.ExecContext(ctx, query, userID)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to clear cart")
@@ -149,71 +264,192 @@ func (r *saleRepository) ClearCart(ctx context.Context, userID uuid.UUID) error
 	return nil
 }
 
-// CreateSale creates a sale, sale items, and receipt in a transaction
-func (r *saleRepository) CreateSale(ctx context.Context, sale domain.Sale, items []domain.SaleItem, receipt domain.Receipt) error {
+// ParkCart moves the lines of the user's active cart into a new parked cart,
+// keeping their reservations, and returns it with its lines counted. An
+// empty cart cannot be parked.
+func (r *saleRepository) ParkCart(ctx context.Context, parked domain.ParkedCart) (*domain.ParkedCart, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		r.logger.Error().Err(err).Msg("Failed to begin transaction")
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	// Insert sale
+	lines, err := r.lockCart(ctx, tx, parked.UserID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock cart")
+		return nil, err
+	}
+	if len(lines) == 0 {
+		r.logger.Info().Str("user_id", parked.UserID.String()).Msg("Cart is empty")
+		return nil, domain.ErrCartEmpty
+	}
+
+	query := `INSERT INTO parked_carts (id, user_id, pharmacy_id, label, parked_at) VALUES ($1, $2, $3, $4, $5)`
+	if _, err := tx.ExecContext(ctx, query, parked.ID, parked.UserID, parked.PharmacyID, parked.Label, parked.ParkedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create parked cart")
+		return nil, err
+	}
+	parkQuery := `UPDATE carts SET parked_cart_id = $1 WHERE user_id = $2 AND parked_cart_id IS NULL`
+	if _, err := tx.ExecContext(ctx, parkQuery, parked.ID, parked.UserID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to park cart")
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+	parked.Lines, parked.Quantity = len(lines), 0
+	for _, c := range lines {
+		parked.Quantity += c.Quantity
+	}
+	return &parked, nil
+}
+
+// GetParkedCarts retrieves a user's parked carts, oldest first
+func (r *saleRepository) GetParkedCarts(ctx context.Context, userID uuid.UUID) ([]domain.ParkedCart, error) {
 	query := `
-        INSERT INTO sales (id, user_id, pharmacy_id, total_price, sale_date, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        SELECT p.id, p.user_id, p.pharmacy_id, p.label, COUNT(c.id), COALESCE(SUM(c.quantity), 0), p.parked_at
+        FROM parked_carts p
+        LEFT JOIN carts c ON c.parked_cart_id = p.id
+        WHERE p.user_id = $1
+        GROUP BY p.id
+        ORDER BY p.parked_at, p.id
     `
-	if _, err := tx.ExecContext(ctx, query, sale.ID, sale.UserID, sale.PharmacyID, sale.TotalPrice, sale.SaleDate, sale.CreatedAt, sale.UpdatedAt); err != nil {
-		r.logger.Error().Err(err).Msg("Failed to create sale")
-		return err
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get parked carts")
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Insert sale items and update stock
-	for _, item := range items {
-		// Update stock with locking
-		updateQuery := `
-            UPDATE medicine_variants
-            SET stock = stock - $1, updated_at = $2
-            WHERE id = $3 AND stock >= $1
-        `
-		result, err := tx.ExecContext(ctx, updateQuery, item.Quantity, time.Now(), item.MedicineVariantID)
-		if err != nil {
-			r.logger.Error().Err(err).Msg("Failed to update stock")
-			return err
-		}
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			r.logger.Error().Err(err).Msg("Failed to check rows affected")
-			return err
-		}
-		if rowsAffected == 0 {
-			r.logger.Info().Str("variant_id", item.MedicineVariantID.String()).Msg("Insufficient stock")
-			return domain.ErrInsufficientStock
+	var parked []domain.ParkedCart
+	for rows.Next() {
+		var p domain.ParkedCart
+		if err := rows.Scan(&p.ID, &p.UserID, &p.PharmacyID, &p.Label, &p.Lines, &p.Quantity, &p.ParkedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan parked cart")
+			return nil, err
 		}
+		parked = append(parked, p)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read parked carts")
+		return nil, err
+	}
+	return parked, nil
+}
 
-		// Insert sale item
-		itemQuery := `
-            INSERT INTO sale_items (id, sale_id, medicine_variant_id, quantity, price_per_unit, created_at)
-            VALUES ($1, $2, $3, $4, $5, $6)
-        `
-		if _, err := tx.ExecContext(ctx, itemQuery, item.ID, item.SaleID, item.MedicineVariantID, item.Quantity, item.PricePerUnit, item.CreatedAt); err != nil {
-			r.logger.Error().Err(err).Msg("Failed to create sale item")
+// ResumeCart makes one of the user's parked carts their active cart again,
+// which must be empty. Each line is reserved afresh until reservedUntil if
+// its quantity is still available to it, and otherwise left unreserved for
+// checkout to settle.
+func (r *saleRepository) ResumeCart(ctx context.Context, userID, parkedCartID uuid.UUID, reservedUntil time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	active, err := r.lockCart(ctx, tx, userID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock cart")
+		return err
+	}
+	if len(active) > 0 {
+		r.logger.Info().Str("user_id", userID.String()).Msg("Cart is not empty")
+		return domain.ErrCartNotEmpty
+	}
+
+	var exists bool
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM parked_carts WHERE id = $1 AND user_id = $2)`
+	if err := tx.QueryRowContext(ctx, existsQuery, parkedCartID, userID).Scan(&exists); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get parked cart")
+		return err
+	}
+	if !exists {
+		r.logger.Info().Str("parked_cart_id", parkedCartID.String()).Msg("Parked cart not found")
+		return domain.ErrParkedCartNotFound
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT medicine_variant_id FROM carts WHERE parked_cart_id = $1`, parkedCartID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get parked cart lines")
+		return err
+	}
+	var variantIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			r.logger.Error().Err(err).Msg("Failed to scan parked cart line")
 			return err
 		}
+		variantIDs = append(variantIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read parked cart lines")
+		return err
+	}
+	if _, err := lockLots(ctx, tx, variantIDs); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return err
 	}
 
-	// Insert receipt
-	receiptQuery := `
-        INSERT INTO receipts (id, sale_id, content, created_at)
-        VALUES ($1, $2, $3, $4)
+	// A parked cart has one line per variant, so no line's reservation
+	// depends on another's
+	resumeQuery := `
+        UPDATE carts c SET parked_cart_id = NULL, reserved_until = CASE
+            WHEN c.quantity <= ` + availableStock("c.medicine_variant_id", "c.id") + ` THEN $2::timestamptz END
+        WHERE c.parked_cart_id = $1
     `
-	content, err := json.Marshal(receipt.Content)
+	if _, err := tx.ExecContext(ctx, resumeQuery, parkedCartID, reservedUntil); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to resume cart")
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM parked_carts WHERE id = $1`, parkedCartID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to delete parked cart")
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+// ReleaseExpiredReservations releases the cart reservations that ran out by
+// now, returning how many were released. The lines stay in their carts.
+func (r *saleRepository) ReleaseExpiredReservations(ctx context.Context, now time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `UPDATE carts SET reserved_until = NULL WHERE reserved_until <= $1`, now)
 	if err != nil {
-		r.logger.Error().Err(err).Msg("Failed to marshal receipt content")
+		r.logger.Error().Err(err).Msg("Failed to release expired reservations")
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// CreateSale creates a sale, sale items, and receipt in a transaction, taking
+// stock first-expiry-first-out from unexpired lots other carts do not hold.
+// The sale's shift must still be open, and a sale whose ID is taken is
+// refused with ErrSaleExists.
+func (r *saleRepository) CreateSale(ctx context.Context, sale domain.Sale, items []domain.SaleItem, receipt *domain.Receipt) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
 		return err
 	}
-	if _, err := tx.ExecContext(ctx, receiptQuery, receipt.ID, receipt.SaleID, content, receipt.CreatedAt); err != nil {
-		r.logger.Error().Err(err).Msg("Failed to create receipt")
+	defer tx.Rollback()
+
+	if err := r.createSale(ctx, tx, sale, items, receipt); err != nil {
 		return err
 	}
 
@@ -224,86 +460,1185 @@ func (r *saleRepository) CreateSale(ctx context.Context, sale domain.Sale, items
 	return nil
 }
 
-// GetSales retrieves sale items for a pharmacy with medicine details
-func (r *saleRepository) GetSales(ctx context.Context, pharmacyID uuid.UUID, limit, offset int) ([]domain.SaleItem, error) {
-	query := `
-        SELECT si.id, si.sale_id, si.medicine_variant_id, si.quantity, si.price_per_unit, si.created_at,
-               m.name, mv.unit, m.picture
-        FROM sale_items si
-        JOIN sales s ON si.sale_id = s.id
-        JOIN medicine_variants mv ON si.medicine_variant_id = mv.id
+// CheckoutCart sells the user's active cart as the sale checkout builds from
+// its lines, in one transaction, and empties the cart with it. The lines are
+// read, with their variants and medicines, after the cart and the variants'
+// stock are locked, so nothing checkout priced can change before the sale is
+// recorded. The sale is recorded as by CreateSale, its reservations turning
+// into the sale's deductions. An empty cart is refused with ErrCartEmpty.
+func (r *saleRepository) CheckoutCart(ctx context.Context, userID uuid.UUID, checkout CheckoutFunc) (*domain.Sale, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Hold the cart until the sale commits, so that a second checkout of the
+	// same cart waits for this one and then finds the cart gone
+	cart, err := r.lockCart(ctx, tx, userID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock cart")
+		return nil, err
+	}
+	if len(cart) == 0 {
+		r.logger.Info().Str("user_id", userID.String()).Msg("Cart is empty")
+		return nil, domain.ErrCartEmpty
+	}
+	variantIDs := make([]uuid.UUID, len(cart))
+	for i, c := range cart {
+		variantIDs[i] = c.MedicineVariantID
+	}
+	if _, err := lockLots(ctx, tx, variantIDs); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return nil, err
+	}
+
+	lines, err := r.checkoutLines(ctx, tx, cart)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get cart products")
+		return nil, err
+	}
+	sale, items, receipt, err := checkout(lines)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.createSale(ctx, tx, sale, items, receipt); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM carts WHERE user_id = $1 AND parked_cart_id IS NULL`, userID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to clear cart")
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+	sale.ReceiptNumber = receipt.Number
+	return &sale, nil
+}
+
+// checkoutLines loads the variant and medicine of every cart line, in line
+// order, within the checkout's transaction
+func (r *saleRepository) checkoutLines(ctx context.Context, tx *sql.Tx, cart []domain.Cart) ([]domain.CheckoutLine, error) {
+	variantIDs := make([]uuid.UUID, len(cart))
+	for i, c := range cart {
+		variantIDs[i] = c.MedicineVariantID
+	}
+	query := variantColumns + `,
+               m.id, m.pharmacy_id, m.name, m.description, m.picture, m.category, m.tax_category, m.created_at, m.updated_at
+    ` + variantFrom + `
         JOIN medicines m ON mv.medicine_id = m.id
-        WHERE ($1::uuid IS NULL OR s.pharmacy_id = $1)
-        ORDER BY si.created_at DESC
-        LIMIT $2 OFFSET $3
+        WHERE mv.id = ANY($1::uuid[])
     `
-	rows, err := r.db.QueryContext(ctx, query, pharmacyID, limit, offset)
+	rows, err := tx.QueryContext(ctx, query, uuidArray(variantIDs))
 	if err != nil {
-		r.logger.Error().Err(err).Msg("Failed to get sale items")
 		return nil, err
 	}
 	defer rows.Close()
 
-	var saleItems []domain.SaleItem
+	products := make(map[uuid.UUID]domain.CheckoutLine, len(cart))
 	for rows.Next() {
-		var si domain.SaleItem
-		var name, unit, picture string
-		if err := rows.Scan(&si.ID, &si.SaleID, &si.MedicineVariantID, &si.Quantity, &si.PricePerUnit, &si.CreatedAt,
-			&name, &unit, &picture); err != nil {
-			r.logger.Error().Err(err).Msg("Failed to scan sale item")
+		var line domain.CheckoutLine
+		m := &line.Medicine
+		if err := scanVariant(rows, &line.Variant, &m.ID, &m.PharmacyID, &m.Name, &m.Description, &m.Picture, &m.Category, &m.TaxCategory,
+			&m.CreatedAt, &m.UpdatedAt); err != nil {
 			return nil, err
 		}
-		// Attach additional fields to SaleItem struct (temporary for response formatting)
-		si.MedicineName = name
-		si.Unit = unit
-		si.ImageURL = picture
-		saleItems = append(saleItems, si)
+		products[line.Variant.ID] = line
 	}
-	return saleItems, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lines := make([]domain.CheckoutLine, len(cart))
+	for i, c := range cart {
+		line, ok := products[c.MedicineVariantID]
+		if !ok {
+			return nil, domain.ErrVariantNotFound
+		}
+		line.Cart = c
+		lines[i] = line
+	}
+	return lines, nil
 }
 
-// GetSaleByID retrieves a sale by ID
-func (r *saleRepository) GetSaleByID(ctx context.Context, saleID uuid.UUID) (*domain.Sale, error) {
-	query := `
-        SELECT id, user_id, pharmacy_id, total_price, sale_date, created_at, updated_at
-        FROM sales WHERE id = $1
-    `
-	var s domain.Sale
-	err := r.db.QueryRowContext(ctx, query, saleID).Scan(&s.ID, &s.UserID, &s.PharmacyID, &s.TotalPrice, &s.SaleDate, &s.CreatedAt, &s.UpdatedAt)
-	if err == sql.ErrNoRows {
-		r.logger.Info().Str("sale_id", saleID.String()).Msg("Sale not found")
-		return nil, domain.ErrSaleNotFound
+// createSale records a sale for CreateSale and CheckoutCart within their
+// transaction
+func (r *saleRepository) createSale(ctx context.Context, tx *sql.Tx, sale domain.Sale, items []domain.SaleItem, receipt *domain.Receipt) error {
+	// Hold the shift open until the sale is recorded against it
+	if sale.ShiftID != nil {
+		var status domain.ShiftStatus
+		err := tx.QueryRowContext(ctx, `SELECT status FROM shifts WHERE id = $1 FOR SHARE`, *sale.ShiftID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return domain.ErrShiftNotFound
+		}
+		if err != nil {
+			r.logger.Error().Err(err).Msg("Failed to lock shift")
+			return err
+		}
+		if status != domain.ShiftOpen {
+			return domain.ErrShiftClosed
+		}
 	}
+
+	// Lock the stock of every variant sold before deducting any of it
+	variantIDs := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		variantIDs[i] = item.MedicineVariantID
+	}
+	onHand, err := lockLots(ctx, tx, variantIDs)
 	if err != nil {
-		r.logger.Error().Err(err).Msg("Failed to get sale by ID")
-		return nil, err
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return err
+	}
+	held, err := r.reservedStock(ctx, tx, variantIDs, sale.UserID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get reserved stock")
+		return err
 	}
-	return &s, nil
-}
 
-// GetReceiptBySaleID retrieves a receipt by sale ID
-func (r *saleRepository) GetReceiptBySaleID(ctx context.Context, saleID uuid.UUID) (*domain.Receipt, error) {
+	// Insert sale
 	query := `
-        SELECT id, sale_id, content, created_at
-        FROM receipts WHERE sale_id = $1
+        INSERT INTO sales (id, user_id, pharmacy_id, shift_id, customer_id, total_price, total_net, total_tax, total_discount, sale_date, created_at,
+                           updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        ON CONFLICT (id) DO NOTHING
     `
-	var receipt domain.Receipt
-	var contentJSON string
-	err := r.db.QueryRowContext(ctx, query, saleID).Scan(&receipt.ID, &receipt.SaleID, &contentJSON, &receipt.CreatedAt)
-	if err == sql.ErrNoRows {
-		r.logger.Info().Str("sale_id", saleID.String()).Msg("Receipt not found")
-		return nil, domain.ErrSaleNotFound
+	result, err := tx.ExecContext(ctx, query, sale.ID, sale.UserID, sale.PharmacyID, sale.ShiftID, sale.CustomerID, sale.TotalPrice, sale.TotalNet,
+		sale.TotalTax, sale.TotalDiscount, sale.SaleDate, sale.CreatedAt, sale.UpdatedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create sale")
+		return err
 	}
+	created, err := result.RowsAffected()
 	if err != nil {
-		r.logger.Error().Err(err).Msg("Failed to get receipt by sale ID")
-		return nil, err
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if created == 0 {
+		r.logger.Info().Str("sale_id", sale.ID.String()).Msg("Sale already exists")
+		return domain.ErrSaleExists
 	}
 
-	var content domain.ReceiptContent
-	if err := json.Unmarshal([]byte(contentJSON), &content); err != nil {
-		r.logger.Error().Err(err).Msg("Failed to unmarshal receipt content")
-		return nil, err
+	// Record discounts; a manual discount can only be used once
+	for i, d := range sale.Discounts {
+		discountQuery := `
+            INSERT INTO sale_discounts (sale_id, promotion_id, manual_discount_id, name, scope, amount, position)
+            VALUES ($1, $2, $3, $4, $5, $6, $7)
+        `
+		if _, err := tx.ExecContext(ctx, discountQuery, sale.ID, d.PromotionID, d.ManualDiscountID, d.Name, d.Scope, d.Amount, i); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to record sale discount")
+			return err
+		}
+		if d.ManualDiscountID == nil {
+			continue
+		}
+		result, err := tx.ExecContext(ctx, `
+            UPDATE manual_discounts SET sale_id = $1
+            WHERE id = $2 AND status = 'approved' AND sale_id IS NULL`, sale.ID, *d.ManualDiscountID)
+		if err != nil {
+			r.logger.Error().Err(err).Msg("Failed to use manual discount")
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to check rows affected")
+			return err
+		} else if n == 0 {
+			return domain.ErrManualDiscountUsed
+		}
 	}
-	receipt.Content = content
-	return &receipt, nil
+
+	for i, p := range sale.Payments {
+		paymentQuery := `
+            INSERT INTO sale_payments (id, sale_id, method, amount, change_given, reference, position, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        `
+		if _, err := tx.ExecContext(ctx, paymentQuery, p.ID, sale.ID, p.Method, p.Amount, p.Change, p.Reference, i, p.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to record sale payment")
+			return err
+		}
+	}
+
+	// Insert sale items, deducting stock from the earliest-expiring lots
+	for i, item := range items {
+		allocations, err := r.allocateLots(ctx, tx, item.MedicineVariantID, item.Quantity, held[item.MedicineVariantID])
+		if err != nil {
+			return err
+		}
+
+		// Insert sale item
+		itemQuery := `
+            INSERT INTO sale_items (id, sale_id, medicine_variant_id, quantity, price_per_unit, unit_cost, discount,
+                                    tax_category, tax_rate, net, tax, gross, created_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+        `
+		if _, err := tx.ExecContext(ctx, itemQuery, item.ID, item.SaleID, item.MedicineVariantID, item.Quantity, item.PricePerUnit, item.UnitCost,
+			item.Discount, item.TaxCategory, item.TaxRate, item.Net, item.Tax, item.Gross, item.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to create sale item")
+			return err
+		}
+
+		lotQuery := `
+            INSERT INTO sale_item_lots (sale_item_id, lot_id, quantity)
+            VALUES ($1, $2, $3)
+        `
+		for _, allocation := range allocations {
+			if _, err := tx.ExecContext(ctx, lotQuery, item.ID, allocation.LotID, allocation.Quantity); err != nil {
+				r.logger.Error().Err(err).Msg("Failed to record sale item lot")
+				return err
+			}
+			movement := domain.StockMovement{
+				ID:             uuid.New(),
+				VariantID:      item.MedicineVariantID,
+				LotID:          allocation.LotID,
+				Type:           domain.StockMovementSale,
+				Quantity:       -allocation.Quantity,
+				QuantityBefore: onHand[item.MedicineVariantID],
+				QuantityAfter:  onHand[item.MedicineVariantID] - allocation.Quantity,
+				UserID:         sale.UserID,
+				SaleID:         &sale.ID,
+				CreatedAt:      sale.CreatedAt,
+			}
+			if err := insertStockMovement(ctx, tx, movement); err != nil {
+				r.logger.Error().Err(err).Msg("Failed to record stock movement")
+				return err
+			}
+			onHand[item.MedicineVariantID] = movement.QuantityAfter
+		}
+
+		items[i].Lots = allocations
+		if i < len(receipt.Content.Items) {
+			receipt.Content.Items[i].Lots = allocations
+		}
+	}
+
+	// Number the receipt last. The sequence row stays locked until the sale
+	// commits, so concurrent sales take consecutive numbers, and a sale that
+	// fails rolls its number back for the next one.
+	var settings domain.ReceiptSettings
+	settingsQuery := `SELECT receipt_number_prefix, receipt_yearly_reset FROM pharmacies WHERE id = $1`
+	if err := tx.QueryRowContext(ctx, settingsQuery, sale.PharmacyID).Scan(&settings.NumberPrefix, &settings.YearlyReset); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get receipt settings")
+		return err
+	}
+	series := settings.NumberSeries(sale.SaleDate)
+	sequenceQuery := `
+        INSERT INTO receipt_sequences (pharmacy_id, series, last_number)
+        VALUES ($1, $2, 1)
+        ON CONFLICT (pharmacy_id, series) DO UPDATE SET last_number = receipt_sequences.last_number + 1
+        RETURNING last_number
+    `
+	var n int64
+	if err := tx.QueryRowContext(ctx, sequenceQuery, sale.PharmacyID, series).Scan(&n); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to allocate receipt number")
+		return err
+	}
+	receipt.Number = settings.ReceiptNumber(series, n)
+	sale.ReceiptNumber = receipt.Number
+
+	// Chain the receipt to the pharmacy's last one, holding the chain's head
+	// the same way until the sale commits
+	headQuery := `
+        INSERT INTO receipt_chains (pharmacy_id) VALUES ($1)
+        ON CONFLICT (pharmacy_id) DO UPDATE SET position = receipt_chains.position
+        RETURNING position, hash
+    `
+	var position int64
+	if err := tx.QueryRowContext(ctx, headQuery, sale.PharmacyID).Scan(&position, &receipt.PreviousHash); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock receipt chain")
+		return err
+	}
+	receipt.ChainPosition = position + 1
+	hash, err := receipt.ComputeHash()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to hash receipt")
+		return err
+	}
+	receipt.Hash = hash
+	if _, err := tx.ExecContext(ctx, `UPDATE receipt_chains SET position = $2, hash = $3 WHERE pharmacy_id = $1`,
+		sale.PharmacyID, receipt.ChainPosition, receipt.Hash); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to advance receipt chain")
+		return err
+	}
+
+	// Insert receipt
+	receiptQuery := `
+        INSERT INTO receipts (id, sale_id, pharmacy_id, number, content, chain_position, previous_hash, hash, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `
+	content, err := json.Marshal(receipt.Content)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to marshal receipt content")
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, receiptQuery, receipt.ID, receipt.SaleID, sale.PharmacyID, receipt.Number, content,
+		receipt.ChainPosition, receipt.PreviousHash, receipt.Hash, receipt.CreatedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create receipt")
+		return err
+	}
+
+	if sale.IdempotencyKey != "" {
+		response, err := json.Marshal(sale)
+		if err != nil {
+			r.logger.Error().Err(err).Msg("Failed to marshal sale")
+			return err
+		}
+		keyQuery := `UPDATE idempotency_keys SET sale_id = $1, response = $2 WHERE user_id = $3 AND key = $4 AND sale_id IS NULL`
+		if _, err := tx.ExecContext(ctx, keyQuery, sale.ID, response, sale.UserID, sale.IdempotencyKey); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to complete idempotency key")
+			return err
+		}
+	}
+	return nil
+}
+
+// availableStock is the SQL for how much of a variant a cart line can hold:
+// the variant's unexpired stock less what every other line holds under a
+// running reservation, whether that line is active or parked and whoever's
+// cart it is in. The line's own quantity is left out, being what is asked
+// for; a line not yet in a cart is uuid.Nil. variantID and lineID are SQL
+// expressions.
+func availableStock(variantID, lineID string) string {
+	return `(COALESCE((SELECT SUM(l.quantity) FROM medicine_lots l WHERE l.variant_id = ` + variantID + ` AND l.expiry_date > NOW()), 0)
+             - COALESCE((SELECT SUM(o.quantity) FROM carts o
+                         WHERE o.medicine_variant_id = ` + variantID + ` AND o.id <> ` + lineID + ` AND o.reserved_until > NOW()), 0))`
+}
+
+// lockCart locks a user's carts and returns the lines of the active one,
+// oldest first. The user's row is locked first, so that writers of the
+// user's carts queue up even while the active cart is empty.
+func (r *saleRepository) lockCart(ctx context.Context, tx *sql.Tx, userID uuid.UUID) ([]domain.Cart, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE`, userID); err != nil {
+		return nil, err
+	}
+	query := `
+        SELECT id, user_id, pharmacy_id, medicine_variant_id, quantity, reserved_until, created_at
+        FROM carts WHERE user_id = $1 AND parked_cart_id IS NULL
+        ORDER BY created_at, id
+        FOR UPDATE
+    `
+	rows, err := tx.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var carts []domain.Cart
+	for rows.Next() {
+		var c domain.Cart
+		if err := rows.Scan(&c.ID, &c.UserID, &c.PharmacyID, &c.MedicineVariantID, &c.Quantity, &c.ReservedUntil, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		carts = append(carts, c)
+	}
+	return carts, rows.Err()
+}
+
+// reservedStock returns how much of each variant carts other than the user's
+// active cart have reserved
+func (r *saleRepository) reservedStock(ctx context.Context, tx *sql.Tx, variantIDs []uuid.UUID, userID uuid.UUID) (map[uuid.UUID]int, error) {
+	query := `
+        SELECT medicine_variant_id, SUM(quantity) FROM carts
+        WHERE medicine_variant_id = ANY($1::uuid[]) AND reserved_until > NOW()
+          AND NOT (user_id = $2 AND parked_cart_id IS NULL)
+        GROUP BY medicine_variant_id
+    `
+	rows, err := tx.QueryContext(ctx, query, uuidArray(variantIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	held := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var variantID uuid.UUID
+		var quantity int
+		if err := rows.Scan(&variantID, &quantity); err != nil {
+			return nil, err
+		}
+		held[variantID] = quantity
+	}
+	return held, rows.Err()
+}
+
+// allocateLots deducts quantity from the variant's unexpired lots, earliest
+// expiry first, leaving at least held units in them. The caller must already
+// hold the lots' locks.
+func (r *saleRepository) allocateLots(ctx context.Context, tx *sql.Tx, variantID uuid.UUID, quantity, held int) ([]domain.LotAllocation, error) {
+	query := `
+        SELECT id, lot_number, expiry_date, quantity
+        FROM medicine_lots
+        WHERE variant_id = $1 AND expiry_date > NOW() AND quantity > 0
+        ORDER BY expiry_date, received_at, id
+    `
+	rows, err := tx.QueryContext(ctx, query, variantID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get medicine lots")
+		return nil, err
+	}
+
+	var allocations []domain.LotAllocation
+	remaining, left := quantity, 0
+	for rows.Next() {
+		var a domain.LotAllocation
+		var available int
+		if err := rows.Scan(&a.LotID, &a.LotNumber, &a.ExpiryDate, &available); err != nil {
+			rows.Close()
+			r.logger.Error().Err(err).Msg("Failed to scan medicine lot")
+			return nil, err
+		}
+		a.Quantity = min(available, remaining)
+		remaining -= a.Quantity
+		left += available - a.Quantity
+		if a.Quantity > 0 {
+			allocations = append(allocations, a)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read medicine lots")
+		return nil, err
+	}
+	if remaining > 0 || left < held {
+		r.logger.Info().Str("variant_id", variantID.String()).Msg("Insufficient stock")
+		return nil, domain.ErrInsufficientStock
+	}
+
+	now := time.Now()
+	for _, a := range allocations {
+		updateQuery := `UPDATE medicine_lots SET quantity = quantity - $1, updated_at = $2 WHERE id = $3`
+		if _, err := tx.ExecContext(ctx, updateQuery, a.Quantity, now, a.LotID); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to update lot stock")
+			return nil, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE medicine_variants SET updated_at = $1 WHERE id = $2`, now, variantID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update medicine variant")
+		return nil, err
+	}
+	return allocations, nil
+}
+
+// saleOrders maps each sale sort to its ORDER BY clause
+var saleOrders = map[domain.SaleSort]string{
+	domain.SaleSortDate:      "s.sale_date, s.id",
+	domain.SaleSortDateDesc:  "s.sale_date DESC, s.id",
+	domain.SaleSortTotal:     "s.total_price, s.id",
+	domain.SaleSortTotalDesc: "s.total_price DESC, s.id",
+}
+
+// GetSales retrieves a page of sale headers matching a filter, with the
+// number of sales matched
+func (r *saleRepository) GetSales(ctx context.Context, filter domain.SaleFilter) (*domain.SalePage, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if filter.PharmacyID != uuid.Nil {
+		where("s.pharmacy_id = ?", filter.PharmacyID)
+	}
+	if !filter.From.IsZero() {
+		where("s.sale_date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("s.sale_date < ?", filter.To)
+	}
+	if filter.UserID != uuid.Nil {
+		where("s.user_id = ?", filter.UserID)
+	}
+	if filter.CustomerID != uuid.Nil {
+		where("s.customer_id = ?", filter.CustomerID)
+	}
+	if filter.MedicineID != uuid.Nil {
+		where(`EXISTS (
+            SELECT 1 FROM sale_items si JOIN medicine_variants mv ON si.medicine_variant_id = mv.id
+            WHERE si.sale_id = s.id AND mv.medicine_id = ?)`, filter.MedicineID)
+	}
+	if filter.MinTotal != nil {
+		where("s.total_price >= ?", *filter.MinTotal)
+	}
+	if filter.MaxTotal != nil {
+		where("s.total_price <= ?", *filter.MaxTotal)
+	}
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	order, ok := saleOrders[filter.Sort]
+	if !ok {
+		order = saleOrders[domain.SaleSortDateDesc]
+	}
+
+	page := domain.SalePage{Sales: []domain.SaleHeader{}, Limit: filter.Limit, Offset: filter.Offset}
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sales s `+whereClause, args...).Scan(&page.Total); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to count sales")
+		return nil, err
+	}
+
+	query := `
+        SELECT s.id, COALESCE(rc.number, ''), s.pharmacy_id, s.user_id, u.full_name, s.customer_id, s.total_price, s.total_refunded,
+               si.item_count, si.unit_count, s.sale_date
+        FROM sales s
+        JOIN users u ON s.user_id = u.id
+        LEFT JOIN receipts rc ON rc.sale_id = s.id
+        CROSS JOIN LATERAL (
+            SELECT COUNT(*) AS item_count, COALESCE(SUM(quantity), 0) AS unit_count FROM sale_items WHERE sale_id = s.id
+        ) si
+        ` + whereClause + `
+        ORDER BY ` + order + `
+        LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+	rows, err := r.db.QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sales")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h domain.SaleHeader
+		if err := rows.Scan(&h.ID, &h.ReceiptNumber, &h.PharmacyID, &h.UserID, &h.Cashier, &h.CustomerID, &h.TotalPrice, &h.TotalRefunded,
+			&h.ItemCount, &h.UnitCount, &h.SaleDate); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan sale")
+			return nil, err
+		}
+		page.Sales = append(page.Sales, h)
+	}
+	return &page, rows.Err()
+}
+
+// GetSaleByID retrieves a sale by ID
+func (r *saleRepository) GetSaleByID(ctx context.Context, saleID uuid.UUID) (*domain.Sale, error) {
+	query := `
+        SELECT s.id, s.user_id, s.pharmacy_id, s.shift_id, s.customer_id, COALESCE(rc.number, ''), u.full_name,
+               s.total_price, s.total_net, s.total_tax, s.total_discount, s.total_refunded, s.sale_date, s.created_at, s.updated_at
+        FROM sales s
+        JOIN users u ON s.user_id = u.id
+        LEFT JOIN receipts rc ON rc.sale_id = s.id
+        WHERE s.id = $1
+    `
+	var s domain.Sale
+	err := r.db.QueryRowContext(ctx, query, saleID).Scan(&s.ID, &s.UserID, &s.PharmacyID, &s.ShiftID, &s.CustomerID, &s.ReceiptNumber, &s.Cashier,
+		&s.TotalPrice, &s.TotalNet, &s.TotalTax, &s.TotalDiscount, &s.TotalRefunded, &s.SaleDate, &s.CreatedAt, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("sale_id", saleID.String()).Msg("Sale not found")
+		return nil, domain.ErrSaleNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale by ID")
+		return nil, err
+	}
+
+	discountQuery := `
+        SELECT promotion_id, manual_discount_id, name, scope, amount
+        FROM sale_discounts WHERE sale_id = $1
+        ORDER BY position
+    `
+	rows, err := r.db.QueryContext(ctx, discountQuery, saleID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale discounts")
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d domain.AppliedDiscount
+		if err := rows.Scan(&d.PromotionID, &d.ManualDiscountID, &d.Name, &d.Scope, &d.Amount); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan sale discount")
+			return nil, err
+		}
+		s.Discounts = append(s.Discounts, d)
+	}
+
+	paymentQuery := `
+        SELECT id, sale_id, method, amount, change_given, reference, created_at
+        FROM sale_payments WHERE sale_id = $1
+        ORDER BY position
+    `
+	paymentRows, err := r.db.QueryContext(ctx, paymentQuery, saleID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale payments")
+		return nil, err
+	}
+	defer paymentRows.Close()
+	for paymentRows.Next() {
+		var p domain.Payment
+		if err := paymentRows.Scan(&p.ID, &p.SaleID, &p.Method, &p.Amount, &p.Change, &p.Reference, &p.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan sale payment")
+			return nil, err
+		}
+		s.Payments = append(s.Payments, p)
+	}
+	s.Change = domain.TotalChange(s.Payments)
+	return &s, nil
+}
+
+const receiptColumns = `id, sale_id, COALESCE(number, ''), content, COALESCE(chain_position, 0), COALESCE(previous_hash, ''),
+               COALESCE(hash, ''), created_at`
+
+func scanReceipt(row rowScanner, receipt *domain.Receipt) error {
+	var content []byte
+	if err := row.Scan(&receipt.ID, &receipt.SaleID, &receipt.Number, &content, &receipt.ChainPosition, &receipt.PreviousHash,
+		&receipt.Hash, &receipt.CreatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(content, &receipt.Content)
+}
+
+// GetReceiptBySaleID retrieves a receipt by sale ID
+func (r *saleRepository) GetReceiptBySaleID(ctx context.Context, saleID uuid.UUID) (*domain.Receipt, error) {
+	query := `SELECT ` + receiptColumns + ` FROM receipts WHERE sale_id = $1`
+	var receipt domain.Receipt
+	err := scanReceipt(r.db.QueryRowContext(ctx, query, saleID), &receipt)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("sale_id", saleID.String()).Msg("Receipt not found")
+		return nil, domain.ErrSaleNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get receipt by sale ID")
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// GetReceiptByNumber retrieves one of a pharmacy's receipts by its number
+func (r *saleRepository) GetReceiptByNumber(ctx context.Context, pharmacyID uuid.UUID, number string) (*domain.Receipt, error) {
+	query := `SELECT ` + receiptColumns + ` FROM receipts WHERE pharmacy_id = $1 AND number = $2`
+	var receipt domain.Receipt
+	err := scanReceipt(r.db.QueryRowContext(ctx, query, pharmacyID, number), &receipt)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("number", number).Msg("Receipt not found")
+		return nil, domain.ErrReceiptNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get receipt by number")
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// GetReceiptChain retrieves up to limit of a pharmacy's chained receipts
+// after the given position, in chain order
+func (r *saleRepository) GetReceiptChain(ctx context.Context, pharmacyID uuid.UUID, afterPosition int64, limit int) ([]domain.Receipt, error) {
+	query := `
+        SELECT ` + receiptColumns + `
+        FROM receipts
+        WHERE pharmacy_id = $1 AND chain_position > $2
+        ORDER BY chain_position
+        LIMIT $3
+    `
+	rows, err := r.db.QueryContext(ctx, query, pharmacyID, afterPosition, limit)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get receipt chain")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receipts []domain.Receipt
+	for rows.Next() {
+		var receipt domain.Receipt
+		if err := scanReceipt(rows, &receipt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan receipt")
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+	}
+	return receipts, rows.Err()
+}
+
+// GetReceiptChainHead retrieves the last receipt chained for a pharmacy,
+// position 0 if it has none
+func (r *saleRepository) GetReceiptChainHead(ctx context.Context, pharmacyID uuid.UUID) (*domain.ReceiptChainHead, error) {
+	head := domain.ReceiptChainHead{PharmacyID: pharmacyID}
+	err := r.db.QueryRowContext(ctx, `SELECT position, hash FROM receipt_chains WHERE pharmacy_id = $1`, pharmacyID).Scan(&head.Position, &head.Hash)
+	if err != nil && err != sql.ErrNoRows {
+		r.logger.Error().Err(err).Msg("Failed to get receipt chain head")
+		return nil, err
+	}
+	return &head, nil
+}
+
+const saleItemSelect = `
+        SELECT si.id, si.sale_id, si.medicine_variant_id, si.quantity, si.price_per_unit, si.unit_cost, si.returned_quantity, si.discount,
+               si.tax_category, si.tax_rate, si.net, si.tax, si.gross, si.created_at,
+               m.name, mv.unit, m.picture
+        FROM sale_items si
+        JOIN medicine_variants mv ON si.medicine_variant_id = mv.id
+        JOIN medicines m ON mv.medicine_id = m.id
+`
+
+func scanSaleItem(row rowScanner, si *domain.SaleItem) error {
+	return row.Scan(&si.ID, &si.SaleID, &si.MedicineVariantID, &si.Quantity, &si.PricePerUnit, &si.UnitCost, &si.ReturnedQuantity,
+		&si.Discount, &si.TaxCategory, &si.TaxRate, &si.Net, &si.Tax, &si.Gross, &si.CreatedAt, &si.MedicineName, &si.Unit, &si.ImageURL)
+}
+
+// GetSaleItems retrieves a sale's items in the order they were sold
+func (r *saleRepository) GetSaleItems(ctx context.Context, saleID uuid.UUID) ([]domain.SaleItem, error) {
+	query := saleItemSelect + `
+        WHERE si.sale_id = $1
+        ORDER BY si.created_at, si.id
+    `
+	rows, err := r.db.QueryContext(ctx, query, saleID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale items")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var saleItems []domain.SaleItem
+	for rows.Next() {
+		var si domain.SaleItem
+		if err := scanSaleItem(rows, &si); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan sale item")
+			return nil, err
+		}
+		saleItems = append(saleItems, si)
+	}
+	return saleItems, nil
+}
+
+// GetSaleItemsBySaleIDs retrieves the items of the given sales, keyed by sale
+// ID, each sale's in the order they were sold
+func (r *saleRepository) GetSaleItemsBySaleIDs(ctx context.Context, saleIDs []uuid.UUID) (map[uuid.UUID][]domain.SaleItem, error) {
+	saleItems := make(map[uuid.UUID][]domain.SaleItem)
+	if len(saleIDs) == 0 {
+		return saleItems, nil
+	}
+	query := saleItemSelect + `
+        WHERE si.sale_id = ANY($1::uuid[])
+        ORDER BY si.created_at, si.id
+    `
+	rows, err := r.db.QueryContext(ctx, query, uuidArray(saleIDs))
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale items")
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var si domain.SaleItem
+		if err := scanSaleItem(rows, &si); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan sale item")
+			return nil, err
+		}
+		saleItems[si.SaleID] = append(saleItems[si.SaleID], si)
+	}
+	return saleItems, rows.Err()
+}
+
+// CreateReturn records the return build makes of a sale's items in one
+// transaction, reading them once earlier returns are locked out so that the
+// refunds are priced from what is really left. Each item of the return is
+// checked against the quantity sold less earlier returns, then put back
+// into the lots it was sold from, latest expiry first. Restocked units are
+// recorded as return movements in the stock ledger; quarantined units are
+// held in each lot's quarantined quantity instead. The sale's refunded total
+// is updated and the credit note stored, with the lots used recorded on each
+// item and on the matching (same index) credit note item. A refund paid in a
+// shift needs the shift still open.
+func (r *saleRepository) CreateReturn(ctx context.Context, saleID uuid.UUID, build ReturnFunc) (*domain.SaleReturn, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Returns against the same sale are serialized on the sale row
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT TRUE FROM sales WHERE id = $1 FOR UPDATE`, saleID).Scan(&exists)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("sale_id", saleID.String()).Msg("Sale not found")
+		return nil, domain.ErrSaleNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock sale")
+		return nil, err
+	}
+
+	query := saleItemSelect + `
+        WHERE si.sale_id = $1
+        ORDER BY si.created_at, si.id
+    `
+	rows, err := tx.QueryContext(ctx, query, saleID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale items")
+		return nil, err
+	}
+	var saleItems []domain.SaleItem
+	for rows.Next() {
+		var si domain.SaleItem
+		if err := scanSaleItem(rows, &si); err != nil {
+			rows.Close()
+			r.logger.Error().Err(err).Msg("Failed to scan sale item")
+			return nil, err
+		}
+		saleItems = append(saleItems, si)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read sale items")
+		return nil, err
+	}
+
+	saleReturn, err := build(saleItems)
+	if err != nil {
+		return nil, err
+	}
+	saleReturn.SaleID = saleID
+
+	type soldItem struct {
+		variantID uuid.UUID
+		left      int
+	}
+	sold := make(map[uuid.UUID]*soldItem, len(saleItems))
+	for _, si := range saleItems {
+		sold[si.ID] = &soldItem{si.MedicineVariantID, si.Quantity - si.ReturnedQuantity}
+	}
+	for i, item := range saleReturn.Items {
+		s, ok := sold[item.SaleItemID]
+		if !ok {
+			r.logger.Info().Str("sale_item_id", item.SaleItemID.String()).Msg("Sale item not found")
+			return nil, domain.ErrSaleItemNotFound
+		}
+		if item.Quantity > s.left {
+			r.logger.Info().Str("sale_item_id", item.SaleItemID.String()).Msg("Return exceeds quantity sold")
+			return nil, domain.ErrReturnExceedsSold
+		}
+		s.left -= item.Quantity
+		saleReturn.Items[i].MedicineVariantID = s.variantID
+	}
+
+	// Lock every affected variant's lots in a fixed order
+	variantIDs := make([]uuid.UUID, len(saleReturn.Items))
+	for i, item := range saleReturn.Items {
+		variantIDs[i] = item.MedicineVariantID
+	}
+	onHand, err := lockLots(ctx, tx, variantIDs)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to lock medicine lots")
+		return nil, err
+	}
+
+	// Hold the shift open until the refund is recorded against it
+	if saleReturn.ShiftID != nil {
+		var status domain.ShiftStatus
+		err := tx.QueryRowContext(ctx, `SELECT status FROM shifts WHERE id = $1 FOR SHARE`, *saleReturn.ShiftID).Scan(&status)
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrShiftNotFound
+		}
+		if err != nil {
+			r.logger.Error().Err(err).Msg("Failed to lock shift")
+			return nil, err
+		}
+		if status != domain.ShiftOpen {
+			return nil, domain.ErrShiftClosed
+		}
+	}
+
+	returnQuery := `
+        INSERT INTO sale_returns (id, sale_id, user_id, shift_id, reason, quarantine, refund_method, total_refund, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `
+	if _, err := tx.ExecContext(ctx, returnQuery, saleReturn.ID, saleReturn.SaleID, saleReturn.UserID, saleReturn.ShiftID, saleReturn.Reason,
+		saleReturn.Quarantine, saleReturn.RefundMethod, saleReturn.TotalRefund, saleReturn.CreatedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create sale return")
+		return nil, err
+	}
+
+	for i, item := range saleReturn.Items {
+		itemQuery := `
+            INSERT INTO sale_return_items (id, return_id, sale_item_id, quantity, price_per_unit, unit_cost, refund, tax, position)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+        `
+		if _, err := tx.ExecContext(ctx, itemQuery, item.ID, saleReturn.ID, item.SaleItemID, item.Quantity, item.PricePerUnit,
+			item.UnitCost, item.Refund, item.Tax, i); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to create sale return item")
+			return nil, err
+		}
+
+		allocations, err := r.returnAllocations(ctx, tx, item.SaleItemID, item.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range allocations {
+			lotQuery := `INSERT INTO sale_return_item_lots (return_item_id, lot_id, quantity) VALUES ($1, $2, $3)`
+			if _, err := tx.ExecContext(ctx, lotQuery, item.ID, a.LotID, a.Quantity); err != nil {
+				r.logger.Error().Err(err).Msg("Failed to record sale return lot")
+				return nil, err
+			}
+
+			if saleReturn.Quarantine {
+				updateQuery := `UPDATE medicine_lots SET quarantined = quarantined + $1, updated_at = $2 WHERE id = $3`
+				if _, err := tx.ExecContext(ctx, updateQuery, a.Quantity, saleReturn.CreatedAt, a.LotID); err != nil {
+					r.logger.Error().Err(err).Msg("Failed to update lot stock")
+					return nil, err
+				}
+				continue
+			}
+
+			updateQuery := `UPDATE medicine_lots SET quantity = quantity + $1, updated_at = $2 WHERE id = $3`
+			if _, err := tx.ExecContext(ctx, updateQuery, a.Quantity, saleReturn.CreatedAt, a.LotID); err != nil {
+				r.logger.Error().Err(err).Msg("Failed to update lot stock")
+				return nil, err
+			}
+			movement := domain.StockMovement{
+				ID:             uuid.New(),
+				VariantID:      item.MedicineVariantID,
+				LotID:          a.LotID,
+				Type:           domain.StockMovementReturn,
+				Quantity:       a.Quantity,
+				QuantityBefore: onHand[item.MedicineVariantID],
+				QuantityAfter:  onHand[item.MedicineVariantID] + a.Quantity,
+				Reason:         saleReturn.Reason,
+				UserID:         saleReturn.UserID,
+				SaleID:         &saleReturn.SaleID,
+				CreatedAt:      saleReturn.CreatedAt,
+			}
+			if err := insertStockMovement(ctx, tx, movement); err != nil {
+				r.logger.Error().Err(err).Msg("Failed to record stock movement")
+				return nil, err
+			}
+			onHand[item.MedicineVariantID] = movement.QuantityAfter
+		}
+
+		soldQuery := `UPDATE sale_items SET returned_quantity = returned_quantity + $1 WHERE id = $2`
+		if _, err := tx.ExecContext(ctx, soldQuery, item.Quantity, item.SaleItemID); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to update sale item")
+			return nil, err
+		}
+
+		saleReturn.Items[i].ReturnID = saleReturn.ID
+		saleReturn.Items[i].Lots = allocations
+		if i < len(saleReturn.CreditNote.Content.Items) {
+			saleReturn.CreditNote.Content.Items[i].Lots = allocations
+		}
+	}
+
+	// Restocking re-arms each variant's low-stock alert
+	if !saleReturn.Quarantine {
+		for _, variantID := range variantIDs {
+			if _, err := tx.ExecContext(ctx, `UPDATE medicine_variants SET low_stock_alerted_at = NULL, updated_at = $1 WHERE id = $2`, saleReturn.CreatedAt, variantID); err != nil {
+				r.logger.Error().Err(err).Msg("Failed to update medicine variant")
+				return nil, err
+			}
+		}
+	}
+
+	saleQuery := `UPDATE sales SET total_refunded = total_refunded + $1, updated_at = $2 WHERE id = $3`
+	if _, err := tx.ExecContext(ctx, saleQuery, saleReturn.TotalRefund, saleReturn.CreatedAt, saleReturn.SaleID); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update sale")
+		return nil, err
+	}
+
+	saleReturn.CreditNote.ReturnID = saleReturn.ID
+	content, err := json.Marshal(saleReturn.CreditNote.Content)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to marshal credit note content")
+		return nil, err
+	}
+	noteQuery := `
+        INSERT INTO credit_notes (id, return_id, receipt_id, content, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+	if _, err := tx.ExecContext(ctx, noteQuery, saleReturn.CreditNote.ID, saleReturn.ID, saleReturn.CreditNote.ReceiptID, content,
+		saleReturn.CreditNote.CreatedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create credit note")
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return nil, err
+	}
+	return &saleReturn, nil
+}
+
+// returnAllocations splits a returned quantity over the lots a sale item was
+// sold from, less what earlier returns already put back, latest expiry
+// first. The caller must hold the lots' locks.
+func (r *saleRepository) returnAllocations(ctx context.Context, tx *sql.Tx, saleItemID uuid.UUID, quantity int) ([]domain.LotAllocation, error) {
+	query := `
+        SELECT l.id, l.lot_number, l.expiry_date,
+               sil.quantity - COALESCE((
+                   SELECT SUM(rl.quantity)
+                   FROM sale_return_item_lots rl
+                   JOIN sale_return_items ri ON rl.return_item_id = ri.id
+                   WHERE ri.sale_item_id = sil.sale_item_id AND rl.lot_id = sil.lot_id
+               ), 0)
+        FROM sale_item_lots sil
+        JOIN medicine_lots l ON sil.lot_id = l.id
+        WHERE sil.sale_item_id = $1
+        ORDER BY l.expiry_date DESC, l.received_at DESC, l.id DESC
+    `
+	rows, err := tx.QueryContext(ctx, query, saleItemID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale item lots")
+		return nil, err
+	}
+
+	var allocations []domain.LotAllocation
+	remaining := quantity
+	for rows.Next() && remaining > 0 {
+		var a domain.LotAllocation
+		var available int
+		if err := rows.Scan(&a.LotID, &a.LotNumber, &a.ExpiryDate, &available); err != nil {
+			rows.Close()
+			r.logger.Error().Err(err).Msg("Failed to scan sale item lot")
+			return nil, err
+		}
+		if available <= 0 {
+			continue
+		}
+		a.Quantity = min(available, remaining)
+		remaining -= a.Quantity
+		allocations = append(allocations, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read sale item lots")
+		return nil, err
+	}
+	if remaining > 0 {
+		// Only sales made before lots were tracked lack lot records
+		r.logger.Info().Str("sale_item_id", saleItemID.String()).Msg("No lot recorded for returned units")
+		return nil, domain.ErrLotNotFound
+	}
+	return allocations, nil
+}
+
+// GetReturns retrieves a sale's returns with their items and credit notes,
+// oldest first
+func (r *saleRepository) GetReturns(ctx context.Context, saleID uuid.UUID) ([]domain.SaleReturn, error) {
+	query := `
+        SELECT sr.id, sr.sale_id, sr.user_id, sr.shift_id, sr.reason, sr.quarantine, sr.refund_method, sr.total_refund, sr.created_at,
+               cn.id, cn.receipt_id, cn.content, cn.created_at
+        FROM sale_returns sr
+        JOIN credit_notes cn ON cn.return_id = sr.id
+        WHERE sr.sale_id = $1
+        ORDER BY sr.created_at, sr.id
+    `
+	rows, err := r.db.QueryContext(ctx, query, saleID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale returns")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []domain.SaleReturn
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var sr domain.SaleReturn
+		var content []byte
+		if err := rows.Scan(&sr.ID, &sr.SaleID, &sr.UserID, &sr.ShiftID, &sr.Reason, &sr.Quarantine, &sr.RefundMethod, &sr.TotalRefund, &sr.CreatedAt,
+			&sr.CreditNote.ID, &sr.CreditNote.ReceiptID, &content, &sr.CreditNote.CreatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan sale return")
+			return nil, err
+		}
+		if err := json.Unmarshal(content, &sr.CreditNote.Content); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to unmarshal credit note content")
+			return nil, err
+		}
+		sr.CreditNote.ReturnID = sr.ID
+		index[sr.ID] = len(returns)
+		returns = append(returns, sr)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to read sale returns")
+		return nil, err
+	}
+	if len(returns) == 0 {
+		return nil, nil
+	}
+
+	itemQuery := `
+        SELECT ri.id, ri.return_id, ri.sale_item_id, si.medicine_variant_id, ri.quantity, ri.price_per_unit, ri.unit_cost, ri.refund, ri.tax
+        FROM sale_return_items ri
+        JOIN sale_returns sr ON ri.return_id = sr.id
+        JOIN sale_items si ON ri.sale_item_id = si.id
+        WHERE sr.sale_id = $1
+        ORDER BY ri.return_id, ri.position
+    `
+	itemRows, err := r.db.QueryContext(ctx, itemQuery, saleID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get sale return items")
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item domain.SaleReturnItem
+		if err := itemRows.Scan(&item.ID, &item.ReturnID, &item.SaleItemID, &item.MedicineVariantID, &item.Quantity,
+			&item.PricePerUnit, &item.UnitCost, &item.Refund, &item.Tax); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan sale return item")
+			return nil, err
+		}
+		sr := &returns[index[item.ReturnID]]
+		if i := len(sr.Items); i < len(sr.CreditNote.Content.Items) {
+			item.Lots = sr.CreditNote.Content.Items[i].Lots
+		}
+		sr.Items = append(sr.Items, item)
+	}
+	return returns, nil
+}
+
+// ReserveIdempotencyKey claims a key for the user's request. It returns nil
+// once the key is reserved, or the unexpired key already held, which carries
+// the sale and response if that request completed. Expired keys are purged
+// first so they can be reused.
+func (r *saleRepository) ReserveIdempotencyKey(ctx context.Context, key domain.IdempotencyKey) (*domain.IdempotencyKey, error) {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, key.CreatedAt); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to purge expired idempotency keys")
+		return nil, err
+	}
+
+	query := `
+        INSERT INTO idempotency_keys (user_id, key, request_hash, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (user_id, key) DO NOTHING
+    `
+	result, err := r.db.ExecContext(ctx, query, key.UserID, key.Key, key.RequestHash, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to reserve idempotency key")
+		return nil, err
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		return nil, nil
+	}
+
+	var existing domain.IdempotencyKey
+	var saleID uuid.NullUUID
+	query = `
+        SELECT key, user_id, request_hash, sale_id, response, created_at, expires_at
+        FROM idempotency_keys
+        WHERE user_id = $1 AND key = $2
+    `
+	err = r.db.QueryRowContext(ctx, query, key.UserID, key.Key).Scan(&existing.Key, &existing.UserID, &existing.RequestHash, &saleID, &existing.Response,
+		&existing.CreatedAt, &existing.ExpiresAt)
+	if err == sql.ErrNoRows {
+		// Released by the request holding it between our insert and select
+		r.logger.Info().Str("key", key.Key).Msg("Idempotency key released while reserving")
+		return nil, domain.ErrIdempotencyKeyInUse
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get idempotency key")
+		return nil, err
+	}
+	if saleID.Valid {
+		existing.SaleID = &saleID.UUID
+	}
+	return &existing, nil
+}
+
+// ReleaseIdempotencyKey gives up a reserved key whose request failed, so the
+// client can retry with it. Completed keys are kept.
+func (r *saleRepository) ReleaseIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND sale_id IS NULL`
+	if _, err := r.db.ExecContext(ctx, query, userID, key); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to release idempotency key")
+		return err
+	}
+	return nil
 }
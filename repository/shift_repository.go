@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// ShiftRepository defines the interface for cashier shift database
+// operations
+type ShiftRepository interface {
+	Create(ctx context.Context, shift domain.Shift) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Shift, error)
+	GetOpen(ctx context.Context, userID uuid.UUID) (*domain.Shift, error)
+	GetAll(ctx context.Context, pharmacyID uuid.UUID, limit, offset int) ([]domain.Shift, error)
+	Close(ctx context.Context, id uuid.UUID, counts []domain.TenderCount, closedAt time.Time) error
+	GetTotals(ctx context.Context, id uuid.UUID) (*domain.ShiftTotals, error)
+}
+
+// shiftRepository implements ShiftRepository
+type shiftRepository struct {
+	db     *sql.DB
+	logger zerolog.Logger
+}
+
+// NewShiftRepository creates a new ShiftRepository
+func NewShiftRepository(db *sql.DB, logger zerolog.Logger) ShiftRepository {
+	return &shiftRepository{db, logger}
+}
+
+const shiftColumns = `id, pharmacy_id, user_id, opening_float, status, opened_at, closed_at`
+
+func scanShift(row rowScanner, s *domain.Shift) error {
+	return row.Scan(&s.ID, &s.PharmacyID, &s.UserID, &s.OpeningFloat, &s.Status, &s.OpenedAt, &s.ClosedAt)
+}
+
+// Create opens a shift. It fails with ErrShiftAlreadyOpen if the user has
+// one open already.
+func (r *shiftRepository) Create(ctx context.Context, shift domain.Shift) error {
+	query := `
+        INSERT INTO shifts (id, pharmacy_id, user_id, opening_float, status, opened_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (user_id) WHERE status = 'open' DO NOTHING
+    `
+	result, err := r.db.ExecContext(ctx, query, shift.ID, shift.PharmacyID, shift.UserID, shift.OpeningFloat, shift.Status, shift.OpenedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create shift")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrShiftAlreadyOpen
+	}
+	return nil
+}
+
+// GetByID retrieves a shift by ID with its counts
+func (r *shiftRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Shift, error) {
+	query := `SELECT ` + shiftColumns + ` FROM shifts WHERE id = $1`
+	var s domain.Shift
+	err := scanShift(r.db.QueryRowContext(ctx, query, id), &s)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("id", id.String()).Msg("Shift not found")
+		return nil, domain.ErrShiftNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get shift by ID")
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT method, amount FROM shift_counts WHERE shift_id = $1 ORDER BY method`, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get shift counts")
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c domain.TenderCount
+		if err := rows.Scan(&c.Method, &c.Amount); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan shift count")
+			return nil, err
+		}
+		s.Counts = append(s.Counts, c)
+	}
+	return &s, nil
+}
+
+// GetOpen retrieves the user's open shift
+func (r *shiftRepository) GetOpen(ctx context.Context, userID uuid.UUID) (*domain.Shift, error) {
+	query := `SELECT ` + shiftColumns + ` FROM shifts WHERE user_id = $1 AND status = 'open'`
+	var s domain.Shift
+	err := scanShift(r.db.QueryRowContext(ctx, query, userID), &s)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("user_id", userID.String()).Msg("No open shift")
+		return nil, domain.ErrNoOpenShift
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get open shift")
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetAll retrieves a pharmacy's shifts, latest opened first, without their
+// counts
+func (r *shiftRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID, limit, offset int) ([]domain.Shift, error) {
+	query := `
+        SELECT ` + shiftColumns + `
+        FROM shifts
+        WHERE pharmacy_id = $1
+        ORDER BY opened_at DESC, id
+        LIMIT $2 OFFSET $3
+    `
+	rows, err := r.db.QueryContext(ctx, query, pharmacyID, limit, offset)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get shifts")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shifts []domain.Shift
+	for rows.Next() {
+		var s domain.Shift
+		if err := scanShift(rows, &s); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan shift")
+			return nil, err
+		}
+		shifts = append(shifts, s)
+	}
+	return shifts, nil
+}
+
+// Close closes an open shift and records its counts. Sales confirmed while
+// it is closing wait for it and are then refused, so its totals are final.
+func (r *shiftRepository) Close(ctx context.Context, id uuid.UUID, counts []domain.TenderCount, closedAt time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to begin transaction")
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE shifts SET status = 'closed', closed_at = $2 WHERE id = $1 AND status = 'open'`, id, closedAt)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to close shift")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		if _, err := r.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return domain.ErrShiftClosed
+	}
+
+	for _, c := range counts {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO shift_counts (shift_id, method, amount) VALUES ($1, $2, $3)`, id, c.Method, c.Amount); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to record shift count")
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to commit transaction")
+		return err
+	}
+	return nil
+}
+
+// GetTotals retrieves what a shift's sales took and its returns refunded, by
+// tender
+func (r *shiftRepository) GetTotals(ctx context.Context, id uuid.UUID) (*domain.ShiftTotals, error) {
+	var totals domain.ShiftTotals
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(total_price), 0) FROM sales WHERE shift_id = $1`, id).
+		Scan(&totals.SaleCount, &totals.TotalSales)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get shift sales")
+		return nil, err
+	}
+
+	query := `
+        SELECT p.method, COUNT(*), SUM(p.amount), SUM(p.change_given)
+        FROM sale_payments p
+        JOIN sales s ON p.sale_id = s.id
+        WHERE s.shift_id = $1
+        GROUP BY p.method
+        ORDER BY p.method
+    `
+	rows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get shift tenders")
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t domain.TenderTotal
+		if err := rows.Scan(&t.Method, &t.Count, &t.Amount, &t.Change); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan shift tender")
+			return nil, err
+		}
+		totals.Tenders = append(totals.Tenders, t)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get shift tenders")
+		return nil, err
+	}
+
+	query = `
+        SELECT refund_method, COUNT(*), SUM(total_refund)
+        FROM sale_returns
+        WHERE shift_id = $1
+        GROUP BY refund_method
+        ORDER BY refund_method
+    `
+	refundRows, err := r.db.QueryContext(ctx, query, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get shift refunds")
+		return nil, err
+	}
+	defer refundRows.Close()
+	for refundRows.Next() {
+		var t domain.TenderTotal
+		if err := refundRows.Scan(&t.Method, &t.Count, &t.Amount); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan shift refund")
+			return nil, err
+		}
+		totals.Refunds = append(totals.Refunds, t)
+	}
+	if err := refundRows.Err(); err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get shift refunds")
+		return nil, err
+	}
+	return &totals, nil
+}
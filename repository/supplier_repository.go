@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"pharmacy-management-backend/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// SupplierRepository defines the interface for supplier-related database operations
+type SupplierRepository interface {
+	Create(ctx context.Context, supplier domain.Supplier) error
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Supplier, error)
+	GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Supplier, error)
+	Update(ctx context.Context, supplier domain.Supplier) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	CountPurchaseOrders(ctx context.Context, supplierID uuid.UUID) (int, error)
+}
+
+// supplierRepository implements SupplierRepository
+type supplierRepository struct {
+	db     *sql.DB
+	logger zerolog.Logger
+}
+
+// NewSupplierRepository creates a new SupplierRepository
+func NewSupplierRepository(db *sql.DB, logger zerolog.Logger) SupplierRepository {
+	return &supplierRepository{db, logger}
+}
+
+// Create inserts a new supplier into the database
+func (r *supplierRepository) Create(ctx context.Context, supplier domain.Supplier) error {
+	query := `
+        INSERT INTO suppliers (id, pharmacy_id, name, contact_name, phone_number, email, address, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `
+	_, err := r.db.ExecContext(ctx, query,
+		supplier.ID, supplier.PharmacyID, supplier.Name, supplier.ContactName, supplier.PhoneNumber, supplier.Email,
+		supplier.Address, supplier.CreatedAt, supplier.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to create supplier")
+		return err
+	}
+	return nil
+}
+
+// GetByID retrieves a supplier by ID
+func (r *supplierRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Supplier, error) {
+	query := `
+        SELECT id, pharmacy_id, name, contact_name, phone_number, email, address, created_at, updated_at
+        FROM suppliers WHERE id = $1
+    `
+	var s domain.Supplier
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&s.ID, &s.PharmacyID, &s.Name, &s.ContactName, &s.PhoneNumber, &s.Email, &s.Address, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		r.logger.Info().Str("id", id.String()).Msg("Supplier not found")
+		return nil, domain.ErrSupplierNotFound
+	}
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get supplier by ID")
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetAll retrieves a pharmacy's suppliers ordered by name
+func (r *supplierRepository) GetAll(ctx context.Context, pharmacyID uuid.UUID) ([]domain.Supplier, error) {
+	query := `
+        SELECT id, pharmacy_id, name, contact_name, phone_number, email, address, created_at, updated_at
+        FROM suppliers
+        WHERE pharmacy_id = $1
+        ORDER BY name
+    `
+	rows, err := r.db.QueryContext(ctx, query, pharmacyID)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to get suppliers")
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suppliers []domain.Supplier
+	for rows.Next() {
+		var s domain.Supplier
+		if err := rows.Scan(&s.ID, &s.PharmacyID, &s.Name, &s.ContactName, &s.PhoneNumber, &s.Email, &s.Address, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			r.logger.Error().Err(err).Msg("Failed to scan supplier")
+			return nil, err
+		}
+		suppliers = append(suppliers, s)
+	}
+	return suppliers, nil
+}
+
+// Update updates a supplier
+func (r *supplierRepository) Update(ctx context.Context, supplier domain.Supplier) error {
+	query := `
+        UPDATE suppliers
+        SET name = $2, contact_name = $3, phone_number = $4, email = $5, address = $6, updated_at = $7
+        WHERE id = $1
+    `
+	result, err := r.db.ExecContext(ctx, query,
+		supplier.ID, supplier.Name, supplier.ContactName, supplier.PhoneNumber, supplier.Email, supplier.Address, supplier.UpdatedAt,
+	)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to update supplier")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		r.logger.Info().Str("id", supplier.ID.String()).Msg("Supplier not found for update")
+		return domain.ErrSupplierNotFound
+	}
+	return nil
+}
+
+// Delete deletes a supplier
+func (r *supplierRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM suppliers WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to delete supplier")
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to check rows affected")
+		return err
+	}
+	if rowsAffected == 0 {
+		r.logger.Info().Str("id", id.String()).Msg("Supplier not found for deletion")
+		return domain.ErrSupplierNotFound
+	}
+	return nil
+}
+
+// CountPurchaseOrders counts purchase orders placed with a supplier
+func (r *supplierRepository) CountPurchaseOrders(ctx context.Context, supplierID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM purchase_orders WHERE supplier_id = $1`
+	var count int
+	err := r.db.QueryRowContext(ctx, query, supplierID).Scan(&count)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("Failed to count purchase orders")
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// customerSearchLimit is the most customers a search returns
+const customerSearchLimit = 50
+
+// CustomerUsecase defines the interface for customer business logic
+type CustomerUsecase interface {
+	Create(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, input domain.CustomerInput) (*domain.Customer, error)
+	GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.Customer, error)
+	Search(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, query string) ([]domain.Customer, error)
+	Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.CustomerInput) (*domain.Customer, error)
+	Delete(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) error
+	GetPurchases(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, limit, offset int) (*domain.CustomerPurchasePage, error)
+}
+
+// customerUsecase implements CustomerUsecase
+type customerUsecase struct {
+	repo     repository.CustomerRepository
+	saleRepo repository.SaleRepository
+}
+
+// NewCustomerUsecase creates a new CustomerUsecase
+func NewCustomerUsecase(repo repository.CustomerRepository, saleRepo repository.SaleRepository) CustomerUsecase {
+	return &customerUsecase{repo, saleRepo}
+}
+
+// Create registers a customer with the caller's pharmacy
+func (u *customerUsecase) Create(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, input domain.CustomerInput) (*domain.Customer, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	now := time.Now()
+	customer := domain.Customer{
+		ID:          uuid.New(),
+		PharmacyID:  callerPharmacyID,
+		FullName:    input.FullName,
+		PhoneNumber: input.PhoneNumber,
+		Notes:       input.Notes,
+		Allergies:   input.Allergies,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := u.repo.Create(ctx, customer); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// GetByID retrieves one of the caller's pharmacy customers
+func (u *customerUsecase) GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.Customer, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.get(ctx, callerPharmacyID, id)
+}
+
+// Search finds the caller's pharmacy customers by name or phone number
+func (u *customerUsecase) Search(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, query string) ([]domain.Customer, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	customers, err := u.repo.Search(ctx, callerPharmacyID, query, customerSearchLimit)
+	if err != nil {
+		return nil, err
+	}
+	if customers == nil {
+		customers = []domain.Customer{}
+	}
+	return customers, nil
+}
+
+// Update updates one of the caller's pharmacy customers
+func (u *customerUsecase) Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.CustomerInput) (*domain.Customer, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	customer, err := u.get(ctx, callerPharmacyID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	customer.FullName = input.FullName
+	customer.PhoneNumber = input.PhoneNumber
+	customer.Notes = input.Notes
+	customer.Allergies = input.Allergies
+	customer.UpdatedAt = time.Now()
+	if err := u.repo.Update(ctx, *customer); err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+// Delete deletes one of the caller's pharmacy customers, keeping their sales
+func (u *customerUsecase) Delete(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) error {
+	if callerRole != string(domain.RoleOwner) {
+		return domain.ErrUnauthorized
+	}
+
+	if _, err := u.get(ctx, callerPharmacyID, id); err != nil {
+		return err
+	}
+	return u.repo.Delete(ctx, id)
+}
+
+// GetPurchases retrieves a page of a customer's purchases with their lines,
+// latest first
+func (u *customerUsecase) GetPurchases(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, limit, offset int) (*domain.CustomerPurchasePage, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	if _, err := u.get(ctx, callerPharmacyID, id); err != nil {
+		return nil, err
+	}
+
+	sales, err := u.saleRepo.GetSales(ctx, domain.SaleFilter{
+		PharmacyID: callerPharmacyID,
+		CustomerID: id,
+		Sort:       domain.SaleSortDateDesc,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	saleIDs := make([]uuid.UUID, len(sales.Sales))
+	for i, s := range sales.Sales {
+		saleIDs[i] = s.ID
+	}
+	saleItems, err := u.saleRepo.GetSaleItemsBySaleIDs(ctx, saleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	page := domain.CustomerPurchasePage{
+		Purchases: make([]domain.CustomerPurchase, len(sales.Sales)),
+		Total:     sales.Total,
+		Limit:     sales.Limit,
+		Offset:    sales.Offset,
+	}
+	for i, s := range sales.Sales {
+		page.Purchases[i] = domain.CustomerPurchase{SaleHeader: s, Items: saleResponses(saleItems[s.ID])}
+	}
+	return &page, nil
+}
+
+// get retrieves a customer, restricted to the caller's pharmacy
+func (u *customerUsecase) get(ctx context.Context, callerPharmacyID, id uuid.UUID) (*domain.Customer, error) {
+	customer, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if customer.PharmacyID != callerPharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+	return customer, nil
+}
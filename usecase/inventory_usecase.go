@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/infrastructure"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// LowStockCheck asks for a pharmacy's variants to be checked against their
+// reorder points after a sale
+type LowStockCheck struct {
+	PharmacyID uuid.UUID
+	VariantIDs []uuid.UUID
+}
+
+// InventoryUsecase defines the interface for stock-level business logic
+type InventoryUsecase interface {
+	GetLowStock(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID) ([]domain.LowStockItem, error)
+	NotifyLowStock(ctx context.Context, pharmacyID uuid.UUID, variantIDs []uuid.UUID) error
+	GetExpiring(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, within time.Duration) ([]domain.ExpiringMedicine, error)
+	WriteOff(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.CreateWriteOffInput) (*domain.WriteOff, error)
+	GetWriteOffs(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, from, to time.Time) (*domain.WriteOffReport, error)
+}
+
+// inventoryUsecase implements InventoryUsecase
+type inventoryUsecase struct {
+	repo         repository.InventoryRepository
+	medicineRepo repository.MedicineRepository
+	authRepo     repository.AuthRepository
+	sms          infrastructure.SMSSender
+}
+
+// NewInventoryUsecase creates a new InventoryUsecase
+func NewInventoryUsecase(repo repository.InventoryRepository, medicineRepo repository.MedicineRepository, authRepo repository.AuthRepository, sms infrastructure.SMSSender) InventoryUsecase {
+	return &inventoryUsecase{repo, medicineRepo, authRepo, sms}
+}
+
+// GetLowStock lists the caller's pharmacy variants below their reorder point
+func (u *inventoryUsecase) GetLowStock(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID) ([]domain.LowStockItem, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.medicineRepo.GetLowStock(ctx, callerPharmacyID)
+}
+
+// NotifyLowStock texts the pharmacy's owners about any of the given variants
+// that are below their reorder point. Each variant is reported once until it
+// is restocked, or until a notification about it fails to send. Variants that
+// could not be checked are skipped and their errors returned together.
+func (u *inventoryUsecase) NotifyLowStock(ctx context.Context, pharmacyID uuid.UUID, variantIDs []uuid.UUID) error {
+	var errs []error
+	var lines []string
+	var claims []uuid.UUID
+	for _, variantID := range variantIDs {
+		variant, err := u.medicineRepo.GetVariantByID(ctx, variantID)
+		if err == domain.ErrVariantNotFound {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if variant.ReorderPoint == 0 || variant.Stock >= variant.ReorderPoint {
+			continue
+		}
+
+		medicine, err := u.medicineRepo.GetByID(ctx, variant.MedicineID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		claimed, err := u.medicineRepo.ClaimLowStockAlert(ctx, variantID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		claims = append(claims, variantID)
+
+		line := fmt.Sprintf("%s %s: %d %s left", medicine.Name, variant.Brand, variant.Stock, variant.Unit)
+		if variant.ReorderQuantity > 0 {
+			line += fmt.Sprintf(", reorder %d", variant.ReorderQuantity)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return errors.Join(errs...)
+	}
+
+	owners, err := u.authRepo.GetOwners(ctx, pharmacyID)
+	if err != nil {
+		return errors.Join(append(append(errs, err), u.releaseLowStockAlerts(ctx, claims)...)...)
+	}
+
+	message := "Low stock: " + strings.Join(lines, "; ")
+	sent := true
+	for _, owner := range owners {
+		if err := u.sms.SendSMS(owner.PhoneNumber, message); err != nil {
+			errs = append(errs, fmt.Errorf("texting owner %s: %w", owner.ID, err))
+			sent = false
+		}
+	}
+	// Every owner hears of the low stock, even if some hear twice
+	if !sent {
+		errs = append(errs, u.releaseLowStockAlerts(ctx, claims)...)
+	}
+	return errors.Join(errs...)
+}
+
+// releaseLowStockAlerts gives up claimed alerts so the next check sends them
+// again, returning the errors of any that could not be released
+func (u *inventoryUsecase) releaseLowStockAlerts(ctx context.Context, variantIDs []uuid.UUID) []error {
+	var errs []error
+	for _, variantID := range variantIDs {
+		if err := u.medicineRepo.ReleaseLowStockAlert(ctx, variantID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// GetExpiring reports the caller's pharmacy stock that expires within the
+// given window, including stock that has already expired
+func (u *inventoryUsecase) GetExpiring(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, within time.Duration) ([]domain.ExpiringMedicine, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.repo.GetExpiring(ctx, callerPharmacyID, time.Now().Add(within))
+}
+
+// WriteOff removes expired stock from sellable inventory, recording the loss
+func (u *inventoryUsecase) WriteOff(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.CreateWriteOffInput) (*domain.WriteOff, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	writeOff := domain.WriteOff{
+		ID:         uuid.New(),
+		PharmacyID: callerPharmacyID,
+		LotID:      input.LotID,
+		Quantity:   input.Quantity,
+		Reason:     input.Reason,
+		UserID:     callerUserID,
+		CreatedAt:  time.Now(),
+	}
+
+	return u.repo.CreateWriteOff(ctx, writeOff)
+}
+
+// GetWriteOffs reports the caller's pharmacy write-offs in [from, to)
+func (u *inventoryUsecase) GetWriteOffs(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, from, to time.Time) (*domain.WriteOffReport, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	writeOffs, err := u.repo.GetWriteOffs(ctx, callerPharmacyID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := domain.WriteOffReport{From: from, To: to, WriteOffs: writeOffs}
+	for _, w := range writeOffs {
+		report.TotalLoss += w.LossValue
+	}
+	return &report, nil
+}
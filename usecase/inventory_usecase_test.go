@@ -0,0 +1,68 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository/memory"
+	"pharmacy-management-backend/usecase"
+
+	"github.com/google/uuid"
+)
+
+// smsOutbox records the messages sent to it, failing while err is set
+type smsOutbox struct {
+	err  error
+	sent []string
+}
+
+func (o *smsOutbox) SendSMS(to, body string) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.sent = append(o.sent, body)
+	return nil
+}
+
+func TestNotifyLowStockSendFails(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	fail := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := memory.NewStore()
+	medicineRepo := memory.NewMedicineRepository(store)
+	authRepo := memory.NewAuthRepository(store)
+	outbox := &smsOutbox{err: errors.New("network unreachable")}
+	inventory := usecase.NewInventoryUsecase(memory.NewInventoryRepository(store), medicineRepo, authRepo, outbox)
+
+	pharmacy := domain.Pharmacy{ID: uuid.New(), Name: "Central Pharmacy", Address: "1 Main St", CreatedAt: now, UpdatedAt: now}
+	fail(memory.NewPharmacyRepository(store).Create(ctx, pharmacy))
+	fail(authRepo.Create(ctx, domain.User{ID: uuid.New(), PhoneNumber: "+251911000002", Password: "hashed", FullName: "Owner",
+		Role: domain.RoleOwner, PharmacyID: pharmacy.ID, CreatedAt: now, UpdatedAt: now}))
+	medicine := domain.Medicine{ID: uuid.New(), PharmacyID: pharmacy.ID, Name: "Omeprazole", CreatedAt: now, UpdatedAt: now}
+	fail(medicineRepo.Create(ctx, medicine))
+	variant := domain.MedicineVariant{ID: uuid.New(), MedicineID: medicine.ID, Brand: "Losec", Barcode: "BC" + uuid.NewString()[:8], Unit: "box",
+		PricePerUnit: 500, ReorderPoint: 10, CreatedAt: now, UpdatedAt: now}
+	lot := domain.MedicineLot{ID: uuid.New(), VariantID: variant.ID, LotNumber: "L1", ExpiryDate: now.AddDate(1, 0, 0), Quantity: 3,
+		ReceivedAt: now, CreatedAt: now, UpdatedAt: now}
+	fail(medicineRepo.CreateVariant(ctx, variant, []domain.MedicineLot{lot}, uuid.Nil))
+
+	if err := inventory.NotifyLowStock(ctx, pharmacy.ID, []uuid.UUID{variant.ID}); err == nil {
+		t.Fatal("expected the failed send to be reported")
+	}
+
+	// The failed alert is not lost: the next check sends it, and only once
+	outbox.err = nil
+	fail(inventory.NotifyLowStock(ctx, pharmacy.ID, []uuid.UUID{variant.ID}))
+	fail(inventory.NotifyLowStock(ctx, pharmacy.ID, []uuid.UUID{variant.ID}))
+	if len(outbox.sent) != 1 {
+		t.Fatalf("expected one low-stock message after the failure, got %q", outbox.sent)
+	}
+}
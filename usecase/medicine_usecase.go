@@ -17,11 +17,16 @@ type MedicineUsecase interface {
 	GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.Medicine, error)
 	Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.UpdateMedicineInput) error
 	Delete(ctx context.Context, callerRole string, id uuid.UUID) error
-	CreateVariant(ctx context.Context, callerRole string, callerPharmacyID, medicineID uuid.UUID, input domain.CreateMedicineVariantInput) error
+	CreateVariant(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, medicineID uuid.UUID, input domain.CreateMedicineVariantInput) error
 	GetVariants(ctx context.Context, callerRole string, callerPharmacyID, medicineID uuid.UUID) ([]domain.MedicineVariant, error)
 	GetVariantByID(ctx context.Context, callerRole string, callerPharmacyID, medicineID, variantID uuid.UUID) (*domain.MedicineVariant, error)
 	UpdateVariant(ctx context.Context, callerRole string, callerPharmacyID, medicineID, variantID uuid.UUID, input domain.UpdateMedicineVariantInput) error
 	DeleteVariant(ctx context.Context, callerRole string, variantID uuid.UUID) error
+	CreateLot(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, medicineID, variantID uuid.UUID, input domain.CreateMedicineLotInput) error
+	GetLots(ctx context.Context, callerRole string, callerPharmacyID, medicineID, variantID uuid.UUID) ([]domain.MedicineLot, error)
+	AdjustStock(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, medicineID, variantID uuid.UUID, input domain.StockAdjustmentInput) (*domain.StockMovement, error)
+	GetStockMovements(ctx context.Context, callerRole string, callerPharmacyID, medicineID, variantID uuid.UUID, limit, offset int) ([]domain.StockMovement, error)
+	GetCatalogChanges(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, cursor string, limit int) (*domain.CatalogDelta, error)
 }
 
 // medicineUsecase implements MedicineUsecase
@@ -57,9 +62,14 @@ func (u *medicineUsecase) Create(ctx context.Context, callerRole string, callerP
 		Name:        input.Name,
 		Description: input.Description,
 		Picture:     input.Picture,
+		Category:    input.Category,
+		TaxCategory: input.TaxCategory,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	if medicine.TaxCategory == "" {
+		medicine.TaxCategory = domain.TaxStandard
+	}
 
 	return u.repo.Create(ctx, medicine)
 }
@@ -102,6 +112,10 @@ func (u *medicineUsecase) Update(ctx context.Context, callerRole string, callerP
 	medicine.Name = input.Name
 	medicine.Description = input.Description
 	medicine.Picture = input.Picture
+	medicine.Category = input.Category
+	if input.TaxCategory != "" {
+		medicine.TaxCategory = input.TaxCategory
+	}
 	medicine.UpdatedAt = time.Now()
 
 	return u.repo.Update(ctx, *medicine)
@@ -125,7 +139,7 @@ func (u *medicineUsecase) Delete(ctx context.Context, callerRole string, id uuid
 }
 
 // CreateVariant creates a new medicine variant
-func (u *medicineUsecase) CreateVariant(ctx context.Context, callerRole string, callerPharmacyID, medicineID uuid.UUID, input domain.CreateMedicineVariantInput) error {
+func (u *medicineUsecase) CreateVariant(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, medicineID uuid.UUID, input domain.CreateMedicineVariantInput) error {
 	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) {
 		return domain.ErrUnauthorized
 	}
@@ -146,19 +160,39 @@ func (u *medicineUsecase) CreateVariant(ctx context.Context, callerRole string,
 	}
 
 	variant := domain.MedicineVariant{
-		ID:           uuid.New(),
-		MedicineID:   medicineID,
-		Brand:        input.Brand,
-		Barcode:      input.Barcode,
-		Unit:         input.Unit,
-		PricePerUnit: input.PricePerUnit,
-		ExpiryDate:   input.ExpiryDate,
-		Stock:        input.Stock,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:              uuid.New(),
+		MedicineID:      medicineID,
+		Brand:           input.Brand,
+		Barcode:         input.Barcode,
+		Unit:            input.Unit,
+		PricePerUnit:    input.PricePerUnit,
+		CostPrice:       input.CostPrice,
+		ReorderPoint:    input.ReorderPoint,
+		ReorderQuantity: input.ReorderQuantity,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	// The opening stock becomes the variant's first lot
+	var lots []domain.MedicineLot
+	if input.Stock > 0 {
+		lotNumber := input.LotNumber
+		if lotNumber == "" {
+			lotNumber = "INITIAL"
+		}
+		lots = append(lots, domain.MedicineLot{
+			ID:         uuid.New(),
+			VariantID:  variant.ID,
+			LotNumber:  lotNumber,
+			ExpiryDate: input.ExpiryDate,
+			Quantity:   input.Stock,
+			ReceivedAt: time.Now(),
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		})
 	}
 
-	return u.repo.CreateVariant(ctx, variant)
+	return u.repo.CreateVariant(ctx, variant, lots, callerUserID)
 }
 
 // GetVariants retrieves variants for a medicine
@@ -234,8 +268,11 @@ func (u *medicineUsecase) UpdateVariant(ctx context.Context, callerRole string,
 	variant.Barcode = input.Barcode
 	variant.Unit = input.Unit
 	variant.PricePerUnit = input.PricePerUnit
-	variant.ExpiryDate = input.ExpiryDate
-	variant.Stock = input.Stock
+	if input.CostPrice != nil {
+		variant.CostPrice = *input.CostPrice
+	}
+	variant.ReorderPoint = input.ReorderPoint
+	variant.ReorderQuantity = input.ReorderQuantity
 	variant.UpdatedAt = time.Now()
 
 	return u.repo.UpdateVariant(ctx, *variant)
@@ -249,3 +286,120 @@ func (u *medicineUsecase) DeleteVariant(ctx context.Context, callerRole string,
 
 	return u.repo.DeleteVariant(ctx, variantID)
 }
+
+// CreateLot records a newly received lot of a variant
+func (u *medicineUsecase) CreateLot(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, medicineID, variantID uuid.UUID, input domain.CreateMedicineLotInput) error {
+	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) {
+		return domain.ErrUnauthorized
+	}
+
+	if _, err := u.GetVariantByID(ctx, callerRole, callerPharmacyID, medicineID, variantID); err != nil {
+		return err
+	}
+
+	lots, err := u.repo.GetLotsByVariantID(ctx, variantID)
+	if err != nil {
+		return err
+	}
+	for _, lot := range lots {
+		if lot.LotNumber == input.LotNumber {
+			return domain.ErrLotNumberTaken
+		}
+	}
+
+	receivedAt := input.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	lot := domain.MedicineLot{
+		ID:         uuid.New(),
+		VariantID:  variantID,
+		LotNumber:  input.LotNumber,
+		ExpiryDate: input.ExpiryDate,
+		Quantity:   input.Quantity,
+		ReceivedAt: receivedAt,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	return u.repo.CreateLot(ctx, lot, callerUserID)
+}
+
+// GetLots retrieves all lots of a variant, including expired and empty ones
+func (u *medicineUsecase) GetLots(ctx context.Context, callerRole string, callerPharmacyID, medicineID, variantID uuid.UUID) ([]domain.MedicineLot, error) {
+	if _, err := u.GetVariantByID(ctx, callerRole, callerPharmacyID, medicineID, variantID); err != nil {
+		return nil, err
+	}
+
+	return u.repo.GetLotsByVariantID(ctx, variantID)
+}
+
+// AdjustStock applies a manual, reasoned change to one lot of a variant
+func (u *medicineUsecase) AdjustStock(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, medicineID, variantID uuid.UUID, input domain.StockAdjustmentInput) (*domain.StockMovement, error) {
+	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+	if input.Type == domain.StockMovementDamage && input.Quantity > 0 {
+		return nil, domain.ErrInvalidAdjustment
+	}
+
+	if _, err := u.GetVariantByID(ctx, callerRole, callerPharmacyID, medicineID, variantID); err != nil {
+		return nil, err
+	}
+
+	movement := domain.StockMovement{
+		ID:        uuid.New(),
+		VariantID: variantID,
+		LotID:     input.LotID,
+		Type:      input.Type,
+		Quantity:  input.Quantity,
+		Reason:    input.Reason,
+		UserID:    callerUserID,
+		CreatedAt: time.Now(),
+	}
+
+	return u.repo.AdjustStock(ctx, movement)
+}
+
+// GetStockMovements retrieves a variant's stock ledger, newest first
+func (u *medicineUsecase) GetStockMovements(ctx context.Context, callerRole string, callerPharmacyID, medicineID, variantID uuid.UUID, limit, offset int) ([]domain.StockMovement, error) {
+	if _, err := u.GetVariantByID(ctx, callerRole, callerPharmacyID, medicineID, variantID); err != nil {
+		return nil, err
+	}
+
+	return u.repo.GetStockMovements(ctx, variantID, limit, offset)
+}
+
+// catalogSettle is how long catalog changes are held back from sync, so that
+// a change still being committed is not skipped by a cursor already past it
+const catalogSettle = 5 * time.Second
+
+// GetCatalogChanges retrieves a page of the caller's pharmacy's catalog
+// changes after cursor, for a POS to keep its offline catalog up to date. An
+// empty cursor starts from the beginning, returning the whole catalog.
+func (u *medicineUsecase) GetCatalogChanges(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, cursor string, limit int) (*domain.CatalogDelta, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	after, err := domain.ParseCatalogCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	items, err := u.repo.GetCatalogChanges(ctx, callerPharmacyID, after, time.Now().Add(-catalogSettle), limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	delta := domain.CatalogDelta{Items: []domain.CatalogItem{}, Cursor: cursor}
+	if len(items) > limit {
+		items, delta.HasMore = items[:limit], true
+	}
+	if len(items) > 0 {
+		last := items[len(items)-1]
+		delta.Items = items
+		delta.Cursor = domain.CatalogCursor{ChangedAt: last.ChangedAt, VariantID: last.VariantID}.String()
+	}
+	return &delta, nil
+}
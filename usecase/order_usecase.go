@@ -60,7 +60,7 @@ func (u *orderUsecase) GetOrderDetails(ctx context.Context, callerRole string, c
 	}
 
 	response.Items = make([]domain.OrderItemResponse, len(items))
-	var totalPrice float64
+	var totalPrice domain.Money
 	for i, item := range items {
 		response.Items[i] = domain.OrderItemResponse{
 			MedicineName: item.MedicineName,
@@ -68,7 +68,7 @@ func (u *orderUsecase) GetOrderDetails(ctx context.Context, callerRole string, c
 			Quantity:     item.Quantity,
 			PricePerUnit: item.PricePerUnit,
 		}
-		totalPrice += float64(item.Quantity) * item.PricePerUnit
+		totalPrice += item.PricePerUnit.Mul(item.Quantity)
 	}
 	response.TotalPrice = totalPrice
 
@@ -16,6 +16,8 @@ type PharmacyUsecase interface {
 	GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID) ([]domain.Pharmacy, error)
 	GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.Pharmacy, error)
 	Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.Pharmacy) error
+	UpdateTaxSettings(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.TaxSettings) (*domain.Pharmacy, error)
+	UpdateReceiptSettings(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.ReceiptSettings) (*domain.Pharmacy, error)
 	Delete(ctx context.Context, callerRole string, id uuid.UUID) error
 }
 
@@ -36,6 +38,9 @@ func (u *pharmacyUsecase) Create(ctx context.Context, callerRole string, input d
 	}
 
 	input.ID = uuid.New()
+	if input.ReceiptSettings.PaperWidth == 0 {
+		input.ReceiptSettings.PaperWidth = domain.DefaultPaperWidth
+	}
 	input.CreatedAt = time.Now()
 	input.UpdatedAt = time.Now()
 
@@ -76,6 +81,39 @@ func (u *pharmacyUsecase) Update(ctx context.Context, callerRole string, callerP
 	return u.repo.Update(ctx, input)
 }
 
+// UpdateTaxSettings sets a pharmacy's VAT rate and whether its prices
+// include VAT. Sales already made keep the tax they were charged.
+func (u *pharmacyUsecase) UpdateTaxSettings(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.TaxSettings) (*domain.Pharmacy, error) {
+	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+	if callerRole == string(domain.RoleOwner) && callerPharmacyID != id {
+		return nil, domain.ErrUnauthorized
+	}
+
+	if err := u.repo.UpdateTaxSettings(ctx, id, input, time.Now()); err != nil {
+		return nil, err
+	}
+	return u.repo.GetByID(ctx, id)
+}
+
+// UpdateReceiptSettings sets a pharmacy's receipt header, footer, paper width
+// and numbering. Numbering carries on from the last receipt of the series;
+// only the prefix printed changes.
+func (u *pharmacyUsecase) UpdateReceiptSettings(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.ReceiptSettings) (*domain.Pharmacy, error) {
+	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+	if callerRole == string(domain.RoleOwner) && callerPharmacyID != id {
+		return nil, domain.ErrUnauthorized
+	}
+
+	if err := u.repo.UpdateReceiptSettings(ctx, id, input, time.Now()); err != nil {
+		return nil, err
+	}
+	return u.repo.GetByID(ctx, id)
+}
+
 // Delete deletes a pharmacy (admin-only)
 func (u *pharmacyUsecase) Delete(ctx context.Context, callerRole string, id uuid.UUID) error {
 	if callerRole != string(domain.RoleAdmin) {
@@ -0,0 +1,241 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// PromotionUsecase defines the interface for promotion and manual discount
+// business logic
+type PromotionUsecase interface {
+	Create(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, input domain.PromotionInput) (*domain.Promotion, error)
+	GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.Promotion, error)
+	GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID) ([]domain.Promotion, error)
+	Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.PromotionInput) (*domain.Promotion, error)
+	Delete(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) error
+
+	RequestManualDiscount(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.ManualDiscountInput) (*domain.ManualDiscount, error)
+	GetManualDiscounts(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, status domain.ManualDiscountStatus) ([]domain.ManualDiscount, error)
+	ReviewManualDiscount(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, id uuid.UUID, approve bool) (*domain.ManualDiscount, error)
+}
+
+// promotionUsecase implements PromotionUsecase
+type promotionUsecase struct {
+	repo         repository.PromotionRepository
+	medicineRepo repository.MedicineRepository
+	saleRepo     repository.SaleRepository
+}
+
+// NewPromotionUsecase creates a new PromotionUsecase
+func NewPromotionUsecase(repo repository.PromotionRepository, medicineRepo repository.MedicineRepository, saleRepo repository.SaleRepository) PromotionUsecase {
+	return &promotionUsecase{repo, medicineRepo, saleRepo}
+}
+
+// Create adds a promotion to the caller's pharmacy
+func (u *promotionUsecase) Create(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, input domain.PromotionInput) (*domain.Promotion, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+	if err := u.checkInput(ctx, callerPharmacyID, input); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	promotion := domain.Promotion{
+		ID:         uuid.New(),
+		PharmacyID: callerPharmacyID,
+		CreatedAt:  now,
+	}
+	applyPromotionInput(&promotion, input, now)
+	if err := u.repo.Create(ctx, promotion); err != nil {
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+// GetByID retrieves one of the caller's pharmacy promotions
+func (u *promotionUsecase) GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.Promotion, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.get(ctx, callerPharmacyID, id)
+}
+
+// GetAll lists the caller's pharmacy promotions
+func (u *promotionUsecase) GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID) ([]domain.Promotion, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.repo.GetAll(ctx, callerPharmacyID)
+}
+
+// Update replaces one of the caller's pharmacy promotions. Sales it was
+// already applied to are unchanged.
+func (u *promotionUsecase) Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.PromotionInput) (*domain.Promotion, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	promotion, err := u.get(ctx, callerPharmacyID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.checkInput(ctx, callerPharmacyID, input); err != nil {
+		return nil, err
+	}
+
+	applyPromotionInput(promotion, input, time.Now())
+	if err := u.repo.Update(ctx, *promotion); err != nil {
+		return nil, err
+	}
+	return promotion, nil
+}
+
+// Delete deletes one of the caller's pharmacy promotions
+func (u *promotionUsecase) Delete(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) error {
+	if callerRole != string(domain.RoleOwner) {
+		return domain.ErrUnauthorized
+	}
+
+	if _, err := u.get(ctx, callerPharmacyID, id); err != nil {
+		return err
+	}
+	return u.repo.Delete(ctx, id)
+}
+
+// RequestManualDiscount asks for an ad-hoc discount on a line of the caller's
+// cart, or on the whole cart. Owners' own requests are approved at once;
+// pharmacists' wait for an owner.
+func (u *promotionUsecase) RequestManualDiscount(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.ManualDiscountInput) (*domain.ManualDiscount, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	if !input.Valid() {
+		return nil, domain.ErrInvalidManualDiscount
+	}
+
+	if input.CartID != nil {
+		cartItems, err := u.saleRepo.GetCart(ctx, callerUserID)
+		if err != nil {
+			return nil, err
+		}
+		found := false
+		for _, item := range cartItems {
+			if item.ID == *input.CartID && item.PharmacyID == callerPharmacyID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, domain.ErrCartItemNotFound
+		}
+	}
+
+	now := time.Now()
+	discount := domain.ManualDiscount{
+		ID:         uuid.New(),
+		PharmacyID: callerPharmacyID,
+		UserID:     callerUserID,
+		CartID:     input.CartID,
+		Rate:       input.Rate,
+		Amount:     input.Amount,
+		Reason:     input.Reason,
+		Status:     domain.ManualDiscountPending,
+		CreatedAt:  now,
+	}
+	if callerRole == string(domain.RoleOwner) {
+		discount.Status = domain.ManualDiscountApproved
+		discount.ReviewedBy = &callerUserID
+		discount.ReviewedAt = &now
+	}
+	if err := u.repo.CreateManualDiscount(ctx, discount); err != nil {
+		return nil, err
+	}
+	return &discount, nil
+}
+
+// GetManualDiscounts lists the caller's pharmacy manual discounts, optionally
+// only those in one status
+func (u *promotionUsecase) GetManualDiscounts(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, status domain.ManualDiscountStatus) ([]domain.ManualDiscount, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.repo.GetManualDiscounts(ctx, callerPharmacyID, status)
+}
+
+// ReviewManualDiscount approves or rejects a pending manual discount
+func (u *promotionUsecase) ReviewManualDiscount(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, id uuid.UUID, approve bool) (*domain.ManualDiscount, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	discount, err := u.repo.GetManualDiscountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if discount.PharmacyID != callerPharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+
+	status := domain.ManualDiscountRejected
+	if approve {
+		status = domain.ManualDiscountApproved
+	}
+	if err := u.repo.ReviewManualDiscount(ctx, id, status, callerUserID, time.Now()); err != nil {
+		return nil, err
+	}
+	return u.repo.GetManualDiscountByID(ctx, id)
+}
+
+// get retrieves a promotion, restricted to the caller's pharmacy
+func (u *promotionUsecase) get(ctx context.Context, callerPharmacyID, id uuid.UUID) (*domain.Promotion, error) {
+	promotion, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promotion.PharmacyID != callerPharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+	return promotion, nil
+}
+
+// checkInput checks an input's fields agree and that any medicine it targets
+// belongs to the caller's pharmacy
+func (u *promotionUsecase) checkInput(ctx context.Context, callerPharmacyID uuid.UUID, input domain.PromotionInput) error {
+	if !input.Valid() {
+		return domain.ErrInvalidPromotion
+	}
+	if input.MedicineID != nil {
+		medicine, err := u.medicineRepo.GetByID(ctx, *input.MedicineID)
+		if err != nil {
+			return err
+		}
+		if medicine.PharmacyID != callerPharmacyID {
+			return domain.ErrUnauthorized
+		}
+	}
+	return nil
+}
+
+// applyPromotionInput copies an input's fields onto a promotion
+func applyPromotionInput(p *domain.Promotion, input domain.PromotionInput, now time.Time) {
+	p.Name = input.Name
+	p.Type = input.Type
+	p.Scope = input.Scope
+	p.Rate = input.Rate
+	p.Amount = input.Amount
+	p.BuyQuantity = input.BuyQuantity
+	p.GetQuantity = input.GetQuantity
+	p.MedicineID = input.MedicineID
+	p.Category = input.Category
+	p.MinSubtotal = input.MinSubtotal
+	p.Active = input.Active == nil || *input.Active
+	p.StartsAt = input.StartsAt
+	p.EndsAt = input.EndsAt
+	p.UpdatedAt = now
+}
@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// PurchaseOrderUsecase defines the interface for purchasing business logic
+type PurchaseOrderUsecase interface {
+	Create(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.PurchaseOrderInput) (*domain.PurchaseOrder, error)
+	GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.PurchaseOrder, error)
+	GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, status domain.PurchaseOrderStatus, limit, offset int) ([]domain.PurchaseOrder, error)
+	Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.PurchaseOrderInput) (*domain.PurchaseOrder, error)
+	UpdateStatus(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.UpdatePurchaseOrderStatusInput) (*domain.PurchaseOrder, error)
+	Receive(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, id uuid.UUID, input domain.ReceiveGoodsInput) (*domain.GoodsReceipt, error)
+}
+
+// purchaseOrderUsecase implements PurchaseOrderUsecase
+type purchaseOrderUsecase struct {
+	repo         repository.PurchaseOrderRepository
+	supplierRepo repository.SupplierRepository
+	medicineRepo repository.MedicineRepository
+}
+
+// NewPurchaseOrderUsecase creates a new PurchaseOrderUsecase
+func NewPurchaseOrderUsecase(repo repository.PurchaseOrderRepository, supplierRepo repository.SupplierRepository, medicineRepo repository.MedicineRepository) PurchaseOrderUsecase {
+	return &purchaseOrderUsecase{repo, supplierRepo, medicineRepo}
+}
+
+// Create drafts a purchase order for the caller's pharmacy
+func (u *purchaseOrderUsecase) Create(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.PurchaseOrderInput) (*domain.PurchaseOrder, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	now := time.Now()
+	order := domain.PurchaseOrder{
+		ID:         uuid.New(),
+		PharmacyID: callerPharmacyID,
+		SupplierID: input.SupplierID,
+		Status:     domain.PurchaseOrderDraft,
+		Notes:      input.Notes,
+		CreatedBy:  callerUserID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	items, err := u.buildItems(ctx, callerPharmacyID, order.ID, input)
+	if err != nil {
+		return nil, err
+	}
+	order.Items = items
+
+	if err := u.repo.Create(ctx, order); err != nil {
+		return nil, err
+	}
+	return u.repo.GetByID(ctx, order.ID)
+}
+
+// GetByID retrieves one of the caller's pharmacy purchase orders with its items
+func (u *purchaseOrderUsecase) GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.PurchaseOrder, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.get(ctx, callerPharmacyID, id)
+}
+
+// GetAll lists the caller's pharmacy purchase orders, optionally by status
+func (u *purchaseOrderUsecase) GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, status domain.PurchaseOrderStatus, limit, offset int) ([]domain.PurchaseOrder, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.repo.GetAll(ctx, callerPharmacyID, status, limit, offset)
+}
+
+// Update replaces the supplier, notes and items of a draft purchase order
+func (u *purchaseOrderUsecase) Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.PurchaseOrderInput) (*domain.PurchaseOrder, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	order, err := u.get(ctx, callerPharmacyID, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != domain.PurchaseOrderDraft {
+		return nil, domain.ErrInvalidPurchaseOrderStatus
+	}
+
+	items, err := u.buildItems(ctx, callerPharmacyID, order.ID, input)
+	if err != nil {
+		return nil, err
+	}
+	order.SupplierID = input.SupplierID
+	order.Notes = input.Notes
+	order.Items = items
+	order.UpdatedAt = time.Now()
+
+	if err := u.repo.Update(ctx, *order); err != nil {
+		return nil, err
+	}
+	return u.repo.GetByID(ctx, order.ID)
+}
+
+// UpdateStatus sends a draft purchase order to its supplier or cancels an
+// order that is not yet fully received
+func (u *purchaseOrderUsecase) UpdateStatus(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.UpdatePurchaseOrderStatusInput) (*domain.PurchaseOrder, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	order, err := u.get(ctx, callerPharmacyID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch input.Status {
+	case domain.PurchaseOrderSent:
+		if order.Status != domain.PurchaseOrderDraft {
+			return nil, domain.ErrInvalidPurchaseOrderStatus
+		}
+	case domain.PurchaseOrderCancelled:
+		if order.Status == domain.PurchaseOrderReceived || order.Status == domain.PurchaseOrderCancelled {
+			return nil, domain.ErrInvalidPurchaseOrderStatus
+		}
+	default:
+		return nil, domain.ErrInvalidPurchaseOrderStatus
+	}
+
+	if err := u.repo.UpdateStatus(ctx, order.ID, order.Status, input.Status, time.Now()); err != nil {
+		return nil, err
+	}
+	return u.repo.GetByID(ctx, order.ID)
+}
+
+// Receive books a delivery against a sent purchase order into stock. Lines
+// without a unit cost are costed at the purchase order line's price.
+func (u *purchaseOrderUsecase) Receive(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, id uuid.UUID, input domain.ReceiveGoodsInput) (*domain.GoodsReceipt, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	order, err := u.get(ctx, callerPharmacyID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := domain.GoodsReceipt{
+		ID:              uuid.New(),
+		PurchaseOrderID: order.ID,
+		ReceivedBy:      callerUserID,
+		ReceivedAt:      time.Now(),
+	}
+	for _, l := range input.Lines {
+		var item *domain.PurchaseOrderItem
+		for i := range order.Items {
+			if order.Items[i].ID == l.ItemID {
+				item = &order.Items[i]
+				break
+			}
+		}
+		if item == nil {
+			return nil, domain.ErrPurchaseOrderItemNotFound
+		}
+
+		unitCost := item.UnitCost
+		if l.UnitCost != nil {
+			unitCost = *l.UnitCost
+		}
+		receipt.Lines = append(receipt.Lines, domain.GoodsReceiptLine{
+			ID:                  uuid.New(),
+			PurchaseOrderItemID: item.ID,
+			VariantID:           item.VariantID,
+			LotNumber:           l.LotNumber,
+			ExpiryDate:          l.ExpiryDate,
+			Quantity:            l.Quantity,
+			UnitCost:            unitCost,
+		})
+	}
+
+	return u.repo.Receive(ctx, receipt)
+}
+
+// get retrieves a purchase order, restricted to the caller's pharmacy
+func (u *purchaseOrderUsecase) get(ctx context.Context, callerPharmacyID, id uuid.UUID) (*domain.PurchaseOrder, error) {
+	order, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.PharmacyID != callerPharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+	return order, nil
+}
+
+// buildItems checks the supplier and every variant belong to the caller's
+// pharmacy and turns the input lines into purchase order items
+func (u *purchaseOrderUsecase) buildItems(ctx context.Context, callerPharmacyID, orderID uuid.UUID, input domain.PurchaseOrderInput) ([]domain.PurchaseOrderItem, error) {
+	supplier, err := u.supplierRepo.GetByID(ctx, input.SupplierID)
+	if err != nil {
+		return nil, err
+	}
+	if supplier.PharmacyID != callerPharmacyID {
+		return nil, domain.ErrSupplierNotFound
+	}
+
+	items := make([]domain.PurchaseOrderItem, 0, len(input.Items))
+	for _, in := range input.Items {
+		variant, err := u.medicineRepo.GetVariantByID(ctx, in.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		medicine, err := u.medicineRepo.GetByID(ctx, variant.MedicineID)
+		if err != nil {
+			return nil, err
+		}
+		if medicine.PharmacyID != callerPharmacyID {
+			return nil, domain.ErrVariantNotFound
+		}
+
+		items = append(items, domain.PurchaseOrderItem{
+			ID:              uuid.New(),
+			PurchaseOrderID: orderID,
+			VariantID:       variant.ID,
+			QuantityOrdered: in.Quantity,
+			UnitCost:        in.UnitCost,
+		})
+	}
+	return items, nil
+}
@@ -2,11 +2,14 @@ package usecase
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
 	"time"
 
 	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/infrastructure/receipt"
 	"pharmacy-management-backend/repository"
 
 	"github.com/google/uuid"
@@ -16,22 +19,51 @@ import (
 type SaleUsecase interface {
 	SearchMedicines(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, query string) ([]domain.MedicineVariant, error)
 	AddToCart(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.CreateCartInput) error
+	UpdateCartItem(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, cartID uuid.UUID, input domain.UpdateCartItemInput) error
 	RemoveFromCart(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, cartID uuid.UUID) error
-	ConfirmSale(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID) (*domain.Sale, error)
-	GetSales(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, limit, offset int) ([]domain.SaleResponse, error)
+	ClearCart(ctx context.Context, callerRole string, callerUserID uuid.UUID) error
+	ParkCart(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.ParkCartInput) (*domain.ParkedCart, error)
+	GetParkedCarts(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID) ([]domain.ParkedCart, error)
+	ResumeCart(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, parkedCartID uuid.UUID) (*domain.CartView, error)
+	ConfirmSale(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, idempotencyKey string, input domain.ConfirmSaleInput) (*domain.Sale, error)
+	SyncSales(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.SyncSalesInput) ([]domain.SyncSaleResult, error)
+	GetSales(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, filter domain.SaleFilter) (*domain.SalePage, error)
+	GetSale(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID) (*domain.SaleDetail, error)
 	GetReceipt(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID) (*domain.Receipt, error)
-	GetCart(ctx context.Context, callerRole string, callerUserID uuid.UUID, callerPharmacyID uuid.UUID) ([]domain.CartResponse, error)
+	GetReceiptByNumber(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, number string) (*domain.Receipt, error)
+	VerifyReceipt(ctx context.Context, pharmacyID uuid.UUID, number, hash string) (*domain.ReceiptVerification, error)
+	VerifyReceiptChain(ctx context.Context, callerRole string, callerPharmacyID, pharmacyID uuid.UUID) (*domain.ChainReport, error)
+	RenderReceipt(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID, format receipt.Format) ([]byte, error)
+	GetCart(ctx context.Context, callerRole string, callerUserID uuid.UUID, callerPharmacyID uuid.UUID) (*domain.CartView, error)
+	CreateReturn(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, saleID uuid.UUID, input domain.CreateSaleReturnInput) (*domain.SaleReturn, error)
+	GetReturns(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID) ([]domain.SaleReturn, error)
+	ReleaseExpiredReservations(ctx context.Context) (int, error)
 }
 
 // saleUsecase implements SaleUsecase
 type saleUsecase struct {
-	saleRepo     repository.SaleRepository
-	medicineRepo repository.MedicineRepository
+	saleRepo          repository.SaleRepository
+	medicineRepo      repository.MedicineRepository
+	pharmacyRepo      repository.PharmacyRepository
+	promotionRepo     repository.PromotionRepository
+	shiftRepo         repository.ShiftRepository
+	customerRepo      repository.CustomerRepository
+	lowStock          chan<- LowStockCheck
+	idempotencyKeyTTL time.Duration
+	reservationTTL    time.Duration
+	verifyURL         string
 }
 
-// NewSaleUsecase creates a new SaleUsecase
-func NewSaleUsecase(saleRepo repository.SaleRepository, medicineRepo repository.MedicineRepository) SaleUsecase {
-	return &saleUsecase{saleRepo, medicineRepo}
+// NewSaleUsecase creates a new SaleUsecase. Idempotency keys sent with
+// ConfirmSale can be replayed for idempotencyKeyTTL. Items added to a cart
+// are reserved for reservationTTL. Receipts link to verifyURL, where anyone
+// can check their hash. Sold variants are queued on lowStock for a reorder
+// check; a nil channel disables the check.
+func NewSaleUsecase(saleRepo repository.SaleRepository, medicineRepo repository.MedicineRepository, pharmacyRepo repository.PharmacyRepository,
+	promotionRepo repository.PromotionRepository, shiftRepo repository.ShiftRepository, customerRepo repository.CustomerRepository,
+	lowStock chan<- LowStockCheck, idempotencyKeyTTL, reservationTTL time.Duration, verifyURL string) SaleUsecase {
+	return &saleUsecase{saleRepo, medicineRepo, pharmacyRepo, promotionRepo, shiftRepo, customerRepo, lowStock, idempotencyKeyTTL, reservationTTL,
+		verifyURL}
 }
 
 // SearchMedicines searches for medicines by name or barcode
@@ -42,7 +74,8 @@ func (u *saleUsecase) SearchMedicines(ctx context.Context, callerRole string, ca
 	return u.saleRepo.SearchMedicines(ctx, callerPharmacyID, query)
 }
 
-// AddToCart adds an item to the cart
+// AddToCart adds an item to the cart, reserving the line's stock for the
+// reservation TTL
 func (u *saleUsecase) AddToCart(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.CreateCartInput) error {
 	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
 		return domain.ErrUnauthorized
@@ -62,25 +95,28 @@ func (u *saleUsecase) AddToCart(ctx context.Context, callerRole string, callerUs
 		return domain.ErrUnauthorized
 	}
 
-	if input.Quantity > variant.Stock {
+	if input.Quantity > variant.Available {
 		return domain.ErrInsufficientStock
 	}
 
+	now := time.Now()
+	reservedUntil := now.Add(u.reservationTTL)
 	cart := domain.Cart{
 		ID:                uuid.New(),
 		UserID:            callerUserID,
 		PharmacyID:        callerPharmacyID,
 		MedicineVariantID: input.MedicineVariantID,
 		Quantity:          input.Quantity,
-		CreatedAt:         time.Now(),
+		ReservedUntil:     &reservedUntil,
+		CreatedAt:         now,
 	}
-	fmt.Println("HEy")
 
 	return u.saleRepo.AddToCart(ctx, cart)
 }
 
-// GetCart retrieves the user's cart
-func (u *saleUsecase) GetCart(ctx context.Context, callerRole string, callerUserID uuid.UUID, callerPharmacyID uuid.UUID) ([]domain.CartResponse, error) {
+// GetCart retrieves the user's cart, priced with the promotions and approved
+// manual discounts checkout would apply now
+func (u *saleUsecase) GetCart(ctx context.Context, callerRole string, callerUserID uuid.UUID, callerPharmacyID uuid.UUID) (*domain.CartView, error) {
 	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
 		return nil, domain.ErrUnauthorized
 	}
@@ -89,22 +125,21 @@ func (u *saleUsecase) GetCart(ctx context.Context, callerRole string, callerUser
 		return nil, err
 	}
 
-	var response []domain.CartResponse
 	for _, cart := range carts {
 		if cart.PharmacyID != callerPharmacyID {
 			return nil, domain.ErrUnauthorized
 		}
+	}
+	variants, medicines, err := u.cartProducts(ctx, carts)
+	if err != nil {
+		return nil, err
+	}
 
-		variant, err := u.medicineRepo.GetVariantByID(ctx, cart.MedicineVariantID)
-		if err != nil {
-			return nil, err
-		}
-
-		medicine, err := u.medicineRepo.GetByID(ctx, variant.MedicineID)
-		if err != nil {
-			return nil, err
-		}
-
+	now := time.Now()
+	var response []domain.CartResponse
+	var lines []domain.PricedLine
+	for i, cart := range carts {
+		variant, medicine := variants[i], medicines[i]
 		response = append(response, domain.CartResponse{
 			ID:           cart.ID,
 			Medicine:     medicine.Name,
@@ -112,10 +147,132 @@ func (u *saleUsecase) GetCart(ctx context.Context, callerRole string, callerUser
 			Unit:         variant.Unit,
 			ImageURL:     medicine.Picture,
 			Quantity:     cart.Quantity,
+			Subtotal:     variant.PricePerUnit.Mul(cart.Quantity),
 			CreatedAt:    cart.CreatedAt,
 		})
+		if cart.Reserved(now) {
+			response[i].ReservedUntil = cart.ReservedUntil
+		}
+		lines = append(lines, pricedLine(cart, variant, medicine))
+	}
+
+	pricing, err := u.price(ctx, callerUserID, callerPharmacyID, lines, now)
+	if err != nil {
+		return nil, err
+	}
+	for i := range response {
+		response[i].Discount = pricing.LineDiscounts[i]
+	}
+	return &domain.CartView{
+		Items:     response,
+		Subtotal:  pricing.Subtotal,
+		Discount:  pricing.Discount,
+		Total:     pricing.Subtotal - pricing.Discount,
+		Discounts: pricing.Applied,
+	}, nil
+}
+
+// cartProducts loads the variant and medicine of every cart line, in line
+// order, with one query for the variants and one for the medicines however
+// long the cart is
+func (u *saleUsecase) cartProducts(ctx context.Context, carts []domain.Cart) ([]*domain.MedicineVariant, []*domain.Medicine, error) {
+	variantIDs := make([]uuid.UUID, len(carts))
+	for i, cart := range carts {
+		variantIDs[i] = cart.MedicineVariantID
+	}
+	byVariantID, err := u.medicineRepo.GetVariantsByIDs(ctx, variantIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	variants := make([]*domain.MedicineVariant, len(carts))
+	medicineIDs := make([]uuid.UUID, len(carts))
+	for i, cart := range carts {
+		variant, ok := byVariantID[cart.MedicineVariantID]
+		if !ok {
+			return nil, nil, domain.ErrVariantNotFound
+		}
+		variants[i] = &variant
+		medicineIDs[i] = variant.MedicineID
+	}
+	byMedicineID, err := u.medicineRepo.GetByIDs(ctx, medicineIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	medicines := make([]*domain.Medicine, len(carts))
+	for i, id := range medicineIDs {
+		medicine, ok := byMedicineID[id]
+		if !ok {
+			return nil, nil, domain.ErrMedicineNotFound
+		}
+		medicines[i] = &medicine
+	}
+	return variants, medicines, nil
+}
+
+// price applies the pharmacy's running promotions and the user's approved
+// manual discounts to cart lines
+func (u *saleUsecase) price(ctx context.Context, callerUserID, callerPharmacyID uuid.UUID, lines []domain.PricedLine, at time.Time) (domain.Pricing, error) {
+	promotions, manual, err := u.discounts(ctx, callerUserID, callerPharmacyID, at)
+	if err != nil {
+		return domain.Pricing{}, err
+	}
+	return domain.ApplyDiscounts(lines, promotions, manual, at), nil
+}
+
+// discounts returns the pharmacy's promotions running at a time and the
+// user's approved manual discounts there
+func (u *saleUsecase) discounts(ctx context.Context, callerUserID, callerPharmacyID uuid.UUID, at time.Time) ([]domain.Promotion, []domain.ManualDiscount, error) {
+	promotions, err := u.promotionRepo.GetRunning(ctx, callerPharmacyID, at)
+	if err != nil {
+		return nil, nil, err
+	}
+	approved, err := u.promotionRepo.GetApprovedManualDiscounts(ctx, callerUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var manual []domain.ManualDiscount
+	for _, d := range approved {
+		if d.PharmacyID == callerPharmacyID {
+			manual = append(manual, d)
+		}
 	}
-	return response, nil
+	return promotions, manual, nil
+}
+
+// pricedLine describes a cart line for pricing
+func pricedLine(cart domain.Cart, variant *domain.MedicineVariant, medicine *domain.Medicine) domain.PricedLine {
+	return domain.PricedLine{
+		CartID:       cart.ID,
+		MedicineID:   medicine.ID,
+		Category:     medicine.Category,
+		PricePerUnit: variant.PricePerUnit,
+		Quantity:     cart.Quantity,
+	}
+}
+
+// UpdateCartItem sets the quantity of a line in the user's cart, reserving
+// the new quantity for the reservation TTL
+func (u *saleUsecase) UpdateCartItem(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, cartID uuid.UUID, input domain.UpdateCartItemInput) error {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return domain.ErrUnauthorized
+	}
+
+	cartItems, err := u.saleRepo.GetCart(ctx, callerUserID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range cartItems {
+		if item.ID == cartID && item.PharmacyID == callerPharmacyID {
+			reservedUntil := time.Now().Add(u.reservationTTL)
+			item.Quantity = input.Quantity
+			item.ReservedUntil = &reservedUntil
+			return u.saleRepo.UpdateCartItem(ctx, item)
+		}
+	}
+	return domain.ErrCartItemNotFound
 }
 
 // RemoveFromCart removes an item from the cart
@@ -137,79 +294,333 @@ func (u *saleUsecase) RemoveFromCart(ctx context.Context, callerRole string, cal
 	return domain.ErrCartItemNotFound
 }
 
-// ConfirmSale confirms the sale and generates a receipt
-func (u *saleUsecase) ConfirmSale(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID) (*domain.Sale, error) {
+// ClearCart empties the user's cart, releasing its reservations
+func (u *saleUsecase) ClearCart(ctx context.Context, callerRole string, callerUserID uuid.UUID) error {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return domain.ErrUnauthorized
+	}
+	return u.saleRepo.ClearCart(ctx, callerUserID)
+}
+
+// ParkCart sets the user's cart aside under a label, leaving their cart empty
+// for the next customer
+func (u *saleUsecase) ParkCart(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.ParkCartInput) (*domain.ParkedCart, error) {
 	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
 		return nil, domain.ErrUnauthorized
 	}
 
-	cartItems, err := u.saleRepo.GetCart(ctx, callerUserID)
+	parked := domain.ParkedCart{
+		ID:         uuid.New(),
+		UserID:     callerUserID,
+		PharmacyID: callerPharmacyID,
+		Label:      input.Label,
+		ParkedAt:   time.Now(),
+	}
+	return u.saleRepo.ParkCart(ctx, parked)
+}
+
+// GetParkedCarts retrieves the user's parked carts
+func (u *saleUsecase) GetParkedCarts(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID) ([]domain.ParkedCart, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	parked, err := u.saleRepo.GetParkedCarts(ctx, callerUserID)
 	if err != nil {
 		return nil, err
 	}
-	if len(cartItems) == 0 {
-		return nil, errors.New("cart is empty")
+	mine := []domain.ParkedCart{}
+	for _, p := range parked {
+		if p.PharmacyID == callerPharmacyID {
+			mine = append(mine, p)
+		}
 	}
+	return mine, nil
+}
 
-	var saleItems []domain.SaleItem
-	var totalPrice float64
-	var receiptItems []domain.ReceiptItem
+// ResumeCart makes a parked cart the user's cart again, reserving its lines
+// afresh where stock allows, and returns it priced. The user's cart must be
+// empty.
+func (u *saleUsecase) ResumeCart(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, parkedCartID uuid.UUID) (*domain.CartView, error) {
+	parked, err := u.GetParkedCarts(ctx, callerRole, callerUserID, callerPharmacyID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(parked, func(p domain.ParkedCart) bool { return p.ID == parkedCartID }) {
+		return nil, domain.ErrParkedCartNotFound
+	}
 
-	for _, cartItem := range cartItems {
-		if cartItem.PharmacyID != callerPharmacyID {
-			return nil, domain.ErrUnauthorized
+	if err := u.saleRepo.ResumeCart(ctx, callerUserID, parkedCartID, time.Now().Add(u.reservationTTL)); err != nil {
+		return nil, err
+	}
+	return u.GetCart(ctx, callerRole, callerUserID, callerPharmacyID)
+}
+
+// ConfirmSale confirms the sale, paid with the input's tenders, and generates
+// a receipt. With an idempotency key, a retry of a confirmed sale returns the
+// original sale instead of confirming the cart again; reusing the key for a
+// different input fails with ErrIdempotencyKeyReused.
+func (u *saleUsecase) ConfirmSale(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, idempotencyKey string, input domain.ConfirmSaleInput) (*domain.Sale, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	if idempotencyKey == "" {
+		return u.confirmSale(ctx, callerUserID, callerPharmacyID, "", input)
+	}
+
+	body, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	requestHash := hex.EncodeToString(sum[:])
+
+	now := time.Now()
+	existing, err := u.saleRepo.ReserveIdempotencyKey(ctx, domain.IdempotencyKey{
+		Key:         idempotencyKey,
+		UserID:      callerUserID,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(u.idempotencyKeyTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.RequestHash != requestHash {
+			return nil, domain.ErrIdempotencyKeyReused
+		}
+		if existing.SaleID == nil {
+			return nil, domain.ErrIdempotencyKeyInUse
 		}
+		var sale domain.Sale
+		if err := json.Unmarshal(existing.Response, &sale); err != nil {
+			return nil, err
+		}
+		return &sale, nil
+	}
+
+	sale, err := u.confirmSale(ctx, callerUserID, callerPharmacyID, idempotencyKey, input)
+	if err != nil {
+		// The key is only kept once the sale is recorded, so the client can retry
+		_ = u.saleRepo.ReleaseIdempotencyKey(context.WithoutCancel(ctx), callerUserID, idempotencyKey)
+		return nil, err
+	}
+	return sale, nil
+}
 
-		variant, err := u.medicineRepo.GetVariantByID(ctx, cartItem.MedicineVariantID)
+// confirmSale turns the user's cart into a sale paid with the input's tenders
+// and taken in the user's open shift, completing idempotencyKey if set. The
+// input's customer must be one of the pharmacy's. Discounts come off each
+// line first, then what is left is taxed at the pharmacy's current settings.
+func (u *saleUsecase) confirmSale(ctx context.Context, callerUserID, callerPharmacyID uuid.UUID, idempotencyKey string, input domain.ConfirmSaleInput) (*domain.Sale, error) {
+	shift, err := u.shiftRepo.GetOpen(ctx, callerUserID)
+	if err != nil {
+		return nil, err
+	}
+	if shift.PharmacyID != callerPharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+
+	pharmacy, err := u.pharmacyRepo.GetByID(ctx, callerPharmacyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.CustomerID != nil {
+		customer, err := u.customerRepo.GetByID(ctx, *input.CustomerID)
 		if err != nil {
 			return nil, err
 		}
+		if customer.PharmacyID != callerPharmacyID {
+			return nil, domain.ErrCustomerNotFound
+		}
+	}
+
+	// A manual discount used by another sale in the meantime is refused when
+	// this one is recorded
+	saleDate := time.Now()
+	promotions, manual, err := u.discounts(ctx, callerUserID, callerPharmacyID, saleDate)
+	if err != nil {
+		return nil, err
+	}
 
-		if cartItem.Quantity > variant.Stock {
-			return nil, domain.ErrInsufficientStock
+	// The cart is priced while checkout holds it and its stock, and is
+	// checked out with the sale, so it can only be sold once
+	var saleItems []domain.SaleItem
+	sale, err := u.saleRepo.CheckoutCart(ctx, callerUserID, func(cart []domain.CheckoutLine) (domain.Sale, []domain.SaleItem, *domain.Receipt, error) {
+		lines := make([]domain.PricedLine, len(cart))
+		for i, line := range cart {
+			if line.Cart.PharmacyID != callerPharmacyID {
+				return domain.Sale{}, nil, nil, domain.ErrUnauthorized
+			}
+			if line.Cart.Quantity > line.Variant.Stock {
+				return domain.Sale{}, nil, nil, domain.ErrInsufficientStock
+			}
+			lines[i] = pricedLine(line.Cart, &line.Variant, &line.Medicine)
 		}
+		pricing := domain.ApplyDiscounts(lines, promotions, manual, saleDate)
 
-		medicine, err := u.medicineRepo.GetByID(ctx, variant.MedicineID)
-		if err != nil {
-			return nil, err
+		saleLines := make([]saleLine, len(cart))
+		for i, line := range cart {
+			saleLines[i] = saleLine{
+				variant:  &line.Variant,
+				medicine: &line.Medicine,
+				quantity: line.Cart.Quantity,
+				price:    line.Variant.PricePerUnit,
+				discount: pricing.LineDiscounts[i],
+			}
+		}
+		sale := domain.Sale{
+			ID:             uuid.New(),
+			UserID:         callerUserID,
+			PharmacyID:     callerPharmacyID,
+			ShiftID:        &shift.ID,
+			CustomerID:     input.CustomerID,
+			TotalDiscount:  pricing.Discount,
+			SaleDate:       saleDate,
+			IdempotencyKey: idempotencyKey,
+			Discounts:      pricing.Applied,
 		}
+		sale, items, receipt, err := buildSale(sale, saleLines, pharmacy.TaxSettings, input.Tenders)
+		saleItems = items
+		return sale, items, receipt, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	u.checkLowStock(*sale, saleItems)
+	return sale, nil
+}
+
+// saleLine is a line of a sale about to be recorded, sold at price less
+// discount
+type saleLine struct {
+	variant  *domain.MedicineVariant
+	medicine *domain.Medicine
+	quantity int
+	price    domain.Money
+	discount domain.Money
+}
+
+// recordSale records a sale built from lines by buildSale, filling in its
+// receipt number
+func (u *saleUsecase) recordSale(ctx context.Context, sale domain.Sale, lines []saleLine, taxSettings domain.TaxSettings, tenders []domain.TenderInput) (*domain.Sale, error) {
+	sale, saleItems, receipt, err := buildSale(sale, lines, taxSettings, tenders)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.saleRepo.CreateSale(ctx, sale, saleItems, receipt); err != nil {
+		return nil, err
+	}
+	sale.ReceiptNumber = receipt.Number
+	u.checkLowStock(sale, saleItems)
+	return &sale, nil
+}
+
+// checkLowStock queues a reorder check of the variants a sale sold, so the
+// owner hears about anything it pushed below its reorder point
+func (u *saleUsecase) checkLowStock(sale domain.Sale, items []domain.SaleItem) {
+	variantIDs := make([]uuid.UUID, len(items))
+	for i, item := range items {
+		variantIDs[i] = item.MedicineVariantID
+	}
+	select {
+	case u.lowStock <- LowStockCheck{PharmacyID: sale.PharmacyID, VariantIDs: variantIDs}:
+	default:
+		// The queue is full. Nothing was claimed, so the next sale of these
+		// variants checks them again.
+	}
+}
+
+// buildSale taxes lines at the tax settings and takes payment from tenders,
+// filling in the sale's totals and payments, and returns it with its items
+// and receipt
+func buildSale(sale domain.Sale, lines []saleLine, taxSettings domain.TaxSettings, tenders []domain.TenderInput) (domain.Sale, []domain.SaleItem, *domain.Receipt, error) {
+	var saleItems []domain.SaleItem
+	var totalPrice, totalNet, totalTax, totalCost domain.Money
+	var taxLines []domain.TaxLine
+	var receiptItems []domain.ReceiptItem
+
+	for _, line := range lines {
+		variant, medicine := line.variant, line.medicine
+		subtotal := line.price.Mul(line.quantity)
+		taxRate := taxSettings.Rate(medicine.TaxCategory)
+		net, tax, gross := taxSettings.Split(subtotal-line.discount, taxRate)
 
 		receiptItem := domain.ReceiptItem{
 			Brand:        variant.Brand,
 			MedicineName: medicine.Name,
-			PricePerUnit: variant.PricePerUnit,
-			Quantity:     cartItem.Quantity,
-			Subtotal:     float64(cartItem.Quantity) * variant.PricePerUnit,
+			PricePerUnit: line.price,
+			Quantity:     line.quantity,
+			Subtotal:     subtotal,
+			Discount:     line.discount,
+			TaxCategory:  medicine.TaxCategory,
+			TaxRate:      taxRate,
+			Net:          net,
+			Tax:          tax,
+			Gross:        gross,
+			UnitCost:     variant.CostPrice,
 		}
+		receiptItem.Margin = net - variant.CostPrice.Mul(line.quantity)
 		receiptItems = append(receiptItems, receiptItem)
 
 		saleItem := domain.SaleItem{
 			ID:                uuid.New(),
-			SaleID:            uuid.New(), // Will be updated after sale creation
-			MedicineVariantID: cartItem.MedicineVariantID,
-			Quantity:          cartItem.Quantity,
-			PricePerUnit:      variant.PricePerUnit,
+			SaleID:            sale.ID,
+			MedicineVariantID: variant.ID,
+			Quantity:          line.quantity,
+			PricePerUnit:      line.price,
+			UnitCost:          variant.CostPrice,
+			Discount:          line.discount,
+			TaxCategory:       medicine.TaxCategory,
+			TaxRate:           taxRate,
+			Net:               net,
+			Tax:               tax,
+			Gross:             gross,
 			CreatedAt:         time.Now(),
 		}
 		saleItems = append(saleItems, saleItem)
-		totalPrice += receiptItem.Subtotal
+		totalPrice += gross
+		totalNet += net
+		totalTax += tax
+		totalCost += variant.CostPrice.Mul(line.quantity)
+		taxLines = domain.AddTaxLine(taxLines, medicine.TaxCategory, taxRate, net, tax, gross)
 	}
 
-	sale := domain.Sale{
-		ID:         uuid.New(),
-		UserID:     callerUserID,
-		PharmacyID: callerPharmacyID,
-		TotalPrice: totalPrice,
-		SaleDate:   time.Now(),
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	payments, err := domain.ApplyTenders(totalPrice, tenders)
+	if err != nil {
+		return domain.Sale{}, nil, nil, err
 	}
 
+	sale.TotalPrice = totalPrice
+	sale.TotalNet = totalNet
+	sale.TotalTax = totalTax
+	sale.CreatedAt = time.Now()
+	sale.UpdatedAt = sale.CreatedAt
+	sale.Change = domain.TotalChange(payments)
+	for i := range payments {
+		payments[i].ID = uuid.New()
+		payments[i].SaleID = sale.ID
+		payments[i].CreatedAt = sale.CreatedAt
+	}
+	sale.Payments = payments
+
 	receiptContent := domain.ReceiptContent{
 		Items:      receiptItems,
-		PharmacyID: callerPharmacyID,
+		PharmacyID: sale.PharmacyID,
 		SaleDate:   sale.SaleDate,
 		TotalPrice: totalPrice,
+		TotalNet:   totalNet,
+		TotalTax:   totalTax,
+		TaxLines:   taxLines,
+		TotalCost:  totalCost,
+		Margin:     totalNet - totalCost,
+
+		TotalDiscount: sale.TotalDiscount,
+		Discounts:     sale.Discounts,
+		Payments:      payments,
+		Change:        sale.Change,
 	}
 
 	receipt := domain.Receipt{
@@ -218,64 +629,524 @@ func (u *saleUsecase) ConfirmSale(ctx context.Context, callerRole string, caller
 		Content:   receiptContent,
 		CreatedAt: time.Now(),
 	}
+	return sale, saleItems, &receipt, nil
+}
+
+// syncClockSkew is how far ahead of the server's clock a POS's may run before
+// its sales count as made in the future
+const syncClockSkew = 5 * time.Minute
+
+// SyncSales records a batch of sales made offline, in order, through the same
+// checkout as ConfirmSale but at the prices the customer was charged and
+// without promotions. Sales go into the user's open shift, if there is one,
+// and are otherwise recorded with a warning. Each sale gets a result: a sale
+// synced before is a duplicate, and one short of stock or charged other than
+// the catalog price when it was made a conflict that records nothing, so it
+// can be synced again once stock or prices are corrected.
+func (u *saleUsecase) SyncSales(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.SyncSalesInput) ([]domain.SyncSaleResult, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
 
-	// Update SaleID in sale items
-	for i := range saleItems {
-		saleItems[i].SaleID = sale.ID
+	pharmacy, err := u.pharmacyRepo.GetByID(ctx, callerPharmacyID)
+	if err != nil {
+		return nil, err
+	}
+	var shiftID *uuid.UUID
+	shift, err := u.shiftRepo.GetOpen(ctx, callerUserID)
+	if err != nil && err != domain.ErrNoOpenShift {
+		return nil, err
+	}
+	if err == nil && shift.PharmacyID == callerPharmacyID {
+		shiftID = &shift.ID
 	}
 
-	if err := u.saleRepo.CreateSale(ctx, sale, saleItems, receipt); err != nil {
+	// Look up every variant in the batch, and their medicines, at once
+	var variantIDs []uuid.UUID
+	for _, sale := range input.Sales {
+		for _, item := range sale.Items {
+			variantIDs = append(variantIDs, item.MedicineVariantID)
+		}
+	}
+	variants, err := u.medicineRepo.GetVariantsByIDs(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	medicineIDs := make([]uuid.UUID, 0, len(variants))
+	for _, v := range variants {
+		medicineIDs = append(medicineIDs, v.MedicineID)
+	}
+	medicines, err := u.medicineRepo.GetByIDs(ctx, medicineIDs)
+	if err != nil {
+		return nil, err
+	}
+	// and their prices since the earliest sale
+	earliest := input.Sales[0].SoldAt
+	for _, sale := range input.Sales {
+		if sale.SoldAt.Before(earliest) {
+			earliest = sale.SoldAt
+		}
+	}
+	prices, err := u.medicineRepo.GetPriceHistory(ctx, variantIDs, earliest)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := u.saleRepo.ClearCart(ctx, callerUserID); err != nil {
+	results := make([]domain.SyncSaleResult, len(input.Sales))
+	for i, in := range input.Sales {
+		result, err := u.syncSale(ctx, callerUserID, callerPharmacyID, shiftID, pharmacy.TaxSettings, in, variants, medicines, prices)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// syncSale records one offline sale for SyncSales, returning an error only
+// for failures that are no fault of the sale
+func (u *saleUsecase) syncSale(ctx context.Context, callerUserID, callerPharmacyID uuid.UUID, shiftID *uuid.UUID, taxSettings domain.TaxSettings,
+	in domain.SyncSaleInput, variants map[uuid.UUID]domain.MedicineVariant, medicines map[uuid.UUID]domain.Medicine,
+	prices map[uuid.UUID][]domain.VariantPrice) (domain.SyncSaleResult, error) {
+	rejected := func(err error) (domain.SyncSaleResult, error) {
+		return domain.SyncSaleResult{ID: in.ID, Status: domain.SyncRejected, Error: err.Error()}, nil
+	}
+	if in.SoldAt.After(time.Now().Add(syncClockSkew)) {
+		return rejected(domain.ErrSaleInFuture)
+	}
+
+	existing, err := u.saleRepo.GetSaleByID(ctx, in.ID)
+	if err == nil {
+		return synced(existing, callerUserID), nil
+	}
+	if err != domain.ErrSaleNotFound {
+		return domain.SyncSaleResult{}, err
+	}
+
+	lines := make([]saleLine, len(in.Items))
+	var priceConflicts []domain.PriceConflict
+	for i, item := range in.Items {
+		variant, ok := variants[item.MedicineVariantID]
+		if !ok {
+			return rejected(domain.ErrVariantNotFound)
+		}
+		medicine, ok := medicines[variant.MedicineID]
+		if !ok {
+			return rejected(domain.ErrMedicineNotFound)
+		}
+		if medicine.PharmacyID != callerPharmacyID {
+			return rejected(domain.ErrUnauthorized)
+		}
+		price, ok := domain.PriceAt(prices[variant.ID], in.SoldAt)
+		if !ok {
+			price = variant.PricePerUnit
+		}
+		if item.PricePerUnit != price {
+			priceConflicts = append(priceConflicts, domain.PriceConflict{MedicineVariantID: variant.ID, Charged: item.PricePerUnit,
+				Price: price})
+		}
+		lines[i] = saleLine{variant: &variant, medicine: &medicine, quantity: item.Quantity, price: item.PricePerUnit}
+	}
+	if priceConflicts != nil {
+		return domain.SyncSaleResult{ID: in.ID, Status: domain.SyncConflict, Error: domain.ErrPriceMismatch.Error(),
+			PriceConflicts: priceConflicts}, nil
+	}
+
+	sale := domain.Sale{
+		ID:         in.ID,
+		UserID:     callerUserID,
+		PharmacyID: callerPharmacyID,
+		ShiftID:    shiftID,
+		SaleDate:   in.SoldAt,
+	}
+	recorded, err := u.recordSale(ctx, sale, lines, taxSettings, in.Tenders)
+	switch err {
+	case nil:
+		result := domain.SyncSaleResult{ID: in.ID, Status: domain.SyncCreated, ReceiptNumber: recorded.ReceiptNumber}
+		if shiftID == nil {
+			result.Warnings = []string{domain.SyncWarningNoShift}
+		}
+		return result, nil
+	case domain.ErrSaleExists:
+		// Synced by a concurrent request since it was looked up
+		existing, err := u.saleRepo.GetSaleByID(ctx, in.ID)
+		if err != nil {
+			return domain.SyncSaleResult{}, err
+		}
+		return synced(existing, callerUserID), nil
+	case domain.ErrInsufficientStock:
+		conflicts, err := u.stockConflicts(ctx, in.Items)
+		if err != nil {
+			return domain.SyncSaleResult{}, err
+		}
+		return domain.SyncSaleResult{ID: in.ID, Status: domain.SyncConflict, Error: domain.ErrInsufficientStock.Error(),
+			Conflicts: conflicts}, nil
+	case domain.ErrInsufficientPayment, domain.ErrOverpayment, domain.ErrTenderReferenceRequired:
+		return rejected(err)
+	}
+	return domain.SyncSaleResult{}, err
+}
+
+// synced is the result of syncing a sale that is already recorded. Only its
+// own user can have synced it.
+func synced(sale *domain.Sale, callerUserID uuid.UUID) domain.SyncSaleResult {
+	if sale.UserID != callerUserID {
+		return domain.SyncSaleResult{ID: sale.ID, Status: domain.SyncRejected, Error: domain.ErrSaleExists.Error()}
+	}
+	return domain.SyncSaleResult{ID: sale.ID, Status: domain.SyncDuplicate, ReceiptNumber: sale.ReceiptNumber}
+}
+
+// stockConflicts lists the variants items ask for more of than is available
+// now
+func (u *saleUsecase) stockConflicts(ctx context.Context, items []domain.SyncSaleItemInput) ([]domain.StockConflict, error) {
+	requested := make(map[uuid.UUID]int)
+	var variantIDs []uuid.UUID
+	for _, item := range items {
+		if _, ok := requested[item.MedicineVariantID]; !ok {
+			variantIDs = append(variantIDs, item.MedicineVariantID)
+		}
+		requested[item.MedicineVariantID] += item.Quantity
+	}
+	variants, err := u.medicineRepo.GetVariantsByIDs(ctx, variantIDs)
+	if err != nil {
 		return nil, err
 	}
 
-	return &sale, nil
+	var conflicts []domain.StockConflict
+	for _, id := range variantIDs {
+		if available := variants[id].Available; requested[id] > available {
+			conflicts = append(conflicts, domain.StockConflict{MedicineVariantID: id, Requested: requested[id], Available: available})
+		}
+	}
+	return conflicts, nil
 }
 
-// GetSales retrieves sales with pagination
-func (u *saleUsecase) GetSales(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, limit, offset int) ([]domain.SaleResponse, error) {
+// GetSales retrieves a page of the sale history. Admins see every pharmacy's
+// sales unless they filter by one; everyone else sees their own pharmacy's.
+func (u *saleUsecase) GetSales(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, filter domain.SaleFilter) (*domain.SalePage, error) {
 	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
 		return nil, domain.ErrUnauthorized
 	}
-	var pharmacyID uuid.UUID
 	if callerRole != string(domain.RoleAdmin) {
-		pharmacyID = callerPharmacyID
+		filter.PharmacyID = callerPharmacyID
 	}
-	saleItems, err := u.saleRepo.GetSales(ctx, pharmacyID, limit, offset)
+	return u.saleRepo.GetSales(ctx, filter)
+}
+
+// GetSale retrieves a sale with its lines
+func (u *saleUsecase) GetSale(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID) (*domain.SaleDetail, error) {
+	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	sale, err := u.saleRepo.GetSaleByID(ctx, saleID)
 	if err != nil {
 		return nil, err
 	}
+	if callerRole != string(domain.RoleAdmin) && callerPharmacyID != sale.PharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+
+	saleItems, err := u.saleRepo.GetSaleItems(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.SaleDetail{Sale: *sale, Items: saleResponses(saleItems)}, nil
+}
 
-	var response []domain.SaleResponse
+// saleResponses turns sale items into the lines of a sale as shown to users
+func saleResponses(saleItems []domain.SaleItem) []domain.SaleResponse {
+	responses := make([]domain.SaleResponse, 0, len(saleItems))
 	for _, item := range saleItems {
-		variant, err := u.medicineRepo.GetVariantByID(ctx, item.MedicineVariantID)
-		if err != nil {
-			return nil, err
-		}
+		responses = append(responses, domain.SaleResponse{
+			ID:               item.ID,
+			Medicine:         item.MedicineName,
+			PricePerUnit:     item.PricePerUnit,
+			UnitCost:         item.UnitCost,
+			Margin:           item.Net - item.Net.Mul(item.ReturnedQuantity).Div(item.Quantity) - item.UnitCost.Mul(item.Quantity-item.ReturnedQuantity),
+			Discount:         item.Discount,
+			TaxCategory:      item.TaxCategory,
+			TaxRate:          item.TaxRate,
+			Net:              item.Net,
+			Tax:              item.Tax,
+			Gross:            item.Gross,
+			Unit:             item.Unit,
+			ImageURL:         item.ImageURL,
+			Quantity:         item.Quantity,
+			ReturnedQuantity: item.ReturnedQuantity,
+			CreatedAt:        item.CreatedAt,
+		})
+	}
+	return responses
+}
+
+// GetReceipt retrieves a receipt by sale ID
+func (u *saleUsecase) GetReceipt(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID) (*domain.Receipt, error) {
+	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	sale, err := u.saleRepo.GetSaleByID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
 
-		medicine, err := u.medicineRepo.GetByID(ctx, variant.MedicineID)
+	if callerRole != string(domain.RoleAdmin) && callerPharmacyID != sale.PharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+
+	receipt, err := u.saleRepo.GetReceiptBySaleID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	receipt.VerifyURL = receipt.VerificationURL(u.verifyURL)
+	return receipt, nil
+}
+
+// GetReceiptByNumber retrieves one of the caller's pharmacy's receipts by the
+// number printed on it
+func (u *saleUsecase) GetReceiptByNumber(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, number string) (*domain.Receipt, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	receipt, err := u.saleRepo.GetReceiptByNumber(ctx, callerPharmacyID, number)
+	if err != nil {
+		return nil, err
+	}
+	receipt.VerifyURL = receipt.VerificationURL(u.verifyURL)
+	return receipt, nil
+}
+
+// VerifyReceipt checks the hash printed on a receipt against the stored
+// receipt, and that the stored receipt still matches it. It needs no login,
+// so that anyone holding the receipt can check it.
+func (u *saleUsecase) VerifyReceipt(ctx context.Context, pharmacyID uuid.UUID, number, hash string) (*domain.ReceiptVerification, error) {
+	receipt, err := u.saleRepo.GetReceiptByNumber(ctx, pharmacyID, number)
+	if err != nil {
+		return nil, err
+	}
+	result := domain.ReceiptVerification{PharmacyID: pharmacyID, Number: number}
+	computed, err := receipt.ComputeHash()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case receipt.Hash == "" || receipt.Hash != hash:
+		result.Reason = "hash does not match the receipt issued"
+	case computed != receipt.Hash:
+		result.Reason = domain.ChainBreakAltered
+	default:
+		result.Valid = true
+		result.SaleDate = &receipt.Content.SaleDate
+		result.TotalPrice = &receipt.Content.TotalPrice
+	}
+	return &result, nil
+}
+
+// receiptChainPage is how many receipts VerifyReceiptChain loads at a time
+const receiptChainPage = 500
+
+// VerifyReceiptChain walks a pharmacy's receipt chain from the first receipt
+// to the last, reporting every receipt that was altered, removed or inserted
+func (u *saleUsecase) VerifyReceiptChain(ctx context.Context, callerRole string, callerPharmacyID, pharmacyID uuid.UUID) (*domain.ChainReport, error) {
+	if callerRole != string(domain.RoleAdmin) && (callerRole != string(domain.RoleOwner) || callerPharmacyID != pharmacyID) {
+		return nil, domain.ErrUnauthorized
+	}
+	if _, err := u.pharmacyRepo.GetByID(ctx, pharmacyID); err != nil {
+		return nil, err
+	}
+
+	// Read the head first: receipts chained after it are not checked
+	head, err := u.saleRepo.GetReceiptChainHead(ctx, pharmacyID)
+	if err != nil {
+		return nil, err
+	}
+	report := domain.ChainReport{PharmacyID: pharmacyID}
+	var after int64
+	for after < head.Position {
+		receipts, err := u.saleRepo.GetReceiptChain(ctx, pharmacyID, after, receiptChainPage)
 		if err != nil {
 			return nil, err
 		}
+		if len(receipts) == 0 {
+			break
+		}
+		for _, r := range receipts {
+			if r.ChainPosition > head.Position {
+				break
+			}
+			if err := report.Check(r); err != nil {
+				return nil, err
+			}
+		}
+		after = receipts[len(receipts)-1].ChainPosition
+	}
+	report.Finish(*head)
+	return &report, nil
+}
 
-		response = append(response, domain.SaleResponse{
-			ID:           item.ID,
-			Medicine:     medicine.Name,
-			PricePerUnit: item.PricePerUnit,
-			Unit:         variant.Unit,
-			ImageURL:     medicine.Picture,
-			Quantity:     item.Quantity,
-			CreatedAt:    item.CreatedAt,
-		})
+// RenderReceipt renders the customer's copy of a sale's receipt for printing,
+// with the header, footer and paper width of the pharmacy that made the sale
+func (u *saleUsecase) RenderReceipt(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID, format receipt.Format) ([]byte, error) {
+	r, err := u.GetReceipt(ctx, callerRole, callerPharmacyID, saleID)
+	if err != nil {
+		return nil, err
 	}
-	return response, nil
+	pharmacy, err := u.pharmacyRepo.GetByID(ctx, r.Content.PharmacyID)
+	if err != nil {
+		return nil, err
+	}
+	r.Content = r.Content.CustomerCopy()
+	return receipt.Render(receipt.Document{Pharmacy: *pharmacy, Receipt: *r}, format)
 }
 
-// GetReceipt retrieves a receipt by sale ID
-func (u *saleUsecase) GetReceipt(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID) (*domain.Receipt, error) {
+// CreateReturn returns items of a sale, refunding them at the price they were
+// sold for, and issues a credit note against the sale's receipt. A line's
+// gross and tax are refunded pro rata, rounded so that returning every unit,
+// in any number of returns, refunds exactly what was charged. The refund is
+// paid in the user's open shift: cash has to come out of a drawer, so a cash
+// refund needs one, while other refunds are made without one if need be.
+func (u *saleUsecase) CreateReturn(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, saleID uuid.UUID, input domain.CreateSaleReturnInput) (*domain.SaleReturn, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	sale, err := u.saleRepo.GetSaleByID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	if callerPharmacyID != sale.PharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+
+	refundMethod := input.RefundMethod
+	if refundMethod == "" {
+		refundMethod = domain.PaymentCash
+	}
+	var shiftID *uuid.UUID
+	shift, err := u.shiftRepo.GetOpen(ctx, callerUserID)
+	if err != nil && err != domain.ErrNoOpenShift {
+		return nil, err
+	}
+	if err == nil && shift.PharmacyID == callerPharmacyID {
+		shiftID = &shift.ID
+	}
+	if shiftID == nil && refundMethod == domain.PaymentCash {
+		return nil, domain.ErrNoOpenShift
+	}
+
+	receipt, err := u.saleRepo.GetReceiptBySaleID(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+
+	saleItems, err := u.saleRepo.GetSaleItems(ctx, saleID)
+	if err != nil {
+		return nil, err
+	}
+	variantIDs := make([]uuid.UUID, len(saleItems))
+	for i, item := range saleItems {
+		variantIDs[i] = item.MedicineVariantID
+	}
+	variants, err := u.medicineRepo.GetVariantsByIDs(ctx, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The return is priced while the repository holds off other returns
+	// against the sale, so each line continues from what they really took
+	now := time.Now()
+	return u.saleRepo.CreateReturn(ctx, saleID, func(saleItems []domain.SaleItem) (domain.SaleReturn, error) {
+		sold := make(map[uuid.UUID]domain.SaleItem, len(saleItems))
+		for _, item := range saleItems {
+			sold[item.ID] = item
+		}
+
+		saleReturn := domain.SaleReturn{
+			ID:           uuid.New(),
+			SaleID:       saleID,
+			UserID:       callerUserID,
+			ShiftID:      shiftID,
+			Reason:       input.Reason,
+			Quarantine:   input.Quarantine,
+			RefundMethod: refundMethod,
+			CreatedAt:    now,
+		}
+		var noteItems []domain.ReceiptItem
+		var totalTax domain.Money
+		var taxLines []domain.TaxLine
+		for _, in := range input.Items {
+			saleItem, ok := sold[in.SaleItemID]
+			if !ok {
+				return domain.SaleReturn{}, domain.ErrSaleItemNotFound
+			}
+
+			// Later lines for the same item continue from this one
+			before, after := saleItem.ReturnedQuantity, saleItem.ReturnedQuantity+in.Quantity
+			saleItem.ReturnedQuantity = after
+			sold[in.SaleItemID] = saleItem
+			refund := proRata(saleItem.Gross, before, after, saleItem.Quantity)
+			tax := proRata(saleItem.Tax, before, after, saleItem.Quantity)
+
+			item := domain.SaleReturnItem{
+				ID:                uuid.New(),
+				ReturnID:          saleReturn.ID,
+				SaleItemID:        saleItem.ID,
+				MedicineVariantID: saleItem.MedicineVariantID,
+				Quantity:          in.Quantity,
+				PricePerUnit:      saleItem.PricePerUnit,
+				UnitCost:          saleItem.UnitCost,
+				Refund:            refund,
+				Tax:               tax,
+			}
+			saleReturn.Items = append(saleReturn.Items, item)
+			saleReturn.TotalRefund += item.Refund
+			totalTax += tax
+			taxLines = domain.AddTaxLine(taxLines, saleItem.TaxCategory, saleItem.TaxRate, refund-tax, tax, refund)
+
+			noteItems = append(noteItems, domain.ReceiptItem{
+				Brand:        variants[saleItem.MedicineVariantID].Brand,
+				MedicineName: saleItem.MedicineName,
+				PricePerUnit: item.PricePerUnit,
+				Quantity:     item.Quantity,
+				Subtotal:     item.Refund,
+				TaxCategory:  saleItem.TaxCategory,
+				TaxRate:      saleItem.TaxRate,
+				Net:          refund - tax,
+				Tax:          tax,
+				Gross:        refund,
+			})
+		}
+
+		saleReturn.CreditNote = domain.CreditNote{
+			ID:        uuid.New(),
+			ReturnID:  saleReturn.ID,
+			ReceiptID: receipt.ID,
+			Content: domain.CreditNoteContent{
+				Items:       noteItems,
+				PharmacyID:  sale.PharmacyID,
+				SaleID:      saleID,
+				ReceiptID:   receipt.ID,
+				Reason:      input.Reason,
+				ReturnDate:  now,
+				TotalRefund: saleReturn.TotalRefund,
+				TotalTax:    totalTax,
+				TaxLines:    taxLines,
+			},
+			CreatedAt: now,
+		}
+		return saleReturn, nil
+	})
+}
+
+// ReleaseExpiredReservations releases the cart reservations that have run
+// out, returning how many were released
+func (u *saleUsecase) ReleaseExpiredReservations(ctx context.Context) (int, error) {
+	return u.saleRepo.ReleaseExpiredReservations(ctx, time.Now())
+}
+
+// GetReturns retrieves the returns made against a sale
+func (u *saleUsecase) GetReturns(ctx context.Context, callerRole string, callerPharmacyID, saleID uuid.UUID) ([]domain.SaleReturn, error) {
 	if callerRole != string(domain.RoleAdmin) && callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
 		return nil, domain.ErrUnauthorized
 	}
@@ -289,5 +1160,11 @@ func (u *saleUsecase) GetReceipt(ctx context.Context, callerRole string, callerP
 		return nil, domain.ErrUnauthorized
 	}
 
-	return u.saleRepo.GetReceiptBySaleID(ctx, saleID)
+	return u.saleRepo.GetReturns(ctx, saleID)
+}
+
+// proRata is the share of amount for units before+1..after of quantity, taken
+// as the difference of two cumulative shares so the rounding never drifts
+func proRata(amount domain.Money, before, after, quantity int) domain.Money {
+	return amount.Mul(after).Div(quantity) - amount.Mul(before).Div(quantity)
 }
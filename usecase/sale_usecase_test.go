@@ -0,0 +1,473 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+	"pharmacy-management-backend/repository/memory"
+	"pharmacy-management-backend/usecase"
+
+	"github.com/google/uuid"
+)
+
+// saleFixture is a pharmacy with one pharmacist on an open shift and one
+// variant in stock, over an in-memory store. calls counts the reads the sale
+// use case makes through its repositories.
+type saleFixture struct {
+	calls      *atomic.Int64
+	sales      usecase.SaleUsecase
+	medicines  repository.MedicineRepository
+	promotions repository.PromotionRepository
+	shifts     repository.ShiftRepository
+	pharmacy   domain.Pharmacy
+	user       domain.User
+	shift      domain.Shift
+	variant    domain.MedicineVariant
+}
+
+func newSaleFixture(t *testing.T, tax domain.TaxSettings, price domain.Money) saleFixture {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	store := memory.NewStore()
+	medicineRepo := memory.NewMedicineRepository(store)
+	saleRepo := memory.NewSaleRepository(store)
+	pharmacyRepo := memory.NewPharmacyRepository(store)
+	promotionRepo := memory.NewPromotionRepository(store)
+	shiftRepo := memory.NewShiftRepository(store)
+
+	calls := new(atomic.Int64)
+	f := saleFixture{
+		calls: calls,
+		sales: usecase.NewSaleUsecase(countingSaleRepo{saleRepo, calls}, countingMedicineRepo{medicineRepo, calls},
+			countingPharmacyRepo{pharmacyRepo, calls}, countingPromotionRepo{promotionRepo, calls}, shiftRepo,
+			memory.NewCustomerRepository(store), nil, time.Hour, 15*time.Minute, ""),
+		medicines:  medicineRepo,
+		promotions: promotionRepo,
+		shifts:     shiftRepo,
+	}
+	f.pharmacy = domain.Pharmacy{ID: uuid.New(), Name: "Central Pharmacy", Address: "1 Main St", TaxSettings: tax,
+		ReceiptSettings: domain.ReceiptSettings{PaperWidth: 80}, CreatedAt: now, UpdatedAt: now}
+	mustNoErr(t, pharmacyRepo.Create(ctx, f.pharmacy))
+	f.user = domain.User{ID: uuid.New(), PhoneNumber: "+251911000001", Password: "hashed", FullName: "Test Pharmacist",
+		Role: domain.RolePharmacist, PharmacyID: f.pharmacy.ID, CreatedAt: now, UpdatedAt: now}
+	mustNoErr(t, memory.NewAuthRepository(store).Create(ctx, f.user))
+
+	f.variant = f.newVariant(t, "Ibuprofen", price)
+
+	f.shift = domain.Shift{ID: uuid.New(), PharmacyID: f.pharmacy.ID, UserID: f.user.ID, Status: domain.ShiftOpen, OpenedAt: now}
+	mustNoErr(t, shiftRepo.Create(ctx, f.shift))
+	return f
+}
+
+// countingSaleRepo and the other counting repositories count the reads a
+// use case can make once per cart line or sale, standing in for the round
+// trips a Postgres repository would make
+type countingSaleRepo struct {
+	repository.SaleRepository
+	calls *atomic.Int64
+}
+
+func (r countingSaleRepo) GetCart(ctx context.Context, userID uuid.UUID) ([]domain.Cart, error) {
+	r.calls.Add(1)
+	return r.SaleRepository.GetCart(ctx, userID)
+}
+
+func (r countingSaleRepo) GetSales(ctx context.Context, filter domain.SaleFilter) (*domain.SalePage, error) {
+	r.calls.Add(1)
+	return r.SaleRepository.GetSales(ctx, filter)
+}
+
+func (r countingSaleRepo) GetSaleByID(ctx context.Context, saleID uuid.UUID) (*domain.Sale, error) {
+	r.calls.Add(1)
+	return r.SaleRepository.GetSaleByID(ctx, saleID)
+}
+
+func (r countingSaleRepo) GetSaleItems(ctx context.Context, saleID uuid.UUID) ([]domain.SaleItem, error) {
+	r.calls.Add(1)
+	return r.SaleRepository.GetSaleItems(ctx, saleID)
+}
+
+type countingMedicineRepo struct {
+	repository.MedicineRepository
+	calls *atomic.Int64
+}
+
+func (r countingMedicineRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Medicine, error) {
+	r.calls.Add(1)
+	return r.MedicineRepository.GetByID(ctx, id)
+}
+
+func (r countingMedicineRepo) GetByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.Medicine, error) {
+	r.calls.Add(1)
+	return r.MedicineRepository.GetByIDs(ctx, ids)
+}
+
+func (r countingMedicineRepo) GetVariantByID(ctx context.Context, id uuid.UUID) (*domain.MedicineVariant, error) {
+	r.calls.Add(1)
+	return r.MedicineRepository.GetVariantByID(ctx, id)
+}
+
+func (r countingMedicineRepo) GetVariantsByIDs(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]domain.MedicineVariant, error) {
+	r.calls.Add(1)
+	return r.MedicineRepository.GetVariantsByIDs(ctx, ids)
+}
+
+type countingPharmacyRepo struct {
+	repository.PharmacyRepository
+	calls *atomic.Int64
+}
+
+func (r countingPharmacyRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Pharmacy, error) {
+	r.calls.Add(1)
+	return r.PharmacyRepository.GetByID(ctx, id)
+}
+
+type countingPromotionRepo struct {
+	repository.PromotionRepository
+	calls *atomic.Int64
+}
+
+func (r countingPromotionRepo) GetRunning(ctx context.Context, pharmacyID uuid.UUID, at time.Time) ([]domain.Promotion, error) {
+	r.calls.Add(1)
+	return r.PromotionRepository.GetRunning(ctx, pharmacyID, at)
+}
+
+func (r countingPromotionRepo) GetApprovedManualDiscounts(ctx context.Context, userID uuid.UUID) ([]domain.ManualDiscount, error) {
+	r.calls.Add(1)
+	return r.PromotionRepository.GetApprovedManualDiscounts(ctx, userID)
+}
+
+// newVariant creates a medicine with one variant holding 20 units
+func (f saleFixture) newVariant(t *testing.T, name string, price domain.Money) domain.MedicineVariant {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	medicine := domain.Medicine{ID: uuid.New(), PharmacyID: f.pharmacy.ID, Name: name, TaxCategory: domain.TaxStandard,
+		CreatedAt: now, UpdatedAt: now}
+	mustNoErr(t, f.medicines.Create(ctx, medicine))
+	variant := domain.MedicineVariant{ID: uuid.New(), MedicineID: medicine.ID, Brand: name, Barcode: "BC" + uuid.NewString()[:8], Unit: "box",
+		PricePerUnit: price, ExpiryDate: now.AddDate(1, 0, 0), Stock: 20, CreatedAt: now, UpdatedAt: now}
+	lot := domain.MedicineLot{ID: uuid.New(), VariantID: variant.ID, LotNumber: "L1", ExpiryDate: variant.ExpiryDate, Quantity: 20,
+		ReceivedAt: now, CreatedAt: now, UpdatedAt: now}
+	mustNoErr(t, f.medicines.CreateVariant(ctx, variant, []domain.MedicineLot{lot}, uuid.Nil))
+	return variant
+}
+
+// addToCart puts quantity units of the fixture's variant in the cart
+func (f saleFixture) addToCart(t *testing.T, quantity int) {
+	t.Helper()
+	f.add(t, f.variant, quantity)
+}
+
+// add puts quantity units of a variant in the cart
+func (f saleFixture) add(t *testing.T, variant domain.MedicineVariant, quantity int) {
+	t.Helper()
+	mustNoErr(t, f.sales.AddToCart(context.Background(), string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID,
+		domain.CreateCartInput{MedicineVariantID: variant.ID, Quantity: quantity}))
+}
+
+// confirm confirms the cart as a sale
+func (f saleFixture) confirm(key string, input domain.ConfirmSaleInput) (*domain.Sale, error) {
+	return f.sales.ConfirmSale(context.Background(), string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID, key, input)
+}
+
+func mustNoErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func cash(amount domain.Money) domain.ConfirmSaleInput {
+	return domain.ConfirmSaleInput{Tenders: []domain.TenderInput{{Method: domain.PaymentCash, Amount: amount}}}
+}
+
+func TestConfirmSaleReplay(t *testing.T) {
+	f := newSaleFixture(t, domain.TaxSettings{}, 1000)
+	f.addToCart(t, 2)
+
+	sale, err := f.confirm("retry-1", cash(5000))
+	mustNoErr(t, err)
+	first, err := json.Marshal(sale)
+	mustNoErr(t, err)
+
+	replayed, err := f.confirm("retry-1", cash(5000))
+	mustNoErr(t, err)
+	second, err := json.Marshal(replayed)
+	mustNoErr(t, err)
+	if !bytes.Equal(first, second) {
+		t.Fatalf("replay differs from the original sale:\n%s\n%s", first, second)
+	}
+
+	// The same key with a different body is refused rather than replayed
+	if _, err := f.confirm("retry-1", cash(2000)); !errors.Is(err, domain.ErrIdempotencyKeyReused) {
+		t.Fatalf("expected ErrIdempotencyKeyReused, got %v", err)
+	}
+}
+
+func TestConfirmSaleTaxDiscountsAndTenders(t *testing.T) {
+	tests := []struct {
+		name  string
+		tax   domain.TaxSettings
+		price domain.Money
+	}{
+		{"tax added", domain.TaxSettings{VATRate: 1500}, 1000},
+		{"tax included", domain.TaxSettings{VATRate: 1500, PricesIncludeTax: true}, 1150},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newSaleFixture(t, tt.tax, tt.price)
+			now := time.Now()
+			mustNoErr(t, f.promotions.Create(ctx, domain.Promotion{ID: uuid.New(), PharmacyID: f.pharmacy.ID, Name: "10% off",
+				Type: domain.PromotionPercentage, Scope: domain.PromotionScopeBasket, Rate: 1000, Active: true,
+				StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), CreatedAt: now, UpdatedAt: now}))
+			f.addToCart(t, 3)
+
+			// Either way 10% comes off before tax, leaving 27.00 net and
+			// 4.05 tax, paid partly by card with change from the cash
+			sale, err := f.confirm("", domain.ConfirmSaleInput{Tenders: []domain.TenderInput{
+				{Method: domain.PaymentCard, Amount: 2000},
+				{Method: domain.PaymentCash, Amount: 2000},
+			}})
+			mustNoErr(t, err)
+			if sale.TotalDiscount != tt.price.Mul(3).Div(10) || sale.TotalNet != 2700 || sale.TotalTax != 405 || sale.TotalPrice != 3105 {
+				t.Fatalf("unexpected totals: discount %d, net %d, tax %d, gross %d", sale.TotalDiscount, sale.TotalNet, sale.TotalTax, sale.TotalPrice)
+			}
+			if len(sale.Discounts) != 1 || sale.Discounts[0].Amount != sale.TotalDiscount {
+				t.Fatalf("expected the basket promotion applied, got %+v", sale.Discounts)
+			}
+			if sale.Change != 895 || len(sale.Payments) != 2 || sale.Payments[0].Change != 0 || sale.Payments[1].Change != 895 {
+				t.Fatalf("expected 8.95 change from the cash tender, got %s in %+v", sale.Change, sale.Payments)
+			}
+
+			receipt, err := f.sales.GetReceipt(ctx, string(domain.RolePharmacist), f.pharmacy.ID, sale.ID)
+			mustNoErr(t, err)
+			content := receipt.Content
+			if content.TotalPrice != sale.TotalPrice || content.TotalTax != sale.TotalTax || len(content.TaxLines) != 1 ||
+				content.TaxLines[0].Tax != sale.TotalTax || content.TaxLines[0].Gross != sale.TotalPrice {
+				t.Fatalf("receipt does not match the sale: %+v", content)
+			}
+
+			cart, err := f.sales.GetCart(ctx, string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID)
+			mustNoErr(t, err)
+			if len(cart.Items) != 0 {
+				t.Fatalf("expected the cart checked out, got %+v", cart.Items)
+			}
+		})
+	}
+}
+
+func TestConfirmSaleUnderpayment(t *testing.T) {
+	ctx := context.Background()
+	f := newSaleFixture(t, domain.TaxSettings{}, 1000)
+	f.addToCart(t, 2)
+
+	if _, err := f.confirm("retry-1", cash(1999)); !errors.Is(err, domain.ErrInsufficientPayment) {
+		t.Fatalf("expected ErrInsufficientPayment, got %v", err)
+	}
+	cart, err := f.sales.GetCart(ctx, string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID)
+	mustNoErr(t, err)
+	if len(cart.Items) != 1 || cart.Total != 2000 {
+		t.Fatalf("a refused sale must leave the cart alone, got %+v", cart)
+	}
+
+	// The failed attempt released its key, so the retry goes through
+	sale, err := f.confirm("retry-1", cash(2000))
+	mustNoErr(t, err)
+	if sale.TotalPrice != 2000 || sale.Change != 0 {
+		t.Fatalf("unexpected sale %+v", sale)
+	}
+}
+
+func TestSyncSalesPricesAndShift(t *testing.T) {
+	ctx := context.Background()
+	f := newSaleFixture(t, domain.TaxSettings{}, 1000)
+	created := f.variant.CreatedAt
+	raised := f.variant
+	raised.PricePerUnit, raised.UpdatedAt = 1200, created.Add(time.Minute)
+	mustNoErr(t, f.medicines.UpdateVariant(ctx, raised))
+
+	sale := func(soldAt time.Time, price domain.Money) domain.SyncSaleInput {
+		return domain.SyncSaleInput{ID: uuid.New(), SoldAt: soldAt,
+			Items:   []domain.SyncSaleItemInput{{MedicineVariantID: f.variant.ID, Quantity: 1, PricePerUnit: price}},
+			Tenders: []domain.TenderInput{{Method: domain.PaymentCash, Amount: price}}}
+	}
+	sync := func(sales ...domain.SyncSaleInput) []domain.SyncSaleResult {
+		t.Helper()
+		results, err := f.sales.SyncSales(ctx, string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID, domain.SyncSalesInput{Sales: sales})
+		mustNoErr(t, err)
+		return results
+	}
+
+	// Each sale is checked against the price in effect when it was made
+	results := sync(sale(created.Add(-time.Hour), 1000), sale(created, 1000), sale(created.Add(2*time.Minute), 1000),
+		sale(created.Add(2*time.Minute), 1200))
+	for i, status := range []domain.SyncStatus{domain.SyncCreated, domain.SyncCreated, domain.SyncConflict, domain.SyncCreated} {
+		if results[i].Status != status || results[i].Warnings != nil {
+			t.Fatalf("sale %d: expected %s without warnings, got %+v", i, status, results[i])
+		}
+	}
+	conflict := results[2]
+	if conflict.Error != domain.ErrPriceMismatch.Error() || len(conflict.PriceConflicts) != 1 ||
+		conflict.PriceConflicts[0] != (domain.PriceConflict{MedicineVariantID: f.variant.ID, Charged: 1000, Price: 1200}) {
+		t.Fatalf("expected a price conflict, got %+v", conflict)
+	}
+	if _, err := f.sales.GetSale(ctx, string(domain.RolePharmacist), f.pharmacy.ID, conflict.ID); !errors.Is(err, domain.ErrSaleNotFound) {
+		t.Fatalf("a price conflict must record nothing, got %v", err)
+	}
+
+	// Without an open shift a sale is still recorded, with a warning
+	mustNoErr(t, f.shifts.Close(ctx, f.shift.ID, nil, time.Now()))
+	results = sync(sale(created.Add(2*time.Minute), 1200))
+	if results[0].Status != domain.SyncCreated || len(results[0].Warnings) != 1 || results[0].Warnings[0] != domain.SyncWarningNoShift {
+		t.Fatalf("expected the sale created with a no-shift warning, got %+v", results[0])
+	}
+}
+
+// Concurrent partial returns each continue from the others, so together they
+// refund exactly what the line was charged
+func TestCreateReturnConcurrent(t *testing.T) {
+	ctx := context.Background()
+	f := newSaleFixture(t, domain.TaxSettings{}, 1000)
+	now := time.Now()
+	mustNoErr(t, f.promotions.Create(ctx, domain.Promotion{ID: uuid.New(), PharmacyID: f.pharmacy.ID, Name: "1.00 off",
+		Type: domain.PromotionFixed, Scope: domain.PromotionScopeBasket, Amount: 100, Active: true,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), CreatedAt: now, UpdatedAt: now}))
+	f.addToCart(t, 3)
+	sale, err := f.confirm("", cash(2900))
+	mustNoErr(t, err)
+	detail, err := f.sales.GetSale(ctx, string(domain.RolePharmacist), f.pharmacy.ID, sale.ID)
+	mustNoErr(t, err)
+	saleItemID := detail.Items[0].ID
+
+	var wg sync.WaitGroup
+	refunds := make([]domain.Money, 3)
+	for i := range refunds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ret, err := f.sales.CreateReturn(ctx, string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID, sale.ID, domain.CreateSaleReturnInput{
+				Items: []domain.SaleReturnItemInput{{SaleItemID: saleItemID, Quantity: 1}}, Reason: "changed mind"})
+			if err != nil {
+				t.Errorf("return %d: %v", i, err)
+				return
+			}
+			refunds[i] = ret.TotalRefund
+		}()
+	}
+	wg.Wait()
+
+	var total domain.Money
+	for _, r := range refunds {
+		total += r
+	}
+	if total != 2900 {
+		t.Fatalf("returns refunded %v, want the 29.00 charged", refunds)
+	}
+}
+
+// A cash refund comes out of the shift's drawer, so the Z-report expects that
+// much less cash instead of showing an overage
+func TestCreateReturnRefundInShift(t *testing.T) {
+	ctx := context.Background()
+	f := newSaleFixture(t, domain.TaxSettings{}, 1000)
+	f.addToCart(t, 3)
+	sale, err := f.confirm("", cash(3000))
+	mustNoErr(t, err)
+	detail, err := f.sales.GetSale(ctx, string(domain.RolePharmacist), f.pharmacy.ID, sale.ID)
+	mustNoErr(t, err)
+	refund := func(method domain.PaymentMethod) (*domain.SaleReturn, error) {
+		return f.sales.CreateReturn(ctx, string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID, sale.ID, domain.CreateSaleReturnInput{
+			Items: []domain.SaleReturnItemInput{{SaleItemID: detail.Items[0].ID, Quantity: 1}}, Reason: "changed mind", RefundMethod: method})
+	}
+
+	ret, err := refund("")
+	mustNoErr(t, err)
+	if ret.RefundMethod != domain.PaymentCash || ret.ShiftID == nil || *ret.ShiftID != f.shift.ID {
+		t.Fatalf("expected a cash refund in the open shift, got %+v", ret)
+	}
+
+	report, err := usecase.NewShiftUsecase(f.shifts).Close(ctx, string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID, f.shift.ID,
+		domain.CloseShiftInput{Counts: []domain.TenderCount{{Method: domain.PaymentCash, Amount: 2000}}})
+	mustNoErr(t, err)
+	if report.Variance != 0 || report.ReturnCount != 1 || report.TotalRefunds != 1000 ||
+		report.Tenders[0].Refunded != 1000 || report.Tenders[0].Expected != 2000 {
+		t.Fatalf("expected the refund off the cash expected, got %+v", report)
+	}
+
+	// Cash cannot be paid out with the shift closed; a card refund still can
+	if _, err := refund(domain.PaymentCash); !errors.Is(err, domain.ErrNoOpenShift) {
+		t.Fatalf("expected ErrNoOpenShift, got %v", err)
+	}
+	ret, err = refund(domain.PaymentCard)
+	mustNoErr(t, err)
+	if ret.ShiftID != nil {
+		t.Fatalf("expected a card refund outside any shift, got %+v", ret)
+	}
+}
+
+// TestSaleUsecaseRepositoryCalls checks that reading a cart or a page of sales
+// makes the same number of repository calls however many lines or rows it has
+func TestSaleUsecaseRepositoryCalls(t *testing.T) {
+	ctx := context.Background()
+	f := newSaleFixture(t, domain.TaxSettings{VATRate: 1500}, 250)
+
+	countCart := func(lines int) int64 {
+		t.Helper()
+		before := f.calls.Load()
+		cart, err := f.sales.GetCart(ctx, string(domain.RolePharmacist), f.user.ID, f.pharmacy.ID)
+		mustNoErr(t, err)
+		if len(cart.Items) != lines {
+			t.Fatalf("GetCart returned %d lines, want %d", len(cart.Items), lines)
+		}
+		return f.calls.Load() - before
+	}
+	variants := []domain.MedicineVariant{f.variant}
+	f.addToCart(t, 1)
+	one := countCart(1)
+	// Every line is a different medicine, so nothing is looked up twice
+	for i := 1; i < 50; i++ {
+		variants = append(variants, f.newVariant(t, fmt.Sprintf("Medicine %02d", i), 250))
+		f.add(t, variants[i], 1)
+	}
+	if fifty := countCart(50); fifty != one {
+		t.Fatalf("GetCart made %d repository calls for 50 lines and %d for 1", fifty, one)
+	}
+
+	countSales := func(rows int) int64 {
+		t.Helper()
+		before := f.calls.Load()
+		page, err := f.sales.GetSales(ctx, string(domain.RolePharmacist), f.pharmacy.ID, domain.SaleFilter{Limit: 100})
+		mustNoErr(t, err)
+		if len(page.Sales) != rows {
+			t.Fatalf("GetSales returned %d rows, want %d", len(page.Sales), rows)
+		}
+		return f.calls.Load() - before
+	}
+	sell := func(i int) {
+		t.Helper()
+		f.add(t, variants[i%len(variants)], 1)
+		_, err := f.confirm("", cash(1000))
+		mustNoErr(t, err)
+	}
+	mustNoErr(t, f.sales.ClearCart(ctx, string(domain.RolePharmacist), f.user.ID))
+	sell(0)
+	one = countSales(1)
+	for i := 1; i < 100; i++ {
+		sell(i)
+	}
+	if hundred := countSales(100); hundred != one {
+		t.Fatalf("GetSales made %d repository calls for 100 rows and %d for 1", hundred, one)
+	}
+}
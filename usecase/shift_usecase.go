@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// ShiftUsecase defines the interface for cashier shift business logic
+type ShiftUsecase interface {
+	Open(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.OpenShiftInput) (*domain.Shift, error)
+	GetCurrent(ctx context.Context, callerRole string, callerUserID uuid.UUID) (*domain.Shift, error)
+	Close(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, id uuid.UUID, input domain.CloseShiftInput) (*domain.ShiftReport, error)
+	GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, limit, offset int) ([]domain.Shift, error)
+	GetReport(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.ShiftReport, error)
+}
+
+// shiftUsecase implements ShiftUsecase
+type shiftUsecase struct {
+	repo repository.ShiftRepository
+}
+
+// NewShiftUsecase creates a new ShiftUsecase
+func NewShiftUsecase(repo repository.ShiftRepository) ShiftUsecase {
+	return &shiftUsecase{repo}
+}
+
+// Open opens a shift for the caller with a float in the cash drawer
+func (u *shiftUsecase) Open(ctx context.Context, callerRole string, callerUserID, callerPharmacyID uuid.UUID, input domain.OpenShiftInput) (*domain.Shift, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	shift := domain.Shift{
+		ID:           uuid.New(),
+		PharmacyID:   callerPharmacyID,
+		UserID:       callerUserID,
+		OpeningFloat: input.OpeningFloat,
+		Status:       domain.ShiftOpen,
+		OpenedAt:     time.Now(),
+	}
+	if err := u.repo.Create(ctx, shift); err != nil {
+		return nil, err
+	}
+	return &shift, nil
+}
+
+// GetCurrent retrieves the caller's open shift
+func (u *shiftUsecase) GetCurrent(ctx context.Context, callerRole string, callerUserID uuid.UUID) (*domain.Shift, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.repo.GetOpen(ctx, callerUserID)
+}
+
+// Close closes a shift with the amounts counted and returns its Z-report.
+// Cashiers close their own shifts; owners can close any in their pharmacy.
+func (u *shiftUsecase) Close(ctx context.Context, callerRole string, callerUserID, callerPharmacyID, id uuid.UUID, input domain.CloseShiftInput) (*domain.ShiftReport, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	counted := make(map[domain.PaymentMethod]bool)
+	for _, c := range input.Counts {
+		if counted[c.Method] {
+			return nil, domain.ErrDuplicateCount
+		}
+		counted[c.Method] = true
+	}
+	if !counted[domain.PaymentCash] {
+		return nil, domain.ErrCashCountRequired
+	}
+
+	shift, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shift.PharmacyID != callerPharmacyID || (shift.UserID != callerUserID && callerRole != string(domain.RoleOwner)) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	if err := u.repo.Close(ctx, id, input.Counts, time.Now()); err != nil {
+		return nil, err
+	}
+	return u.report(ctx, id)
+}
+
+// GetAll lists the caller's pharmacy shifts, latest first
+func (u *shiftUsecase) GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, limit, offset int) ([]domain.Shift, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.repo.GetAll(ctx, callerPharmacyID, limit, offset)
+}
+
+// GetReport retrieves a shift's Z-report. An open shift's report shows
+// what it has taken so far, with nothing counted.
+func (u *shiftUsecase) GetReport(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.ShiftReport, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	shift, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if shift.PharmacyID != callerPharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.report(ctx, id)
+}
+
+// report builds a shift's Z-report
+func (u *shiftUsecase) report(ctx context.Context, id uuid.UUID) (*domain.ShiftReport, error) {
+	shift, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := u.repo.GetTotals(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	report := domain.NewShiftReport(*shift, *totals)
+	return &report, nil
+}
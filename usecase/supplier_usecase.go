@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"pharmacy-management-backend/domain"
+	"pharmacy-management-backend/repository"
+
+	"github.com/google/uuid"
+)
+
+// SupplierUsecase defines the interface for supplier business logic
+type SupplierUsecase interface {
+	Create(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, input domain.SupplierInput) (*domain.Supplier, error)
+	GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.Supplier, error)
+	GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID) ([]domain.Supplier, error)
+	Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.SupplierInput) (*domain.Supplier, error)
+	Delete(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) error
+}
+
+// supplierUsecase implements SupplierUsecase
+type supplierUsecase struct {
+	repo repository.SupplierRepository
+}
+
+// NewSupplierUsecase creates a new SupplierUsecase
+func NewSupplierUsecase(repo repository.SupplierRepository) SupplierUsecase {
+	return &supplierUsecase{repo}
+}
+
+// Create adds a supplier to the caller's pharmacy
+func (u *supplierUsecase) Create(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID, input domain.SupplierInput) (*domain.Supplier, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	now := time.Now()
+	supplier := domain.Supplier{
+		ID:          uuid.New(),
+		PharmacyID:  callerPharmacyID,
+		Name:        input.Name,
+		ContactName: input.ContactName,
+		PhoneNumber: input.PhoneNumber,
+		Email:       input.Email,
+		Address:     input.Address,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := u.repo.Create(ctx, supplier); err != nil {
+		return nil, err
+	}
+	return &supplier, nil
+}
+
+// GetByID retrieves one of the caller's pharmacy suppliers
+func (u *supplierUsecase) GetByID(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) (*domain.Supplier, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.get(ctx, callerPharmacyID, id)
+}
+
+// GetAll lists the caller's pharmacy suppliers
+func (u *supplierUsecase) GetAll(ctx context.Context, callerRole string, callerPharmacyID uuid.UUID) ([]domain.Supplier, error) {
+	if callerRole != string(domain.RoleOwner) && callerRole != string(domain.RolePharmacist) {
+		return nil, domain.ErrUnauthorized
+	}
+	return u.repo.GetAll(ctx, callerPharmacyID)
+}
+
+// Update updates one of the caller's pharmacy suppliers
+func (u *supplierUsecase) Update(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID, input domain.SupplierInput) (*domain.Supplier, error) {
+	if callerRole != string(domain.RoleOwner) {
+		return nil, domain.ErrUnauthorized
+	}
+
+	supplier, err := u.get(ctx, callerPharmacyID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	supplier.Name = input.Name
+	supplier.ContactName = input.ContactName
+	supplier.PhoneNumber = input.PhoneNumber
+	supplier.Email = input.Email
+	supplier.Address = input.Address
+	supplier.UpdatedAt = time.Now()
+	if err := u.repo.Update(ctx, *supplier); err != nil {
+		return nil, err
+	}
+	return supplier, nil
+}
+
+// Delete deletes a supplier no purchase order was placed with
+func (u *supplierUsecase) Delete(ctx context.Context, callerRole string, callerPharmacyID, id uuid.UUID) error {
+	if callerRole != string(domain.RoleOwner) {
+		return domain.ErrUnauthorized
+	}
+
+	if _, err := u.get(ctx, callerPharmacyID, id); err != nil {
+		return err
+	}
+
+	count, err := u.repo.CountPurchaseOrders(ctx, id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return domain.ErrSupplierHasPurchaseOrders
+	}
+
+	return u.repo.Delete(ctx, id)
+}
+
+// get retrieves a supplier, restricted to the caller's pharmacy
+func (u *supplierUsecase) get(ctx context.Context, callerPharmacyID, id uuid.UUID) (*domain.Supplier, error) {
+	supplier, err := u.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if supplier.PharmacyID != callerPharmacyID {
+		return nil, domain.ErrUnauthorized
+	}
+	return supplier, nil
+}